@@ -0,0 +1,58 @@
+package common
+
+import (
+	core_v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+
+	"github.com/kiali/kiali/kubernetes"
+)
+
+// GetSelectingServicePorts returns the port names of the Services (by selector) and of the ServiceEntries (by
+// workloadSelector) selecting the workload labels, and whether any of them selects those labels
+func GetSelectingServicePorts(workloadLabels map[string]string, services []core_v1.Service, serviceEntries []kubernetes.IstioObject) (map[string]bool, bool) {
+	ports := map[string]bool{}
+	selected := false
+	labelSet := labels.Set(workloadLabels)
+
+	for _, s := range services {
+		if len(s.Spec.Selector) == 0 || !labels.SelectorFromSet(s.Spec.Selector).Matches(labelSet) {
+			continue
+		}
+		selected = true
+		for _, p := range s.Spec.Ports {
+			ports[p.Name] = true
+		}
+	}
+
+	for _, se := range serviceEntries {
+		selector := GetWorkloadSelectorLabels(se)
+		if len(selector) == 0 || !labels.SelectorFromSet(selector).Matches(labelSet) {
+			continue
+		}
+		selected = true
+		if sePorts, ok := se.GetSpec()["ports"].([]interface{}); ok {
+			for _, p := range sePorts {
+				if port, ok := p.(map[string]interface{}); ok {
+					if name, ok := port["name"].(string); ok {
+						ports[name] = true
+					}
+				}
+			}
+		}
+	}
+
+	return ports, selected
+}
+
+// GetStringMap returns a map of strings from a spec field, non string values are ignored
+func GetStringMap(field interface{}) map[string]string {
+	result := map[string]string{}
+	if m, ok := field.(map[string]interface{}); ok {
+		for k, v := range m {
+			if s, ok := v.(string); ok {
+				result[k] = s
+			}
+		}
+	}
+	return result
+}
@@ -0,0 +1,73 @@
+package destinationrules
+
+import (
+	"github.com/kiali/kiali/kubernetes"
+	"github.com/kiali/kiali/models"
+)
+
+// ExportedMultiMatchChecker validates that no two DestinationRules of different namespaces, both exported to every
+// namespace, target the same host: namespaces other than theirs would see two rules for the host.
+// DestinationRules without exportTo are exported to every namespace.
+type ExportedMultiMatchChecker struct {
+	DestinationRules []kubernetes.IstioObject
+	Namespaces       models.Namespaces
+}
+
+// Check validates that no two DestinationRules exported to every namespace target the same host
+func (m ExportedMultiMatchChecker) Check() models.IstioValidations {
+	validations := models.IstioValidations{}
+	seenHosts := make(map[string][]models.IstioValidationKey)
+
+	for _, dr := range m.DestinationRules {
+		dHost, ok := dr.GetSpec()["host"].(string)
+		if !ok || !isExportedToAll(dr) {
+			continue
+		}
+		fqdn := kubernetes.GetHost(dHost, dr.GetObjectMeta().Namespace, dr.GetObjectMeta().ClusterName, m.Namespaces.GetNames())
+		host := fqdn.Service + "." + fqdn.Namespace
+		key := models.BuildKey(DestinationRulesCheckerType, dr.GetObjectMeta().Name, dr.GetObjectMeta().Namespace)
+
+		for _, refKey := range seenHosts[host] {
+			if refKey.Namespace == key.Namespace {
+				// Same namespace conflicts are reported by the MultiMatchChecker
+				continue
+			}
+			currentValidation := createExportedMultiMatchWarning(key)
+			refValidation := createExportedMultiMatchWarning(refKey)
+			refValidation = refValidation.MergeReferences(currentValidation)
+			currentValidation = currentValidation.MergeReferences(refValidation)
+			validations = validations.MergeValidations(refValidation)
+			validations = validations.MergeValidations(currentValidation)
+		}
+		seenHosts[host] = append(seenHosts[host], key)
+	}
+
+	return validations
+}
+
+func isExportedToAll(dr kubernetes.IstioObject) bool {
+	exportTo, found := dr.GetSpec()["exportTo"].([]interface{})
+	if !found || len(exportTo) == 0 {
+		return true
+	}
+	for _, ns := range exportTo {
+		if ns == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+func createExportedMultiMatchWarning(key models.IstioValidationKey) models.IstioValidations {
+	check := models.Build("destinationrules.multimatch.exported", "spec/host")
+	validation := &models.IstioValidation{
+		Name:       key.Name,
+		ObjectType: DestinationRulesCheckerType,
+		Valid:      true,
+		Checks: []*models.IstioCheck{
+			&check,
+		},
+	}
+
+	return models.IstioValidations{key: validation}
+}
@@ -0,0 +1,65 @@
+package destinationrules
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/kiali/kiali/config"
+	"github.com/kiali/kiali/kubernetes"
+	"github.com/kiali/kiali/models"
+	"github.com/kiali/kiali/tests/data"
+)
+
+func exportedDestinationRule(namespace, name, host string, exportTo ...interface{}) kubernetes.IstioObject {
+	dr := data.CreateTestDestinationRule(namespace, name, host)
+	if len(exportTo) > 0 {
+		dr.GetSpec()["exportTo"] = exportTo
+	}
+	return dr
+}
+
+func TestExportedMultiMatch(t *testing.T) {
+	config.Set(config.NewConfig())
+	assert := assert.New(t)
+
+	namespaces := models.Namespaces{{Name: "bookinfo"}, {Name: "bookinfo2"}, {Name: "bookinfo3"}}
+	validations := ExportedMultiMatchChecker{
+		DestinationRules: []kubernetes.IstioObject{
+			exportedDestinationRule("bookinfo", "reviews", "reviews", "*"),
+			exportedDestinationRule("bookinfo2", "reviews", "reviews.bookinfo.svc.cluster.local"),
+			exportedDestinationRule("bookinfo3", "reviews", "reviews.bookinfo", "."),
+		},
+		Namespaces: namespaces,
+	}.Check()
+
+	assert.Len(validations, 2)
+	for _, ns := range []string{"bookinfo", "bookinfo2"} {
+		validation, ok := validations[models.BuildKey(DestinationRulesCheckerType, "reviews", ns)]
+		assert.True(ok)
+		assert.True(validation.Valid)
+		assert.Len(validation.Checks, 1)
+		assert.Equal(models.CheckMessage("destinationrules.multimatch.exported"), validation.Checks[0].Message)
+		assert.Equal(models.WarningSeverity, validation.Checks[0].Severity)
+		assert.Equal("spec/host", validation.Checks[0].Path)
+		assert.Len(validation.References, 1)
+		assert.NotEqual(ns, validation.References[0].Namespace)
+	}
+}
+
+func TestExportedMultiMatchSameNamespace(t *testing.T) {
+	config.Set(config.NewConfig())
+	assert := assert.New(t)
+
+	// same namespace rules and different hosts are not reported
+	validations := ExportedMultiMatchChecker{
+		DestinationRules: []kubernetes.IstioObject{
+			exportedDestinationRule("bookinfo", "reviews", "reviews"),
+			exportedDestinationRule("bookinfo", "reviews2", "reviews"),
+			exportedDestinationRule("bookinfo2", "ratings", "ratings.bookinfo.svc.cluster.local"),
+		},
+		Namespaces: models.Namespaces{{Name: "bookinfo"}, {Name: "bookinfo2"}},
+	}.Check()
+
+	assert.Empty(validations)
+}
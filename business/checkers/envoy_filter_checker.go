@@ -0,0 +1,67 @@
+package checkers
+
+import (
+	"github.com/kiali/kiali/business/checkers/common"
+	"github.com/kiali/kiali/business/checkers/envoyfilters"
+	"github.com/kiali/kiali/kubernetes"
+	"github.com/kiali/kiali/models"
+)
+
+const EnvoyFilterCheckerType = "envoyfilter"
+
+type EnvoyFilterChecker struct {
+	EnvoyFilters []kubernetes.IstioObject
+	IstioVersion string // the running Istio version, empty if unknown
+	WorkloadList models.WorkloadList
+}
+
+func (e EnvoyFilterChecker) Check() models.IstioValidations {
+	validations := models.IstioValidations{}
+
+	validations = validations.MergeValidations(e.runIndividualChecks())
+	validations = validations.MergeValidations(e.runGroupChecks())
+
+	return validations
+}
+
+func (e EnvoyFilterChecker) runGroupChecks() models.IstioValidations {
+	validations := models.IstioValidations{}
+
+	enabledCheckers := []GroupChecker{
+		envoyfilters.PriorityChecker{EnvoyFilters: e.EnvoyFilters},
+	}
+
+	for _, checker := range enabledCheckers {
+		validations = validations.MergeValidations(checker.Check())
+	}
+
+	return validations
+}
+
+func (e EnvoyFilterChecker) runIndividualChecks() models.IstioValidations {
+	validations := models.IstioValidations{}
+
+	for _, envoyFilter := range e.EnvoyFilters {
+		validations.MergeValidations(e.runChecks(envoyFilter))
+	}
+
+	return validations
+}
+
+func (e EnvoyFilterChecker) runChecks(envoyFilter kubernetes.IstioObject) models.IstioValidations {
+	key, rrValidation := EmptyValidValidation(envoyFilter.GetObjectMeta().Name, envoyFilter.GetObjectMeta().Namespace, EnvoyFilterCheckerType)
+
+	enabledCheckers := []Checker{
+		common.WorkloadSelectorNoWorkloadFoundChecker(EnvoyFilterCheckerType, envoyFilter, e.WorkloadList),
+		envoyfilters.PatchChecker{EnvoyFilter: envoyFilter},
+		envoyfilters.ProxyVersionChecker{EnvoyFilter: envoyFilter, IstioVersion: e.IstioVersion},
+	}
+
+	for _, checker := range enabledCheckers {
+		checks, validChecker := checker.Check()
+		rrValidation.Checks = append(rrValidation.Checks, checks...)
+		rrValidation.Valid = rrValidation.Valid && validChecker
+	}
+
+	return models.IstioValidations{key: rrValidation}
+}
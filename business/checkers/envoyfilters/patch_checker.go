@@ -0,0 +1,97 @@
+package envoyfilters
+
+import (
+	"fmt"
+
+	"github.com/kiali/kiali/kubernetes"
+	"github.com/kiali/kiali/models"
+)
+
+// applyToMatches maps the applyTo values to the match type selecting their configuration objects, "" when the
+// patch does not select configuration objects
+var applyToMatches = map[string]string{
+	"LISTENER":            "listener",
+	"FILTER_CHAIN":        "listener",
+	"NETWORK_FILTER":      "listener",
+	"HTTP_FILTER":         "listener",
+	"ROUTE_CONFIGURATION": "routeConfiguration",
+	"VIRTUAL_HOST":        "routeConfiguration",
+	"HTTP_ROUTE":          "routeConfiguration",
+	"CLUSTER":             "cluster",
+	"EXTENSION_CONFIG":    "",
+	"BOOTSTRAP":           "",
+}
+
+var patchContexts = map[string]bool{
+	"ANY":              true,
+	"SIDECAR_INBOUND":  true,
+	"SIDECAR_OUTBOUND": true,
+	"GATEWAY":          true,
+}
+
+var matchTypes = []string{"listener", "routeConfiguration", "cluster"}
+
+// PatchChecker validates the applyTo and the match context of the config patches, and that the match fits the
+// applyTo type: e.g. a CLUSTER patch can not select a listener
+type PatchChecker struct {
+	EnvoyFilter kubernetes.IstioObject
+}
+
+func (pc PatchChecker) Check() ([]*models.IstioCheck, bool) {
+	checks, valid := make([]*models.IstioCheck, 0), true
+
+	for i, patch := range getConfigPatches(pc.EnvoyFilter) {
+		if patch == nil {
+			continue
+		}
+		path := fmt.Sprintf("spec/configPatches[%d]", i)
+
+		applyTo, _ := patch["applyTo"].(string)
+		matchType, known := applyToMatches[applyTo]
+		if !known {
+			check := models.Build("envoyfilter.patch.unknownapplyto", path+"/applyTo")
+			checks = append(checks, &check)
+			valid = false
+		}
+
+		match, ok := patch["match"].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if context, found := match["context"]; found {
+			if c, ok := context.(string); !ok || !patchContexts[c] {
+				check := models.Build("envoyfilter.patch.unknowncontext", path+"/match/context")
+				checks = append(checks, &check)
+				valid = false
+			}
+		}
+		if !known {
+			continue
+		}
+		for _, t := range matchTypes {
+			if _, found := match[t]; found && t != matchType {
+				check := models.Build("envoyfilter.patch.matchmismatch", path+"/match/"+t)
+				checks = append(checks, &check)
+				valid = false
+			}
+		}
+	}
+
+	return checks, valid
+}
+
+// getConfigPatches returns the config patches of an EnvoyFilter, a patch that is not an object is returned as nil
+// to keep the patch indexes
+func getConfigPatches(ef kubernetes.IstioObject) []map[string]interface{} {
+	patches, ok := ef.GetSpec()["configPatches"].([]interface{})
+	if !ok {
+		return nil
+	}
+	result := make([]map[string]interface{}, len(patches))
+	for i, p := range patches {
+		if patch, ok := p.(map[string]interface{}); ok {
+			result[i] = patch
+		}
+	}
+	return result
+}
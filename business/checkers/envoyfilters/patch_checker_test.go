@@ -0,0 +1,71 @@
+package envoyfilters
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/kiali/kiali/config"
+	"github.com/kiali/kiali/models"
+	"github.com/kiali/kiali/tests/data"
+)
+
+func TestValidPatches(t *testing.T) {
+	assert := assert.New(t)
+	config.Set(config.NewConfig())
+
+	ef := data.CreateEnvoyFilter("ef", "bookinfo")
+	ef = data.AddPatchToEnvoyFilter("HTTP_FILTER", map[string]interface{}{
+		"context":  "SIDECAR_INBOUND",
+		"listener": map[string]interface{}{"portNumber": 8080},
+	}, ef)
+	ef = data.AddPatchToEnvoyFilter("CLUSTER", map[string]interface{}{
+		"context": "SIDECAR_OUTBOUND",
+		"cluster": map[string]interface{}{"service": "reviews.bookinfo.svc.cluster.local"},
+	}, ef)
+	ef = data.AddPatchToEnvoyFilter("BOOTSTRAP", nil, ef)
+
+	validations, valid := PatchChecker{EnvoyFilter: ef}.Check()
+	assert.True(valid)
+	assert.Empty(validations)
+}
+
+func TestUnknownApplyToAndContext(t *testing.T) {
+	assert := assert.New(t)
+	config.Set(config.NewConfig())
+
+	ef := data.CreateEnvoyFilter("ef", "bookinfo")
+	ef = data.AddPatchToEnvoyFilter("HTTP_FILTERS", map[string]interface{}{
+		"context": "SIDECAR",
+	}, ef)
+
+	validations, valid := PatchChecker{EnvoyFilter: ef}.Check()
+	assert.False(valid)
+	assert.Len(validations, 2)
+	assert.Equal(models.CheckMessage("envoyfilter.patch.unknownapplyto"), validations[0].Message)
+	assert.Equal(models.ErrorSeverity, validations[0].Severity)
+	assert.Equal("spec/configPatches[0]/applyTo", validations[0].Path)
+	assert.Equal(models.CheckMessage("envoyfilter.patch.unknowncontext"), validations[1].Message)
+	assert.Equal("spec/configPatches[0]/match/context", validations[1].Path)
+}
+
+func TestMatchMismatch(t *testing.T) {
+	assert := assert.New(t)
+	config.Set(config.NewConfig())
+
+	ef := data.CreateEnvoyFilter("ef", "bookinfo")
+	ef = data.AddPatchToEnvoyFilter("HTTP_ROUTE", map[string]interface{}{
+		"context":            "GATEWAY",
+		"routeConfiguration": map[string]interface{}{"portNumber": 443},
+	}, ef)
+	ef = data.AddPatchToEnvoyFilter("CLUSTER", map[string]interface{}{
+		"context":  "SIDECAR_OUTBOUND",
+		"listener": map[string]interface{}{"portNumber": 8080},
+	}, ef)
+
+	validations, valid := PatchChecker{EnvoyFilter: ef}.Check()
+	assert.False(valid)
+	assert.Len(validations, 1)
+	assert.Equal(models.CheckMessage("envoyfilter.patch.matchmismatch"), validations[0].Message)
+	assert.Equal("spec/configPatches[1]/match/listener", validations[0].Path)
+}
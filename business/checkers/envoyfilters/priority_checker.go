@@ -0,0 +1,91 @@
+package envoyfilters
+
+import (
+	"fmt"
+	"reflect"
+
+	"k8s.io/apimachinery/pkg/labels"
+
+	"github.com/kiali/kiali/business/checkers/common"
+	"github.com/kiali/kiali/kubernetes"
+	"github.com/kiali/kiali/models"
+)
+
+const EnvoyFilterCheckerType = "envoyfilter"
+
+// PriorityChecker validates that no two EnvoyFilters applied to the same workloads patch the same match with the
+// same priority: their patches are then applied in creation order, which is error prone
+type PriorityChecker struct {
+	EnvoyFilters []kubernetes.IstioObject
+}
+
+// patchWithIndex identifies a config patch of an EnvoyFilter
+type patchWithIndex struct {
+	key      models.IstioValidationKey
+	index    int
+	applyTo  interface{}
+	match    interface{}
+	priority int
+	selector string
+}
+
+// Check validates that no two EnvoyFilters patch the same match with the same priority
+func (pc PriorityChecker) Check() models.IstioValidations {
+	validations := models.IstioValidations{}
+	existing := make([]patchWithIndex, 0)
+
+	for _, ef := range pc.EnvoyFilters {
+		key := models.BuildKey(EnvoyFilterCheckerType, ef.GetObjectMeta().Name, ef.GetObjectMeta().Namespace)
+		selector := labels.Set(common.GetWorkloadSelectorLabels(ef)).String()
+		priority := getPriority(ef)
+
+		for i, patch := range getConfigPatches(ef) {
+			if patch == nil {
+				continue
+			}
+			current := patchWithIndex{key: key, index: i, applyTo: patch["applyTo"], match: patch["match"], priority: priority, selector: selector}
+			for _, p := range existing {
+				if p.key == key || p.selector != selector || p.priority != priority ||
+					!reflect.DeepEqual(p.applyTo, current.applyTo) || !reflect.DeepEqual(p.match, current.match) {
+					continue
+				}
+				currentValidation := createConflictWarning(current)
+				refValidation := createConflictWarning(p)
+				refValidation = refValidation.MergeReferences(currentValidation)
+				currentValidation = currentValidation.MergeReferences(refValidation)
+				validations = validations.MergeValidations(refValidation)
+				validations = validations.MergeValidations(currentValidation)
+			}
+			existing = append(existing, current)
+		}
+	}
+
+	return validations
+}
+
+func createConflictWarning(p patchWithIndex) models.IstioValidations {
+	check := models.Build("envoyfilter.patch.conflictingpriority", fmt.Sprintf("spec/configPatches[%d]/match", p.index))
+	validation := &models.IstioValidation{
+		Name:       p.key.Name,
+		ObjectType: EnvoyFilterCheckerType,
+		Valid:      true,
+		Checks: []*models.IstioCheck{
+			&check,
+		},
+	}
+
+	return models.IstioValidations{p.key: validation}
+}
+
+// getPriority returns the priority of an EnvoyFilter, 0 by default
+func getPriority(ef kubernetes.IstioObject) int {
+	switch priority := ef.GetSpec()["priority"].(type) {
+	case float64:
+		return int(priority)
+	case int64:
+		return int(priority)
+	case int:
+		return priority
+	}
+	return 0
+}
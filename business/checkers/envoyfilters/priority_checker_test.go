@@ -0,0 +1,74 @@
+package envoyfilters
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/kiali/kiali/config"
+	"github.com/kiali/kiali/kubernetes"
+	"github.com/kiali/kiali/models"
+	"github.com/kiali/kiali/tests/data"
+)
+
+func priorityEnvoyFilter(name string, priority interface{}, selector map[string]interface{}) kubernetes.IstioObject {
+	ef := data.CreateEnvoyFilter(name, "bookinfo")
+	if priority != nil {
+		ef.GetSpec()["priority"] = priority
+	}
+	if selector != nil {
+		ef = data.AddSelectorToEnvoyFilter(map[string]interface{}{"labels": selector}, ef)
+	}
+	return data.AddPatchToEnvoyFilter("HTTP_FILTER", map[string]interface{}{
+		"context":  "SIDECAR_INBOUND",
+		"listener": map[string]interface{}{"portNumber": float64(8080)},
+	}, ef)
+}
+
+func TestConflictingPriority(t *testing.T) {
+	assert := assert.New(t)
+	config.Set(config.NewConfig())
+
+	validations := PriorityChecker{
+		EnvoyFilters: []kubernetes.IstioObject{
+			priorityEnvoyFilter("ef1", nil, map[string]interface{}{"app": "reviews"}),
+			priorityEnvoyFilter("ef2", float64(0), map[string]interface{}{"app": "reviews"}),
+		},
+	}.Check()
+
+	assert.Len(validations, 2)
+	for _, name := range []string{"ef1", "ef2"} {
+		validation, ok := validations[models.BuildKey(EnvoyFilterCheckerType, name, "bookinfo")]
+		assert.True(ok)
+		assert.True(validation.Valid)
+		assert.Len(validation.Checks, 1)
+		assert.Equal(models.CheckMessage("envoyfilter.patch.conflictingpriority"), validation.Checks[0].Message)
+		assert.Equal("spec/configPatches[0]/match", validation.Checks[0].Path)
+		assert.Len(validation.References, 1)
+	}
+}
+
+func TestNoConflictingPriority(t *testing.T) {
+	assert := assert.New(t)
+	config.Set(config.NewConfig())
+
+	// different priorities, selectors or matches do not conflict
+	validations := PriorityChecker{
+		EnvoyFilters: []kubernetes.IstioObject{
+			priorityEnvoyFilter("ef1", nil, map[string]interface{}{"app": "reviews"}),
+			priorityEnvoyFilter("ef2", float64(10), map[string]interface{}{"app": "reviews"}),
+			priorityEnvoyFilter("ef3", nil, map[string]interface{}{"app": "ratings"}),
+			priorityEnvoyFilter("ef4", nil, nil),
+		},
+	}.Check()
+	assert.Empty(validations)
+
+	ef := data.AddPatchToEnvoyFilter("CLUSTER", nil, data.CreateEnvoyFilter("ef5", "bookinfo"))
+	validations = PriorityChecker{
+		EnvoyFilters: []kubernetes.IstioObject{
+			ef,
+			priorityEnvoyFilter("ef6", nil, nil),
+		},
+	}.Check()
+	assert.Empty(validations)
+}
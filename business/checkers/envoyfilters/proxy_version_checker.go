@@ -0,0 +1,51 @@
+package envoyfilters
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/kiali/kiali/kubernetes"
+	"github.com/kiali/kiali/models"
+)
+
+// ProxyVersionChecker validates the proxy version matches of the config patches. The proxy version is a regular
+// expression matched against the version of the proxies, expected to be the running Istio version. An empty
+// IstioVersion, when unknown, only validates the regular expressions.
+type ProxyVersionChecker struct {
+	EnvoyFilter  kubernetes.IstioObject
+	IstioVersion string
+}
+
+func (pvc ProxyVersionChecker) Check() ([]*models.IstioCheck, bool) {
+	checks, valid := make([]*models.IstioCheck, 0), true
+
+	for i, patch := range getConfigPatches(pvc.EnvoyFilter) {
+		match, ok := patch["match"].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		proxy, ok := match["proxy"].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		proxyVersion, ok := proxy["proxyVersion"].(string)
+		if !ok || proxyVersion == "" {
+			continue
+		}
+
+		path := fmt.Sprintf("spec/configPatches[%d]/match/proxy/proxyVersion", i)
+		re, err := regexp.Compile(proxyVersion)
+		if err != nil {
+			check := models.Build("envoyfilter.proxyversion.invalid", path)
+			checks = append(checks, &check)
+			valid = false
+			continue
+		}
+		if pvc.IstioVersion != "" && !re.MatchString(pvc.IstioVersion) {
+			check := models.Build("envoyfilter.proxyversion.nomatch", path)
+			checks = append(checks, &check)
+		}
+	}
+
+	return checks, valid
+}
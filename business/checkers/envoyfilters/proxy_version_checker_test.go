@@ -0,0 +1,37 @@
+package envoyfilters
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/kiali/kiali/config"
+	"github.com/kiali/kiali/models"
+	"github.com/kiali/kiali/tests/data"
+)
+
+func TestProxyVersion(t *testing.T) {
+	assert := assert.New(t)
+	config.Set(config.NewConfig())
+
+	ef := data.CreateEnvoyFilter("ef", "bookinfo")
+	for _, proxyVersion := range []string{`^1\.9.*`, `^1\.8.*`, `^1\.(9`} {
+		ef = data.AddPatchToEnvoyFilter("HTTP_FILTER", map[string]interface{}{
+			"proxy": map[string]interface{}{"proxyVersion": proxyVersion},
+		}, ef)
+	}
+
+	// the running version is unknown, only the regular expressions are validated
+	validations, valid := ProxyVersionChecker{EnvoyFilter: ef}.Check()
+	assert.False(valid)
+	assert.Len(validations, 1)
+	assert.Equal(models.CheckMessage("envoyfilter.proxyversion.invalid"), validations[0].Message)
+	assert.Equal("spec/configPatches[2]/match/proxy/proxyVersion", validations[0].Path)
+
+	validations, valid = ProxyVersionChecker{EnvoyFilter: ef, IstioVersion: "1.9.2"}.Check()
+	assert.False(valid)
+	assert.Len(validations, 2)
+	assert.Equal(models.CheckMessage("envoyfilter.proxyversion.nomatch"), validations[0].Message)
+	assert.Equal(models.WarningSeverity, validations[0].Severity)
+	assert.Equal("spec/configPatches[1]/match/proxy/proxyVersion", validations[0].Path)
+}
@@ -0,0 +1,28 @@
+package checkers
+
+import (
+	"github.com/kiali/kiali/business/checkers/destinationrules"
+	"github.com/kiali/kiali/kubernetes"
+	"github.com/kiali/kiali/models"
+)
+
+// MeshChecker runs the checks validating the Istio objects of all the namespaces as a single set, to find the
+// conflicts between namespaces that the namespace checkers can not see
+type MeshChecker struct {
+	DestinationRules []kubernetes.IstioObject
+	Namespaces       models.Namespaces
+}
+
+func (m MeshChecker) Check() models.IstioValidations {
+	validations := models.IstioValidations{}
+
+	enabledCheckers := []GroupChecker{
+		destinationrules.ExportedMultiMatchChecker{DestinationRules: m.DestinationRules, Namespaces: m.Namespaces},
+	}
+
+	for _, checker := range enabledCheckers {
+		validations = validations.MergeValidations(checker.Check())
+	}
+
+	return validations
+}
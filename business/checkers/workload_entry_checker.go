@@ -0,0 +1,67 @@
+package checkers
+
+import (
+	core_v1 "k8s.io/api/core/v1"
+
+	"github.com/kiali/kiali/business/checkers/workloadentries"
+	"github.com/kiali/kiali/kubernetes"
+	"github.com/kiali/kiali/models"
+)
+
+const WorkloadEntryCheckerType = "workloadentry"
+
+type WorkloadEntryChecker struct {
+	WorkloadEntries []kubernetes.IstioObject
+	Services        []core_v1.Service
+	ServiceEntries  []kubernetes.IstioObject
+}
+
+func (w WorkloadEntryChecker) Check() models.IstioValidations {
+	validations := models.IstioValidations{}
+
+	validations = validations.MergeValidations(w.runIndividualChecks())
+	validations = validations.MergeValidations(w.runGroupChecks())
+
+	return validations
+}
+
+func (w WorkloadEntryChecker) runGroupChecks() models.IstioValidations {
+	validations := models.IstioValidations{}
+
+	enabledCheckers := []GroupChecker{
+		workloadentries.DuplicateAddressChecker{WorkloadEntries: w.WorkloadEntries},
+	}
+
+	for _, checker := range enabledCheckers {
+		validations = validations.MergeValidations(checker.Check())
+	}
+
+	return validations
+}
+
+func (w WorkloadEntryChecker) runIndividualChecks() models.IstioValidations {
+	validations := models.IstioValidations{}
+
+	for _, workloadEntry := range w.WorkloadEntries {
+		validations.MergeValidations(w.runChecks(workloadEntry))
+	}
+
+	return validations
+}
+
+func (w WorkloadEntryChecker) runChecks(workloadEntry kubernetes.IstioObject) models.IstioValidations {
+	key, rrValidation := EmptyValidValidation(workloadEntry.GetObjectMeta().Name, workloadEntry.GetObjectMeta().Namespace, WorkloadEntryCheckerType)
+
+	enabledCheckers := []Checker{
+		workloadentries.SelectingServiceChecker{WorkloadEntry: workloadEntry, Services: w.Services, ServiceEntries: w.ServiceEntries},
+		workloadentries.PortChecker{WorkloadEntry: workloadEntry, Services: w.Services, ServiceEntries: w.ServiceEntries},
+	}
+
+	for _, checker := range enabledCheckers {
+		checks, validChecker := checker.Check()
+		rrValidation.Checks = append(rrValidation.Checks, checks...)
+		rrValidation.Valid = rrValidation.Valid && validChecker
+	}
+
+	return models.IstioValidations{key: rrValidation}
+}
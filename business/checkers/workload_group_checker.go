@@ -0,0 +1,44 @@
+package checkers
+
+import (
+	core_v1 "k8s.io/api/core/v1"
+
+	"github.com/kiali/kiali/business/checkers/workloadgroups"
+	"github.com/kiali/kiali/kubernetes"
+	"github.com/kiali/kiali/models"
+)
+
+const WorkloadGroupCheckerType = "workloadgroup"
+
+type WorkloadGroupChecker struct {
+	WorkloadGroups []kubernetes.IstioObject
+	Services       []core_v1.Service
+	ServiceEntries []kubernetes.IstioObject
+}
+
+func (w WorkloadGroupChecker) Check() models.IstioValidations {
+	validations := models.IstioValidations{}
+
+	for _, workloadGroup := range w.WorkloadGroups {
+		validations.MergeValidations(w.runChecks(workloadGroup))
+	}
+
+	return validations
+}
+
+func (w WorkloadGroupChecker) runChecks(workloadGroup kubernetes.IstioObject) models.IstioValidations {
+	key, rrValidation := EmptyValidValidation(workloadGroup.GetObjectMeta().Name, workloadGroup.GetObjectMeta().Namespace, WorkloadGroupCheckerType)
+
+	enabledCheckers := []Checker{
+		workloadgroups.TemplateChecker{WorkloadGroup: workloadGroup},
+		workloadgroups.PortChecker{WorkloadGroup: workloadGroup, Services: w.Services, ServiceEntries: w.ServiceEntries},
+	}
+
+	for _, checker := range enabledCheckers {
+		checks, validChecker := checker.Check()
+		rrValidation.Checks = append(rrValidation.Checks, checks...)
+		rrValidation.Valid = rrValidation.Valid && validChecker
+	}
+
+	return models.IstioValidations{key: rrValidation}
+}
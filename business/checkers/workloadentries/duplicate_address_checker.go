@@ -0,0 +1,52 @@
+package workloadentries
+
+import (
+	"github.com/kiali/kiali/kubernetes"
+	"github.com/kiali/kiali/models"
+)
+
+const WorkloadEntryCheckerType = "workloadentry"
+
+// DuplicateAddressChecker validates that no two WorkloadEntries share the same address
+type DuplicateAddressChecker struct {
+	WorkloadEntries []kubernetes.IstioObject
+}
+
+// Check validates that no two WorkloadEntries share the same address
+func (dc DuplicateAddressChecker) Check() models.IstioValidations {
+	validations := models.IstioValidations{}
+	existing := make(map[string][]models.IstioValidationKey)
+
+	for _, we := range dc.WorkloadEntries {
+		address, _ := we.GetSpec()["address"].(string)
+		if address == "" {
+			continue
+		}
+		key := models.BuildKey(WorkloadEntryCheckerType, we.GetObjectMeta().Name, we.GetObjectMeta().Namespace)
+		for _, refKey := range existing[address] {
+			currentValidation := createDuplicateWarning(key)
+			refValidation := createDuplicateWarning(refKey)
+			refValidation = refValidation.MergeReferences(currentValidation)
+			currentValidation = currentValidation.MergeReferences(refValidation)
+			validations = validations.MergeValidations(refValidation)
+			validations = validations.MergeValidations(currentValidation)
+		}
+		existing[address] = append(existing[address], key)
+	}
+
+	return validations
+}
+
+func createDuplicateWarning(key models.IstioValidationKey) models.IstioValidations {
+	check := models.Build("workloadentries.address.duplicate", "spec/address")
+	validation := &models.IstioValidation{
+		Name:       key.Name,
+		ObjectType: WorkloadEntryCheckerType,
+		Valid:      true,
+		Checks: []*models.IstioCheck{
+			&check,
+		},
+	}
+
+	return models.IstioValidations{key: validation}
+}
@@ -0,0 +1,52 @@
+package workloadentries
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/kiali/kiali/config"
+	"github.com/kiali/kiali/kubernetes"
+	"github.com/kiali/kiali/models"
+	"github.com/kiali/kiali/tests/data"
+)
+
+func TestDuplicateAddresses(t *testing.T) {
+	assert := assert.New(t)
+	config.Set(config.NewConfig())
+
+	validations := DuplicateAddressChecker{
+		WorkloadEntries: []kubernetes.IstioObject{
+			data.CreateWorkloadEntry("details-vm1", "bookinfo", "10.0.0.1", map[string]interface{}{"app": "details"}),
+			data.CreateWorkloadEntry("details-vm2", "bookinfo", "10.0.0.1", map[string]interface{}{"app": "details"}),
+			data.CreateWorkloadEntry("reviews-vm", "bookinfo", "10.0.0.2", map[string]interface{}{"app": "reviews"}),
+		},
+	}.Check()
+
+	assert.Len(validations, 2)
+	for _, name := range []string{"details-vm1", "details-vm2"} {
+		validation, ok := validations[models.BuildKey(WorkloadEntryCheckerType, name, "bookinfo")]
+		assert.True(ok)
+		assert.True(validation.Valid)
+		assert.Len(validation.Checks, 1)
+		assert.Equal(models.CheckMessage("workloadentries.address.duplicate"), validation.Checks[0].Message)
+		assert.Equal("spec/address", validation.Checks[0].Path)
+		assert.Len(validation.References, 1)
+	}
+}
+
+func TestNoDuplicateAddresses(t *testing.T) {
+	assert := assert.New(t)
+	config.Set(config.NewConfig())
+
+	// entries without address are not compared
+	validations := DuplicateAddressChecker{
+		WorkloadEntries: []kubernetes.IstioObject{
+			data.CreateWorkloadEntry("details-vm", "bookinfo", "10.0.0.1", map[string]interface{}{"app": "details"}),
+			data.CreateWorkloadEntry("reviews-vm1", "bookinfo", "", map[string]interface{}{"app": "reviews"}),
+			data.CreateWorkloadEntry("reviews-vm2", "bookinfo", "", map[string]interface{}{"app": "reviews"}),
+		},
+	}.Check()
+
+	assert.Empty(validations)
+}
@@ -0,0 +1,51 @@
+package workloadentries
+
+import (
+	"sort"
+
+	core_v1 "k8s.io/api/core/v1"
+
+	"github.com/kiali/kiali/business/checkers/common"
+	"github.com/kiali/kiali/kubernetes"
+	"github.com/kiali/kiali/models"
+)
+
+// PortChecker validates that the port names of a WorkloadEntry match the port names of the Services and
+// ServiceEntries selecting it: the entry ports are looked up by the name of the service ports
+type PortChecker struct {
+	WorkloadEntry  kubernetes.IstioObject
+	Services       []core_v1.Service
+	ServiceEntries []kubernetes.IstioObject
+}
+
+func (pc PortChecker) Check() ([]*models.IstioCheck, bool) {
+	labels := common.GetStringMap(pc.WorkloadEntry.GetSpec()["labels"])
+	servicePorts, selected := common.GetSelectingServicePorts(labels, pc.Services, pc.ServiceEntries)
+	if !selected {
+		// Reported by the SelectingServiceChecker
+		return []*models.IstioCheck{}, true
+	}
+
+	ports, _ := pc.WorkloadEntry.GetSpec()["ports"].(map[string]interface{})
+	return CheckPortNames(ports, servicePorts, "spec/ports", "workloadentries.port.nomatch"), true
+}
+
+// CheckPortNames returns a check for every port name not found in the service ports, sorted by name
+func CheckPortNames(ports map[string]interface{}, servicePorts map[string]bool, path, checkId string) []*models.IstioCheck {
+	checks := make([]*models.IstioCheck, 0)
+
+	names := make([]string, 0, len(ports))
+	for name := range ports {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if !servicePorts[name] {
+			check := models.Build(checkId, path+"/"+name)
+			checks = append(checks, &check)
+		}
+	}
+
+	return checks
+}
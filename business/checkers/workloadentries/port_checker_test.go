@@ -0,0 +1,67 @@
+package workloadentries
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	core_v1 "k8s.io/api/core/v1"
+
+	"github.com/kiali/kiali/config"
+	"github.com/kiali/kiali/kubernetes"
+	"github.com/kiali/kiali/models"
+	"github.com/kiali/kiali/tests/data"
+)
+
+func TestPortNamesMatch(t *testing.T) {
+	assert := assert.New(t)
+	config.Set(config.NewConfig())
+
+	we := data.AddPortsToWorkloadEntry(map[string]interface{}{"http": float64(8080), "grpc": float64(9090)},
+		data.CreateWorkloadEntry("details-vm", "bookinfo", "10.0.0.1", map[string]interface{}{"app": "details"}))
+
+	checks, valid := PortChecker{
+		WorkloadEntry:  we,
+		Services:       []core_v1.Service{fakeService("details", map[string]string{"app": "details"}, "http")},
+		ServiceEntries: []kubernetes.IstioObject{fakeServiceEntry("details", map[string]interface{}{"app": "details"}, "grpc")},
+	}.Check()
+
+	assert.True(valid)
+	assert.Empty(checks)
+}
+
+func TestPortNamesMismatch(t *testing.T) {
+	assert := assert.New(t)
+	config.Set(config.NewConfig())
+
+	we := data.AddPortsToWorkloadEntry(map[string]interface{}{"web": float64(8080), "http": float64(8081), "admin": float64(9000)},
+		data.CreateWorkloadEntry("details-vm", "bookinfo", "10.0.0.1", map[string]interface{}{"app": "details"}))
+
+	checks, valid := PortChecker{
+		WorkloadEntry: we,
+		Services:      []core_v1.Service{fakeService("details", map[string]string{"app": "details"}, "http")},
+	}.Check()
+
+	assert.True(valid)
+	assert.Len(checks, 2)
+	assert.Equal(models.CheckMessage("workloadentries.port.nomatch"), checks[0].Message)
+	assert.Equal(models.WarningSeverity, checks[0].Severity)
+	assert.Equal("spec/ports/admin", checks[0].Path)
+	assert.Equal("spec/ports/web", checks[1].Path)
+}
+
+func TestPortNamesNotSelected(t *testing.T) {
+	assert := assert.New(t)
+	config.Set(config.NewConfig())
+
+	// without selecting services there are no port names to compare with
+	we := data.AddPortsToWorkloadEntry(map[string]interface{}{"web": float64(8080)},
+		data.CreateWorkloadEntry("details-vm", "bookinfo", "10.0.0.1", map[string]interface{}{"app": "details"}))
+
+	checks, valid := PortChecker{
+		WorkloadEntry: we,
+		Services:      []core_v1.Service{fakeService("reviews", map[string]string{"app": "reviews"}, "http")},
+	}.Check()
+
+	assert.True(valid)
+	assert.Empty(checks)
+}
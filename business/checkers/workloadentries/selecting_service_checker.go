@@ -0,0 +1,29 @@
+package workloadentries
+
+import (
+	core_v1 "k8s.io/api/core/v1"
+
+	"github.com/kiali/kiali/business/checkers/common"
+	"github.com/kiali/kiali/kubernetes"
+	"github.com/kiali/kiali/models"
+)
+
+// SelectingServiceChecker validates that the labels of a WorkloadEntry are selected by a Service or by a
+// ServiceEntry workloadSelector, otherwise the entry receives no traffic
+type SelectingServiceChecker struct {
+	WorkloadEntry  kubernetes.IstioObject
+	Services       []core_v1.Service
+	ServiceEntries []kubernetes.IstioObject
+}
+
+func (sc SelectingServiceChecker) Check() ([]*models.IstioCheck, bool) {
+	checks := make([]*models.IstioCheck, 0)
+
+	labels := common.GetStringMap(sc.WorkloadEntry.GetSpec()["labels"])
+	if _, selected := common.GetSelectingServicePorts(labels, sc.Services, sc.ServiceEntries); !selected {
+		check := models.Build("workloadentries.selector.noservice", "spec/labels")
+		checks = append(checks, &check)
+	}
+
+	return checks, true
+}
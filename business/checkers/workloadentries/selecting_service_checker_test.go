@@ -0,0 +1,84 @@
+package workloadentries
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	core_v1 "k8s.io/api/core/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/kiali/kiali/config"
+	"github.com/kiali/kiali/kubernetes"
+	"github.com/kiali/kiali/models"
+	"github.com/kiali/kiali/tests/data"
+)
+
+func fakeService(name string, selector map[string]string, portNames ...string) core_v1.Service {
+	service := core_v1.Service{
+		ObjectMeta: meta_v1.ObjectMeta{
+			Name:      name,
+			Namespace: "bookinfo",
+		},
+		Spec: core_v1.ServiceSpec{
+			Selector: selector,
+		},
+	}
+	for _, p := range portNames {
+		service.Spec.Ports = append(service.Spec.Ports, core_v1.ServicePort{Name: p})
+	}
+	return service
+}
+
+func fakeServiceEntry(name string, selector map[string]interface{}, portNames ...string) kubernetes.IstioObject {
+	se := data.CreateEmptyMeshExternalServiceEntry(name, "bookinfo", []string{name + ".example.com"})
+	se.GetSpec()["workloadSelector"] = map[string]interface{}{"labels": selector}
+	ports := make([]interface{}, 0, len(portNames))
+	for _, p := range portNames {
+		ports = append(ports, map[string]interface{}{"name": p, "number": 80, "protocol": "HTTP"})
+	}
+	se.GetSpec()["ports"] = ports
+	return se
+}
+
+func TestSelectedByService(t *testing.T) {
+	assert := assert.New(t)
+	config.Set(config.NewConfig())
+
+	checks, valid := SelectingServiceChecker{
+		WorkloadEntry: data.CreateWorkloadEntry("details-vm", "bookinfo", "10.0.0.1", map[string]interface{}{"app": "details", "version": "vm"}),
+		Services:      []core_v1.Service{fakeService("details", map[string]string{"app": "details"})},
+	}.Check()
+
+	assert.True(valid)
+	assert.Empty(checks)
+}
+
+func TestSelectedByServiceEntry(t *testing.T) {
+	assert := assert.New(t)
+	config.Set(config.NewConfig())
+
+	checks, valid := SelectingServiceChecker{
+		WorkloadEntry:  data.CreateWorkloadEntry("details-vm", "bookinfo", "10.0.0.1", map[string]interface{}{"app": "details"}),
+		ServiceEntries: []kubernetes.IstioObject{fakeServiceEntry("details", map[string]interface{}{"app": "details"})},
+	}.Check()
+
+	assert.True(valid)
+	assert.Empty(checks)
+}
+
+func TestNotSelected(t *testing.T) {
+	assert := assert.New(t)
+	config.Set(config.NewConfig())
+
+	checks, valid := SelectingServiceChecker{
+		WorkloadEntry:  data.CreateWorkloadEntry("details-vm", "bookinfo", "10.0.0.1", map[string]interface{}{"app": "details"}),
+		Services:       []core_v1.Service{fakeService("reviews", map[string]string{"app": "reviews"}), fakeService("selectorless", nil)},
+		ServiceEntries: []kubernetes.IstioObject{fakeServiceEntry("details", map[string]interface{}{"app": "details", "version": "v2"})},
+	}.Check()
+
+	assert.True(valid)
+	assert.Len(checks, 1)
+	assert.Equal(models.CheckMessage("workloadentries.selector.noservice"), checks[0].Message)
+	assert.Equal(models.WarningSeverity, checks[0].Severity)
+	assert.Equal("spec/labels", checks[0].Path)
+}
@@ -0,0 +1,34 @@
+package workloadgroups
+
+import (
+	core_v1 "k8s.io/api/core/v1"
+
+	"github.com/kiali/kiali/business/checkers/common"
+	"github.com/kiali/kiali/business/checkers/workloadentries"
+	"github.com/kiali/kiali/kubernetes"
+	"github.com/kiali/kiali/models"
+)
+
+// PortChecker validates that the template port names of a WorkloadGroup match the port names of the Services and
+// ServiceEntries selecting the labels of the group
+type PortChecker struct {
+	WorkloadGroup  kubernetes.IstioObject
+	Services       []core_v1.Service
+	ServiceEntries []kubernetes.IstioObject
+}
+
+func (pc PortChecker) Check() ([]*models.IstioCheck, bool) {
+	metadata, _ := pc.WorkloadGroup.GetSpec()["metadata"].(map[string]interface{})
+	labels := common.GetStringMap(metadata["labels"])
+	if len(labels) == 0 {
+		return []*models.IstioCheck{}, true
+	}
+	servicePorts, selected := common.GetSelectingServicePorts(labels, pc.Services, pc.ServiceEntries)
+	if !selected {
+		return []*models.IstioCheck{}, true
+	}
+
+	template, _ := pc.WorkloadGroup.GetSpec()["template"].(map[string]interface{})
+	ports, _ := template["ports"].(map[string]interface{})
+	return workloadentries.CheckPortNames(ports, servicePorts, "spec/template/ports", "workloadgroups.port.nomatch"), true
+}
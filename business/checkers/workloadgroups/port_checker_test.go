@@ -0,0 +1,50 @@
+package workloadgroups
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	core_v1 "k8s.io/api/core/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/kiali/kiali/config"
+	"github.com/kiali/kiali/models"
+	"github.com/kiali/kiali/tests/data"
+)
+
+func TestTemplatePortNames(t *testing.T) {
+	assert := assert.New(t)
+	config.Set(config.NewConfig())
+
+	services := []core_v1.Service{
+		{
+			ObjectMeta: meta_v1.ObjectMeta{Name: "details", Namespace: "bookinfo"},
+			Spec: core_v1.ServiceSpec{
+				Selector: map[string]string{"app": "details"},
+				Ports:    []core_v1.ServicePort{{Name: "http"}},
+			},
+		},
+	}
+	template := map[string]interface{}{
+		"ports": map[string]interface{}{"http": float64(8080), "web": float64(8081)},
+	}
+
+	checks, valid := PortChecker{
+		WorkloadGroup: data.CreateWorkloadGroup("details", "bookinfo", map[string]interface{}{"app": "details"}, template),
+		Services:      services,
+	}.Check()
+
+	assert.True(valid)
+	assert.Len(checks, 1)
+	assert.Equal(models.CheckMessage("workloadgroups.port.nomatch"), checks[0].Message)
+	assert.Equal("spec/template/ports/web", checks[0].Path)
+
+	// a group not selected by any service is not validated
+	checks, valid = PortChecker{
+		WorkloadGroup: data.CreateWorkloadGroup("reviews", "bookinfo", map[string]interface{}{"app": "reviews"}, template),
+		Services:      services,
+	}.Check()
+
+	assert.True(valid)
+	assert.Empty(checks)
+}
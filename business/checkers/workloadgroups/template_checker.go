@@ -0,0 +1,28 @@
+package workloadgroups
+
+import (
+	"github.com/kiali/kiali/kubernetes"
+	"github.com/kiali/kiali/models"
+)
+
+// TemplateChecker validates that the template of a WorkloadGroup sets the service account and the network of the
+// WorkloadEntries created from it
+type TemplateChecker struct {
+	WorkloadGroup kubernetes.IstioObject
+}
+
+func (tc TemplateChecker) Check() ([]*models.IstioCheck, bool) {
+	checks := make([]*models.IstioCheck, 0)
+
+	template, _ := tc.WorkloadGroup.GetSpec()["template"].(map[string]interface{})
+	if serviceAccount, _ := template["serviceAccount"].(string); serviceAccount == "" {
+		check := models.Build("workloadgroups.template.noserviceaccount", "spec/template")
+		checks = append(checks, &check)
+	}
+	if network, _ := template["network"].(string); network == "" {
+		check := models.Build("workloadgroups.template.nonetwork", "spec/template")
+		checks = append(checks, &check)
+	}
+
+	return checks, true
+}
@@ -0,0 +1,42 @@
+package workloadgroups
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/kiali/kiali/config"
+	"github.com/kiali/kiali/models"
+	"github.com/kiali/kiali/tests/data"
+)
+
+func TestCompleteTemplate(t *testing.T) {
+	assert := assert.New(t)
+	config.Set(config.NewConfig())
+
+	checks, valid := TemplateChecker{
+		WorkloadGroup: data.CreateWorkloadGroup("details", "bookinfo", map[string]interface{}{"app": "details"},
+			map[string]interface{}{"serviceAccount": "bookinfo-details", "network": "vm-network"}),
+	}.Check()
+
+	assert.True(valid)
+	assert.Empty(checks)
+}
+
+func TestIncompleteTemplate(t *testing.T) {
+	assert := assert.New(t)
+	config.Set(config.NewConfig())
+
+	checks, valid := TemplateChecker{
+		WorkloadGroup: data.CreateWorkloadGroup("details", "bookinfo", map[string]interface{}{"app": "details"},
+			map[string]interface{}{}),
+	}.Check()
+
+	assert.True(valid)
+	assert.Len(checks, 2)
+	assert.Equal(models.CheckMessage("workloadgroups.template.noserviceaccount"), checks[0].Message)
+	assert.Equal(models.WarningSeverity, checks[0].Severity)
+	assert.Equal("spec/template", checks[0].Path)
+	assert.Equal(models.CheckMessage("workloadgroups.template.nonetwork"), checks[1].Message)
+	assert.Equal("spec/template", checks[1].Path)
+}
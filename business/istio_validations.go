@@ -13,6 +13,7 @@ import (
 	"github.com/kiali/kiali/kubernetes"
 	"github.com/kiali/kiali/log"
 	"github.com/kiali/kiali/models"
+	"github.com/kiali/kiali/status"
 )
 
 type IstioValidationsService struct {
@@ -87,7 +88,8 @@ func (in *IstioValidationsService) GetValidations(namespace, service string) (mo
 		}
 	}
 
-	objectCheckers := in.getAllObjectCheckers(namespace, istioDetails, services, workloadsPerNamespace, workloads, gatewaysPerNamespace, mtlsDetails, rbacDetails, namespaces, registryStatus)
+	istioVersion := envoyFiltersIstioVersion(istioDetails.EnvoyFilters)
+	objectCheckers := in.getAllObjectCheckers(namespace, istioDetails, services, workloadsPerNamespace, workloads, gatewaysPerNamespace, mtlsDetails, rbacDetails, namespaces, registryStatus, istioVersion)
 
 	if service != "" {
 		objectCheckers = append(objectCheckers, in.getServiceCheckers(namespace, services, deployments, pods)...)
@@ -102,13 +104,276 @@ func (in *IstioValidationsService) GetValidations(namespace, service string) (mo
 	return validations, nil
 }
 
+// namespaceValidationDetails holds the objects of a namespace validated by the multi namespace validations
+type namespaceValidationDetails struct {
+	istioDetails kubernetes.IstioDetails
+	services     []core_v1.Service
+	workloads    models.WorkloadList
+	mtlsDetails  kubernetes.MTLSDetails
+	rbacDetails  kubernetes.RBACDetails
+}
+
+// validationDetails holds the objects validated by the multi namespace validations, fetched once for all the
+// validated namespaces
+type validationDetails struct {
+	namespaces            models.Namespaces
+	validated             []string
+	perNamespace          map[string]*namespaceValidationDetails
+	workloadsPerNamespace map[string]models.WorkloadList
+	gatewaysPerNamespace  [][]kubernetes.IstioObject
+	registryStatus        []*kubernetes.RegistryStatus
+	istioVersion          string
+	istioVersionFetched   bool
+}
+
+// GetMeshValidations returns the validations of all the accessible namespaces, grouped by namespace. The objects of
+// the whole mesh are fetched once, every namespace is validated and then the mesh checkers validate the combined
+// set, adding the conflicts between namespaces with their cross-namespace references.
+func (in *IstioValidationsService) GetMeshValidations() (models.NamespaceValidations, error) {
+	nss, err := in.businessLayer.Namespace.GetNamespaces()
+	if err != nil {
+		return nil, err
+	}
+	namespaces := models.Namespaces(nss)
+
+	details, err := in.fetchValidationDetails(namespaces, namespaces.GetNames())
+	if err != nil {
+		return nil, err
+	}
+
+	validations := in.validateNamespaces(details)
+	var destinationRules []kubernetes.IstioObject
+	for _, ns := range details.validated {
+		destinationRules = append(destinationRules, details.perNamespace[ns].istioDetails.DestinationRules...)
+	}
+	validations.MergeValidations(checkers.MeshChecker{DestinationRules: destinationRules, Namespaces: namespaces}.Check())
+
+	namespaceValidations := validations.GroupByNamespace()
+	for _, ns := range namespaces {
+		if _, found := namespaceValidations[ns.Name]; !found {
+			namespaceValidations[ns.Name] = models.IstioValidations{}
+		}
+	}
+
+	return namespaceValidations, nil
+}
+
+// GetDryRunValidations validates Istio objects as if they were applied, nothing is written to the cluster. The
+// objects are overlaid on the live objects of their namespaces, replacing the objects with the same name, and all
+// the checkers run on both configurations. It returns the checks introduced and resolved by the objects.
+func (in *IstioValidationsService) GetDryRunValidations(objects []kubernetes.IstioObject) (models.IstioValidationsDiff, error) {
+	validated := make([]string, 0)
+	seenNamespaces := make(map[string]bool)
+	objectTypes := make([]string, len(objects))
+	for i, obj := range objects {
+		objectType, found := kubernetes.GetResourceType(obj.GetTypeMeta().Kind)
+		if !found || !dryRunObjectTypes[objectType] {
+			return models.IstioValidationsDiff{}, errors.NewBadRequest(fmt.Sprintf("Object [kind: %s] [name: %s] is not supported for Validations.", obj.GetTypeMeta().Kind, obj.GetObjectMeta().Name))
+		}
+		objectTypes[i] = objectType
+
+		namespace := obj.GetObjectMeta().Namespace
+		if namespace == "" {
+			return models.IstioValidationsDiff{}, errors.NewBadRequest(fmt.Sprintf("Object [kind: %s] [name: %s] has no namespace.", obj.GetTypeMeta().Kind, obj.GetObjectMeta().Name))
+		}
+		if !seenNamespaces[namespace] {
+			// Check if user has access to the namespace (RBAC) in cache scenarios and/or
+			// if namespace is accessible from Kiali (Deployment.AccessibleNamespaces)
+			if _, err := in.businessLayer.Namespace.GetNamespace(namespace); err != nil {
+				return models.IstioValidationsDiff{}, err
+			}
+			seenNamespaces[namespace] = true
+			validated = append(validated, namespace)
+		}
+	}
+
+	nss, err := in.businessLayer.Namespace.GetNamespaces()
+	if err != nil {
+		return models.IstioValidationsDiff{}, err
+	}
+
+	details, err := in.fetchValidationDetails(models.Namespaces(nss), validated)
+	if err != nil {
+		return models.IstioValidationsDiff{}, err
+	}
+
+	before := in.validateNamespaces(details)
+	for i, obj := range objects {
+		details.overlay(objectTypes[i], obj)
+	}
+	after := in.validateNamespaces(details)
+
+	return models.DiffValidations(before, after), nil
+}
+
+// fetchValidationDetails fetches the objects needed to validate the validated namespaces
+func (in *IstioValidationsService) fetchValidationDetails(namespaces models.Namespaces, validated []string) (*validationDetails, error) {
+	wg := sync.WaitGroup{}
+	errChan := make(chan error, 1)
+
+	details := &validationDetails{
+		namespaces:   namespaces,
+		validated:    validated,
+		perNamespace: make(map[string]*namespaceValidationDetails, len(validated)),
+	}
+
+	wg.Add(3 + 5*len(validated))
+
+	go in.fetchAllWorkloads(&details.workloadsPerNamespace, errChan, &wg)
+	go in.fetchGatewaysPerNamespace(&details.gatewaysPerNamespace, errChan, &wg)
+	go in.fetchRegistryStatus(&details.registryStatus, errChan, &wg)
+	for _, ns := range validated {
+		d := &namespaceValidationDetails{}
+		details.perNamespace[ns] = d
+		go in.fetchDetails(&d.istioDetails, ns, errChan, &wg)
+		go in.fetchServices(&d.services, ns, errChan, &wg)
+		go in.fetchWorkloads(&d.workloads, ns, errChan, &wg)
+		go in.fetchNonLocalmTLSConfigs(&d.mtlsDetails, ns, errChan, &wg)
+		go in.fetchAuthorizationDetails(&d.rbacDetails, ns, errChan, &wg)
+	}
+
+	wg.Wait()
+	close(errChan)
+	for e := range errChan {
+		if e != nil { // Check that default value wasn't returned
+			return nil, e
+		}
+	}
+
+	return details, nil
+}
+
+// validateNamespaces runs all the object checkers on every validated namespace
+func (in *IstioValidationsService) validateNamespaces(details *validationDetails) models.IstioValidations {
+	istioVersion := details.envoyFiltersIstioVersion()
+	validations := models.IstioValidations{}
+	for _, ns := range details.validated {
+		d := details.perNamespace[ns]
+		objectCheckers := in.getAllObjectCheckers(ns, d.istioDetails, d.services, details.workloadsPerNamespace, d.workloads, details.gatewaysPerNamespace, d.mtlsDetails, d.rbacDetails, details.namespaces, details.registryStatus, istioVersion)
+		validations.MergeValidations(runObjectCheckers(objectCheckers))
+	}
+	return validations
+}
+
+// envoyFiltersIstioVersion returns the Istio version to validate the proxy versions of the EnvoyFilters of all the
+// validated namespaces with. It is fetched at most once, when an EnvoyFilter sets a proxy version.
+func (d *validationDetails) envoyFiltersIstioVersion() string {
+	if d.istioVersionFetched {
+		return d.istioVersion
+	}
+	var envoyFilters []kubernetes.IstioObject
+	for _, ns := range d.validated {
+		envoyFilters = append(envoyFilters, d.perNamespace[ns].istioDetails.EnvoyFilters...)
+	}
+	if hasProxyVersion(envoyFilters) {
+		d.istioVersion = envoyFiltersIstioVersion(envoyFilters)
+		d.istioVersionFetched = true
+	}
+	return d.istioVersion
+}
+
+// dryRunObjectTypes are the object types that can be overlaid by GetDryRunValidations
+var dryRunObjectTypes = map[string]bool{
+	kubernetes.AuthorizationPolicies:  true,
+	kubernetes.DestinationRules:       true,
+	kubernetes.EnvoyFilters:           true,
+	kubernetes.Gateways:               true,
+	kubernetes.PeerAuthentications:    true,
+	kubernetes.RequestAuthentications: true,
+	kubernetes.ServiceEntries:         true,
+	kubernetes.Sidecars:               true,
+	kubernetes.VirtualServices:        true,
+	kubernetes.WorkloadEntries:        true,
+	kubernetes.WorkloadGroups:         true,
+}
+
+// overlay replaces the object with the same type, namespace and name in the details, or adds it. The object
+// namespace must be validated.
+func (d *validationDetails) overlay(objectType string, obj kubernetes.IstioObject) {
+	nsDetails := d.perNamespace[obj.GetObjectMeta().Namespace]
+	istioDetails := &nsDetails.istioDetails
+
+	switch objectType {
+	case kubernetes.AuthorizationPolicies:
+		nsDetails.rbacDetails.AuthorizationPolicies = overlayObject(nsDetails.rbacDetails.AuthorizationPolicies, obj)
+	case kubernetes.DestinationRules:
+		istioDetails.DestinationRules = overlayObject(istioDetails.DestinationRules, obj)
+		// The non-local mTLS details hold the DestinationRules of all the namespaces
+		for _, nd := range d.perNamespace {
+			nd.mtlsDetails.DestinationRules = overlayObject(nd.mtlsDetails.DestinationRules, obj)
+		}
+	case kubernetes.EnvoyFilters:
+		istioDetails.EnvoyFilters = overlayObject(istioDetails.EnvoyFilters, obj)
+	case kubernetes.Gateways:
+		istioDetails.Gateways = overlayObject(istioDetails.Gateways, obj)
+		d.overlayGateway(obj)
+	case kubernetes.PeerAuthentications:
+		nsDetails.mtlsDetails.PeerAuthentications = overlayObject(nsDetails.mtlsDetails.PeerAuthentications, obj)
+		if obj.GetObjectMeta().Namespace == config.Get().IstioNamespace {
+			for _, nd := range d.perNamespace {
+				nd.mtlsDetails.MeshPeerAuthentications = overlayObject(nd.mtlsDetails.MeshPeerAuthentications, obj)
+			}
+		}
+	case kubernetes.RequestAuthentications:
+		istioDetails.RequestAuthentications = overlayObject(istioDetails.RequestAuthentications, obj)
+	case kubernetes.ServiceEntries:
+		istioDetails.ServiceEntries = overlayObject(istioDetails.ServiceEntries, obj)
+	case kubernetes.Sidecars:
+		istioDetails.Sidecars = overlayObject(istioDetails.Sidecars, obj)
+	case kubernetes.VirtualServices:
+		istioDetails.VirtualServices = overlayObject(istioDetails.VirtualServices, obj)
+	case kubernetes.WorkloadEntries:
+		istioDetails.WorkloadEntries = overlayObject(istioDetails.WorkloadEntries, obj)
+	case kubernetes.WorkloadGroups:
+		istioDetails.WorkloadGroups = overlayObject(istioDetails.WorkloadGroups, obj)
+	}
+}
+
+// overlayGateway replaces the Gateway in the Gateways of all the namespaces, or adds it
+func (d *validationDetails) overlayGateway(obj kubernetes.IstioObject) {
+	gatewaysPerNamespace := make([][]kubernetes.IstioObject, 0, len(d.gatewaysPerNamespace)+1)
+	found := false
+	for _, gws := range d.gatewaysPerNamespace {
+		if !found && indexOfObject(gws, obj) >= 0 {
+			gws = overlayObject(gws, obj)
+			found = true
+		}
+		gatewaysPerNamespace = append(gatewaysPerNamespace, gws)
+	}
+	if !found {
+		gatewaysPerNamespace = append(gatewaysPerNamespace, []kubernetes.IstioObject{obj})
+	}
+	d.gatewaysPerNamespace = gatewaysPerNamespace
+}
+
+// overlayObject returns a copy of the objects where the object with the same namespace and name is replaced, or
+// where the object is added
+func overlayObject(objects []kubernetes.IstioObject, obj kubernetes.IstioObject) []kubernetes.IstioObject {
+	result := make([]kubernetes.IstioObject, len(objects), len(objects)+1)
+	copy(result, objects)
+	if i := indexOfObject(result, obj); i >= 0 {
+		result[i] = obj
+		return result
+	}
+	return append(result, obj)
+}
+
+func indexOfObject(objects []kubernetes.IstioObject, obj kubernetes.IstioObject) int {
+	for i, o := range objects {
+		if o.GetObjectMeta().Name == obj.GetObjectMeta().Name && o.GetObjectMeta().Namespace == obj.GetObjectMeta().Namespace {
+			return i
+		}
+	}
+	return -1
+}
+
 func (in *IstioValidationsService) getServiceCheckers(namespace string, services []core_v1.Service, deployments []apps_v1.Deployment, pods []core_v1.Pod) []ObjectChecker {
 	return []ObjectChecker{
 		checkers.ServiceChecker{Services: services, Deployments: deployments, Pods: pods},
 	}
 }
 
-func (in *IstioValidationsService) getAllObjectCheckers(namespace string, istioDetails kubernetes.IstioDetails, services []core_v1.Service, workloadsPerNamespace map[string]models.WorkloadList, workloads models.WorkloadList, gatewaysPerNamespace [][]kubernetes.IstioObject, mtlsDetails kubernetes.MTLSDetails, rbacDetails kubernetes.RBACDetails, namespaces []models.Namespace, registryStatus []*kubernetes.RegistryStatus) []ObjectChecker {
+func (in *IstioValidationsService) getAllObjectCheckers(namespace string, istioDetails kubernetes.IstioDetails, services []core_v1.Service, workloadsPerNamespace map[string]models.WorkloadList, workloads models.WorkloadList, gatewaysPerNamespace [][]kubernetes.IstioObject, mtlsDetails kubernetes.MTLSDetails, rbacDetails kubernetes.RBACDetails, namespaces []models.Namespace, registryStatus []*kubernetes.RegistryStatus, istioVersion string) []ObjectChecker {
 	return []ObjectChecker{
 		checkers.NoServiceChecker{Namespace: namespace, Namespaces: namespaces, IstioDetails: &istioDetails, Services: services, WorkloadList: workloads, GatewaysPerNamespace: gatewaysPerNamespace, AuthorizationDetails: &rbacDetails, RegistryStatus: registryStatus},
 		checkers.VirtualServiceChecker{Namespace: namespace, Namespaces: namespaces, DestinationRules: istioDetails.DestinationRules, VirtualServices: istioDetails.VirtualServices},
@@ -119,6 +384,9 @@ func (in *IstioValidationsService) getAllObjectCheckers(namespace string, istioD
 		checkers.AuthorizationPolicyChecker{AuthorizationPolicies: rbacDetails.AuthorizationPolicies, Namespace: namespace, Namespaces: namespaces, Services: services, ServiceEntries: istioDetails.ServiceEntries, WorkloadList: workloads, MtlsDetails: mtlsDetails, VirtualServices: istioDetails.VirtualServices, RegistryStatus: registryStatus},
 		checkers.SidecarChecker{Sidecars: istioDetails.Sidecars, Namespaces: namespaces, WorkloadList: workloads, Services: services, ServiceEntries: istioDetails.ServiceEntries},
 		checkers.RequestAuthenticationChecker{RequestAuthentications: istioDetails.RequestAuthentications, WorkloadList: workloads},
+		checkers.EnvoyFilterChecker{EnvoyFilters: istioDetails.EnvoyFilters, IstioVersion: istioVersion, WorkloadList: workloads},
+		checkers.WorkloadEntryChecker{WorkloadEntries: istioDetails.WorkloadEntries, Services: services, ServiceEntries: istioDetails.ServiceEntries},
+		checkers.WorkloadGroupChecker{WorkloadGroups: istioDetails.WorkloadGroups, Services: services, ServiceEntries: istioDetails.ServiceEntries},
 	}
 }
 
@@ -187,15 +455,18 @@ func (in *IstioValidationsService) GetIstioObjectValidations(namespace string, o
 		peerAuthnChecker := checkers.PeerAuthenticationChecker{PeerAuthentications: mtlsDetails.PeerAuthentications, MTLSDetails: mtlsDetails, WorkloadList: workloads}
 		objectCheckers = []ObjectChecker{peerAuthnChecker}
 	case kubernetes.WorkloadEntries:
-		// Validation on WorkloadEntries are not yet in place
+		workloadEntryChecker := checkers.WorkloadEntryChecker{WorkloadEntries: istioDetails.WorkloadEntries, Services: services, ServiceEntries: istioDetails.ServiceEntries}
+		objectCheckers = []ObjectChecker{workloadEntryChecker}
 	case kubernetes.WorkloadGroups:
-		// Validation on WorkloadGroups are not yet in place
+		workloadGroupChecker := checkers.WorkloadGroupChecker{WorkloadGroups: istioDetails.WorkloadGroups, Services: services, ServiceEntries: istioDetails.ServiceEntries}
+		objectCheckers = []ObjectChecker{workloadGroupChecker}
 	case kubernetes.RequestAuthentications:
 		// Validation on RequestAuthentications are not yet in place
 		requestAuthnChecker := checkers.RequestAuthenticationChecker{RequestAuthentications: istioDetails.RequestAuthentications, WorkloadList: workloads}
 		objectCheckers = []ObjectChecker{requestAuthnChecker}
 	case kubernetes.EnvoyFilters:
-		// Validation on EnvoyFilters are not yet in place
+		envoyFilterChecker := checkers.EnvoyFilterChecker{EnvoyFilters: istioDetails.EnvoyFilters, IstioVersion: envoyFiltersIstioVersion(istioDetails.EnvoyFilters), WorkloadList: workloads}
+		objectCheckers = []ObjectChecker{envoyFilterChecker}
 	default:
 		err = fmt.Errorf("object type not found: %v", objectType)
 	}
@@ -214,6 +485,34 @@ func (in *IstioValidationsService) GetIstioObjectValidations(namespace string, o
 	return runObjectCheckers(objectCheckers).FilterByKey(models.ObjectTypeSingular[objectType], object), nil
 }
 
+// envoyFiltersIstioVersion returns the running Istio version to validate the proxy versions of the EnvoyFilters
+// with. It is only fetched when a proxy version is set, and is empty when unknown.
+func envoyFiltersIstioVersion(envoyFilters []kubernetes.IstioObject) string {
+	if !hasProxyVersion(envoyFilters) {
+		return ""
+	}
+	version, err := status.IstioVersion()
+	if err != nil {
+		log.Warningf("Unable to get the Istio version, the EnvoyFilter proxy versions are not validated: %v", err)
+	}
+	return version
+}
+
+// hasProxyVersion returns true when a patch of the EnvoyFilters matches a proxy version
+func hasProxyVersion(envoyFilters []kubernetes.IstioObject) bool {
+	for _, ef := range envoyFilters {
+		patches, _ := ef.GetSpec()["configPatches"].([]interface{})
+		for _, p := range patches {
+			patch, _ := p.(map[string]interface{})
+			match, _ := patch["match"].(map[string]interface{})
+			if proxy, ok := match["proxy"].(map[string]interface{}); ok && proxy["proxyVersion"] != nil {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 func runObjectCheckers(objectCheckers []ObjectChecker) models.IstioValidations {
 	objectTypeValidations := models.IstioValidations{}
 
@@ -462,6 +761,33 @@ func (in *IstioValidationsService) fetchDetails(rValue *kubernetes.IstioDetails,
 			}
 			go fetchIstioObjects(&istioDetails.RequestAuthentications, namespace, getRequestAuthentications, &wg2, errChan2)
 		}
+		if IsResourceCached(namespace, kubernetes.EnvoyFilters) {
+			istioDetails.EnvoyFilters, err = kialiCache.GetIstioObjects(namespace, kubernetes.EnvoyFilters, "")
+		} else {
+			wg2.Add(1)
+			getEnvoyFilters := func(namespace string) ([]kubernetes.IstioObject, error) {
+				return in.k8s.GetIstioObjects(namespace, kubernetes.EnvoyFilters, "")
+			}
+			go fetchIstioObjects(&istioDetails.EnvoyFilters, namespace, getEnvoyFilters, &wg2, errChan2)
+		}
+		if IsResourceCached(namespace, kubernetes.WorkloadEntries) {
+			istioDetails.WorkloadEntries, err = kialiCache.GetIstioObjects(namespace, kubernetes.WorkloadEntries, "")
+		} else {
+			wg2.Add(1)
+			getWorkloadEntries := func(namespace string) ([]kubernetes.IstioObject, error) {
+				return in.k8s.GetIstioObjects(namespace, kubernetes.WorkloadEntries, "")
+			}
+			go fetchIstioObjects(&istioDetails.WorkloadEntries, namespace, getWorkloadEntries, &wg2, errChan2)
+		}
+		if IsResourceCached(namespace, kubernetes.WorkloadGroups) {
+			istioDetails.WorkloadGroups, err = kialiCache.GetIstioObjects(namespace, kubernetes.WorkloadGroups, "")
+		} else {
+			wg2.Add(1)
+			getWorkloadGroups := func(namespace string) ([]kubernetes.IstioObject, error) {
+				return in.k8s.GetIstioObjects(namespace, kubernetes.WorkloadGroups, "")
+			}
+			go fetchIstioObjects(&istioDetails.WorkloadGroups, namespace, getWorkloadGroups, &wg2, errChan2)
+		}
 		wg2.Wait()
 
 		// Error may come either from errChan2 (when goroutines are used / without cache) or err (with cache / synchronous)
@@ -1,6 +1,9 @@
 package business
 
 import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
 
 	osapps_v1 "github.com/openshift/api/apps/v1"
@@ -10,6 +13,7 @@ import (
 	batch_v1 "k8s.io/api/batch/v1"
 	batch_v1beta1 "k8s.io/api/batch/v1beta1"
 	core_v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
 	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	"github.com/kiali/kiali/config"
@@ -44,6 +48,55 @@ func TestGetIstioObjectValidations(t *testing.T) {
 	assert.NotEmpty(validations)
 }
 
+func TestEnvoyFilterValidation(t *testing.T) {
+	assert := assert.New(t)
+	conf := config.NewConfig()
+	config.Set(conf)
+
+	vs := mockCombinedValidationService(fakeCombinedIstioDetails(), []string{"details", "product", "customer"}, fakePods())
+
+	validations, _ := vs.GetIstioObjectValidations("test", "envoyfilters", "product-ef")
+
+	validation, ok := validations[models.IstioValidationKey{ObjectType: "envoyfilter", Namespace: "test", Name: "product-ef"}]
+	assert.True(ok)
+	assert.False(validation.Valid)
+	assert.Equal(models.CheckMessage("envoyfilter.patch.unknownapplyto"), validation.Checks[0].Message)
+}
+
+func TestWorkloadEntryValidation(t *testing.T) {
+	assert := assert.New(t)
+	conf := config.NewConfig()
+	config.Set(conf)
+
+	vs := mockCombinedValidationService(fakeCombinedIstioDetails(), []string{"details", "product", "customer"}, fakePods())
+
+	validations, _ := vs.GetIstioObjectValidations("test", "workloadentries", "product-vm1")
+
+	validation, ok := validations[models.IstioValidationKey{ObjectType: "workloadentry", Namespace: "test", Name: "product-vm1"}]
+	assert.True(ok)
+	assert.True(validation.Valid)
+	assert.Len(validation.Checks, 2)
+	assert.Equal(models.CheckMessage("workloadentries.selector.noservice"), validation.Checks[0].Message)
+	assert.Equal(models.CheckMessage("workloadentries.address.duplicate"), validation.Checks[1].Message)
+	assert.Len(validation.References, 1)
+}
+
+func TestWorkloadGroupValidation(t *testing.T) {
+	assert := assert.New(t)
+	conf := config.NewConfig()
+	config.Set(conf)
+
+	vs := mockCombinedValidationService(fakeCombinedIstioDetails(), []string{"details", "product", "customer"}, fakePods())
+
+	validations, _ := vs.GetIstioObjectValidations("test", "workloadgroups", "product-wg")
+
+	validation, ok := validations[models.IstioValidationKey{ObjectType: "workloadgroup", Namespace: "test", Name: "product-wg"}]
+	assert.True(ok)
+	assert.True(validation.Valid)
+	assert.Len(validation.Checks, 1)
+	assert.Equal(models.CheckMessage("workloadgroups.template.nonetwork"), validation.Checks[0].Message)
+}
+
 func TestGatewayValidation(t *testing.T) {
 	assert := assert.New(t)
 	conf := config.NewConfig()
@@ -54,6 +107,125 @@ func TestGatewayValidation(t *testing.T) {
 	assert.NotEmpty(validations)
 }
 
+func TestGetMeshValidations(t *testing.T) {
+	assert := assert.New(t)
+	conf := config.NewConfig()
+	config.Set(conf)
+
+	v := mockMeshValidationService()
+	validations, err := v.GetMeshValidations()
+	assert.NoError(err)
+	assert.Len(validations, 2)
+
+	// the DestinationRules of both namespaces are exported to every namespace for the same host
+	key := models.IstioValidationKey{ObjectType: "destinationrule", Namespace: "test", Name: "product-dr"}
+	validation, ok := validations["test"][key]
+	assert.True(ok)
+	assert.Equal(models.CheckMessage("destinationrules.multimatch.exported"), validation.Checks[0].Message)
+	assert.Equal([]models.IstioValidationKey{{ObjectType: "destinationrule", Namespace: "test2", Name: "product-dr"}}, validation.References)
+
+	validation, ok = validations["test2"][models.IstioValidationKey{ObjectType: "destinationrule", Namespace: "test2", Name: "product-dr"}]
+	assert.True(ok)
+	assert.Equal([]models.IstioValidationKey{key}, validation.References)
+}
+
+func TestGetMeshValidationsIstioVersion(t *testing.T) {
+	assert := assert.New(t)
+
+	requests := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		_, _ = w.Write([]byte("1.9.0"))
+	}))
+	defer ts.Close()
+
+	istioDetails := fakeCombinedIstioDetails()
+	istioDetails.EnvoyFilters = []kubernetes.IstioObject{
+		data.AddPatchToEnvoyFilter("HTTP_FILTER", map[string]interface{}{"proxy": map[string]interface{}{"proxyVersion": "^1\\.8.*"}}, data.CreateEnvoyFilter("product-ef", "test")),
+	}
+	vs := mockCombinedValidationService(istioDetails, []string{"details", "product", "customer"}, fakePods())
+
+	// the mocks reset the config
+	conf := config.NewConfig()
+	conf.ExternalServices.Istio.UrlServiceVersion = ts.URL
+	config.Set(conf)
+
+	validations, err := vs.GetMeshValidations()
+	assert.NoError(err)
+	// the Istio version is fetched once for all the validated namespaces
+	assert.Equal(1, requests)
+
+	validation, ok := validations["test"][models.IstioValidationKey{ObjectType: "envoyfilter", Namespace: "test", Name: "product-ef"}]
+	assert.True(ok)
+	assert.Equal(models.CheckMessage("envoyfilter.proxyversion.nomatch"), validation.Checks[0].Message)
+}
+
+func TestGetDryRunValidations(t *testing.T) {
+	assert := assert.New(t)
+	conf := config.NewConfig()
+	config.Set(conf)
+
+	objects, err := kubernetes.ParseIstioObjects(strings.NewReader(`
+apiVersion: networking.istio.io/v1alpha3
+kind: DestinationRule
+metadata:
+  name: product-dr
+  namespace: test2
+spec:
+  host: product
+---
+apiVersion: networking.istio.io/v1alpha3
+kind: WorkloadGroup
+metadata:
+  name: product-wg
+  namespace: test
+spec:
+  metadata:
+    labels:
+      app: product
+  template:
+    serviceAccount: product
+`))
+	assert.NoError(err)
+
+	v := mockMeshValidationService()
+	diff, err := v.GetDryRunValidations(objects)
+	assert.NoError(err)
+
+	// the DestinationRule now targets a host of the service registry
+	assert.Len(diff.Resolved, 1)
+	validation, ok := diff.Resolved["test2"][models.IstioValidationKey{ObjectType: "destinationrule", Namespace: "test2", Name: "product-dr"}]
+	assert.True(ok)
+	assert.Len(validation.Checks, 1)
+	assert.Equal(models.CheckMessage("destinationrules.nodest.matchingregistry"), validation.Checks[0].Message)
+
+	assert.Len(diff.New, 1)
+	validation, ok = diff.New["test"][models.IstioValidationKey{ObjectType: "workloadgroup", Namespace: "test", Name: "product-wg"}]
+	assert.True(ok)
+	assert.Len(validation.Checks, 1)
+	assert.Equal(models.CheckMessage("workloadgroups.template.nonetwork"), validation.Checks[0].Message)
+}
+
+func TestGetDryRunValidationsErrors(t *testing.T) {
+	assert := assert.New(t)
+	conf := config.NewConfig()
+	config.Set(conf)
+
+	v := mockMeshValidationService()
+
+	// the namespace is required
+	objects, err := kubernetes.ParseIstioObjects(strings.NewReader("kind: VirtualService\nmetadata:\n  name: product-vs\n"))
+	assert.NoError(err)
+	_, err = v.GetDryRunValidations(objects)
+	assert.True(errors.IsBadRequest(err))
+
+	// Iter8 experiments are not validated
+	objects, err = kubernetes.ParseIstioObjects(strings.NewReader("kind: Experiment\nmetadata:\n  name: product\n  namespace: test\n"))
+	assert.NoError(err)
+	_, err = v.GetDryRunValidations(objects)
+	assert.True(errors.IsBadRequest(err))
+}
+
 func mockWorkLoadService(k8s *kubetest.K8SClientMock) WorkloadService {
 	// Setup mocks
 	k8s.On("IsOpenShift").Return(true)
@@ -88,6 +260,9 @@ func mockMultiNamespaceGatewaysValidationService() IstioValidationsService {
 	k8s.On("GetMeshPolicies", mock.AnythingOfType("string")).Return(fakeMeshPolicies(), nil)
 	k8s.On("GetIstioObjects", mock.AnythingOfType("string"), "peerauthentications", "").Return(fakePolicies(), nil)
 	k8s.On("GetIstioObjects", mock.AnythingOfType("string"), "requestauthentications", "").Return([]kubernetes.IstioObject{}, nil)
+	k8s.On("GetIstioObjects", mock.AnythingOfType("string"), "envoyfilters", "").Return([]kubernetes.IstioObject{}, nil)
+	k8s.On("GetIstioObjects", mock.AnythingOfType("string"), "workloadentries", "").Return([]kubernetes.IstioObject{}, nil)
+	k8s.On("GetIstioObjects", mock.AnythingOfType("string"), "workloadgroups", "").Return([]kubernetes.IstioObject{}, nil)
 	k8s.On("GetIstioObjects", mock.AnythingOfType("string"), "clusterrbacconfigs", "").Return([]kubernetes.IstioObject{}, nil)
 	k8s.On("GetIstioObjects", mock.AnythingOfType("string"), "authorizationpolicies", "").Return([]kubernetes.IstioObject{}, nil)
 	k8s.On("GetIstioObjects", mock.AnythingOfType("string"), "servicerolebindings", "").Return([]kubernetes.IstioObject{}, nil)
@@ -98,10 +273,35 @@ func mockMultiNamespaceGatewaysValidationService() IstioValidationsService {
 	return IstioValidationsService{k8s: k8s, businessLayer: NewWithBackends(k8s, nil, nil)}
 }
 
+func mockMeshValidationService() IstioValidationsService {
+	k8s := new(kubetest.K8SClientMock)
+	k8s.On("IsOpenShift").Return(false)
+	k8s.On("GetNamespace", mock.AnythingOfType("string")).Return(&core_v1.Namespace{}, nil)
+	k8s.On("IsMaistraApi").Return(false)
+	k8s.On("GetNamespaces", mock.AnythingOfType("string")).Return(fakeNamespaces(), nil)
+	mockWorkLoadService(k8s)
+	k8s.On("GetIstioObjects", "test", "destinationrules", "").Return([]kubernetes.IstioObject{
+		data.CreateEmptyDestinationRule("test", "product-dr", "product"),
+	}, nil)
+	k8s.On("GetIstioObjects", "test2", "destinationrules", "").Return([]kubernetes.IstioObject{
+		data.CreateEmptyDestinationRule("test2", "product-dr", "product.test.svc.cluster.local"),
+	}, nil)
+	k8s.On("GetServices", mock.AnythingOfType("string"), mock.AnythingOfType("map[string]string")).Return(fakeCombinedServices([]string{"product"}), nil)
+	for _, objectType := range []string{"peerauthentications", "gateways", "sidecars", "requestauthentications", "envoyfilters", "workloadentries", "workloadgroups",
+		"clusterrbacconfigs", "authorizationpolicies", "servicerolebindings", "serviceroles", "virtualservices", "serviceentries"} {
+		k8s.On("GetIstioObjects", mock.AnythingOfType("string"), objectType, "").Return([]kubernetes.IstioObject{}, nil)
+	}
+
+	return IstioValidationsService{k8s: k8s, businessLayer: NewWithBackends(k8s, nil, nil)}
+}
+
 func mockCombinedValidationService(istioObjects *kubernetes.IstioDetails, services []string, podList *core_v1.PodList) IstioValidationsService {
 	k8s := new(kubetest.K8SClientMock)
 	k8s.On("GetIstioObjects", mock.AnythingOfType("string"), "sidecars", "").Return(istioObjects.Sidecars, nil)
 	k8s.On("GetIstioObjects", mock.AnythingOfType("string"), "requestauthentications", "").Return(istioObjects.RequestAuthentications, nil)
+	k8s.On("GetIstioObjects", mock.AnythingOfType("string"), "envoyfilters", "").Return(istioObjects.EnvoyFilters, nil)
+	k8s.On("GetIstioObjects", mock.AnythingOfType("string"), "workloadentries", "").Return(istioObjects.WorkloadEntries, nil)
+	k8s.On("GetIstioObjects", mock.AnythingOfType("string"), "workloadgroups", "").Return(istioObjects.WorkloadGroups, nil)
 	k8s.On("GetServices", mock.AnythingOfType("string"), mock.AnythingOfType("map[string]string")).Return(fakeCombinedServices(services), nil)
 	k8s.On("GetDeployments", mock.AnythingOfType("string"), mock.AnythingOfType("string")).Return(FakeDepSyncedWithRS(), nil)
 	k8s.On("GetIstioObjects", mock.AnythingOfType("string"), "virtualservices", "").Return(fakeCombinedIstioDetails().VirtualServices, nil)
@@ -139,6 +339,19 @@ func fakeCombinedIstioDetails() *kubernetes.IstioDetails {
 		data.AddSubsetToDestinationRule(data.CreateSubset("v1", "v1"), data.CreateEmptyDestinationRule("test", "product-dr", "product")),
 		data.CreateEmptyDestinationRule("test", "customer-dr", "customer"),
 	}
+
+	istioDetails.EnvoyFilters = []kubernetes.IstioObject{
+		data.AddPatchToEnvoyFilter("HTTP_FILTERS", nil, data.CreateEnvoyFilter("product-ef", "test")),
+	}
+
+	istioDetails.WorkloadEntries = []kubernetes.IstioObject{
+		data.CreateWorkloadEntry("product-vm1", "test", "10.0.0.1", map[string]interface{}{"app": "product"}),
+		data.CreateWorkloadEntry("product-vm2", "test", "10.0.0.1", map[string]interface{}{"app": "product"}),
+	}
+
+	istioDetails.WorkloadGroups = []kubernetes.IstioObject{
+		data.CreateWorkloadGroup("product-wg", "test", map[string]interface{}{"app": "product"}, map[string]interface{}{"serviceAccount": "product"}),
+	}
 	return &istioDetails
 }
 
@@ -168,6 +168,23 @@ func (in *MeshService) IsMeshConfigured() (bool, error) {
 	return isMeshConfigured, nil
 }
 
+// OutboundTrafficPolicyMode returns the mode of the mesh outbound traffic policy, as set in the Istio mesh
+// configuration: ALLOW_ANY or REGISTRY_ONLY. A Sidecar may override it for the workloads it selects.
+func (in *MeshService) OutboundTrafficPolicyMode() (string, error) {
+	cfg := config.Get()
+
+	istioConfig, err := in.k8s.GetConfigMap(cfg.IstioNamespace, cfg.ExternalServices.Istio.ConfigMapName)
+	if err != nil {
+		return "", err
+	}
+
+	meshConfig, err := kubernetes.GetIstioConfigMap(istioConfig)
+	if err != nil {
+		return "", err
+	}
+	return meshConfig.GetOutboundTrafficPolicyMode(), nil
+}
+
 // The Kiali Home Cluster does not change and can be cached
 
 // kialiControlPlaneClusterCached just indicates whether we have cached the home cluster (because it may be nil)
@@ -346,3 +346,26 @@ func TestResolveKialiControlPlaneClusterIsCached(t *testing.T) {
 	check.NotNil(result)
 	check.Equal("KialiCluster", result.Name)
 }
+
+func TestOutboundTrafficPolicyMode(t *testing.T) {
+	check := assert.New(t)
+
+	conf := config.NewConfig()
+	conf.IstioNamespace = "foo"
+	conf.ExternalServices.Istio.ConfigMapName = "bar"
+	config.Set(conf)
+
+	for mesh, expected := range map[string]string{
+		"{ \"outboundTrafficPolicy\": { \"mode\": \"REGISTRY_ONLY\" } }": kubernetes.OutboundTrafficPolicyRegistryOnly,
+		"{ \"defaultConfig\": { \"meshId\": \"kialiMesh\" } }":           kubernetes.OutboundTrafficPolicyAllowAny,
+	} {
+		k8s := new(kubetest.K8SClientMock)
+		k8s.On("IsOpenShift").Return(false)
+		k8s.On("GetConfigMap", "foo", "bar").Return(&core_v1.ConfigMap{Data: map[string]string{"mesh": mesh}}, nil)
+
+		layer := NewWithBackends(k8s, nil, nil)
+		mode, err := layer.Mesh.OutboundTrafficPolicyMode()
+		check.Nil(err)
+		check.Equal(expected, mode)
+	}
+}
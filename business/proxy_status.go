@@ -1,12 +1,17 @@
 package business
 
 import (
+	"fmt"
+
 	"k8s.io/client-go/tools/clientcmd/api"
 
 	"github.com/kiali/kiali/kubernetes"
 	"github.com/kiali/kiali/models"
 )
 
+// envoyStatsPort is the port on which the Envoy proxy exposes its stats, in the Prometheus format
+const envoyStatsPort = 15090
+
 type ProxyStatusService struct {
 	k8s           kubernetes.ClientInterface
 	businessLayer *Layer
@@ -81,6 +86,12 @@ func xdsStatus(sent, acked string) string {
 	return "Stale"
 }
 
+// GetProxyStats returns the stats of the pod's Envoy proxy, in the Prometheus text format. The stats are
+// fetched through the Kubernetes API server pod proxy, from the port on which Envoy exposes its stats.
+func (in *ProxyStatusService) GetProxyStats(namespace, pod string) ([]byte, error) {
+	return in.k8s.GetPodProxy(namespace, fmt.Sprintf("%s:%d", pod, envoyStatsPort), "/stats/prometheus")
+}
+
 func (in *ProxyStatusService) GetConfigDump(namespace, pod string) (models.EnvoyProxyDump, error) {
 	dump, err := in.k8s.GetConfigDump(namespace, pod)
 	return models.EnvoyProxyDump{ConfigDump: dump}, err
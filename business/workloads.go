@@ -231,6 +231,11 @@ func (in *WorkloadService) UpdateWorkload(namespace string, workloadName string,
 	return in.GetWorkload(namespace, workloadName, workloadType, includeServices)
 }
 
+// GetWorkloads returns the workloads of a given namespace, including their pods
+func (in *WorkloadService) GetWorkloads(namespace string) (models.Workloads, error) {
+	return fetchWorkloads(in.businessLayer, namespace, "")
+}
+
 func (in *WorkloadService) GetPods(namespace string, labelSelector string) (models.Pods, error) {
 	var err error
 	var ps []core_v1.Pod
@@ -0,0 +1,92 @@
+// Package client provides a Go client of the Kiali API, calling the graph and metrics endpoints and returning
+// their typed responses: cytoscape.Config for the graphs and models.MetricsMap for the metrics.
+//
+// The graph responses are versioned (see cytoscape.SchemaVersion). The client rejects a graph of a major schema
+// version other than the one it is built with, rather than returning a partially decoded graph.
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// Client calls the Kiali API
+type Client struct {
+	httpClient *http.Client
+	token      string
+	url        *url.URL
+}
+
+// Error is returned when the Kiali API responds with an error status code
+type Error struct {
+	StatusCode int
+	Message    string `json:"error"`
+	Detail     string `json:"detail"`
+}
+
+func (e *Error) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("kiali responded with status code %d", e.StatusCode)
+	}
+	return fmt.Sprintf("kiali responded with status code %d: %s", e.StatusCode, e.Message)
+}
+
+// NewClient returns a client of the Kiali API served at kialiURL, e.g. https://kiali.example.com/kiali. When not
+// empty, the token is sent as a bearer token. A nil httpClient uses http.DefaultClient.
+func NewClient(kialiURL, token string, httpClient *http.Client) (*Client, error) {
+	u, err := url.Parse(kialiURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Kiali URL [%s]: %v", kialiURL, err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("invalid Kiali URL [%s]: the scheme must be http or https", kialiURL)
+	}
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return &Client{httpClient: httpClient, token: token, url: u}, nil
+}
+
+// get calls the API endpoint, e.g. /namespaces/graph, and decodes the JSON response into result
+func (c *Client) get(ctx context.Context, endpoint string, params url.Values, result interface{}) error {
+	u := *c.url
+	u.Path = path.Join("/", u.Path, "api", endpoint)
+	u.RawQuery = params.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		apiErr := &Error{StatusCode: resp.StatusCode}
+		if json.Unmarshal(body, apiErr) != nil {
+			apiErr.Message = strings.TrimSpace(string(body))
+		}
+		return apiErr
+	}
+	if err := json.Unmarshal(body, result); err != nil {
+		return fmt.Errorf("cannot decode the response of [%s]: %v", endpoint, err)
+	}
+	return nil
+}
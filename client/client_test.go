@@ -0,0 +1,151 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/kiali/kiali/graph/config/cytoscape"
+	"github.com/kiali/kiali/models"
+)
+
+func newTestServer(t *testing.T, handler http.HandlerFunc) (*Client, *httptest.Server) {
+	ts := httptest.NewServer(handler)
+	client, err := NewClient(ts.URL+"/kiali", "token", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client, ts
+}
+
+func TestNamespacesGraph(t *testing.T) {
+	assert := assert.New(t)
+
+	var request *http.Request
+	client, ts := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		request = r
+		_ = json.NewEncoder(w).Encode(cytoscape.Config{
+			Version:   cytoscape.SchemaVersion,
+			GraphType: "app",
+			Elements: cytoscape.Elements{
+				Nodes: []*cytoscape.NodeWrapper{{Data: &cytoscape.NodeData{ID: "n0", App: "reviews"}}},
+			},
+		})
+	})
+	defer ts.Close()
+
+	config, err := client.NamespacesGraph(context.Background(), []string{"bookinfo", "istio-system"}, GraphOptions{
+		Appenders: []string{},
+		Duration:  10 * time.Minute,
+		GraphType: "app",
+		QueryTime: time.Unix(1523364075, 0),
+	})
+	assert.NoError(err)
+	assert.Equal("app", config.GraphType)
+	assert.Equal("reviews", config.Elements.Nodes[0].Data.App)
+
+	assert.Equal("/kiali/api/namespaces/graph", request.URL.Path)
+	assert.Equal("Bearer token", request.Header.Get("Authorization"))
+	query := request.URL.Query()
+	assert.Equal("bookinfo,istio-system", query.Get("namespaces"))
+	assert.Equal("", query.Get("appenders"))
+	assert.Contains(query, "appenders")
+	assert.Equal("600s", query.Get("duration"))
+	assert.Equal("1523364075", query.Get("queryTime"))
+	assert.Equal("cytoscape", query.Get("configVendor"))
+	assert.NotContains(query, "boxBy")
+}
+
+func TestNodeGraph(t *testing.T) {
+	assert := assert.New(t)
+
+	var path string
+	client, ts := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		_, _ = w.Write([]byte(`{"version":"1.9","elements":{"nodes":[],"edges":[]}}`))
+	})
+	defer ts.Close()
+
+	// a newer minor version is supported
+	_, err := client.NodeGraph(context.Background(), Node{Namespace: "bookinfo", App: "reviews", Version: "v1"}, GraphOptions{})
+	assert.NoError(err)
+	assert.Equal("/kiali/api/namespaces/bookinfo/applications/reviews/versions/v1/graph", path)
+
+	_, err = client.NodeGraph(context.Background(), Node{Namespace: "bookinfo", Workload: "reviews-v1"}, GraphOptions{})
+	assert.NoError(err)
+	assert.Equal("/kiali/api/namespaces/bookinfo/workloads/reviews-v1/graph", path)
+
+	_, err = client.NodeGraph(context.Background(), Node{Namespace: "bookinfo"}, GraphOptions{})
+	assert.Error(err)
+}
+
+func TestGraphVersion(t *testing.T) {
+	assert := assert.New(t)
+
+	for _, version := range []string{"", "0.9", "2.0", "10.0"} {
+		client, ts := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+			_ = json.NewEncoder(w).Encode(cytoscape.Config{Version: version})
+		})
+		_, err := client.NamespacesGraph(context.Background(), []string{"bookinfo"}, GraphOptions{})
+		ts.Close()
+
+		versionErr, ok := err.(*VersionError)
+		if assert.True(ok, version) {
+			assert.Equal(version, versionErr.Version)
+		}
+	}
+}
+
+func TestMetrics(t *testing.T) {
+	assert := assert.New(t)
+
+	var request *http.Request
+	client, ts := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		request = r
+		_ = json.NewEncoder(w).Encode(models.MetricsMap{
+			"request_count": []models.Metric{{Name: "request_count", Datapoints: []models.Datapoint{{Timestamp: 1523364075, Value: 2.5}}}},
+		})
+	})
+	defer ts.Close()
+
+	metrics, err := client.WorkloadMetrics(context.Background(), "bookinfo", "reviews-v1", MetricsOptions{
+		Direction: "inbound",
+		Duration:  30 * time.Minute,
+		Filters:   []string{"request_count", "request_error_count"},
+		Step:      time.Minute,
+	})
+	assert.NoError(err)
+	assert.Equal(2.5, metrics["request_count"][0].Datapoints[0].Value)
+
+	assert.Equal("/kiali/api/namespaces/bookinfo/workloads/reviews-v1/metrics", request.URL.Path)
+	query := request.URL.Query()
+	assert.Equal([]string{"request_count", "request_error_count"}, query["filters[]"])
+	assert.Equal("inbound", query.Get("direction"))
+	assert.Equal("1800", query.Get("duration"))
+	assert.Equal("60", query.Get("step"))
+}
+
+func TestError(t *testing.T) {
+	assert := assert.New(t)
+
+	client, ts := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(`{"error":"Invalid graphType [foo]"}`))
+	})
+	defer ts.Close()
+
+	_, err := client.NamespacesGraph(context.Background(), []string{"bookinfo"}, GraphOptions{GraphType: "foo"})
+	apiErr, ok := err.(*Error)
+	if assert.True(ok) {
+		assert.Equal(http.StatusBadRequest, apiErr.StatusCode)
+		assert.Equal("Invalid graphType [foo]", apiErr.Message)
+	}
+
+	_, err = NewClient("kiali.example.com", "", nil)
+	assert.Error(err)
+}
@@ -0,0 +1,113 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kiali/kiali/graph/config/cytoscape"
+)
+
+// GraphOptions holds the graph query parameters. Zero values are not sent, the server defaults apply.
+type GraphOptions struct {
+	Appenders       []string      // the appenders to run, nil runs the default appenders
+	BoxBy           []string      // e.g. app, cluster, namespace, version or a configured label
+	Duration        time.Duration // the time window
+	GraphType       string        // app | service | versionedApp | workload
+	QueryTime       time.Time     // the end of the time window
+	TelemetryVendor string        // istio | envoy | tracing
+	Params          url.Values    // any other query parameter, e.g. find, hide or depth
+}
+
+// Node identifies the node of a node graph. Set exactly one of App, Service or Workload, and optionally the
+// Version of an App.
+type Node struct {
+	Namespace string
+	App       string
+	Version   string
+	Service   string
+	Workload  string
+}
+
+// VersionError is returned for a graph of a schema version the client does not support
+type VersionError struct {
+	Version string
+}
+
+func (e *VersionError) Error() string {
+	return fmt.Sprintf("unsupported graph schema version [%s], the client supports version %s", e.Version, cytoscape.SchemaVersion)
+}
+
+// NamespacesGraph returns the graph of the namespaces
+func (c *Client) NamespacesGraph(ctx context.Context, namespaces []string, o GraphOptions) (*cytoscape.Config, error) {
+	params := o.params()
+	params.Set("namespaces", strings.Join(namespaces, ","))
+	return c.graph(ctx, "/namespaces/graph", params)
+}
+
+// NodeGraph returns the graph of a node, detailing its incoming and outgoing traffic
+func (c *Client) NodeGraph(ctx context.Context, node Node, o GraphOptions) (*cytoscape.Config, error) {
+	var endpoint string
+	switch {
+	case node.App != "" && node.Version != "":
+		endpoint = fmt.Sprintf("/namespaces/%s/applications/%s/versions/%s/graph", node.Namespace, node.App, node.Version)
+	case node.App != "":
+		endpoint = fmt.Sprintf("/namespaces/%s/applications/%s/graph", node.Namespace, node.App)
+	case node.Service != "":
+		endpoint = fmt.Sprintf("/namespaces/%s/services/%s/graph", node.Namespace, node.Service)
+	case node.Workload != "":
+		endpoint = fmt.Sprintf("/namespaces/%s/workloads/%s/graph", node.Namespace, node.Workload)
+	default:
+		return nil, fmt.Errorf("invalid node, one of app, service or workload is required")
+	}
+	return c.graph(ctx, endpoint, o.params())
+}
+
+func (c *Client) graph(ctx context.Context, endpoint string, params url.Values) (*cytoscape.Config, error) {
+	// only the cytoscape config vendor is versioned
+	params.Set("configVendor", "cytoscape")
+
+	config := &cytoscape.Config{}
+	if err := c.get(ctx, endpoint, params, config); err != nil {
+		return nil, err
+	}
+	if !IsSupportedVersion(config.Version) {
+		return nil, &VersionError{Version: config.Version}
+	}
+	return config, nil
+}
+
+// IsSupportedVersion returns true if the graph schema version has the major version of cytoscape.SchemaVersion
+func IsSupportedVersion(version string) bool {
+	major := strings.SplitN(cytoscape.SchemaVersion, ".", 2)[0]
+	return strings.HasPrefix(version, major+".")
+}
+
+func (o GraphOptions) params() url.Values {
+	params := url.Values{}
+	for k, v := range o.Params {
+		params[k] = v
+	}
+	if o.Appenders != nil {
+		params.Set("appenders", strings.Join(o.Appenders, ","))
+	}
+	if len(o.BoxBy) > 0 {
+		params.Set("boxBy", strings.Join(o.BoxBy, ","))
+	}
+	if o.Duration > 0 {
+		params.Set("duration", fmt.Sprintf("%ds", int64(o.Duration.Seconds())))
+	}
+	if o.GraphType != "" {
+		params.Set("graphType", o.GraphType)
+	}
+	if !o.QueryTime.IsZero() {
+		params.Set("queryTime", strconv.FormatInt(o.QueryTime.Unix(), 10))
+	}
+	if o.TelemetryVendor != "" {
+		params.Set("telemetryVendor", o.TelemetryVendor)
+	}
+	return params
+}
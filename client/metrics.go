@@ -0,0 +1,88 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/kiali/kiali/models"
+)
+
+// MetricsOptions holds the metrics query parameters. Zero values are not sent, the server defaults apply.
+type MetricsOptions struct {
+	ByLabels     []string      // the labels to group the metrics by
+	Direction    string        // inbound | outbound
+	Duration     time.Duration // the time window
+	Filters      []string      // the metric names, e.g. request_count
+	Quantiles    []string      // the histogram quantiles, e.g. 0.99
+	QueryTime    time.Time     // the end of the time window
+	RateInterval string        // e.g. 1m
+	Reporter     string        // source | destination
+	Step         time.Duration // the resolution of the time series
+	Params       url.Values    // any other query parameter, e.g. requestProtocol or avg
+}
+
+// NamespaceMetrics returns the metrics of a namespace
+func (c *Client) NamespaceMetrics(ctx context.Context, namespace string, o MetricsOptions) (models.MetricsMap, error) {
+	return c.metrics(ctx, fmt.Sprintf("/namespaces/%s/metrics", namespace), o)
+}
+
+// AppMetrics returns the metrics of an app
+func (c *Client) AppMetrics(ctx context.Context, namespace, app string, o MetricsOptions) (models.MetricsMap, error) {
+	return c.metrics(ctx, fmt.Sprintf("/namespaces/%s/apps/%s/metrics", namespace, app), o)
+}
+
+// ServiceMetrics returns the metrics of a service
+func (c *Client) ServiceMetrics(ctx context.Context, namespace, service string, o MetricsOptions) (models.MetricsMap, error) {
+	return c.metrics(ctx, fmt.Sprintf("/namespaces/%s/services/%s/metrics", namespace, service), o)
+}
+
+// WorkloadMetrics returns the metrics of a workload
+func (c *Client) WorkloadMetrics(ctx context.Context, namespace, workload string, o MetricsOptions) (models.MetricsMap, error) {
+	return c.metrics(ctx, fmt.Sprintf("/namespaces/%s/workloads/%s/metrics", namespace, workload), o)
+}
+
+func (c *Client) metrics(ctx context.Context, endpoint string, o MetricsOptions) (models.MetricsMap, error) {
+	metrics := models.MetricsMap{}
+	if err := c.get(ctx, endpoint, o.params(), &metrics); err != nil {
+		return nil, err
+	}
+	return metrics, nil
+}
+
+func (o MetricsOptions) params() url.Values {
+	params := url.Values{}
+	for k, v := range o.Params {
+		params[k] = v
+	}
+	if len(o.ByLabels) > 0 {
+		params["byLabels[]"] = o.ByLabels
+	}
+	if o.Direction != "" {
+		params.Set("direction", o.Direction)
+	}
+	if o.Duration > 0 {
+		params.Set("duration", strconv.FormatInt(int64(o.Duration.Seconds()), 10))
+	}
+	if len(o.Filters) > 0 {
+		params["filters[]"] = o.Filters
+	}
+	if len(o.Quantiles) > 0 {
+		params["quantiles[]"] = o.Quantiles
+	}
+	if !o.QueryTime.IsZero() {
+		params.Set("queryTime", strconv.FormatInt(o.QueryTime.Unix(), 10))
+	}
+	if o.RateInterval != "" {
+		params.Set("rateInterval", o.RateInterval)
+	}
+	if o.Reporter != "" {
+		params.Set("reporter", o.Reporter)
+	}
+	if o.Step > 0 {
+		params.Set("step", strconv.FormatInt(int64(o.Step.Seconds()), 10))
+	}
+	return params
+}
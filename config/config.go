@@ -323,6 +323,23 @@ type GraphFindOption struct {
 	Expression  string `yaml:"expression,omitempty" json:"expression,omitempty"`
 }
 
+// GraphBoxingConfig defines the workload labels, in addition to the version, by which graph nodes can be boxed
+type GraphBoxingConfig struct {
+	Labels []string `yaml:"labels,omitempty"`
+}
+
+// GraphCacheConfig defines the caching of generated TrafficMaps, shared by identical graph requests
+type GraphCacheConfig struct {
+	Duration int  `yaml:"duration,omitempty"` // expressed in seconds, query times are aligned to the duration
+	Enabled  bool `yaml:"enabled"`
+}
+
+// GraphSnapshotsConfig defines where graph snapshots are persisted
+type GraphSnapshotsConfig struct {
+	Path  string `yaml:"path,omitempty"`  // the directory of the filesystem store
+	Store string `yaml:"store,omitempty"` // the store type, currently only "filesystem"
+}
+
 // GraphUIDefaults defines UI Defaults specific to the UI Graph
 type GraphUIDefaults struct {
 	FindOptions []GraphFindOption `yaml:"find_options,omitempty" json:"findOptions,omitempty"`
@@ -375,6 +392,9 @@ type Config struct {
 	Deployment               DeploymentConfig                    `yaml:"deployment,omitempty"`
 	Extensions               Extensions                          `yaml:"extensions,omitempty"`
 	ExternalServices         ExternalServices                    `yaml:"external_services,omitempty"`
+	GraphBoxing              GraphBoxingConfig                   `yaml:"graph_boxing,omitempty"`
+	GraphCache               GraphCacheConfig                    `yaml:"graph_cache,omitempty"`
+	GraphSnapshots           GraphSnapshotsConfig                `yaml:"graph_snapshots,omitempty"`
 	HealthConfig             HealthConfig                        `yaml:"health_config,omitempty" json:"healthConfig,omitempty"`
 	Identity                 security.Identity                   `yaml:",omitempty"`
 	InCluster                bool                                `yaml:"in_cluster,omitempty"`
@@ -504,6 +524,17 @@ func NewConfig() (c *Config) {
 				WhiteListIstioSystem: []string{"jaeger-query", "istio-ingressgateway"},
 			},
 		},
+		GraphBoxing: GraphBoxingConfig{
+			Labels: []string{"team", "tier"},
+		},
+		GraphCache: GraphCacheConfig{
+			Duration: 10,
+			Enabled:  true,
+		},
+		GraphSnapshots: GraphSnapshotsConfig{
+			Path:  "/tmp/kiali/graph-snapshots",
+			Store: "filesystem",
+		},
 		IstioLabels: IstioLabels{
 			AppLabelName:       "app",
 			InjectionLabelName: "istio-injection",
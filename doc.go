@@ -4,7 +4,9 @@ import (
 	jaegerModels "github.com/jaegertracing/jaeger/model/json"
 
 	"github.com/kiali/kiali/business"
+	"github.com/kiali/kiali/graph/blastradius"
 	"github.com/kiali/kiali/graph/config/cytoscape"
+	"github.com/kiali/kiali/graph/snapshot"
 	"github.com/kiali/kiali/handlers"
 	"github.com/kiali/kiali/jaeger"
 	"github.com/kiali/kiali/models"
@@ -34,7 +36,7 @@ type AggregateValueParam struct {
 	Name string `json:"aggregateValue"`
 }
 
-// swagger:parameters appMetrics appDetails graphApp graphAppVersion appDashboard appSpans appTraces errorTraces
+// swagger:parameters appMetrics appDetails graphApp graphAppVersion appDashboard appSpans appTraces errorTraces graphAppBlastRadius graphAppVersionBlastRadius
 type AppParam struct {
 	// The app name (label value).
 	//
@@ -43,7 +45,7 @@ type AppParam struct {
 	Name string `json:"app"`
 }
 
-// swagger:parameters graphAppVersion
+// swagger:parameters graphAppVersion graphAppVersionBlastRadius
 type AppVersionParam struct {
 	// The app version (label value).
 	//
@@ -52,7 +54,7 @@ type AppVersionParam struct {
 	Name string `json:"version"`
 }
 
-// swagger:parameters graphAggregate graphAggregateByService graphApp graphAppVersion graphService graphWorkload
+// swagger:parameters graphAggregate graphAggregateByService graphApp graphAppVersion graphService graphWorkload graphAppBlastRadius graphAppVersionBlastRadius graphServiceBlastRadius graphWorkloadBlastRadius
 type ClusterParam struct {
 	// The cluster name. If not supplied queries/results will not be constrained by cluster.
 	//
@@ -70,7 +72,7 @@ type ContainerParam struct {
 	Name string `json:"container"`
 }
 
-// swagger:parameters istioConfigList workloadList workloadDetails workloadUpdate serviceDetails serviceUpdate appSpans serviceSpans workloadSpans appTraces serviceTraces workloadTraces errorTraces workloadValidations appList serviceMetrics aggregateMetrics appMetrics workloadMetrics istioConfigDetails istioConfigDetailsSubtype istioConfigDelete istioConfigDeleteSubtype istioConfigUpdate istioConfigUpdateSubtype serviceList appDetails graphAggregate graphAggregateByService graphApp graphAppVersion graphNamespace graphService graphWorkload namespaceMetrics customDashboard appDashboard serviceDashboard workloadDashboard istioConfigCreate istioConfigCreateSubtype namespaceUpdate namespaceTls podDetails podLogs namespaceValidations getIter8Experiments postIter8Experiments patchIter8Experiments deleteIter8Experiments podProxyDump podProxyResource
+// swagger:parameters istioConfigList workloadList workloadDetails workloadUpdate serviceDetails serviceUpdate appSpans serviceSpans workloadSpans appTraces serviceTraces workloadTraces errorTraces workloadValidations appList serviceMetrics aggregateMetrics appMetrics workloadMetrics istioConfigDetails istioConfigDetailsSubtype istioConfigDelete istioConfigDeleteSubtype istioConfigUpdate istioConfigUpdateSubtype serviceList appDetails graphAggregate graphAggregateByService graphApp graphAppVersion graphNamespace graphService graphWorkload namespaceMetrics customDashboard appDashboard serviceDashboard workloadDashboard istioConfigCreate istioConfigCreateSubtype namespaceUpdate namespaceTls podDetails podLogs namespaceValidations getIter8Experiments postIter8Experiments patchIter8Experiments deleteIter8Experiments podProxyDump podProxyResource graphAppBlastRadius graphAppVersionBlastRadius graphServiceBlastRadius graphWorkloadBlastRadius
 type NamespaceParam struct {
 	// The namespace name.
 	//
@@ -79,6 +81,15 @@ type NamespaceParam struct {
 	Name string `json:"namespace"`
 }
 
+// swagger:parameters istioValidationsDryRun
+type DryRunNamespaceParam struct {
+	// The namespace of the objects without namespace.
+	//
+	// in: query
+	// required: false
+	Name string `json:"namespace"`
+}
+
 // swagger:parameters getIter8Experiments patchIter8Experiments deleteIter8Experiments
 type NameParam struct {
 	// The name param
@@ -125,7 +136,7 @@ type ResourceParam struct {
 	Name string `json:"resource"`
 }
 
-// swagger:parameters serviceDetails serviceUpdate serviceMetrics graphService graphAggregateByService serviceDashboard serviceSpans serviceTraces
+// swagger:parameters serviceDetails serviceUpdate serviceMetrics graphService graphAggregateByService serviceDashboard serviceSpans serviceTraces graphServiceBlastRadius
 type ServiceParam struct {
 	// The service name.
 	//
@@ -171,7 +182,7 @@ type DashboardParam struct {
 	Name string `json:"dashboard"`
 }
 
-// swagger:parameters workloadDetails workloadUpdate workloadValidations workloadMetrics graphWorkload workloadDashboard workloadSpans workloadTraces
+// swagger:parameters workloadDetails workloadUpdate workloadValidations workloadMetrics graphWorkload workloadDashboard workloadSpans workloadTraces graphWorkloadBlastRadius
 type WorkloadParam struct {
 	// The workload name.
 	//
@@ -185,19 +196,39 @@ type WorkloadParam struct {
 // - keep this alphabetized
 /////////////////////
 
-// swagger:parameters graphApp graphAppVersion graphNamespaces graphService graphWorkload
+// swagger:parameters graphApp graphAppVersion graphNamespaces graphNamespacesDiff graphNamespacesStream graphService graphSnapshotCreate graphWorkload
+type AnomalyBaselineParam struct {
+	// Used only with anomaly appender. The baseline compared with the graph time window. One of: yesterday (the same window one day earlier) | week (the 7 days preceding the window).
+	//
+	// in: query
+	// required: false
+	// default: yesterday
+	Name string `json:"anomalyBaseline"`
+}
+
+// swagger:parameters graphApp graphAppVersion graphNamespaces graphNamespacesDiff graphNamespacesStream graphService graphSnapshotCreate graphWorkload
+type AnomalyThresholdParam struct {
+	// Used only with anomaly appender. The anomaly score, log2(current/baseline), from which an edge is marked anomalous.
+	//
+	// in: query
+	// required: false
+	// default: 1
+	Name string `json:"anomalyThreshold"`
+}
+
+// swagger:parameters graphApp graphAppVersion graphNamespaces graphNamespacesDiff graphNamespacesStream graphService graphSnapshotCreate graphWorkload graphAppBlastRadius graphAppVersionBlastRadius graphServiceBlastRadius graphWorkloadBlastRadius
 type AppendersParam struct {
-	// Comma-separated list of Appenders to run. Available appenders: [aggregateNode, deadNode, healthConfig, idleNode, istio, responseTime, securityPolicy, serviceEntry, sidecarsCheck, throughput].
+	// Comma-separated list of Appenders to run. Available appenders: [aggregateNode, anomaly, criticalPath, deadNode, externalHost, healthConfig, idleNode, istio, labels, operations, responseTime, saturation, securityPolicy, serviceEntry, sidecarsCheck, tcpConnections, throughput].
 	//
 	// in: query
 	// required: false
-	// default: run all appenders
+	// default: run all appenders except [anomaly, criticalPath, externalHost, labels, operations, saturation, tcpConnections], labels runs when boxing by label
 	Name string `json:"appenders"`
 }
 
-// swagger:parameters graphApp graphAppVersion graphNamespaces graphService graphWorkload
+// swagger:parameters graphApp graphAppVersion graphNamespaces graphNamespacesDiff graphNamespacesStream graphService graphSnapshot graphSnapshotCreate graphWorkload
 type BoxByParam struct {
-	// Comma-separated list of desired node boxing. Available boxings: [app, cluster, namespace, none].
+	// Comma-separated list of desired node boxing. Available boxings: [app, cluster, namespace, none, version], or a label of the graph_boxing config (boxing by label requires the labels appender). Label boxes are nested in the requested order.
 	//
 	// in: query
 	// required: false
@@ -205,7 +236,67 @@ type BoxByParam struct {
 	Name string `json:"boxBy"`
 }
 
-// swagger:parameters graphApp graphAppVersion graphNamespaces graphService graphWorkload
+// swagger:parameters graphNamespacesDiff
+type CompareDurationParam struct {
+	// Compare time-range duration (Golang string duration). Used only by the diff graph.
+	//
+	// in: query
+	// required: false
+	// default: the duration
+	Name string `json:"compareDuration"`
+}
+
+// swagger:parameters graphNamespacesDiff
+type CompareQueryTimeParam struct {
+	// Unix time (seconds) for the compare query such that the compare time range is [compareQueryTime-compareDuration..compareQueryTime]. Used only by the diff graph.
+	//
+	// in: query
+	// required: false
+	// default: queryTime-duration
+	Name string `json:"compareQueryTime"`
+}
+
+// swagger:parameters graphApp graphAppVersion graphNamespaces graphNamespacesDiff graphNamespacesStream graphService graphSnapshot graphSnapshotCreate graphWorkload
+type ConfigVendorParam struct {
+	// The config vendor used to render the graph. Available vendors: [cytoscape, dot, graphml].
+	//
+	// in: query
+	// required: false
+	// default: cytoscape
+	Name string `json:"configVendor"`
+}
+
+// swagger:parameters graphApp graphAppVersion graphNamespaces graphNamespacesDiff graphService graphWorkload
+type DebugParam struct {
+	// Return, with the graph, the generation timings (traffic map build, appenders, marshalling) and the executed Prometheus queries. Supported only by the cytoscape config vendor, the graph is not served from the cache.
+	//
+	// in: query
+	// required: false
+	// default: false
+	Name string `json:"debug"`
+}
+
+// swagger:parameters graphApp graphAppVersion graphService graphWorkload
+type DepthParam struct {
+	// Number of hops from the node, in both directions, expanded in the node graph. Nodes outside of the requested namespaces are not expanded. Maximum 5.
+	//
+	// in: query
+	// required: false
+	// default: 1
+	Name string `json:"depth"`
+}
+
+// swagger:parameters graphNamespacesDiff
+type DiffToleranceParam struct {
+	// Percentage change of request rate, error rate or response time required to mark a node or edge as changed. Used only by the diff graph.
+	//
+	// in: query
+	// required: false
+	// default: 10
+	Name string `json:"diffTolerance"`
+}
+
+// swagger:parameters graphApp graphAppVersion graphNamespaces graphNamespacesDiff graphNamespacesStream graphService graphSnapshotCreate graphWorkload graphAppBlastRadius graphAppVersionBlastRadius graphServiceBlastRadius graphWorkloadBlastRadius
 type DurationGraphParam struct {
 	// Query time-range duration (Golang string duration).
 	//
@@ -215,7 +306,16 @@ type DurationGraphParam struct {
 	Name string `json:"duration"`
 }
 
-// swagger:parameters graphApp graphAppVersion graphNamespaces graphService graphWorkload
+// swagger:parameters graphApp graphAppVersion graphNamespaces graphNamespacesDiff graphNamespacesStream graphService graphSnapshot graphSnapshotCreate graphWorkload
+type FindParam struct {
+	// Find expression, using the graph find syntax of the Kiali UI (e.g. "rt > 1000 or ! healthy"). Matching nodes and edges are marked isFound.
+	//
+	// in: query
+	// required: false
+	Name string `json:"find"`
+}
+
+// swagger:parameters graphApp graphAppVersion graphNamespaces graphNamespacesDiff graphNamespacesStream graphService graphSnapshotCreate graphWorkload graphAppBlastRadius graphAppVersionBlastRadius graphServiceBlastRadius graphWorkloadBlastRadius
 type GraphTypeParam struct {
 	// Graph type. Available graph types: [app, service, versionedApp, workload].
 	//
@@ -225,7 +325,16 @@ type GraphTypeParam struct {
 	Name string `json:"graphType"`
 }
 
-// swagger:parameters graphApp graphAppVersion graphNamespaces graphWorkload
+// swagger:parameters graphApp graphAppVersion graphNamespaces graphNamespacesDiff graphNamespacesStream graphService graphSnapshot graphSnapshotCreate graphWorkload
+type HideParam struct {
+	// Hide expression, using the graph hide syntax of the Kiali UI (e.g. "name = unknown"). Matching nodes and edges are removed, as are nodes left without edges.
+	//
+	// in: query
+	// required: false
+	Name string `json:"hide"`
+}
+
+// swagger:parameters graphApp graphAppVersion graphNamespaces graphNamespacesDiff graphNamespacesStream graphSnapshotCreate graphWorkload graphAppBlastRadius graphAppVersionBlastRadius graphServiceBlastRadius graphWorkloadBlastRadius
 type IncludeIdleEdges struct {
 	// Flag for including edges that have no request traffic for the time period.
 	//
@@ -235,7 +344,7 @@ type IncludeIdleEdges struct {
 	Name string `json:"includeIdleEdges"`
 }
 
-// swagger:parameters graphApp graphAppVersion graphNamespaces graphWorkload
+// swagger:parameters graphApp graphAppVersion graphNamespaces graphNamespacesDiff graphNamespacesStream graphSnapshotCreate graphWorkload graphAppBlastRadius graphAppVersionBlastRadius graphServiceBlastRadius graphWorkloadBlastRadius
 type InjectServiceNodes struct {
 	// Flag for injecting the requested service node between source and destination nodes.
 	//
@@ -245,7 +354,7 @@ type InjectServiceNodes struct {
 	Name string `json:"injectServiceNodes"`
 }
 
-// swagger:parameters graphNamespaces
+// swagger:parameters graphNamespaces graphNamespacesDiff graphNamespacesStream graphSnapshotCreate
 type NamespacesParam struct {
 	// Comma-separated list of namespaces to include in the graph. The namespaces must be accessible to the client.
 	//
@@ -254,7 +363,45 @@ type NamespacesParam struct {
 	Name string `json:"namespaces"`
 }
 
-// swagger:parameters graphApp graphAppVersion graphNamespaces graphService graphWorkload
+// swagger:parameters graphApp graphAppVersion graphService graphWorkload
+type NamespacesNodeParam struct {
+	// Comma-separated list of namespaces, in addition to the node's namespace, in which nodes are expanded. Used only with a depth greater than 1. The namespaces must be accessible to the client.
+	//
+	// in: query
+	// required: false
+	Name string `json:"namespaces"`
+}
+
+// swagger:parameters graphAppBlastRadius graphAppVersionBlastRadius graphServiceBlastRadius graphWorkloadBlastRadius
+type NamespacesBlastRadiusParam struct {
+	// Comma-separated list of namespaces, in addition to the node's namespace, in which to search the dependents. The namespaces must be accessible to the client.
+	//
+	// in: query
+	// required: false
+	Name string `json:"namespaces"`
+}
+
+// swagger:parameters graphApp graphAppVersion graphNamespaces graphNamespacesDiff graphNamespacesStream graphService graphSnapshotCreate graphWorkload
+type OperationLabelParam struct {
+	// Used only with operations appender. The metric attribute identifying the request operation, e.g. a request path or gRPC method attribute.
+	//
+	// in: query
+	// required: false
+	// default: request_operation
+	Name string `json:"operationLabel"`
+}
+
+// swagger:parameters graphApp graphAppVersion graphNamespaces graphNamespacesDiff graphNamespacesStream graphService graphSnapshotCreate graphWorkload
+type OperationLimitParam struct {
+	// Used only with operations appender. The maximum number of operations reported per edge, by request rate.
+	//
+	// in: query
+	// required: false
+	// default: 5
+	Name string `json:"operationLimit"`
+}
+
+// swagger:parameters graphApp graphAppVersion graphNamespaces graphNamespacesDiff graphService graphSnapshotCreate graphWorkload graphAppBlastRadius graphAppVersionBlastRadius graphServiceBlastRadius graphWorkloadBlastRadius
 type QueryTimeParam struct {
 	// Unix time (seconds) for query such that time range is [queryTime-duration..queryTime]. Default is now.
 	//
@@ -264,7 +411,7 @@ type QueryTimeParam struct {
 	Name string `json:"queryTime"`
 }
 
-// swagger:parameters graphApp graphAppVersion graphNamespaces graphService graphWorkload
+// swagger:parameters graphApp graphAppVersion graphNamespaces graphNamespacesDiff graphNamespacesStream graphService graphSnapshotCreate graphWorkload
 type ResponseTimeParam struct {
 	// Used only with responseTime appender. One of: avg | 50 | 95 | 99.
 	//
@@ -274,7 +421,17 @@ type ResponseTimeParam struct {
 	Name string `json:"responseTime"`
 }
 
-// swagger:parameters graphApp graphAppVersion graphNamespaces graphService graphWorkload
+// swagger:parameters graphApp graphAppVersion graphNamespaces graphNamespacesDiff graphNamespacesStream graphService graphSnapshotCreate graphWorkload
+type SaturationThresholdParam struct {
+	// Used only with the saturation appender. Percentage of the CPU or memory limits from which a workload node is marked saturated.
+	//
+	// in: query
+	// required: false
+	// default: 90
+	Name string `json:"saturationThreshold"`
+}
+
+// swagger:parameters graphApp graphAppVersion graphNamespaces graphNamespacesDiff graphNamespacesStream graphService graphSnapshotCreate graphWorkload
 type ThroughputParam struct {
 	// Used only with throughput appender. One of: request | response.
 	//
@@ -284,6 +441,24 @@ type ThroughputParam struct {
 	Name string `json:"throughput"`
 }
 
+// swagger:parameters graphSnapshot graphSnapshotDelete
+type SnapshotParam struct {
+	// The graph snapshot ID.
+	//
+	// in: path
+	// required: true
+	Name string `json:"snapshot"`
+}
+
+// swagger:parameters graphSnapshotCreate
+type SnapshotNameParam struct {
+	// An optional name for the graph snapshot.
+	//
+	// in: query
+	// required: false
+	Name string `json:"name"`
+}
+
 /////////////////////
 // SWAGGER PARAMETERS - METRICS
 // - keep this alphabetized
@@ -535,6 +710,34 @@ type GraphResponse struct {
 	Body cytoscape.Config
 }
 
+// HTTP status code 200 and the JSON Schema of the cytoscape graph
+// swagger:response graphSchemaResponse
+type GraphSchemaResponse struct {
+	// in:body
+	Body map[string]interface{}
+}
+
+// HTTP status code 200 and the dependents of a graph node in data
+// swagger:response graphBlastRadiusResponse
+type GraphBlastRadiusResponse struct {
+	// in:body
+	Body blastradius.BlastRadius
+}
+
+// HTTP status code 201 and the created graph snapshot description in data
+// swagger:response graphSnapshotResponse
+type GraphSnapshotResponse struct {
+	// in:body
+	Body snapshot.Info
+}
+
+// HTTP status code 200 and the list of graph snapshot descriptions in data
+// swagger:response graphSnapshotsResponse
+type GraphSnapshotsResponse struct {
+	// in:body
+	Body []snapshot.Info
+}
+
 // HTTP status code 200 and IstioConfigList model in data
 // swagger:response istioConfigList
 type IstioConfigResponse struct {
@@ -710,6 +913,20 @@ type NamespaceValidationSummaryResponse struct {
 	Body models.IstioValidationSummary
 }
 
+// Return the checks introduced and resolved by Istio objects validated as if they were applied
+// swagger:response istioValidationsDryRunResponse
+type IstioValidationsDryRunResponse struct {
+	// in:body
+	Body models.IstioValidationsDiff
+}
+
+// Return the validations of all the accessible namespaces, grouped by namespace
+// swagger:response meshValidationsResponse
+type MeshValidationsResponse struct {
+	// in:body
+	Body models.NamespaceValidations
+}
+
 // Return a dump of the configuration of a given envoy proxy
 // swagger:response configDump
 type ConfigDumpResponse struct {
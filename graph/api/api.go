@@ -6,27 +6,66 @@ import (
 
 	"github.com/kiali/kiali/business"
 	"github.com/kiali/kiali/graph"
+	"github.com/kiali/kiali/graph/blastradius"
 	"github.com/kiali/kiali/graph/config/cytoscape"
+	"github.com/kiali/kiali/graph/config/dot"
+	"github.com/kiali/kiali/graph/config/graphml"
+	"github.com/kiali/kiali/graph/find"
+	"github.com/kiali/kiali/graph/snapshot"
+	"github.com/kiali/kiali/graph/telemetry"
+	"github.com/kiali/kiali/graph/telemetry/envoy"
 	"github.com/kiali/kiali/graph/telemetry/istio"
+	"github.com/kiali/kiali/graph/telemetry/tracing"
 	"github.com/kiali/kiali/log"
 	"github.com/kiali/kiali/prometheus"
 	"github.com/kiali/kiali/prometheus/internalmetrics"
 )
 
+// trafficMapBuilder builds the TrafficMap of a telemetry vendor, see the graph/TelemetryVendor interface
+type trafficMapBuilder func(o graph.TelemetryOptions, client *prometheus.Client, globalInfo *graph.AppenderGlobalInfo) graph.TrafficMap
+
 // GraphNamespaces generates a namespaces graph using the provided options
 func GraphNamespaces(business *business.Layer, o graph.Options) (code int, config interface{}) {
 	// time how long it takes to generate this graph
 	promtimer := internalmetrics.GetGraphGenerationTimePrometheusTimer(o.GetGraphKind(), o.TelemetryOptions.GraphType, o.InjectServiceNodes)
 	defer promtimer.ObserveDuration()
 
-	switch o.TelemetryVendor {
-	case graph.VendorIstio:
-		prom, err := prometheus.NewClient()
-		graph.CheckError(err)
-		code, config = graphNamespacesIstio(business, prom, o)
-	default:
-		graph.Error(fmt.Sprintf("TelemetryVendor [%s] not supported", o.TelemetryVendor))
+	build := getNamespacesBuilder(o.TelemetryVendor)
+	code, config = buildGraph(business, getPrometheusClient(o.TelemetryVendor), o, build)
+
+	// update metrics
+	internalmetrics.SetGraphNodes(o.GetGraphKind(), o.TelemetryOptions.GraphType, o.InjectServiceNodes, 0)
+
+	return code, config
+}
+
+// buildGraph provides a test hook that accepts mock clients. The Prometheus client is nil when the telemetry
+// vendor does not use Prometheus, see getPrometheusClient.
+func buildGraph(business *business.Layer, prom *prometheus.Client, o graph.Options, build trafficMapBuilder) (code int, config interface{}) {
+
+	// Create a 'global' object to store the business. Global only to the request.
+	globalInfo := newAppenderGlobalInfo(business, prom, o)
+
+	trafficMap := getTrafficMap(o.TelemetryVendor, o.TelemetryOptions, globalInfo.Debug, func() graph.TrafficMap {
+		return build(o.TelemetryOptions, prom, globalInfo)
+	})
+	code, config = generateGraph(trafficMap, o, globalInfo.Debug)
+
+	return code, config
+}
+
+// GraphNamespacesDiff generates a namespaces graph comparing the requested time window to the compare time window
+func GraphNamespacesDiff(business *business.Layer, o graph.Options) (code int, config interface{}) {
+	// time how long it takes to generate this graph
+	promtimer := internalmetrics.GetGraphGenerationTimePrometheusTimer(o.GetGraphKind(), o.TelemetryOptions.GraphType, o.InjectServiceNodes)
+	defer promtimer.ObserveDuration()
+
+	if o.TelemetryVendor == graph.VendorEnvoy {
+		// the Envoy stats reflect the current state of the proxies, there is no compare time window
+		graph.BadRequest(fmt.Sprintf("Graph comparison is not supported by telemetryVendor [%s]", o.TelemetryVendor))
 	}
+	build := getNamespacesBuilder(o.TelemetryVendor)
+	code, config = buildDiffGraph(business, getPrometheusClient(o.TelemetryVendor), o, build)
 
 	// update metrics
 	internalmetrics.SetGraphNodes(o.GetGraphKind(), o.TelemetryOptions.GraphType, o.InjectServiceNodes, 0)
@@ -34,70 +73,213 @@ func GraphNamespaces(business *business.Layer, o graph.Options) (code int, confi
 	return code, config
 }
 
-// graphNamespacesIstio provides a test hook that accepts mock clients
-func graphNamespacesIstio(business *business.Layer, prom *prometheus.Client, o graph.Options) (code int, config interface{}) {
+// buildDiffGraph is the diff counterpart of buildGraph, it builds the traffic maps of both time windows
+func buildDiffGraph(business *business.Layer, prom *prometheus.Client, o graph.Options, build trafficMapBuilder) (code int, config interface{}) {
+
+	// Create a 'global' object to store the business. Global only to the request, the cached
+	// information is not time-dependent and can be shared by both time windows.
+	globalInfo := newAppenderGlobalInfo(business, prom, o)
+
+	trafficMap := getTrafficMap(o.TelemetryVendor, o.TelemetryOptions, globalInfo.Debug, func() graph.TrafficMap {
+		return build(o.TelemetryOptions, prom, globalInfo)
+	})
+	compareOptions := o.GetCompareTelemetryOptions()
+	compareTrafficMap := getTrafficMap(o.TelemetryVendor, compareOptions, globalInfo.Debug, func() graph.TrafficMap {
+		return build(compareOptions, prom, globalInfo)
+	})
+	trafficMap = graph.DiffTrafficMaps(trafficMap, compareTrafficMap, o.Tolerance)
+	code, config = generateGraph(trafficMap, o, globalInfo.Debug)
+
+	return code, config
+}
+
+// SnapshotNamespaces generates a namespaces graph using the provided options and saves it to the store. The
+// snapshot holds the TrafficMap, find and hide are applied only when the snapshot is replayed.
+func SnapshotNamespaces(business *business.Layer, store snapshot.Store, name string, o graph.Options) (code int, info snapshot.Info) {
+	// time how long it takes to generate this graph
+	promtimer := internalmetrics.GetGraphGenerationTimePrometheusTimer(o.GetGraphKind(), o.TelemetryOptions.GraphType, o.InjectServiceNodes)
+	defer promtimer.ObserveDuration()
+
+	build := getNamespacesBuilder(o.TelemetryVendor)
+	return buildSnapshot(business, getPrometheusClient(o.TelemetryVendor), store, name, o, build)
+}
+
+// buildSnapshot is the snapshot counterpart of buildGraph, it saves the traffic map instead of generating the graph
+func buildSnapshot(business *business.Layer, prom *prometheus.Client, store snapshot.Store, name string, o graph.Options, build trafficMapBuilder) (code int, info snapshot.Info) {
 
 	// Create a 'global' object to store the business. Global only to the request.
 	globalInfo := graph.NewAppenderGlobalInfo()
 	globalInfo.Business = business
 
-	trafficMap := istio.BuildNamespacesTrafficMap(o.TelemetryOptions, prom, globalInfo)
-	code, config = generateGraph(trafficMap, o)
+	trafficMap := getTrafficMap(o.TelemetryVendor, o.TelemetryOptions, nil, func() graph.TrafficMap {
+		return build(o.TelemetryOptions, prom, globalInfo)
+	})
 
-	return code, config
+	s, err := snapshot.NewSnapshot(name, trafficMap, o)
+	graph.CheckError(err)
+	graph.CheckError(store.Save(s))
+
+	return http.StatusCreated, s.Info
+}
+
+// GraphSnapshot replays the snapshot through the config vendor of the provided options, see
+// graph.NewReplayOptions. Telemetry is not queried.
+func GraphSnapshot(s *snapshot.Snapshot, o graph.Options) (code int, config interface{}) {
+	trafficMap, err := s.TrafficMap()
+	graph.CheckError(err)
+
+	// the nodes were marked inaccessible for the user who generated the snapshot, mark them for the requesting user
+	for _, n := range trafficMap {
+		delete(n.Metadata, graph.IsInaccessible)
+		delete(n.Metadata, graph.IsOutside)
+	}
+	telemetry.MarkOutsideOrInaccessible(trafficMap, o.TelemetryOptions)
+
+	return generateGraph(trafficMap, o, nil)
 }
 
 // GraphNode generates a node graph using the provided options
 func GraphNode(business *business.Layer, o graph.Options) (code int, config interface{}) {
-	if len(o.Namespaces) != 1 {
-		graph.Error(fmt.Sprintf("Node graph does not support the 'namespaces' query parameter or the 'all' namespace"))
+	if len(o.Namespaces) != 1 && o.NodeOptions.Depth == 1 {
+		graph.Error(fmt.Sprintf("Node graph does not support the 'namespaces' query parameter or the 'all' namespace, unless depth > 1"))
 	}
 
 	// time how long it takes to generate this graph
 	promtimer := internalmetrics.GetGraphGenerationTimePrometheusTimer(o.GetGraphKind(), o.TelemetryOptions.GraphType, o.InjectServiceNodes)
 	defer promtimer.ObserveDuration()
 
-	switch o.TelemetryVendor {
-	case graph.VendorIstio:
-		prom, err := prometheus.NewClient()
-		graph.CheckError(err)
-		code, config = graphNodeIstio(business, prom, o)
-	default:
-		graph.Error(fmt.Sprintf("TelemetryVendor [%s] not supported", o.TelemetryVendor))
-	}
+	build := getNodeBuilder(o.TelemetryVendor)
+	code, config = buildGraph(business, getPrometheusClient(o.TelemetryVendor), o, build)
+
 	// update metrics
 	internalmetrics.SetGraphNodes(o.GetGraphKind(), o.TelemetryOptions.GraphType, o.InjectServiceNodes, 0)
 
 	return code, config
 }
 
-// graphNodeIstio provides a test hook that accepts mock clients
-func graphNodeIstio(business *business.Layer, client *prometheus.Client, o graph.Options) (code int, config interface{}) {
+// BlastRadius returns the dependents of the node of the provided options, i.e. the nodes whose requests
+// depend on the node, directly or transitively. The dependents are found in the namespaces graph of the
+// provided options.
+func BlastRadius(business *business.Layer, o graph.Options) (code int, result blastradius.BlastRadius) {
+	if o.NodeOptions.Aggregate != "" {
+		graph.BadRequest("Blast radius is not supported for aggregate nodes")
+	}
+
+	// time how long it takes to generate this graph
+	promtimer := internalmetrics.GetGraphGenerationTimePrometheusTimer(o.GetGraphKind(), o.TelemetryOptions.GraphType, o.InjectServiceNodes)
+	defer promtimer.ObserveDuration()
+
+	return blastRadius(business, getPrometheusClient(o.TelemetryVendor), o)
+}
+
+// blastRadius provides a test hook that accepts mock clients
+func blastRadius(business *business.Layer, prom *prometheus.Client, o graph.Options) (code int, result blastradius.BlastRadius) {
+	target := graph.NewNode(o.NodeOptions.Cluster, o.NodeOptions.Namespace, o.NodeOptions.Service, o.NodeOptions.Namespace, o.NodeOptions.Workload, o.NodeOptions.App, o.NodeOptions.Version, o.TelemetryOptions.GraphType)
 
 	// Create a 'global' object to store the business. Global only to the request.
 	globalInfo := graph.NewAppenderGlobalInfo()
 	globalInfo.Business = business
 
-	trafficMap := istio.BuildNodeTrafficMap(o.TelemetryOptions, client, globalInfo)
-	code, config = generateGraph(trafficMap, o)
+	// the namespaces graph, shared with (and cached as) a namespaces graph request with the same options
+	telemetryOptions := o.TelemetryOptions
+	telemetryOptions.NodeOptions = graph.NodeOptions{}
 
-	return code, config
+	build := getNamespacesBuilder(o.TelemetryVendor)
+	trafficMap := getTrafficMap(o.TelemetryVendor, telemetryOptions, nil, func() graph.TrafficMap {
+		return build(telemetryOptions, prom, globalInfo)
+	})
+
+	return http.StatusOK, blastradius.Compute(trafficMap, target, o)
+}
+
+// getNamespacesBuilder returns the namespaces TrafficMap builder of the telemetry vendor
+func getNamespacesBuilder(vendor string) trafficMapBuilder {
+	switch vendor {
+	case graph.VendorIstio:
+		return istio.BuildNamespacesTrafficMap
+	case graph.VendorEnvoy:
+		return envoy.BuildNamespacesTrafficMap
+	case graph.VendorTracing:
+		return tracing.BuildNamespacesTrafficMap
+	default:
+		graph.Error(fmt.Sprintf("TelemetryVendor [%s] not supported", vendor))
+		return nil
+	}
 }
 
-func generateGraph(trafficMap graph.TrafficMap, o graph.Options) (int, interface{}) {
+// getNodeBuilder returns the node TrafficMap builder of the telemetry vendor
+func getNodeBuilder(vendor string) trafficMapBuilder {
+	switch vendor {
+	case graph.VendorIstio:
+		return istio.BuildNodeTrafficMap
+	case graph.VendorEnvoy:
+		return envoy.BuildNodeTrafficMap
+	case graph.VendorTracing:
+		return tracing.BuildNodeTrafficMap
+	default:
+		graph.Error(fmt.Sprintf("TelemetryVendor [%s] not supported", vendor))
+		return nil
+	}
+}
+
+// getPrometheusClient returns a Prometheus client for the telemetry vendors using Prometheus, otherwise nil
+func getPrometheusClient(vendor string) *prometheus.Client {
+	if vendor != graph.VendorIstio {
+		return nil
+	}
+	prom, err := prometheus.NewClient()
+	graph.CheckError(err)
+	return prom
+}
+
+// newAppenderGlobalInfo returns the 'global' object for the request. When debugging, it collects the generation timings
+// and the queries of the provided Prometheus client (nil if the telemetry vendor does not use Prometheus), which is
+// then shared with the appenders.
+func newAppenderGlobalInfo(business *business.Layer, prom *prometheus.Client, o graph.Options) *graph.AppenderGlobalInfo {
+	globalInfo := graph.NewAppenderGlobalInfo()
+	globalInfo.Business = business
+
+	if o.Debug {
+		globalInfo.Debug = graph.NewDebug()
+		if prom != nil {
+			prom.Inject(globalInfo.Debug.API(prom.API()))
+			globalInfo.PromClient = prom
+		}
+	}
+
+	return globalInfo
+}
+
+// generateGraph returns the config vendor's graph. When debugging (debug not nil) the graph includes the debug
+// information, only cytoscape supports it.
+func generateGraph(trafficMap graph.TrafficMap, o graph.Options, debug *graph.Debug) (int, interface{}) {
+	find.Apply(trafficMap, o.FindOptions)
+
 	log.Tracef("Generating config for [%s] graph...", o.ConfigVendor)
 
 	promtimer := internalmetrics.GetGraphMarshalTimePrometheusTimer(o.GetGraphKind(), o.TelemetryOptions.GraphType, o.InjectServiceNodes)
 	defer promtimer.ObserveDuration()
 
+	debugTimer := debug.Time(graph.DebugPhaseMarshal, "", "")
 	var vendorConfig interface{}
 	switch o.ConfigVendor {
 	case graph.VendorCytoscape:
 		vendorConfig = cytoscape.NewConfig(trafficMap, o.ConfigOptions)
+	case graph.VendorDot:
+		vendorConfig = dot.NewConfig(trafficMap, o.ConfigOptions)
+	case graph.VendorGraphML:
+		vendorConfig = graphml.NewConfig(trafficMap, o.ConfigOptions)
 	default:
 		graph.Error(fmt.Sprintf("ConfigVendor [%s] not supported", o.ConfigVendor))
 	}
 
+	debugTimer()
+
+	if config, ok := vendorConfig.(cytoscape.Config); ok && debug != nil {
+		config.Debug = debug
+		vendorConfig = config
+	}
+
 	log.Tracef("Done generating config for [%s] graph", o.ConfigVendor)
 	return http.StatusOK, vendorConfig
 }
@@ -23,6 +23,8 @@ import (
 	"github.com/kiali/kiali/business"
 	"github.com/kiali/kiali/config"
 	"github.com/kiali/kiali/graph"
+	"github.com/kiali/kiali/graph/config/cytoscape"
+	"github.com/kiali/kiali/graph/telemetry/istio"
 	"github.com/kiali/kiali/kubernetes/kubetest"
 	"github.com/kiali/kiali/prometheus"
 	"github.com/kiali/kiali/prometheus/prometheustest"
@@ -33,6 +35,7 @@ import (
 func setupMocked() (*prometheus.Client, *prometheustest.PromAPIMock, *kubetest.K8SClientMock, error) {
 	conf := config.NewConfig()
 	conf.KubernetesConfig.CacheEnabled = false
+	conf.GraphCache.Enabled = false
 	config.Set(conf)
 
 	k8s := new(kubetest.K8SClientMock)
@@ -85,6 +88,7 @@ func setupMocked() (*prometheus.Client, *prometheustest.PromAPIMock, *kubetest.K
 
 func setupMockedWithIstioComponentNamespaces() (*prometheus.Client, *prometheustest.PromAPIMock, *kubetest.K8SClientMock, error) {
 	testConfig := config.NewConfig()
+	testConfig.GraphCache.Enabled = false
 	testConfig.KubernetesConfig.CacheEnabled = false
 	config.Set(testConfig)
 	k8s := new(kubetest.K8SClientMock)
@@ -885,6 +889,18 @@ func mockNamespaceGraph(t *testing.T) (*prometheus.Client, error) {
 	return client, nil
 }
 
+func graphNamespacesIstio(b *business.Layer, p *prometheus.Client, o graph.Options) (int, interface{}) {
+	return buildGraph(b, p, o, istio.BuildNamespacesTrafficMap)
+}
+
+func graphNamespacesDiffIstio(b *business.Layer, p *prometheus.Client, o graph.Options) (int, interface{}) {
+	return buildDiffGraph(b, p, o, istio.BuildNamespacesTrafficMap)
+}
+
+func graphNodeIstio(b *business.Layer, p *prometheus.Client, o graph.Options) (int, interface{}) {
+	return buildGraph(b, p, o, istio.BuildNodeTrafficMap)
+}
+
 func respond(w http.ResponseWriter, code int, payload interface{}) {
 	response, err := json.MarshalIndent(payload, "", "  ")
 	if err != nil {
@@ -1053,6 +1069,91 @@ func TestWorkloadGraph(t *testing.T) {
 	assert.Equal(t, 200, resp.StatusCode)
 }
 
+func TestWorkloadGraphDebug(t *testing.T) {
+	assert := assert.New(t)
+
+	client, err := mockNamespaceGraph(t)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	r := httptest.NewRequest("GET", "/api/namespaces/graph?namespaces=bookinfo&graphType=workload&appenders&queryTime=1523364075&debug=true", nil)
+	r = r.WithContext(context.WithValue(r.Context(), "authInfo", &api.AuthInfo{Token: "test"}))
+	code, config := graphNamespacesIstio(nil, client, graph.NewOptions(r))
+	assert.Equal(200, code)
+
+	debug := config.(cytoscape.Config).Debug
+	if !assert.NotNil(debug) {
+		return
+	}
+	assert.Len(debug.Phases, 2)
+	assert.Equal(graph.DebugPhaseTrafficMap, debug.Phases[0].Phase)
+	assert.Equal(graph.DebugPhaseMarshal, debug.Phases[1].Phase)
+
+	// the http and tcp queries, for the incoming and outgoing traffic
+	assert.Len(debug.Queries, 6)
+	series := 0
+	for _, q := range debug.Queries {
+		series += q.Series
+	}
+	assert.NotZero(series)
+
+	// without debug
+	r = httptest.NewRequest("GET", "/api/namespaces/graph?namespaces=bookinfo&graphType=workload&appenders&queryTime=1523364075", nil)
+	r = r.WithContext(context.WithValue(r.Context(), "authInfo", &api.AuthInfo{Token: "test"}))
+	_, config = graphNamespacesIstio(nil, client, graph.NewOptions(r))
+	assert.Nil(config.(cytoscape.Config).Debug)
+}
+
+func TestWorkloadDiffGraph(t *testing.T) {
+	client, err := mockNamespaceGraph(t)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	var fut func(b *business.Layer, p *prometheus.Client, o graph.Options) (int, interface{})
+
+	mr := mux.NewRouter()
+	mr.HandleFunc("/api/namespaces/graph/diff", http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			context := context.WithValue(r.Context(), "authInfo", &api.AuthInfo{Token: "test"})
+			code, config := fut(nil, client, graph.NewOptions(r.WithContext(context)))
+			respond(w, code, config)
+		}))
+
+	ts := httptest.NewServer(mr)
+	defer ts.Close()
+
+	// the mocks return the same telemetry for both time windows, so nothing changes
+	fut = graphNamespacesDiffIstio
+	url := ts.URL + "/api/namespaces/graph/diff?namespaces=bookinfo&graphType=workload&appenders&queryTime=1523364075&compareQueryTime=1523360475"
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	actual, _ := ioutil.ReadAll(resp.Body)
+	assert.Equal(t, 200, resp.StatusCode)
+
+	var config cytoscape.Config
+	assert.NoError(t, json.Unmarshal(actual, &config))
+	assert.NotEmpty(t, config.Elements.Nodes)
+	assert.NotEmpty(t, config.Elements.Edges)
+	for _, nw := range config.Elements.Nodes {
+		if assert.NotNil(t, nw.Data.Diff) {
+			assert.Equal(t, graph.DiffStatusUnchanged, nw.Data.Diff.Status)
+			assert.Empty(t, nw.Data.Diff.Rate)
+		}
+	}
+	for _, ew := range config.Elements.Edges {
+		if assert.NotNil(t, ew.Data.Diff) {
+			assert.Equal(t, graph.DiffStatusUnchanged, ew.Data.Diff.Status)
+			assert.Empty(t, ew.Data.Diff.Rate)
+		}
+	}
+}
+
 func TestAppNodeGraph(t *testing.T) {
 	q0 := `round(sum(rate(istio_requests_total{reporter="destination",destination_service_namespace="bookinfo",destination_canonical_service="productpage"} [600s])) by (source_cluster,source_workload_namespace,source_workload,source_canonical_service,source_canonical_revision,destination_cluster,destination_service_namespace,destination_service,destination_service_name,destination_workload_namespace,destination_workload,destination_canonical_service,destination_canonical_revision,request_protocol,response_code,grpc_response_status,response_flags) > 0,0.001)`
 	q0m0 := model.Metric{
@@ -1881,6 +1982,104 @@ func TestWorkloadNodeGraph(t *testing.T) {
 	assert.Equal(t, 200, resp.StatusCode)
 }
 
+// workloadNodeQueries returns the request and TCP queries, incoming and outgoing, of a workload node graph
+func workloadNodeQueries(namespace, workload string) (in, out, tcpIn, tcpOut string) {
+	groupBy := "source_cluster,source_workload_namespace,source_workload,source_canonical_service,source_canonical_revision,destination_cluster,destination_service_namespace,destination_service,destination_service_name,destination_workload_namespace,destination_workload,destination_canonical_service,destination_canonical_revision"
+	in = fmt.Sprintf(`round(sum(rate(istio_requests_total{reporter="destination",destination_workload_namespace="%s",destination_workload="%s"} [600s])) by (%s,request_protocol,response_code,grpc_response_status,response_flags) > 0,0.001)`, namespace, workload, groupBy)
+	out = fmt.Sprintf(`round(sum(rate(istio_requests_total{reporter="source",source_workload_namespace="%s",source_workload="%s"} [600s])) by (%s,request_protocol,response_code,grpc_response_status,response_flags) > 0,0.001)`, namespace, workload, groupBy)
+	tcpIn = fmt.Sprintf(`round(sum(rate(istio_tcp_sent_bytes_total{reporter="source",destination_workload_namespace="%s",destination_workload="%s"} [600s])) by (%s,response_flags) > 0,0.001)`, namespace, workload, groupBy)
+	tcpOut = fmt.Sprintf(`round(sum(rate(istio_tcp_sent_bytes_total{reporter="source",source_workload_namespace="%s",source_workload="%s"} [600s])) by (%s,response_flags) > 0,0.001)`, namespace, workload, groupBy)
+	return in, out, tcpIn, tcpOut
+}
+
+func workloadRequests(sourceNamespace, source, sourceApp, destNamespace, dest, destApp string, value float64) *model.Sample {
+	return &model.Sample{
+		Metric: model.Metric{
+			"source_workload_namespace":      model.LabelValue(sourceNamespace),
+			"source_workload":                model.LabelValue(source),
+			"source_canonical_service":       model.LabelValue(sourceApp),
+			"source_canonical_revision":      "v1",
+			"destination_service_namespace":  model.LabelValue(destNamespace),
+			"destination_service":            model.LabelValue(destApp + ":9080"),
+			"destination_service_name":       model.LabelValue(destApp),
+			"destination_workload_namespace": model.LabelValue(destNamespace),
+			"destination_workload":           model.LabelValue(dest),
+			"destination_canonical_service":  model.LabelValue(destApp),
+			"destination_canonical_revision": "v1",
+			"request_protocol":               "http",
+			"response_code":                  "200",
+			"grpc_response_status":           "0",
+			"response_flags":                 "-"},
+		Value: model.SampleValue(value)}
+}
+
+func TestWorkloadNodeGraphDepth(t *testing.T) {
+	// hop 1: unknown -> productpage-v1 -> reviews-v1, details-v1, tutorial/customer-v1
+	q0, q1, _, _ := workloadNodeQueries("bookinfo", "productpage-v1")
+	v0 := model.Vector{
+		workloadRequests("unknown", "unknown", "unknown", "bookinfo", "productpage-v1", "productpage", 50)}
+	v1 := model.Vector{
+		workloadRequests("bookinfo", "productpage-v1", "productpage", "bookinfo", "reviews-v1", "reviews", 20),
+		workloadRequests("bookinfo", "productpage-v1", "productpage", "bookinfo", "details-v1", "details", 10),
+		workloadRequests("bookinfo", "productpage-v1", "productpage", "tutorial", "customer-v1", "customer", 5)}
+
+	// hop 2: the traffic already reported by hop 1 is not added twice
+	q2, q3, _, _ := workloadNodeQueries("bookinfo", "reviews-v1")
+	v2 := model.Vector{
+		workloadRequests("bookinfo", "productpage-v1", "productpage", "bookinfo", "reviews-v1", "reviews", 20)}
+	v3 := model.Vector{
+		workloadRequests("bookinfo", "reviews-v1", "reviews", "bookinfo", "ratings-v1", "ratings", 10)}
+
+	// the tutorial namespace is not requested, customer-v1 is not expanded
+	_, q4, _, _ := workloadNodeQueries("tutorial", "customer-v1")
+	v4 := model.Vector{
+		workloadRequests("tutorial", "customer-v1", "customer", "tutorial", "preference-v1", "preference", 5)}
+
+	client, xapi, _, err := setupMocked()
+	if err != nil {
+		return
+	}
+
+	mockQuery(xapi, q0, &v0)
+	mockQuery(xapi, q1, &v1)
+	mockQuery(xapi, q2, &v2)
+	mockQuery(xapi, q3, &v3)
+	mockQuery(xapi, q4, &v4)
+	// any other node reports no traffic
+	xapi.On("Query", mock.Anything, mock.AnythingOfType("string"), mock.AnythingOfType("time.Time")).Return(model.Vector{}, nil)
+
+	var fut func(b *business.Layer, p *prometheus.Client, o graph.Options) (int, interface{})
+
+	mr := mux.NewRouter()
+	mr.HandleFunc("/api/namespaces/{namespace}/workloads/{workload}/graph", http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			context := context.WithValue(r.Context(), "authInfo", &api.AuthInfo{Token: "test"})
+			code, config := fut(nil, client, graph.NewOptions(r.WithContext(context)))
+			respond(w, code, config)
+		}))
+
+	ts := httptest.NewServer(mr)
+	defer ts.Close()
+
+	fut = graphNodeIstio
+	url := ts.URL + "/api/namespaces/bookinfo/workloads/productpage-v1/graph?graphType=workload&appenders&queryTime=1523364075&depth=2"
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	actual, _ := ioutil.ReadAll(resp.Body)
+	expected, _ := ioutil.ReadFile("testdata/test_workload_node_graph_depth.expected")
+	if runtime.GOOS == "windows" {
+		expected = bytes.Replace(expected, []byte("\r\n"), []byte("\n"), -1)
+	}
+	expected = expected[:len(expected)-1] // remove EOF byte
+
+	if !assert.Equal(t, expected, actual) {
+		fmt.Printf("\nActual:\n%v", string(actual))
+	}
+	assert.Equal(t, 200, resp.StatusCode)
+}
+
 func TestServiceNodeGraph(t *testing.T) {
 	q0 := `round(sum(rate(istio_requests_total{reporter="source",destination_workload="unknown",destination_service=~"^productpage\\.bookinfo\\..*$"} [600s])) by (source_cluster,source_workload_namespace,source_workload,source_canonical_service,source_canonical_revision,destination_cluster,destination_service_namespace,destination_service,destination_service_name,destination_workload_namespace,destination_workload,destination_canonical_service,destination_canonical_revision,request_protocol,response_code,grpc_response_status,response_flags) > 0,0.001)`
 	v0 := model.Vector{}
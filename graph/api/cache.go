@@ -0,0 +1,229 @@
+package api
+
+// Cache.go provides the caching of generated TrafficMaps. Identical graph requests, typically many users
+// opening the same namespace graph, share a single TrafficMap generation, and its result for a short time.
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sort"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+
+	"github.com/kiali/kiali/config"
+	"github.com/kiali/kiali/graph"
+	"github.com/kiali/kiali/log"
+	"github.com/kiali/kiali/prometheus/internalmetrics"
+)
+
+// trafficMapKeyIgnoredParams are the query params not affecting TrafficMap generation, they are applied
+// to the TrafficMap afterwards (e.g. find, hide) or are normalized separately (e.g. duration, queryTime).
+var trafficMapKeyIgnoredParams = map[string]bool{
+	"boxBy":            true,
+	"compareDuration":  true,
+	"compareQueryTime": true,
+	"configVendor":     true,
+	"diffTolerance":    true,
+	"duration":         true,
+	"find":             true,
+	"hide":             true,
+	"name":             true,
+	"queryTime":        true,
+	"refreshInterval":  true,
+}
+
+type trafficMapCacheEntry struct {
+	created    time.Time
+	trafficMap graph.TrafficMap
+}
+
+type trafficMapCache struct {
+	entries map[string]trafficMapCacheEntry
+	group   singleflight.Group
+	mutex   sync.RWMutex
+}
+
+var tmCache = trafficMapCache{entries: make(map[string]trafficMapCacheEntry)}
+
+// flightPanic holds the panic value of a failed TrafficMap generation, so that it can be re-raised for
+// every request waiting on the generation.
+type flightPanic struct {
+	value interface{}
+}
+
+func (fp flightPanic) Error() string {
+	return fmt.Sprintf("%v", fp.value)
+}
+
+// getTrafficMap returns the TrafficMap for the telemetry options, generated by build unless it is cached.
+// Concurrent requests with the same key wait for a single generation. The caller receives its own copy
+// of the TrafficMap and is free to modify it.
+func getTrafficMap(telemetryVendor string, o graph.TelemetryOptions, debug *graph.Debug, build func() graph.TrafficMap) graph.TrafficMap {
+	// when debugging the generation is timed, it is neither cached nor served from the cache
+	if debug != nil {
+		defer debug.Time(graph.DebugPhaseTrafficMap, "", "")()
+		return build()
+	}
+
+	conf := config.Get().GraphCache
+	if !conf.Enabled || conf.Duration <= 0 {
+		return build()
+	}
+	ttl := time.Duration(conf.Duration) * time.Second
+
+	key, err := trafficMapKey(telemetryVendor, o, ttl)
+	if err != nil {
+		log.Debugf("Graph cache not used: %v", err)
+		return build()
+	}
+
+	if trafficMap, ok := tmCache.get(key, ttl); ok {
+		internalmetrics.GetGraphCacheHitsMetric(o.GetGraphKind(), o.GraphType, o.InjectServiceNodes).Inc()
+		return cloneTrafficMap(trafficMap)
+	}
+
+	generated := false
+	result, err, _ := tmCache.group.Do(key, func() (result interface{}, err error) {
+		generated = true
+		defer func() {
+			if r := recover(); r != nil {
+				err = flightPanic{value: r}
+			}
+		}()
+		trafficMap := build()
+		tmCache.set(key, trafficMap, ttl)
+		return trafficMap, nil
+	})
+	if generated {
+		internalmetrics.GetGraphCacheMissesMetric(o.GetGraphKind(), o.GraphType, o.InjectServiceNodes).Inc()
+	} else {
+		internalmetrics.GetGraphCacheHitsMetric(o.GetGraphKind(), o.GraphType, o.InjectServiceNodes).Inc()
+	}
+	if err != nil {
+		if fp, ok := err.(flightPanic); ok {
+			panic(fp.value)
+		}
+		graph.CheckError(err)
+	}
+
+	return cloneTrafficMap(result.(graph.TrafficMap))
+}
+
+func (c *trafficMapCache) get(key string, ttl time.Duration) (graph.TrafficMap, bool) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	entry, ok := c.entries[key]
+	if !ok || time.Since(entry.created) >= ttl {
+		return nil, false
+	}
+	return entry.trafficMap, true
+}
+
+// set caches the TrafficMap, expired entries are removed at the same time
+func (c *trafficMapCache) set(key string, trafficMap graph.TrafficMap, ttl time.Duration) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	now := time.Now()
+	for k, entry := range c.entries {
+		if now.Sub(entry.created) >= ttl {
+			delete(c.entries, k)
+		}
+	}
+	c.entries[key] = trafficMapCacheEntry{created: now, trafficMap: trafficMap}
+}
+
+// trafficMapKey returns the cache key for the normalized telemetry options. The query time is aligned to
+// the cache duration, so that requests for the current time share a key until the cached entry expires.
+// The accessible namespaces are part of the key, only users with the same namespace access share a
+// TrafficMap.
+func trafficMapKey(telemetryVendor string, o graph.TelemetryOptions, ttl time.Duration) (string, error) {
+	accessibleNamespaces := make([]string, 0, len(o.AccessibleNamespaces))
+	for namespace := range o.AccessibleNamespaces {
+		accessibleNamespaces = append(accessibleNamespaces, namespace)
+	}
+	sort.Strings(accessibleNamespaces)
+
+	appenders := o.Appenders
+	appenders.AppenderNames = append([]string{}, o.Appenders.AppenderNames...)
+	sort.Strings(appenders.AppenderNames)
+
+	params := url.Values{}
+	for k, v := range o.Params {
+		if !trafficMapKeyIgnoredParams[k] {
+			params[k] = v
+		}
+	}
+
+	ttlSeconds := int64(ttl.Seconds())
+
+	// encoding/json sorts map keys, providing a stable key
+	keyData, err := json.Marshal(struct {
+		AccessibleNamespaces []string
+		Appenders            graph.RequestedAppenders
+		Duration             time.Duration
+		GraphKind            string
+		GraphType            string
+		IncludeIdleEdges     bool
+		InjectServiceNodes   bool
+		Namespaces           graph.NamespaceInfoMap
+		Node                 graph.NodeOptions
+		Params               url.Values
+		QueryTime            int64
+		TelemetryVendor      string
+	}{
+		AccessibleNamespaces: accessibleNamespaces,
+		Appenders:            appenders,
+		Duration:             o.Duration,
+		GraphKind:            o.GetGraphKind(),
+		GraphType:            o.GraphType,
+		IncludeIdleEdges:     o.IncludeIdleEdges,
+		InjectServiceNodes:   o.InjectServiceNodes,
+		Namespaces:           o.Namespaces,
+		Node:                 o.NodeOptions,
+		Params:               params,
+		QueryTime:            o.QueryTime - o.QueryTime%ttlSeconds,
+		TelemetryVendor:      telemetryVendor,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%x", sha256.Sum256(keyData)), nil
+}
+
+// cloneTrafficMap returns a copy of the TrafficMap with new nodes, edges and metadata maps. Metadata
+// values are shared, they are not modified after the TrafficMap is generated.
+func cloneTrafficMap(trafficMap graph.TrafficMap) graph.TrafficMap {
+	clone := graph.NewTrafficMap()
+	for id, n := range trafficMap {
+		nodeClone := *n
+		nodeClone.Edges = make([]*graph.Edge, 0, len(n.Edges))
+		nodeClone.Metadata = cloneMetadata(n.Metadata)
+		clone[id] = &nodeClone
+	}
+	for id, n := range trafficMap {
+		source := clone[id]
+		for _, e := range n.Edges {
+			source.Edges = append(source.Edges, &graph.Edge{
+				Source:   source,
+				Dest:     clone[e.Dest.ID],
+				Metadata: cloneMetadata(e.Metadata),
+			})
+		}
+	}
+	return clone
+}
+
+func cloneMetadata(md graph.Metadata) graph.Metadata {
+	clone := graph.NewMetadata()
+	for k, v := range md {
+		clone[k] = v
+	}
+	return clone
+}
@@ -0,0 +1,198 @@
+package api
+
+import (
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/kiali/kiali/config"
+	"github.com/kiali/kiali/graph"
+)
+
+func cacheTestTelemetryOptions() graph.TelemetryOptions {
+	o := graph.TelemetryOptions{
+		AccessibleNamespaces: map[string]time.Time{"bookinfo": {}, "istio-system": {}},
+		Appenders:            graph.RequestedAppenders{AppenderNames: []string{"responseTime", "deadNode"}},
+		Namespaces:           graph.NamespaceInfoMap{"bookinfo": {Name: "bookinfo", Duration: 10 * time.Minute}},
+	}
+	o.Duration = 10 * time.Minute
+	o.GraphType = graph.GraphTypeVersionedApp
+	o.Params = url.Values{"namespaces": []string{"bookinfo"}, "responseTime": []string{"avg"}}
+	o.QueryTime = 1523364075
+	return o
+}
+
+func cacheTestTrafficMap() graph.TrafficMap {
+	trafficMap := graph.NewTrafficMap()
+	productpage := graph.NewNode(graph.Unknown, "bookinfo", "productpage", "bookinfo", "productpage-v1", "productpage", "v1", graph.GraphTypeVersionedApp)
+	reviews := graph.NewNode(graph.Unknown, "bookinfo", "reviews", "bookinfo", "reviews-v1", "reviews", "v1", graph.GraphTypeVersionedApp)
+	trafficMap[productpage.ID] = &productpage
+	trafficMap[reviews.ID] = &reviews
+	e := productpage.AddEdge(&reviews)
+	graph.AddToMetadata("http", 10.0, "200", "-", "reviews", productpage.Metadata, reviews.Metadata, e.Metadata)
+	return trafficMap
+}
+
+func setupGraphCache(duration int) {
+	conf := config.NewConfig()
+	conf.GraphCache.Duration = duration
+	conf.GraphCache.Enabled = true
+	config.Set(conf)
+
+	tmCache.mutex.Lock()
+	tmCache.entries = make(map[string]trafficMapCacheEntry)
+	tmCache.mutex.Unlock()
+}
+
+func TestTrafficMapKey(t *testing.T) {
+	assert := assert.New(t)
+
+	ttl := 10 * time.Second
+	o := cacheTestTelemetryOptions()
+	key, err := trafficMapKey(graph.VendorIstio, o, ttl)
+	assert.NoError(err)
+
+	// config vendor options and the query time within the cache duration do not change the key
+	same := cacheTestTelemetryOptions()
+	same.Appenders.AppenderNames = []string{"deadNode", "responseTime"}
+	same.Params.Set("boxBy", "app")
+	same.Params.Set("find", "rt > 10")
+	same.Params.Set("queryTime", "1523364079")
+	same.QueryTime = 1523364079
+	sameKey, err := trafficMapKey(graph.VendorIstio, same, ttl)
+	assert.NoError(err)
+	assert.Equal(key, sameKey)
+
+	// anything affecting the generated TrafficMap changes the key
+	for name, change := range map[string]func(o *graph.TelemetryOptions){
+		"accessibleNamespaces": func(o *graph.TelemetryOptions) { delete(o.AccessibleNamespaces, "istio-system") },
+		"appenders":            func(o *graph.TelemetryOptions) { o.Appenders.AppenderNames = []string{"deadNode"} },
+		"graphType":            func(o *graph.TelemetryOptions) { o.GraphType = graph.GraphTypeWorkload },
+		"injectServiceNodes":   func(o *graph.TelemetryOptions) { o.InjectServiceNodes = true },
+		"node":                 func(o *graph.TelemetryOptions) { o.NodeOptions.App = "reviews" },
+		"params":               func(o *graph.TelemetryOptions) { o.Params.Set("responseTime", "95") },
+		"queryTime":            func(o *graph.TelemetryOptions) { o.QueryTime = 1523364080 },
+	} {
+		other := cacheTestTelemetryOptions()
+		change(&other)
+		otherKey, err := trafficMapKey(graph.VendorIstio, other, ttl)
+		assert.NoError(err)
+		assert.NotEqual(key, otherKey, name)
+	}
+}
+
+func TestGetTrafficMapCached(t *testing.T) {
+	assert := assert.New(t)
+	setupGraphCache(10)
+
+	builds := 0
+	build := func() graph.TrafficMap {
+		builds++
+		return cacheTestTrafficMap()
+	}
+
+	o := cacheTestTelemetryOptions()
+	first := getTrafficMap(graph.VendorIstio, o, nil, build)
+	assert.Equal(1, builds)
+	assert.Len(first, 2)
+
+	// the caller's copy can be modified without affecting the cache
+	for id, n := range first {
+		n.Metadata[graph.IsFound] = true
+		n.Edges = nil
+		delete(first, id)
+		break
+	}
+
+	second := getTrafficMap(graph.VendorIstio, o, nil, build)
+	assert.Equal(1, builds)
+	assert.Equal(cacheTestTrafficMap(), second)
+
+	// an expired entry is generated again
+	tmCache.mutex.Lock()
+	for k, entry := range tmCache.entries {
+		entry.created = entry.created.Add(-10 * time.Second)
+		tmCache.entries[k] = entry
+	}
+	tmCache.mutex.Unlock()
+	getTrafficMap(graph.VendorIstio, o, nil, build)
+	assert.Equal(2, builds)
+
+	// a debugged generation is timed, and not served from the cache
+	debug := graph.NewDebug()
+	getTrafficMap(graph.VendorIstio, o, debug, build)
+	assert.Equal(3, builds)
+	assert.Len(debug.Phases, 1)
+	assert.Equal(graph.DebugPhaseTrafficMap, debug.Phases[0].Phase)
+
+	// the cache can be disabled
+	conf := config.Get()
+	conf.GraphCache.Enabled = false
+	config.Set(conf)
+	getTrafficMap(graph.VendorIstio, o, nil, build)
+	getTrafficMap(graph.VendorIstio, o, nil, build)
+	assert.Equal(5, builds)
+}
+
+func TestGetTrafficMapSingleFlight(t *testing.T) {
+	assert := assert.New(t)
+	setupGraphCache(10)
+
+	var builds int32
+	release := make(chan struct{})
+	build := func() graph.TrafficMap {
+		atomic.AddInt32(&builds, 1)
+		<-release
+		return cacheTestTrafficMap()
+	}
+
+	o := cacheTestTelemetryOptions()
+	results := make([]graph.TrafficMap, 5)
+	var started, done sync.WaitGroup
+	for i := range results {
+		started.Add(1)
+		done.Add(1)
+		go func(i int) {
+			defer done.Done()
+			started.Done()
+			results[i] = getTrafficMap(graph.VendorIstio, o, nil, build)
+		}(i)
+	}
+	started.Wait()
+	// give the requests time to join the in-flight generation
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	done.Wait()
+
+	assert.Equal(int32(1), atomic.LoadInt32(&builds))
+	for i, trafficMap := range results {
+		assert.Equal(cacheTestTrafficMap(), trafficMap)
+		// every request receives its own copy
+		for j := i + 1; j < len(results); j++ {
+			for id := range trafficMap {
+				assert.False(trafficMap[id] == results[j][id])
+			}
+		}
+	}
+}
+
+func TestGetTrafficMapPanic(t *testing.T) {
+	assert := assert.New(t)
+	setupGraphCache(10)
+
+	o := cacheTestTelemetryOptions()
+	assert.PanicsWithValue(graph.Response{Message: "prometheus unavailable", Code: 503}, func() {
+		getTrafficMap(graph.VendorIstio, o, nil, func() graph.TrafficMap {
+			graph.Panic("prometheus unavailable", 503)
+			return nil
+		})
+	})
+
+	// a failed generation is not cached
+	trafficMap := getTrafficMap(graph.VendorIstio, o, nil, cacheTestTrafficMap)
+	assert.Len(trafficMap, 2)
+}
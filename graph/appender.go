@@ -15,6 +15,7 @@ type AppenderGlobalInfo struct {
 	Business    *business.Layer
 	HomeCluster string
 	PromClient  *prometheus.Client
+	Debug       *Debug             // collects the timings and queries when debugging, nil otherwise
 	Vendor      AppenderVendorInfo // telemetry vendor's global info
 }
 
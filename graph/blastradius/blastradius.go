@@ -0,0 +1,267 @@
+// Package blastradius provides the upstream dependents of a graph node, i.e. the nodes whose requests
+// depend on the node, directly or transitively. It answers which entry points are affected when the node
+// fails, e.g. before a risky deploy or a node drain.
+//
+// The dependents are found by walking the TrafficMap upstream from the target node, following the edges
+// in reverse. Each dependent is reported with its distance to the target (in hops) and with the volume of
+// the target's traffic passing through it. The volume is apportioned upstream: the traffic attributed to a
+// node is split among its callers in proportion to the request rates of their edges to the node (or to the
+// TCP rates, for a node receiving only TCP traffic). A node's volume is the sum of the volumes it receives
+// from its callees. Cycles are broken by ignoring the edges closing them, and edges to nodes not leading to
+// the target do not carry the target's traffic.
+package blastradius
+
+import (
+	"sort"
+
+	"github.com/kiali/kiali/graph"
+	"github.com/kiali/kiali/graph/telemetry"
+)
+
+// Node is a node of the blast radius, the target node or one of its dependents
+type Node struct {
+	Cluster      string  `json:"cluster,omitempty"`
+	Namespace    string  `json:"namespace"`
+	NodeType     string  `json:"nodeType"`
+	Workload     string  `json:"workload,omitempty"`
+	App          string  `json:"app,omitempty"`
+	Version      string  `json:"version,omitempty"`
+	Service      string  `json:"service,omitempty"`
+	Distance     int     `json:"distance"`               // hops to the target, 0 for the target
+	IsEntryPoint bool    `json:"isEntryPoint,omitempty"` // the node has no callers in the graph
+	RequestRate  float64 `json:"requestRate"`            // requests per second (HTTP and gRPC) of the target, through the node
+	TCPRate      float64 `json:"tcpRate"`                // bytes per second (TCP) sent to the target, through the node
+}
+
+// BlastRadius holds the target nodes (e.g. the versions of an app) and their dependents. The dependents
+// are sorted by distance, then by decreasing request rate.
+type BlastRadius struct {
+	Timestamp  int64  `json:"timestamp"`
+	Duration   int64  `json:"duration"`
+	GraphType  string `json:"graphType"`
+	Targets    []Node `json:"targets"`
+	Dependents []Node `json:"dependents"`
+}
+
+// rateFunc returns the rate of an edge, for a class of traffic
+type rateFunc func(e *graph.Edge) float64
+
+func requestRate(e *graph.Edge) float64 {
+	switch e.Metadata[graph.ProtocolKey] {
+	case graph.GRPC.Name:
+		return rateOf(e, graph.GRPC.EdgeRates[0].Name)
+	case graph.HTTP.Name:
+		return rateOf(e, graph.HTTP.EdgeRates[0].Name)
+	default:
+		return 0.0
+	}
+}
+
+func tcpRate(e *graph.Edge) float64 {
+	if e.Metadata[graph.ProtocolKey] != graph.TCP.Name {
+		return 0.0
+	}
+	return rateOf(e, graph.TCP.EdgeRates[0].Name)
+}
+
+func rateOf(e *graph.Edge, key graph.MetadataKey) float64 {
+	if val, ok := e.Metadata[key]; ok {
+		return val.(float64)
+	}
+	return 0.0
+}
+
+// Compute returns the blast radius of the target node in the TrafficMap. The target can match several
+// nodes of the TrafficMap, e.g. the versions of an app in a versionedApp graph. A target not in the
+// TrafficMap (i.e. without traffic) has no dependents.
+func Compute(trafficMap graph.TrafficMap, target graph.Node, o graph.Options) BlastRadius {
+	// the walk is ordered, so that the same cycle edges are ignored for the same TrafficMap
+	inbound := make(map[string][]*graph.Edge)
+	for _, n := range trafficMap {
+		for _, e := range n.Edges {
+			inbound[e.Dest.ID] = append(inbound[e.Dest.ID], e)
+		}
+	}
+	for _, edges := range inbound {
+		sort.Slice(edges, func(i, j int) bool {
+			return edges[i].Source.ID < edges[j].Source.ID
+		})
+	}
+
+	// walk upstream, breadth first, so the distance of a node is its shortest distance to the target
+	distance := make(map[string]int)
+	targets := []*graph.Node{}
+	for _, n := range trafficMap {
+		if telemetry.IsNode(n, target) {
+			distance[n.ID] = 0
+			targets = append(targets, n)
+		}
+	}
+	sort.Slice(targets, func(i, j int) bool {
+		return targets[i].ID < targets[j].ID
+	})
+	dependents := []*graph.Node{}
+	for level := targets; len(level) > 0; {
+		next := []*graph.Node{}
+		for _, n := range level {
+			for _, e := range inbound[n.ID] {
+				if _, seen := distance[e.Source.ID]; !seen {
+					distance[e.Source.ID] = distance[n.ID] + 1
+					next = append(next, e.Source)
+				}
+			}
+		}
+		dependents = append(dependents, next...)
+		level = next
+	}
+
+	kept := keptEdges(targets, inbound, distance)
+	order := upstreamOrder(targets, inbound, kept)
+	requests := apportion(order, len(targets), inbound, kept, requestRate)
+	tcp := apportion(order, len(targets), inbound, kept, tcpRate)
+
+	result := BlastRadius{
+		Timestamp:  o.TelemetryOptions.QueryTime,
+		Duration:   int64(o.TelemetryOptions.Duration.Seconds()),
+		GraphType:  o.TelemetryOptions.GraphType,
+		Targets:    []Node{},
+		Dependents: []Node{},
+	}
+	toNode := func(n *graph.Node) Node {
+		return Node{
+			Cluster:      n.Cluster,
+			Namespace:    n.Namespace,
+			NodeType:     n.NodeType,
+			Workload:     n.Workload,
+			App:          n.App,
+			Version:      n.Version,
+			Service:      n.Service,
+			Distance:     distance[n.ID],
+			IsEntryPoint: len(inbound[n.ID]) == 0,
+			RequestRate:  requests[n.ID],
+			TCPRate:      tcp[n.ID],
+		}
+	}
+	for _, n := range targets {
+		result.Targets = append(result.Targets, toNode(n))
+	}
+	for _, n := range dependents {
+		result.Dependents = append(result.Dependents, toNode(n))
+	}
+	sortNodes(result.Targets)
+	sortNodes(result.Dependents)
+
+	return result
+}
+
+// keptEdges returns the edges carrying the target's traffic, those from a dependent to a dependent or
+// to a target. The edges closing a cycle are not kept, so that the kept edges form an acyclic graph.
+func keptEdges(targets []*graph.Node, inbound map[string][]*graph.Edge, distance map[string]int) map[*graph.Edge]bool {
+	const (
+		visiting = 1
+		visited  = 2
+	)
+	kept := make(map[*graph.Edge]bool)
+	state := make(map[string]int)
+
+	var visit func(n *graph.Node)
+	visit = func(n *graph.Node) {
+		state[n.ID] = visiting
+		for _, e := range inbound[n.ID] {
+			if distance[e.Source.ID] == 0 {
+				continue
+			}
+			switch state[e.Source.ID] {
+			case visiting:
+				// the edge closes a cycle
+				continue
+			case 0:
+				visit(e.Source)
+			}
+			kept[e] = true
+		}
+		state[n.ID] = visited
+	}
+	for _, n := range targets {
+		visit(n)
+	}
+	return kept
+}
+
+// upstreamOrder returns the targets and their dependents, ordered such that a node follows every node
+// it sends the target's traffic to (i.e. a reverse topological order of the kept edges).
+func upstreamOrder(targets []*graph.Node, inbound map[string][]*graph.Edge, kept map[*graph.Edge]bool) []*graph.Node {
+	pending := make(map[string]int)
+	for e := range kept {
+		pending[e.Source.ID]++
+	}
+
+	order := append([]*graph.Node{}, targets...)
+	for i := 0; i < len(order); i++ {
+		for _, e := range inbound[order[i].ID] {
+			if !kept[e] {
+				continue
+			}
+			if pending[e.Source.ID]--; pending[e.Source.ID] == 0 {
+				order = append(order, e.Source)
+			}
+		}
+	}
+	return order
+}
+
+// apportion returns the rate of the target's traffic passing through each node (key=id). The order starts
+// with the target nodes, whose traffic is the traffic they receive from their dependents.
+func apportion(order []*graph.Node, targetCount int, inbound map[string][]*graph.Edge, kept map[*graph.Edge]bool, rate rateFunc) map[string]float64 {
+	attributed := make(map[string]float64)
+	for i, n := range order {
+		share := rate
+		if i < targetCount {
+			attributed[n.ID] = sumRates(inbound[n.ID], kept, rate)
+		} else if sumRates(inbound[n.ID], kept, requestRate) > 0.0 {
+			share = requestRate
+		} else {
+			share = tcpRate
+		}
+
+		total := sumRates(inbound[n.ID], kept, share)
+		if total == 0.0 || attributed[n.ID] == 0.0 {
+			continue
+		}
+		for _, e := range inbound[n.ID] {
+			if kept[e] {
+				attributed[e.Source.ID] += attributed[n.ID] * share(e) / total
+			}
+		}
+	}
+	return attributed
+}
+
+func sumRates(edges []*graph.Edge, kept map[*graph.Edge]bool, rate rateFunc) float64 {
+	total := 0.0
+	for _, e := range edges {
+		if kept[e] {
+			total += rate(e)
+		}
+	}
+	return total
+}
+
+func sortNodes(nodes []Node) {
+	sort.Slice(nodes, func(i, j int) bool {
+		ni, nj := nodes[i], nodes[j]
+		if ni.Distance != nj.Distance {
+			return ni.Distance < nj.Distance
+		}
+		if ni.RequestRate != nj.RequestRate {
+			return ni.RequestRate > nj.RequestRate
+		}
+		if ni.TCPRate != nj.TCPRate {
+			return ni.TCPRate > nj.TCPRate
+		}
+		if ni.Namespace != nj.Namespace {
+			return ni.Namespace < nj.Namespace
+		}
+		return ni.Workload+ni.App+ni.Version+ni.Service < nj.Workload+nj.App+nj.Version+nj.Service
+	})
+}
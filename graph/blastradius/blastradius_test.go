@@ -0,0 +1,156 @@
+package blastradius
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/kiali/kiali/graph"
+)
+
+func addNode(trafficMap graph.TrafficMap, namespace, workload, app, version, graphType string) *graph.Node {
+	n := graph.NewNode(graph.Unknown, namespace, "", namespace, workload, app, version, graphType)
+	trafficMap[n.ID] = &n
+	return &n
+}
+
+func addEdge(source, dest *graph.Node, protocol string, rate float64) {
+	e := source.AddEdge(dest)
+	e.Metadata[graph.ProtocolKey] = protocol
+	switch protocol {
+	case graph.HTTP.Name:
+		e.Metadata[graph.HTTP.EdgeRates[0].Name] = rate
+	case graph.GRPC.Name:
+		e.Metadata[graph.GRPC.EdgeRates[0].Name] = rate
+	case graph.TCP.Name:
+		e.Metadata[graph.TCP.EdgeRates[0].Name] = rate
+	}
+}
+
+// The bookinfo traffic, entering through the ingress gateway:
+//
+//	unknown -> ingress -> productpage -> reviews-v1, reviews-v2, ratings, details
+//	reviews-v1, reviews-v2 -> ratings -> mysql (tcp)
+//	reviews-v1 <-> reviews-v2 (a cycle)
+func setupTrafficMap(graphType string) graph.TrafficMap {
+	trafficMap := graph.NewTrafficMap()
+	unknown := addNode(trafficMap, graph.Unknown, graph.Unknown, graph.Unknown, graph.Unknown, graphType)
+	ingress := addNode(trafficMap, "istio-system", "istio-ingressgateway", "istio-ingressgateway", "latest", graphType)
+	productpage := addNode(trafficMap, "bookinfo", "productpage-v1", "productpage", "v1", graphType)
+	reviewsV1 := addNode(trafficMap, "bookinfo", "reviews-v1", "reviews", "v1", graphType)
+	reviewsV2 := addNode(trafficMap, "bookinfo", "reviews-v2", "reviews", "v2", graphType)
+	ratings := addNode(trafficMap, "bookinfo", "ratings-v1", "ratings", "v1", graphType)
+	details := addNode(trafficMap, "bookinfo", "details-v1", "details", "v1", graphType)
+	mysql := addNode(trafficMap, "bookinfo", "mysql-v1", "mysql", "v1", graphType)
+
+	addEdge(unknown, ingress, graph.HTTP.Name, 10.0)
+	addEdge(ingress, productpage, graph.HTTP.Name, 10.0)
+	addEdge(productpage, reviewsV1, graph.HTTP.Name, 4.0)
+	addEdge(productpage, reviewsV2, graph.HTTP.Name, 6.0)
+	addEdge(productpage, ratings, graph.HTTP.Name, 2.0)
+	addEdge(productpage, details, graph.HTTP.Name, 10.0)
+	addEdge(reviewsV1, ratings, graph.GRPC.Name, 4.0)
+	addEdge(reviewsV2, ratings, graph.GRPC.Name, 6.0)
+	addEdge(reviewsV2, reviewsV1, graph.HTTP.Name, 1.0)
+	addEdge(reviewsV1, reviewsV2, graph.HTTP.Name, 1.0)
+	addEdge(ratings, mysql, graph.TCP.Name, 300.0)
+
+	return trafficMap
+}
+
+func testOptions() graph.Options {
+	o := graph.Options{}
+	o.TelemetryOptions.GraphType = graph.GraphTypeWorkload
+	o.TelemetryOptions.Duration = 10 * time.Minute
+	o.TelemetryOptions.QueryTime = 1523364075
+	return o
+}
+
+func dependentsByWorkload(br BlastRadius) map[string]Node {
+	dependents := make(map[string]Node)
+	for _, n := range br.Dependents {
+		dependents[n.Workload] = n
+	}
+	return dependents
+}
+
+func TestComputeRequests(t *testing.T) {
+	assert := assert.New(t)
+
+	target := graph.NewNode(graph.Unknown, "bookinfo", "", "bookinfo", "ratings-v1", "", "", graph.GraphTypeWorkload)
+	br := Compute(setupTrafficMap(graph.GraphTypeWorkload), target, testOptions())
+
+	assert.Equal(int64(600), br.Duration)
+	assert.Equal(graph.GraphTypeWorkload, br.GraphType)
+	assert.Len(br.Targets, 1)
+	assert.Equal("ratings-v1", br.Targets[0].Workload)
+	assert.Equal(12.0, br.Targets[0].RequestRate)
+
+	// details and mysql do not depend on ratings
+	dependents := dependentsByWorkload(br)
+	assert.Len(dependents, 5)
+	assert.NotContains(dependents, "details-v1")
+	assert.NotContains(dependents, "mysql-v1")
+
+	assert.Equal(1, dependents["reviews-v2"].Distance)
+	assert.Equal(1, dependents["productpage-v1"].Distance)
+	assert.Equal(2, dependents["istio-ingressgateway"].Distance)
+	assert.Equal(3, dependents[graph.Unknown].Distance)
+	assert.True(dependents[graph.Unknown].IsEntryPoint)
+	assert.False(dependents["istio-ingressgateway"].IsEntryPoint)
+
+	// all of the ratings traffic originates from the entry point, whatever the path
+	assert.InDelta(12.0, dependents["productpage-v1"].RequestRate, 0.0001)
+	assert.InDelta(12.0, dependents["istio-ingressgateway"].RequestRate, 0.0001)
+	assert.InDelta(12.0, dependents[graph.Unknown].RequestRate, 0.0001)
+	// reviews-v2 also reaches ratings through reviews-v1, the edge closing the reviews cycle is ignored
+	assert.InDelta(4.0, dependents["reviews-v1"].RequestRate, 0.0001)
+	assert.InDelta(6.8, dependents["reviews-v2"].RequestRate, 0.0001)
+
+	// sorted by distance, then by decreasing request rate
+	assert.Equal("productpage-v1", br.Dependents[0].Workload)
+	assert.Equal(graph.Unknown, br.Dependents[4].Workload)
+}
+
+func TestComputeTcp(t *testing.T) {
+	assert := assert.New(t)
+
+	target := graph.NewNode(graph.Unknown, "bookinfo", "", "bookinfo", "mysql-v1", "", "", graph.GraphTypeWorkload)
+	br := Compute(setupTrafficMap(graph.GraphTypeWorkload), target, testOptions())
+
+	assert.Equal(300.0, br.Targets[0].TCPRate)
+	assert.Equal(0.0, br.Targets[0].RequestRate)
+
+	dependents := dependentsByWorkload(br)
+	assert.Len(dependents, 6)
+	assert.Equal(300.0, dependents["ratings-v1"].TCPRate)
+	assert.InDelta(300.0, dependents[graph.Unknown].TCPRate, 0.0001)
+}
+
+func TestComputeApp(t *testing.T) {
+	assert := assert.New(t)
+
+	// all versions of the app are targets, the traffic between them is not a dependency
+	target := graph.NewNode(graph.Unknown, "bookinfo", "", "bookinfo", "", "reviews", "", graph.GraphTypeApp)
+	br := Compute(setupTrafficMap(graph.GraphTypeVersionedApp), target, testOptions())
+	assert.Len(br.Targets, 2)
+	assert.Equal(10.0, br.Targets[0].RequestRate+br.Targets[1].RequestRate)
+	assert.Len(br.Dependents, 3)
+
+	// a single version is also a dependency of the other version
+	target = graph.NewNode(graph.Unknown, "bookinfo", "", "bookinfo", "", "reviews", "v1", graph.GraphTypeVersionedApp)
+	br = Compute(setupTrafficMap(graph.GraphTypeVersionedApp), target, testOptions())
+	assert.Len(br.Targets, 1)
+	assert.Equal(5.0, br.Targets[0].RequestRate)
+	assert.Len(br.Dependents, 4)
+}
+
+func TestComputeUnknownTarget(t *testing.T) {
+	assert := assert.New(t)
+
+	target := graph.NewNode(graph.Unknown, "bookinfo", "", "bookinfo", "foo-v1", "", "", graph.GraphTypeWorkload)
+	br := Compute(setupTrafficMap(graph.GraphTypeWorkload), target, testOptions())
+	assert.Empty(br.Targets)
+	assert.Empty(br.Dependents)
+}
@@ -8,3 +8,13 @@ type ConfigVendor interface {
 	// definitions for error handling. Refer to the Cytoscape implementation as an example.
 	NewConfig(trafficMap TrafficMap, o ConfigOptions) interface{}
 }
+
+// RawConfig can be implemented by a Config that is not meant to be rendered as JSON. It
+// allows the vendor to supply the response content, as-is, along with its content type.
+type RawConfig interface {
+	// ContentType returns the media type of the content (e.g. "text/vnd.graphviz")
+	ContentType() string
+
+	// Content returns the full, rendered vendor config
+	Content() []byte
+}
@@ -12,17 +12,27 @@
 //            nodes for requested boxing.
 //
 // The package provides the Cytoscape implementation of graph/ConfigVendor.
+//
+// Config is the graph JSON contract, its version is reported in every response. The JSON Schema of Config,
+// Schema, is generated from the types of this package, and their field comments, by go generate.
 package cytoscape
 
 import (
 	"crypto/md5"
 	"fmt"
+	"math"
 	"sort"
-	"strings"
 
 	"github.com/kiali/kiali/graph"
 )
 
+//go:generate go run ./schemagen
+
+// SchemaVersion is the version of the graph JSON schema. The minor version is incremented for compatible
+// changes, i.e. added fields or values, the major version for incompatible changes, i.e. removed, renamed
+// or retyped fields. Clients should reject a major version they do not know.
+const SchemaVersion = "1.2"
+
 // ResponseFlags is a map of maps. Each response code is broken down by responseFlags:percentageOfTraffic, e.g.:
 // "200" : {
 //    "-"     : "80.0",
@@ -49,14 +59,68 @@ type Responses map[string]*ResponseDetail
 
 // ProtocolTraffic supplies all of the traffic information for a single protocol
 type ProtocolTraffic struct {
-	Protocol  string            `json:"protocol,omitempty"`  // protocol
-	Rates     map[string]string `json:"rates,omitempty"`     // map[rate]value
-	Responses Responses         `json:"responses,omitempty"` // see comment above
+	Protocol            string            `json:"protocol,omitempty"`            // protocol
+	Rates               map[string]string `json:"rates,omitempty"`               // map[rate]value
+	Responses           Responses         `json:"responses,omitempty"`           // see comment above
+	ConnectionResponses Responses         `json:"connectionResponses,omitempty"` // tcp only, the responses weighted by the closed connections
 }
 
 // HealthConfig maps annotations information for health
 type HealthConfig map[string]string
 
+// DiffData holds the change in a node or edge when comparing two time windows (diff graph only)
+type DiffData struct {
+	Status       string `json:"status"`                 // added | removed | changed | unchanged
+	PercentErr   string `json:"percentErr,omitempty"`   // change in error percentage
+	Rate         string `json:"rate,omitempty"`         // change in request rate (or byte rate for tcp)
+	ResponseTime string `json:"responseTime,omitempty"` // change in millis
+}
+
+// AnomalyData holds the anomaly scores of an edge, the base 2 logarithm of the ratio of the current value
+// to the baseline value (anomaly appender only)
+type AnomalyData struct {
+	ErrorRate    string `json:"errorRate,omitempty"`    // error percentage score
+	RequestRate  string `json:"requestRate,omitempty"`  // request rate score
+	ResponseTime string `json:"responseTime,omitempty"` // average response time score
+}
+
+// OperationData holds the traffic of a request operation of an edge (operations appender only)
+type OperationData struct {
+	Name         string `json:"name"`                   // the operation, e.g. a request path or gRPC method
+	PercentErr   string `json:"percentErr,omitempty"`   // percentage of failed requests
+	Rate         string `json:"rate"`                   // requests per second
+	ResponseTime string `json:"responseTime,omitempty"` // 95th percentile in millis
+}
+
+// String returns the operation in the edge label format, e.g. "GET /reviews 10.00rps 5.0%err 20ms"
+func (od OperationData) String() string {
+	s := fmt.Sprintf("%s %srps", od.Name, od.Rate)
+	if od.PercentErr != "" {
+		s += fmt.Sprintf(" %s%%err", od.PercentErr)
+	}
+	if od.ResponseTime != "" {
+		s += fmt.Sprintf(" %sms", od.ResponseTime)
+	}
+	return s
+}
+
+// SaturationData holds the resource saturation of a workload (saturation appender only)
+type SaturationData struct {
+	AvailableReplicas int32         `json:"availableReplicas"`
+	CPU               *ResourceData `json:"cpu,omitempty"` // in millicores
+	DesiredReplicas   int32         `json:"desiredReplicas"`
+	Memory            *ResourceData `json:"memory,omitempty"`   // in MiB
+	Restarts          string        `json:"restarts,omitempty"` // container restarts in the time window
+}
+
+// ResourceData holds the usage of a resource, and the usage as a percentage of the requests and limits
+type ResourceData struct {
+	PercentLimits   string `json:"percentLimits,omitempty"`   // not set without limits
+	PercentRequests string `json:"percentRequests,omitempty"` // not set without requests
+	Usage           string `json:"usage"`
+}
+
+// NodeData holds the data of a node, including box (compound) nodes
 type NodeData struct {
 	// Cytoscape Fields
 	ID     string `json:"id"`               // unique internal node ID (n0, n1...)
@@ -71,7 +135,9 @@ type NodeData struct {
 	Version               string              `json:"version,omitempty"`
 	Service               string              `json:"service,omitempty"`               // requested service for NodeTypeService
 	Aggregate             string              `json:"aggregate,omitempty"`             // set like "<aggregate>=<aggregateVal>"
+	CriticalPathDepth     int                 `json:"criticalPathDepth,omitempty"`     // set for a root node to the number of edges in its critical path
 	DestServices          []graph.ServiceName `json:"destServices,omitempty"`          // requested services for [dest] node
+	Diff                  *DiffData           `json:"diff,omitempty"`                  // set for diff graphs
 	Traffic               []ProtocolTraffic   `json:"traffic,omitempty"`               // traffic rates for all detected protocols
 	HasCB                 bool                `json:"hasCB,omitempty"`                 // true (has circuit breaker) | false
 	HasFaultInjection     bool                `json:"hasFaultInjection,omitempty"`     // true (vs has fault injection) | false
@@ -82,15 +148,22 @@ type NodeData struct {
 	HasTCPTrafficShifting bool                `json:"hasTCPTrafficShifting,omitempty"` // true (vs has tcp traffic shifting) | false
 	HasTrafficShifting    bool                `json:"hasTrafficShifting,omitempty"`    // true (vs has traffic shifting) | false
 	HasVS                 bool                `json:"hasVS,omitempty"`                 // true (has route rule) | false
-	IsBox                 string              `json:"isBox,omitempty"`                 // set for NodeTypeBox, current values: [ 'app', 'cluster', 'namespace' ]
+	IsBox                 string              `json:"isBox,omitempty"`                 // set for NodeTypeBox, current values: [ 'app', 'cluster', 'namespace', 'version', <label> ]
+	IsCriticalPath        bool                `json:"isCriticalPath,omitempty"`        // true (is on a critical path) | false
 	IsDead                bool                `json:"isDead,omitempty"`                // true (has no pods) | false
+	IsExternalHost        *graph.ExternalHost `json:"isExternalHost,omitempty"`        // set for a host reached through an egress cluster
+	IsFound               bool                `json:"isFound,omitempty"`               // true (matches the find expression) | false
 	IsIdle                bool                `json:"isIdle,omitempty"`                // true | false
 	IsInaccessible        bool                `json:"isInaccessible,omitempty"`        // true if the node exists in an inaccessible namespace
 	IsOutside             bool                `json:"isOutside,omitempty"`             // true | false
 	IsRoot                bool                `json:"isRoot,omitempty"`                // true | false
+	IsSaturated           bool                `json:"isSaturated,omitempty"`           // true (resource usage near the limits, or unavailable replicas) | false
 	IsServiceEntry        *graph.SEInfo       `json:"isServiceEntry,omitempty"`        // set static service entry information
+	Labels                map[string]string   `json:"labels,omitempty"`                // values of the box labels, for a box the values shared by its members
+	Saturation            *SaturationData     `json:"saturation,omitempty"`            // resource usage and replica availability of the workload
 }
 
+// EdgeData holds the data of an edge, an edge reports the traffic of a single protocol
 type EdgeData struct {
 	// Cytoscape Fields
 	ID     string `json:"id"`     // unique internal edge ID (e0, e1...)
@@ -98,32 +171,46 @@ type EdgeData struct {
 	Target string `json:"target"` // child node ID
 
 	// App Fields (not required by Cytoscape)
+	Anomaly         *AnomalyData    `json:"anomaly,omitempty"`         // set to the anomaly scores, when compared with a baseline
 	DestPrincipal   string          `json:"destPrincipal,omitempty"`   // principal used for the edge destination
+	Diff            *DiffData       `json:"diff,omitempty"`            // set for diff graphs
+	IsAnomalous     bool            `json:"isAnomalous,omitempty"`     // true (traffic deviates from the baseline) | false
+	IsCriticalPath  bool            `json:"isCriticalPath,omitempty"`  // true (is on a critical path) | false
+	IsFound         bool            `json:"isFound,omitempty"`         // true (matches the find expression) | false
 	IsMTLS          string          `json:"isMTLS,omitempty"`          // set to the percentage of traffic using a mutual TLS connection
+	Operations      []OperationData `json:"operations,omitempty"`      // the top request operations, by request rate
+	PercentIn       string          `json:"percentIn,omitempty"`       // percentage of the dest node's inbound traffic, for the edge protocol
+	PercentOut      string          `json:"percentOut,omitempty"`      // percentage of the source node's outbound traffic, for the edge protocol
 	ResponseTime    string          `json:"responseTime,omitempty"`    // in millis
 	SourcePrincipal string          `json:"sourcePrincipal,omitempty"` // principal used for the edge source
 	Throughput      string          `json:"throughput,omitempty"`      // in bytes/sec (request or response, depends on client request)
 	Traffic         ProtocolTraffic `json:"traffic,omitempty"`         // traffic rates for the edge protocol
 }
 
+// NodeWrapper wraps a node, as expected by CytoscapeJS
 type NodeWrapper struct {
 	Data *NodeData `json:"data"`
 }
 
+// EdgeWrapper wraps an edge, as expected by CytoscapeJS
 type EdgeWrapper struct {
 	Data *EdgeData `json:"data"`
 }
 
+// Elements holds the nodes and edges of the graph
 type Elements struct {
 	Nodes []*NodeWrapper `json:"nodes"`
 	Edges []*EdgeWrapper `json:"edges"`
 }
 
+// Config is the graph returned by the cytoscape config vendor
 type Config struct {
-	Timestamp int64    `json:"timestamp"`
-	Duration  int64    `json:"duration"`
-	GraphType string   `json:"graphType"`
-	Elements  Elements `json:"elements"`
+	Version   string       `json:"version"`         // the schema version, see SchemaVersion
+	Timestamp int64        `json:"timestamp"`       // unix time in seconds, the end of the time window
+	Duration  int64        `json:"duration"`        // the time window in seconds
+	GraphType string       `json:"graphType"`       // app | service | versionedApp | workload
+	Elements  Elements     `json:"elements"`        // the nodes and edges
+	Debug     *graph.Debug `json:"debug,omitempty"` // the generation timings and queries, when requested
 }
 
 func nodeHash(id string) string {
@@ -142,13 +229,17 @@ func NewConfig(trafficMap graph.TrafficMap, o graph.ConfigOptions) (result Confi
 	buildConfig(trafficMap, &nodes, &edges, o)
 
 	// Add compound nodes as needed, inner boxes first
-	if strings.Contains(o.BoxBy, graph.BoxByApp) || o.GraphType == graph.GraphTypeApp || o.GraphType == graph.GraphTypeVersionedApp {
+	if o.IsBoxBy(graph.BoxByApp) || o.GraphType == graph.GraphTypeApp || o.GraphType == graph.GraphTypeVersionedApp {
 		boxByApp(&nodes)
 	}
-	if strings.Contains(o.BoxBy, graph.BoxByNamespace) {
+	labels := o.BoxByLabels()
+	for i := len(labels) - 1; i >= 0; i-- {
+		boxByLabel(&nodes, labels[i])
+	}
+	if o.IsBoxBy(graph.BoxByNamespace) {
 		boxByNamespace(&nodes)
 	}
-	if strings.Contains(o.BoxBy, graph.BoxByCluster) {
+	if o.IsBoxBy(graph.BoxByCluster) {
 		boxByCluster(&nodes)
 	}
 
@@ -164,9 +255,16 @@ func NewConfig(trafficMap graph.TrafficMap, o graph.ConfigOptions) (result Confi
 				case graph.BoxByNamespace:
 					return 1
 				case graph.BoxByApp:
-					return 2
+					return 2 + len(labels)
+				case "":
+					return 3 + len(labels)
 				default:
-					return 3
+					for i, label := range labels {
+						if boxBy == label {
+							return 2 + i
+						}
+					}
+					return 3 + len(labels)
 				}
 			}
 			return rank(nodes[i].Data.IsBox) < rank(nodes[j].Data.IsBox)
@@ -198,6 +296,7 @@ func NewConfig(trafficMap graph.TrafficMap, o graph.ConfigOptions) (result Confi
 
 	elements := Elements{nodes, edges}
 	result = Config{
+		Version:   SchemaVersion,
 		Duration:  int64(o.Duration.Seconds()),
 		Timestamp: o.QueryTime,
 		GraphType: o.GraphType,
@@ -248,6 +347,19 @@ func buildConfig(trafficMap graph.TrafficMap, nodes *[]*NodeWrapper, edges *[]*E
 			nd.IsInaccessible = val.(bool)
 		}
 
+		// node may be on a critical path
+		if val, ok := n.Metadata[graph.IsCriticalPath]; ok {
+			nd.IsCriticalPath = val.(bool)
+		}
+		if val, ok := n.Metadata[graph.CriticalPathDepth]; ok {
+			nd.CriticalPathDepth = val.(int)
+		}
+
+		// node may match the find expression
+		if val, ok := n.Metadata[graph.IsFound]; ok {
+			nd.IsFound = val.(bool)
+		}
+
 		// node may have a circuit breaker
 		if val, ok := n.Metadata[graph.HasCB]; ok {
 			nd.HasCB = val.(bool)
@@ -301,6 +413,25 @@ func buildConfig(trafficMap graph.TrafficMap, nodes *[]*NodeWrapper, edges *[]*E
 			nd.IsServiceEntry = val.(*graph.SEInfo)
 		}
 
+		// node may be an external host
+		if val, ok := n.Metadata[graph.IsExternalHost]; ok {
+			nd.IsExternalHost = val.(*graph.ExternalHost)
+		}
+
+		// node may be saturated
+		if val, ok := n.Metadata[graph.IsSaturated]; ok {
+			nd.IsSaturated = val.(bool)
+		}
+		nd.Saturation = getSaturationData(n.Metadata)
+
+		// node may have box labels
+		if val, ok := n.Metadata[graph.Labels]; ok {
+			nd.Labels = val.(map[string]string)
+		}
+
+		// node may be compared to another time window
+		nd.Diff = getDiffData(n.Metadata)
+
 		// node may be an aggregate
 		if n.NodeType == graph.NodeTypeAggregate {
 			nd.Aggregate = fmt.Sprintf("%s=%s", n.Metadata[graph.Aggregate].(string), n.Metadata[graph.AggregateValue].(string))
@@ -365,6 +496,16 @@ func addNodeTelemetry(n *graph.Node, nd *NodeData) {
 }
 
 func addEdgeTelemetry(e *graph.Edge, ed *EdgeData) {
+	ed.Anomaly = getAnomalyData(e.Metadata)
+	if val, ok := e.Metadata[graph.IsAnomalous]; ok {
+		ed.IsAnomalous = val.(bool)
+	}
+	if val, ok := e.Metadata[graph.IsCriticalPath]; ok {
+		ed.IsCriticalPath = val.(bool)
+	}
+	if val, ok := e.Metadata[graph.IsFound]; ok {
+		ed.IsFound = val.(bool)
+	}
 	if val, ok := e.Metadata[graph.IsMTLS]; ok {
 		ed.IsMTLS = fmt.Sprintf("%.0f", val.(float64))
 	}
@@ -376,12 +517,15 @@ func addEdgeTelemetry(e *graph.Edge, ed *EdgeData) {
 		throughput := val.(float64)
 		ed.Throughput = fmt.Sprintf("%.0f", throughput)
 	}
+	ed.Diff = getDiffData(e.Metadata)
+	ed.Operations = getOperationsData(e.Metadata)
 
 	// an edge represents traffic for at most one protocol
 	for _, p := range graph.Protocols {
 		protocolTraffic := ProtocolTraffic{Protocol: p.Name}
 		total := 0.0
 		err := 0.0
+		errTotal := -1.0
 		var percentErr, percentReq graph.Rate
 		for _, r := range p.EdgeRates {
 			rateVal := getRate(e.Metadata, r.Name)
@@ -389,6 +533,9 @@ func addEdgeTelemetry(e *graph.Edge, ed *EdgeData) {
 			case r.IsTotal:
 				// there is one field holding the total traffic
 				total = rateVal
+			case r.IsErrTotal:
+				// the error rates are relative to this field, rather than to the total traffic
+				errTotal = rateVal
 			case r.IsErr:
 				// error rates can be reported for several error status codes, so sum up all
 				// of the error traffic to be used in the percentErr calculation below.
@@ -409,41 +556,31 @@ func addEdgeTelemetry(e *graph.Edge, ed *EdgeData) {
 		}
 		if protocolTraffic.Rates != nil {
 			if total > 0 {
-				if percentErr.Name != "" {
-					rateVal := err / total * 100
+				if errTotal < 0.0 {
+					errTotal = total
+				}
+				if percentErr.Name != "" && errTotal > 0.0 {
+					rateVal := err / errTotal * 100
 					if rateVal > 0.0 {
 						protocolTraffic.Rates[string(percentErr.Name)] = fmt.Sprintf("%.*f", percentErr.Precision, rateVal)
 					}
 				}
-				if percentReq.Name != "" {
-					rateVal := 0.0
-					for _, r := range p.NodeRates {
-						if !r.IsOut {
-							continue
-						}
-						rateVal = total / getRate(e.Source.Metadata, r.Name) * 100.0
-						break
-					}
-					if rateVal > 0.0 {
-						protocolTraffic.Rates[string(percentReq.Name)] = fmt.Sprintf("%.*f", percentReq.Precision, rateVal)
-					}
+				percentOut := getPercentOfNodeRate(total, e.Source.Metadata, p, false)
+				if percentReq.Name != "" && percentOut > 0.0 {
+					protocolTraffic.Rates[string(percentReq.Name)] = fmt.Sprintf("%.*f", percentReq.Precision, percentOut)
 				}
-				mdResponses := e.Metadata[p.EdgeResponses].(graph.Responses)
-				for code, detail := range mdResponses {
-					responseFlags := make(ResponseFlags)
-					responseHosts := make(ResponseHosts)
-					for flags, value := range detail.Flags {
-						responseFlags[flags] = fmt.Sprintf("%.*f", 1, value/total*100.0)
-					}
-					for host, value := range detail.Hosts {
-						responseHosts[host] = fmt.Sprintf("%.*f", 1, value/total*100.0)
-					}
-					responseDetail := &ResponseDetail{Flags: responseFlags, Hosts: responseHosts}
-					if protocolTraffic.Responses == nil {
-						protocolTraffic.Responses = Responses{code: responseDetail}
-					} else {
-						protocolTraffic.Responses[code] = responseDetail
-					}
+				// request-rate weighted for http and grpc, byte-rate weighted for tcp
+				if percentOut > 0.0 {
+					ed.PercentOut = fmt.Sprintf("%.1f", percentOut)
+				}
+				if percentIn := getPercentOfNodeRate(total, e.Dest.Metadata, p, true); percentIn > 0.0 {
+					ed.PercentIn = fmt.Sprintf("%.1f", percentIn)
+				}
+				// request-weighted for http and grpc, byte-weighted for tcp
+				protocolTraffic.Responses = getResponses(e.Metadata[p.EdgeResponses].(graph.Responses), total)
+				// connection-weighted for tcp, if the closed connections are reported
+				if mdResponses, ok := e.Metadata[p.EdgeConnectionResponses].(graph.Responses); ok && errTotal > 0.0 {
+					protocolTraffic.ConnectionResponses = getResponses(mdResponses, errTotal)
 				}
 				ed.Traffic = protocolTraffic
 			}
@@ -452,6 +589,40 @@ func addEdgeTelemetry(e *graph.Edge, ed *EdgeData) {
 	}
 }
 
+// getResponses returns the responses as percentages of the total
+func getResponses(mdResponses graph.Responses, total float64) Responses {
+	var responses Responses
+	for code, detail := range mdResponses {
+		responseFlags := make(ResponseFlags)
+		responseHosts := make(ResponseHosts)
+		for flags, value := range detail.Flags {
+			responseFlags[flags] = fmt.Sprintf("%.*f", 1, value/total*100.0)
+		}
+		for host, value := range detail.Hosts {
+			responseHosts[host] = fmt.Sprintf("%.*f", 1, value/total*100.0)
+		}
+		if responses == nil {
+			responses = Responses{}
+		}
+		responses[code] = &ResponseDetail{Flags: responseFlags, Hosts: responseHosts}
+	}
+	return responses
+}
+
+// getPercentOfNodeRate returns the edge total as a percentage of the node's total inbound (in=true) or outbound
+// traffic for the protocol, or 0 if the node reports no such traffic.
+func getPercentOfNodeRate(total float64, md graph.Metadata, p graph.Protocol, in bool) float64 {
+	for _, r := range p.NodeRates {
+		if (in && r.IsIn) || (!in && r.IsOut) {
+			if nodeRate := getRate(md, r.Name); nodeRate > 0.0 {
+				return total / nodeRate * 100.0
+			}
+			return 0.0
+		}
+	}
+	return 0.0
+}
+
 func getRate(md graph.Metadata, k graph.MetadataKey) float64 {
 	if rate, ok := md[k]; ok {
 		return rate.(float64)
@@ -459,6 +630,103 @@ func getRate(md graph.Metadata, k graph.MetadataKey) float64 {
 	return 0.0
 }
 
+// getAnomalyData returns the AnomalyData for an edge, or nil if the edge was not compared with a baseline
+func getAnomalyData(md graph.Metadata) *AnomalyData {
+	if _, ok := md[graph.AnomalyRequestRate]; !ok {
+		return nil
+	}
+	anomaly := &AnomalyData{}
+	if val, ok := md[graph.AnomalyErrorRate]; ok {
+		anomaly.ErrorRate = fmt.Sprintf("%+.2f", val.(float64))
+	}
+	if val, ok := md[graph.AnomalyRequestRate]; ok {
+		anomaly.RequestRate = fmt.Sprintf("%+.2f", val.(float64))
+	}
+	if val, ok := md[graph.AnomalyResponseTime]; ok {
+		anomaly.ResponseTime = fmt.Sprintf("%+.2f", val.(float64))
+	}
+	return anomaly
+}
+
+// getOperationsData returns the OperationData for an edge, or nil if the edge has no operations
+func getOperationsData(md graph.Metadata) []OperationData {
+	val, ok := md[graph.OperationsKey]
+	if !ok {
+		return nil
+	}
+	operations := []OperationData{}
+	for _, o := range val.(graph.Operations) {
+		od := OperationData{
+			Name: o.Name,
+			Rate: rateToString(2, o.Rate),
+		}
+		if o.ErrorRate > 0.0 {
+			od.PercentErr = fmt.Sprintf("%.1f", o.ErrorRate)
+		}
+		if o.ResponseTime > 0.0 {
+			od.ResponseTime = fmt.Sprintf("%.0f", o.ResponseTime)
+		}
+		operations = append(operations, od)
+	}
+	return operations
+}
+
+// getDiffData returns the DiffData for a diff graph node or edge, or nil if not a diff graph
+func getDiffData(md graph.Metadata) *DiffData {
+	status, ok := md[graph.DiffStatus]
+	if !ok {
+		return nil
+	}
+	diff := &DiffData{Status: status.(string)}
+	if val, ok := md[graph.DiffPercentErr]; ok {
+		diff.PercentErr = fmt.Sprintf("%+.1f", val.(float64))
+	}
+	if val, ok := md[graph.DiffRate]; ok && val.(float64) != 0 {
+		diff.Rate = diffToString(2, val.(float64))
+	}
+	if val, ok := md[graph.DiffResponseTime]; ok {
+		diff.ResponseTime = fmt.Sprintf("%+.0f", val.(float64))
+	}
+	return diff
+}
+
+// getSaturationData returns the SaturationData for a node, or nil if the node has no saturation
+func getSaturationData(md graph.Metadata) *SaturationData {
+	val, ok := md[graph.SaturationKey]
+	if !ok {
+		return nil
+	}
+	saturation := val.(graph.Saturation)
+	sd := &SaturationData{
+		AvailableReplicas: saturation.AvailableReplicas,
+		CPU:               getResourceData(saturation.CPU, 1000.0),
+		DesiredReplicas:   saturation.DesiredReplicas,
+		Memory:            getResourceData(saturation.Memory, 1.0/(1024.0*1024.0)),
+	}
+	if saturation.Restarts > 0.0 {
+		sd.Restarts = fmt.Sprintf("%.0f", saturation.Restarts)
+	}
+	return sd
+}
+
+// getResourceData returns the ResourceData for a resource, the usage converted by the factor, or nil if the
+// resource is not used
+func getResourceData(r graph.ResourceUsage, factor float64) *ResourceData {
+	if r.Usage == 0.0 {
+		return nil
+	}
+	rd := &ResourceData{
+		Usage: fmt.Sprintf("%.0f", r.Usage*factor),
+	}
+	if r.Requests > 0.0 {
+		rd.PercentRequests = fmt.Sprintf("%.1f", 100.0*r.Usage/r.Requests)
+	}
+	if r.Limits > 0.0 {
+		rd.PercentLimits = fmt.Sprintf("%.1f", 100.0*r.Usage/r.Limits)
+	}
+	return rd
+}
+
 // boxByApp adds compound nodes to box nodes for the same app
 func boxByApp(nodes *[]*NodeWrapper) {
 	box := make(map[string][]*NodeData)
@@ -501,6 +769,62 @@ func boxByCluster(nodes *[]*NodeWrapper) {
 	generateBoxCompoundNodes(box, nodes, graph.BoxByCluster)
 }
 
+// boxByLabel adds compound nodes to box nodes with the same label value in the same namespace. A box (e.g. an
+// app box) is boxed when all of its members have the same label value.
+func boxByLabel(nodes *[]*NodeWrapper, label string) {
+	members := make(map[string][]*NodeData)
+	for _, nw := range *nodes {
+		if nw.Data.Parent != "" {
+			members[nw.Data.Parent] = append(members[nw.Data.Parent], nw.Data)
+		}
+	}
+
+	box := make(map[string][]*NodeData)
+
+	for _, nw := range *nodes {
+		if nw.Data.Parent != "" {
+			continue
+		}
+		if value, ok := labelValue(nw.Data, label, members); ok {
+			k := fmt.Sprintf("box_%s_%s_%s_%s", nw.Data.Cluster, nw.Data.Namespace, label, value)
+			box[k] = append(box[k], nw.Data)
+		}
+	}
+
+	generateBoxCompoundNodes(box, nodes, label)
+}
+
+// labelValue returns the label value of the node, the version label is the node version. The value of a box
+// is the value shared by all of its members, it is added to the box labels.
+func labelValue(nd *NodeData, label string, members map[string][]*NodeData) (string, bool) {
+	if value, ok := nd.Labels[label]; ok {
+		return value, true
+	}
+	if nd.NodeType != graph.NodeTypeBox {
+		if label == graph.BoxByVersion && graph.IsOKVersion(nd.Version) {
+			return nd.Version, true
+		}
+		return "", false
+	}
+
+	value := ""
+	for i, m := range members[nd.ID] {
+		memberValue, ok := labelValue(m, label, members)
+		if !ok || (i > 0 && memberValue != value) {
+			return "", false
+		}
+		value = memberValue
+	}
+	if value == "" {
+		return "", false
+	}
+	if nd.Labels == nil {
+		nd.Labels = make(map[string]string)
+	}
+	nd.Labels[label] = value
+	return value, true
+}
+
 func generateBoxCompoundNodes(box map[string][]*NodeData, nodes *[]*NodeWrapper, boxBy string) {
 	for k, members := range box {
 		if boxBy != graph.BoxByApp || len(members) > 1 {
@@ -508,12 +832,19 @@ func generateBoxCompoundNodes(box map[string][]*NodeData, nodes *[]*NodeWrapper,
 			nodeID := nodeHash(k)
 			namespace := ""
 			app := ""
+			var labels map[string]string
 			switch boxBy {
+			case graph.BoxByCluster:
+				// no namespace
 			case graph.BoxByNamespace:
 				namespace = members[0].Namespace
 			case graph.BoxByApp:
 				namespace = members[0].Namespace
 				app = members[0].App
+			default:
+				value, _ := labelValue(members[0], boxBy, nil)
+				namespace = members[0].Namespace
+				labels = map[string]string{boxBy: value}
 			}
 			nd := NodeData{
 				ID:        nodeID,
@@ -523,6 +854,7 @@ func generateBoxCompoundNodes(box map[string][]*NodeData, nodes *[]*NodeWrapper,
 				App:       app,
 				Version:   "",
 				IsBox:     boxBy,
+				Labels:    labels,
 			}
 
 			nw := NodeWrapper{
@@ -560,6 +892,16 @@ func rateToString(minPrecision int, rateVal float64) string {
 	return fmt.Sprintf("%.*f", precision, rateVal)
 }
 
+// diffToString is like rateToString but always includes the sign
+func diffToString(minPrecision int, diffVal float64) string {
+	precision := minPrecision
+	if requiredPrecision := calcPrecision(math.Abs(diffVal), 5); requiredPrecision > minPrecision {
+		precision = requiredPrecision
+	}
+
+	return fmt.Sprintf("%+.*f", precision, diffVal)
+}
+
 // calcPrecision returns the precision necessary to see at least one significant digit (up to max)
 func calcPrecision(val float64, max int) int {
 	if val <= 0 {
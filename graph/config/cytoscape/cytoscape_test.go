@@ -1,9 +1,16 @@
 package cytoscape
 
 import (
+	"encoding/json"
+	"reflect"
+	"regexp"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+
+	"github.com/kiali/kiali/graph"
 )
 
 func TestRateStrings(t *testing.T) {
@@ -81,3 +88,250 @@ func TestRateStrings(t *testing.T) {
 	assert.Equal("0.0009", rateToString(2, 0.00094))
 	assert.Equal("0.0010", rateToString(2, 0.00099))
 }
+
+func TestEdgePercentages(t *testing.T) {
+	assert := assert.New(t)
+
+	trafficMap := graph.NewTrafficMap()
+	productpage := graph.NewNode("east", "bookinfo", "productpage", "bookinfo", "productpage-v1", "productpage", "v1", graph.GraphTypeVersionedApp)
+	reviews := graph.NewNode("east", "bookinfo", "reviews", "bookinfo", "reviews-v1", "reviews", "v1", graph.GraphTypeVersionedApp)
+	details := graph.NewNode("east", "bookinfo", "details", "bookinfo", "details-v1", "details", "v1", graph.GraphTypeVersionedApp)
+	ratings := graph.NewNode("east", "bookinfo", "ratings", "bookinfo", "ratings-v1", "ratings", "v1", graph.GraphTypeVersionedApp)
+	mongodb := graph.NewNode("east", "bookinfo", "mongodb", "bookinfo", "mongodb-v1", "mongodb", "v1", graph.GraphTypeVersionedApp)
+	trafficMap[productpage.ID] = &productpage
+	trafficMap[reviews.ID] = &reviews
+	trafficMap[details.ID] = &details
+	trafficMap[ratings.ID] = &ratings
+	trafficMap[mongodb.ID] = &mongodb
+
+	// productpage sends 75% of its requests to reviews, which receives 75% of its requests from productpage
+	e := productpage.AddEdge(&reviews)
+	graph.AddToMetadata("http", 30.0, "200", "-", "reviews", productpage.Metadata, reviews.Metadata, e.Metadata)
+	e = productpage.AddEdge(&details)
+	graph.AddToMetadata("http", 10.0, "200", "-", "details", productpage.Metadata, details.Metadata, e.Metadata)
+	e = ratings.AddEdge(&reviews)
+	graph.AddToMetadata("http", 10.0, "200", "-", "reviews", ratings.Metadata, reviews.Metadata, e.Metadata)
+	// tcp is weighted by bytes, independent of the http traffic
+	e = productpage.AddEdge(&mongodb)
+	graph.AddToMetadata("tcp", 100.0, "", "-", "mongodb", productpage.Metadata, mongodb.Metadata, e.Metadata)
+	e = ratings.AddEdge(&mongodb)
+	graph.AddToMetadata("tcp", 300.0, "", "-", "mongodb", ratings.Metadata, mongodb.Metadata, e.Metadata)
+
+	config := NewConfig(trafficMap, graph.ConfigOptions{
+		BoxBy: graph.BoxByNone,
+		CommonOptions: graph.CommonOptions{
+			Duration:  10 * time.Minute,
+			GraphType: graph.GraphTypeVersionedApp,
+			QueryTime: 1523364075,
+		},
+	})
+
+	// the cytoscape IDs are hashed, key the edges by app
+	apps := make(map[string]string)
+	for _, nw := range config.Elements.Nodes {
+		apps[nw.Data.ID] = nw.Data.App
+	}
+	percentages := make(map[string][2]string)
+	for _, ew := range config.Elements.Edges {
+		percentages[apps[ew.Data.Source]+" "+apps[ew.Data.Target]] = [2]string{ew.Data.PercentOut, ew.Data.PercentIn}
+	}
+	assert.Len(percentages, 5)
+	assert.Equal([2]string{"75.0", "75.0"}, percentages["productpage reviews"])
+	assert.Equal([2]string{"25.0", "100.0"}, percentages["productpage details"])
+	assert.Equal([2]string{"100.0", "25.0"}, percentages["ratings reviews"])
+	assert.Equal([2]string{"100.0", "25.0"}, percentages["productpage mongodb"])
+	assert.Equal([2]string{"100.0", "75.0"}, percentages["ratings mongodb"])
+}
+
+func TestTCPConnections(t *testing.T) {
+	assert := assert.New(t)
+
+	ratings := graph.NewNode("east", "bookinfo", "ratings", "bookinfo", "ratings-v1", "ratings", "v1", graph.GraphTypeVersionedApp)
+	mysqldb := graph.NewNode("east", "bookinfo", "mysqldb", "bookinfo", "mysqldb-v1", "mysqldb", "v1", graph.GraphTypeVersionedApp)
+	e := ratings.AddEdge(&mysqldb)
+	e.Metadata[graph.ProtocolKey] = "tcp"
+	graph.AddToMetadata("tcp", 1000.0, "", "-", "mysqldb", ratings.Metadata, mysqldb.Metadata, e.Metadata)
+
+	// without connections the responses are byte-weighted
+	ed := EdgeData{}
+	addEdgeTelemetry(e, &ed)
+	assert.Equal(map[string]string{"tcp": "1000.00"}, ed.Traffic.Rates)
+	assert.Equal(ResponseFlags{"-": "100.0"}, ed.Traffic.Responses["-"].Flags)
+	assert.Nil(ed.Traffic.ConnectionResponses)
+
+	// with connections the errors and connection responses are relative to the closed connections, the
+	// responses remain byte-weighted
+	graph.AddTCPConnectionsToMetadata(4.0, 3.0, 20.0, "-", "mysqldb", e.Metadata)
+	graph.AddTCPConnectionsToMetadata(0.0, 1.0, 0.0, "UF", "mysqldb", e.Metadata)
+	ed = EdgeData{}
+	addEdgeTelemetry(e, &ed)
+	assert.Equal(map[string]string{
+		"tcp":           "1000.00",
+		"tcpOpened":     "4.00",
+		"tcpClosed":     "4.00",
+		"tcpActive":     "20",
+		"tcpErr":        "1.00",
+		"tcpPercentErr": "25.0",
+	}, ed.Traffic.Rates)
+	assert.Equal(Responses{"-": {Flags: ResponseFlags{"-": "100.0"}, Hosts: ResponseHosts{"mysqldb": "100.0"}}}, ed.Traffic.Responses)
+	assert.Equal(ResponseFlags{"-": "75.0"}, ed.Traffic.ConnectionResponses["-"].Flags)
+	assert.Equal(ResponseFlags{"UF": "25.0"}, ed.Traffic.ConnectionResponses[graph.TCPErrCode].Flags)
+}
+
+func TestOperationsData(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Nil(getOperationsData(graph.NewMetadata()))
+
+	md := graph.NewMetadata()
+	md[graph.OperationsKey] = graph.Operations{
+		{Name: "GET /reviews", ErrorRate: 20.0, Rate: 10.0, ResponseTime: 40.4},
+		{Name: "POST /reviews", Rate: 0.5},
+	}
+	operations := getOperationsData(md)
+	assert.Equal([]OperationData{
+		{Name: "GET /reviews", PercentErr: "20.0", Rate: "10.00", ResponseTime: "40"},
+		{Name: "POST /reviews", Rate: "0.50"},
+	}, operations)
+	assert.Equal("GET /reviews 10.00rps 20.0%err 40ms", operations[0].String())
+	assert.Equal("POST /reviews 0.50rps", operations[1].String())
+}
+
+func TestBoxByLabel(t *testing.T) {
+	assert := assert.New(t)
+
+	trafficMap := graph.NewTrafficMap()
+	addWorkload := func(workload, app, version string, labels map[string]string) {
+		n := graph.NewNode("east", "bookinfo", "", "bookinfo", workload, app, version, graph.GraphTypeWorkload)
+		if labels != nil {
+			n.Metadata[graph.Labels] = labels
+		}
+		trafficMap[n.ID] = &n
+	}
+	addWorkload("productpage-v1", "productpage", "v1", map[string]string{"team": "front", "tier": "web"})
+	addWorkload("reviews-v1", "reviews", "v1", map[string]string{"team": "back", "tier": "api"})
+	addWorkload("reviews-v2", "reviews", "v2", map[string]string{"team": "back", "tier": "api"})
+	addWorkload("ratings-v1", "ratings", "v1", map[string]string{"team": "back", "tier": "data"})
+	addWorkload("details-v1", "details", "v1", nil)
+
+	newConfig := func(boxBy string) (boxes map[string]*NodeData, parents map[string]string) {
+		config := NewConfig(trafficMap, graph.ConfigOptions{
+			BoxBy: boxBy,
+			CommonOptions: graph.CommonOptions{
+				Duration:  10 * time.Minute,
+				GraphType: graph.GraphTypeWorkload,
+				QueryTime: 1523364075,
+			},
+		})
+
+		// key the boxes by their label value (or app, or namespace), and the nodes by workload
+		boxes = make(map[string]*NodeData)
+		names := make(map[string]string)
+		for _, nw := range config.Elements.Nodes {
+			switch nw.Data.IsBox {
+			case "":
+				names[nw.Data.ID] = nw.Data.Workload
+			case graph.BoxByApp:
+				names[nw.Data.ID] = nw.Data.App
+			case graph.BoxByNamespace:
+				names[nw.Data.ID] = nw.Data.Namespace
+			default:
+				names[nw.Data.ID] = nw.Data.Labels[nw.Data.IsBox]
+			}
+			if nw.Data.IsBox != "" {
+				boxes[names[nw.Data.ID]] = nw.Data
+			}
+		}
+		// parent nodes must come before their children
+		parents = make(map[string]string)
+		seen := make(map[string]bool)
+		for _, nw := range config.Elements.Nodes {
+			assert.True(nw.Data.Parent == "" || seen[nw.Data.Parent], names[nw.Data.ID])
+			seen[nw.Data.ID] = true
+			parents[names[nw.Data.ID]] = names[nw.Data.Parent]
+		}
+		return boxes, parents
+	}
+
+	// team boxes enclose tier boxes, which enclose the app box of the reviews workloads
+	boxes, parents := newConfig("app,team,tier,namespace")
+	assert.Len(boxes, 7)
+	assert.Equal("reviews", parents["reviews-v1"])
+	assert.Equal("reviews", parents["reviews-v2"])
+	assert.Equal(map[string]string{"team": "back", "tier": "api"}, boxes["reviews"].Labels)
+	assert.Equal("api", parents["reviews"])
+	assert.Equal("data", parents["ratings-v1"])
+	assert.Equal("web", parents["productpage-v1"])
+	assert.Equal("back", parents["api"])
+	assert.Equal("back", parents["data"])
+	assert.Equal("front", parents["web"])
+	assert.Equal("bookinfo", parents["back"])
+	assert.Equal("bookinfo", parents["front"])
+	assert.Equal("team", boxes["back"].IsBox)
+	assert.Equal("bookinfo", boxes["back"].Namespace)
+	// a node without the label is not boxed by label
+	assert.Equal("bookinfo", parents["details-v1"])
+
+	// the version boxes use the node version, the reviews app box has no single version
+	boxes, parents = newConfig("app,version")
+	assert.Len(boxes, 2)
+	assert.Equal("reviews", parents["reviews-v1"])
+	assert.Equal("", parents["reviews"])
+	assert.Equal("v1", parents["ratings-v1"])
+	assert.Equal("v1", parents["details-v1"])
+
+	boxes, parents = newConfig("version")
+	assert.Len(boxes, 2)
+	assert.Equal("v1", parents["reviews-v1"])
+	assert.Equal("v2", parents["reviews-v2"])
+}
+
+func TestSaturationData(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Nil(getSaturationData(graph.NewMetadata()))
+
+	md := graph.NewMetadata()
+	md[graph.SaturationKey] = graph.Saturation{
+		CPU:               graph.ResourceUsage{Usage: 0.45, Requests: 0.5},
+		Memory:            graph.ResourceUsage{Usage: 96.0 * 1024 * 1024, Requests: 64.0 * 1024 * 1024, Limits: 128.0 * 1024 * 1024},
+		DesiredReplicas:   2,
+		AvailableReplicas: 2,
+	}
+	assert.Equal(&SaturationData{
+		AvailableReplicas: 2,
+		CPU:               &ResourceData{PercentRequests: "90.0", Usage: "450"},
+		DesiredReplicas:   2,
+		Memory:            &ResourceData{PercentLimits: "75.0", PercentRequests: "150.0", Usage: "96"},
+	}, getSaturationData(md))
+}
+
+func TestSchema(t *testing.T) {
+	assert := assert.New(t)
+
+	config := NewConfig(graph.NewTrafficMap(), graph.ConfigOptions{BoxBy: graph.BoxByNone})
+	assert.Equal(SchemaVersion, config.Version)
+
+	type schema struct {
+		Properties  map[string]interface{} `json:"properties"`
+		Definitions map[string]struct {
+			Properties map[string]interface{} `json:"properties"`
+		} `json:"definitions"`
+	}
+	var s schema
+	assert.NoError(json.Unmarshal([]byte(Schema), &s))
+
+	version := s.Properties["version"].(map[string]interface{})
+	assert.Regexp(regexp.MustCompile(version["pattern"].(string)), SchemaVersion)
+
+	// every node and edge field is described
+	for _, v := range []interface{}{NodeData{}, EdgeData{}} {
+		typ := reflect.TypeOf(v)
+		properties := s.Definitions[typ.Name()].Properties
+		assert.Len(properties, typ.NumField(), typ.Name())
+		for i := 0; i < typ.NumField(); i++ {
+			name := strings.Split(typ.Field(i).Tag.Get("json"), ",")[0]
+			assert.Contains(properties, name, typ.Name())
+		}
+	}
+}
@@ -0,0 +1,719 @@
+// Code generated by schemagen. DO NOT EDIT.
+
+package cytoscape
+
+// Schema is the JSON Schema of Config, for the SchemaVersion
+const Schema = `{
+  "$schema": "http://json-schema.org/draft-07/schema#",
+  "title": "Kiali graph, schema version 1.2",
+  "description": "Config is the graph returned by the cytoscape config vendor",
+  "type": "object",
+  "properties": {
+    "debug": {
+      "allOf": [
+        {
+          "$ref": "#/definitions/Debug"
+        }
+      ],
+      "description": "the generation timings and queries, when requested"
+    },
+    "duration": {
+      "description": "the time window in seconds",
+      "type": "integer"
+    },
+    "elements": {
+      "allOf": [
+        {
+          "$ref": "#/definitions/Elements"
+        }
+      ],
+      "description": "the nodes and edges"
+    },
+    "graphType": {
+      "description": "app | service | versionedApp | workload",
+      "type": "string"
+    },
+    "timestamp": {
+      "description": "unix time in seconds, the end of the time window",
+      "type": "integer"
+    },
+    "version": {
+      "description": "the schema version, see SchemaVersion",
+      "type": "string",
+      "pattern": "^1\\.[0-9]+$"
+    }
+  },
+  "required": [
+    "version",
+    "timestamp",
+    "duration",
+    "graphType",
+    "elements"
+  ],
+  "definitions": {
+    "AnomalyData": {
+      "description": "AnomalyData holds the anomaly scores of an edge, the base 2 logarithm of the ratio of the current value to the baseline value (anomaly appender only)",
+      "type": "object",
+      "properties": {
+        "errorRate": {
+          "description": "error percentage score",
+          "type": "string"
+        },
+        "requestRate": {
+          "description": "request rate score",
+          "type": "string"
+        },
+        "responseTime": {
+          "description": "average response time score",
+          "type": "string"
+        }
+      }
+    },
+    "Debug": {
+      "description": "Debug collects the timings of a graph generation and the Prometheus queries it executes. It is returned with the graph when requested (debug=true). A nil Debug collects nothing, so that it can be used unconditionally.",
+      "type": "object",
+      "properties": {
+        "phases": {
+          "type": "array",
+          "items": {
+            "$ref": "#/definitions/DebugPhase"
+          }
+        },
+        "queries": {
+          "type": "array",
+          "items": {
+            "$ref": "#/definitions/DebugQuery"
+          }
+        }
+      },
+      "required": [
+        "phases",
+        "queries"
+      ]
+    },
+    "DebugPhase": {
+      "description": "DebugPhase is the timing of a phase of the graph generation",
+      "type": "object",
+      "properties": {
+        "appender": {
+          "type": "string"
+        },
+        "duration": {
+          "description": "milliseconds",
+          "type": "number"
+        },
+        "namespace": {
+          "type": "string"
+        },
+        "phase": {
+          "type": "string"
+        }
+      },
+      "required": [
+        "phase",
+        "duration"
+      ]
+    },
+    "DebugQuery": {
+      "description": "DebugQuery is a Prometheus query executed for the graph generation",
+      "type": "object",
+      "properties": {
+        "duration": {
+          "description": "milliseconds",
+          "type": "number"
+        },
+        "error": {
+          "type": "string"
+        },
+        "query": {
+          "type": "string"
+        },
+        "series": {
+          "description": "the number of series of the result",
+          "type": "integer"
+        }
+      },
+      "required": [
+        "query",
+        "duration",
+        "series"
+      ]
+    },
+    "DiffData": {
+      "description": "DiffData holds the change in a node or edge when comparing two time windows (diff graph only)",
+      "type": "object",
+      "properties": {
+        "percentErr": {
+          "description": "change in error percentage",
+          "type": "string"
+        },
+        "rate": {
+          "description": "change in request rate (or byte rate for tcp)",
+          "type": "string"
+        },
+        "responseTime": {
+          "description": "change in millis",
+          "type": "string"
+        },
+        "status": {
+          "description": "added | removed | changed | unchanged",
+          "type": "string"
+        }
+      },
+      "required": [
+        "status"
+      ]
+    },
+    "EdgeData": {
+      "description": "EdgeData holds the data of an edge, an edge reports the traffic of a single protocol",
+      "type": "object",
+      "properties": {
+        "anomaly": {
+          "allOf": [
+            {
+              "$ref": "#/definitions/AnomalyData"
+            }
+          ],
+          "description": "set to the anomaly scores, when compared with a baseline"
+        },
+        "destPrincipal": {
+          "description": "principal used for the edge destination",
+          "type": "string"
+        },
+        "diff": {
+          "allOf": [
+            {
+              "$ref": "#/definitions/DiffData"
+            }
+          ],
+          "description": "set for diff graphs"
+        },
+        "id": {
+          "description": "unique internal edge ID (e0, e1...)",
+          "type": "string"
+        },
+        "isAnomalous": {
+          "description": "true (traffic deviates from the baseline) | false",
+          "type": "boolean"
+        },
+        "isCriticalPath": {
+          "description": "true (is on a critical path) | false",
+          "type": "boolean"
+        },
+        "isFound": {
+          "description": "true (matches the find expression) | false",
+          "type": "boolean"
+        },
+        "isMTLS": {
+          "description": "set to the percentage of traffic using a mutual TLS connection",
+          "type": "string"
+        },
+        "operations": {
+          "description": "the top request operations, by request rate",
+          "type": "array",
+          "items": {
+            "$ref": "#/definitions/OperationData"
+          }
+        },
+        "percentIn": {
+          "description": "percentage of the dest node's inbound traffic, for the edge protocol",
+          "type": "string"
+        },
+        "percentOut": {
+          "description": "percentage of the source node's outbound traffic, for the edge protocol",
+          "type": "string"
+        },
+        "responseTime": {
+          "description": "in millis",
+          "type": "string"
+        },
+        "source": {
+          "description": "parent node ID",
+          "type": "string"
+        },
+        "sourcePrincipal": {
+          "description": "principal used for the edge source",
+          "type": "string"
+        },
+        "target": {
+          "description": "child node ID",
+          "type": "string"
+        },
+        "throughput": {
+          "description": "in bytes/sec (request or response, depends on client request)",
+          "type": "string"
+        },
+        "traffic": {
+          "allOf": [
+            {
+              "$ref": "#/definitions/ProtocolTraffic"
+            }
+          ],
+          "description": "traffic rates for the edge protocol"
+        }
+      },
+      "required": [
+        "id",
+        "source",
+        "target"
+      ]
+    },
+    "EdgeWrapper": {
+      "description": "EdgeWrapper wraps an edge, as expected by CytoscapeJS",
+      "type": "object",
+      "properties": {
+        "data": {
+          "$ref": "#/definitions/EdgeData"
+        }
+      },
+      "required": [
+        "data"
+      ]
+    },
+    "Elements": {
+      "description": "Elements holds the nodes and edges of the graph",
+      "type": "object",
+      "properties": {
+        "edges": {
+          "type": "array",
+          "items": {
+            "$ref": "#/definitions/EdgeWrapper"
+          }
+        },
+        "nodes": {
+          "type": "array",
+          "items": {
+            "$ref": "#/definitions/NodeWrapper"
+          }
+        }
+      },
+      "required": [
+        "nodes",
+        "edges"
+      ]
+    },
+    "ExternalHost": {
+      "description": "ExternalHost holds a host reached through the PassthroughCluster or the BlackHoleCluster, i.e. a host not defined in the service registry. The traffic is blocked when sent to the BlackHoleCluster, or when the outbound traffic policy is REGISTRY_ONLY and no ServiceEntry defines the host.",
+      "type": "object",
+      "properties": {
+        "blocked": {
+          "type": "boolean"
+        },
+        "egressCluster": {
+          "description": "PassthroughCluster | BlackHoleCluster",
+          "type": "string"
+        },
+        "host": {
+          "description": "the requested host, the authority or SNI",
+          "type": "string"
+        },
+        "outboundTrafficPolicy": {
+          "description": "ALLOW_ANY | REGISTRY_ONLY",
+          "type": "string"
+        },
+        "serviceEntry": {
+          "description": "the namespace/name of a ServiceEntry defining the host",
+          "type": "string"
+        }
+      },
+      "required": [
+        "blocked",
+        "egressCluster",
+        "host",
+        "outboundTrafficPolicy"
+      ]
+    },
+    "HealthConfig": {
+      "description": "HealthConfig maps annotations information for health",
+      "type": "object",
+      "additionalProperties": {
+        "type": "string"
+      }
+    },
+    "NodeData": {
+      "description": "NodeData holds the data of a node, including box (compound) nodes",
+      "type": "object",
+      "properties": {
+        "aggregate": {
+          "description": "set like \"\u003caggregate\u003e=\u003caggregateVal\u003e\"",
+          "type": "string"
+        },
+        "app": {
+          "type": "string"
+        },
+        "cluster": {
+          "type": "string"
+        },
+        "criticalPathDepth": {
+          "description": "set for a root node to the number of edges in its critical path",
+          "type": "integer"
+        },
+        "destServices": {
+          "description": "requested services for [dest] node",
+          "type": "array",
+          "items": {
+            "$ref": "#/definitions/ServiceName"
+          }
+        },
+        "diff": {
+          "allOf": [
+            {
+              "$ref": "#/definitions/DiffData"
+            }
+          ],
+          "description": "set for diff graphs"
+        },
+        "hasCB": {
+          "description": "true (has circuit breaker) | false",
+          "type": "boolean"
+        },
+        "hasFaultInjection": {
+          "description": "true (vs has fault injection) | false",
+          "type": "boolean"
+        },
+        "hasHealthConfig": {
+          "allOf": [
+            {
+              "$ref": "#/definitions/HealthConfig"
+            }
+          ],
+          "description": "set to the health config override"
+        },
+        "hasMissingSC": {
+          "description": "true (has missing sidecar) | false",
+          "type": "boolean"
+        },
+        "hasRequestRouting": {
+          "description": "true (vs has request routing) | false",
+          "type": "boolean"
+        },
+        "hasRequestTimeout": {
+          "description": "true (vs has request timeout) | false",
+          "type": "boolean"
+        },
+        "hasTCPTrafficShifting": {
+          "description": "true (vs has tcp traffic shifting) | false",
+          "type": "boolean"
+        },
+        "hasTrafficShifting": {
+          "description": "true (vs has traffic shifting) | false",
+          "type": "boolean"
+        },
+        "hasVS": {
+          "description": "true (has route rule) | false",
+          "type": "boolean"
+        },
+        "id": {
+          "description": "unique internal node ID (n0, n1...)",
+          "type": "string"
+        },
+        "isBox": {
+          "description": "set for NodeTypeBox, current values: [ 'app', 'cluster', 'namespace', 'version', \u003clabel\u003e ]",
+          "type": "string"
+        },
+        "isCriticalPath": {
+          "description": "true (is on a critical path) | false",
+          "type": "boolean"
+        },
+        "isDead": {
+          "description": "true (has no pods) | false",
+          "type": "boolean"
+        },
+        "isExternalHost": {
+          "allOf": [
+            {
+              "$ref": "#/definitions/ExternalHost"
+            }
+          ],
+          "description": "set for a host reached through an egress cluster"
+        },
+        "isFound": {
+          "description": "true (matches the find expression) | false",
+          "type": "boolean"
+        },
+        "isIdle": {
+          "description": "true | false",
+          "type": "boolean"
+        },
+        "isInaccessible": {
+          "description": "true if the node exists in an inaccessible namespace",
+          "type": "boolean"
+        },
+        "isOutside": {
+          "description": "true | false",
+          "type": "boolean"
+        },
+        "isRoot": {
+          "description": "true | false",
+          "type": "boolean"
+        },
+        "isSaturated": {
+          "description": "true (resource usage near the limits, or unavailable replicas) | false",
+          "type": "boolean"
+        },
+        "isServiceEntry": {
+          "allOf": [
+            {
+              "$ref": "#/definitions/SEInfo"
+            }
+          ],
+          "description": "set static service entry information"
+        },
+        "labels": {
+          "description": "values of the box labels, for a box the values shared by its members",
+          "type": "object",
+          "additionalProperties": {
+            "type": "string"
+          }
+        },
+        "namespace": {
+          "type": "string"
+        },
+        "nodeType": {
+          "description": "App Fields (not required by Cytoscape)",
+          "type": "string"
+        },
+        "parent": {
+          "description": "Compound Node parent ID",
+          "type": "string"
+        },
+        "saturation": {
+          "allOf": [
+            {
+              "$ref": "#/definitions/SaturationData"
+            }
+          ],
+          "description": "resource usage and replica availability of the workload"
+        },
+        "service": {
+          "description": "requested service for NodeTypeService",
+          "type": "string"
+        },
+        "traffic": {
+          "description": "traffic rates for all detected protocols",
+          "type": "array",
+          "items": {
+            "$ref": "#/definitions/ProtocolTraffic"
+          }
+        },
+        "version": {
+          "type": "string"
+        },
+        "workload": {
+          "type": "string"
+        }
+      },
+      "required": [
+        "id",
+        "nodeType",
+        "cluster",
+        "namespace"
+      ]
+    },
+    "NodeWrapper": {
+      "description": "NodeWrapper wraps a node, as expected by CytoscapeJS",
+      "type": "object",
+      "properties": {
+        "data": {
+          "$ref": "#/definitions/NodeData"
+        }
+      },
+      "required": [
+        "data"
+      ]
+    },
+    "OperationData": {
+      "description": "OperationData holds the traffic of a request operation of an edge (operations appender only)",
+      "type": "object",
+      "properties": {
+        "name": {
+          "description": "the operation, e.g. a request path or gRPC method",
+          "type": "string"
+        },
+        "percentErr": {
+          "description": "percentage of failed requests",
+          "type": "string"
+        },
+        "rate": {
+          "description": "requests per second",
+          "type": "string"
+        },
+        "responseTime": {
+          "description": "95th percentile in millis",
+          "type": "string"
+        }
+      },
+      "required": [
+        "name",
+        "rate"
+      ]
+    },
+    "ProtocolTraffic": {
+      "description": "ProtocolTraffic supplies all of the traffic information for a single protocol",
+      "type": "object",
+      "properties": {
+        "connectionResponses": {
+          "allOf": [
+            {
+              "$ref": "#/definitions/Responses"
+            }
+          ],
+          "description": "tcp only, the responses weighted by the closed connections"
+        },
+        "protocol": {
+          "description": "protocol",
+          "type": "string"
+        },
+        "rates": {
+          "description": "map[rate]value",
+          "type": "object",
+          "additionalProperties": {
+            "type": "string"
+          }
+        },
+        "responses": {
+          "allOf": [
+            {
+              "$ref": "#/definitions/Responses"
+            }
+          ],
+          "description": "see comment above"
+        }
+      }
+    },
+    "ResourceData": {
+      "description": "ResourceData holds the usage of a resource, and the usage as a percentage of the requests and limits",
+      "type": "object",
+      "properties": {
+        "percentLimits": {
+          "description": "not set without limits",
+          "type": "string"
+        },
+        "percentRequests": {
+          "description": "not set without requests",
+          "type": "string"
+        },
+        "usage": {
+          "type": "string"
+        }
+      },
+      "required": [
+        "usage"
+      ]
+    },
+    "ResponseDetail": {
+      "description": "ResponseDetail holds information broken down by response code.",
+      "type": "object",
+      "properties": {
+        "flags": {
+          "$ref": "#/definitions/ResponseFlags"
+        },
+        "hosts": {
+          "$ref": "#/definitions/ResponseHosts"
+        }
+      }
+    },
+    "ResponseFlags": {
+      "description": "ResponseFlags is a map of maps. Each response code is broken down by responseFlags:percentageOfTraffic, e.g.: \"200\" : { \"-\" : \"80.0\", \"DC\" : \"10.0\", \"FI,FD\" : \"10.0\" }, ...",
+      "type": "object",
+      "additionalProperties": {
+        "type": "string"
+      }
+    },
+    "ResponseHosts": {
+      "description": "ResponseHosts is a map of maps. Each response host is broken down by responseFlags:percentageOfTraffic, e.g.: \"200\" : { \"www.google.com\" : \"80.0\", \"www.yahoo.com\" : \"20.0\" }, ...",
+      "type": "object",
+      "additionalProperties": {
+        "type": "string"
+      }
+    },
+    "Responses": {
+      "description": "Responses maps responseCodes to detailed information for that code",
+      "type": "object",
+      "additionalProperties": {
+        "$ref": "#/definitions/ResponseDetail"
+      }
+    },
+    "SEInfo": {
+      "description": "SEInfo provides static information about the service entry",
+      "type": "object",
+      "properties": {
+        "hosts": {
+          "description": "configured list of hosts",
+          "type": "array",
+          "items": {
+            "type": "string"
+          }
+        },
+        "location": {
+          "description": "e.g. MESH_EXTERNAL, MESH_INTERNAL",
+          "type": "string"
+        },
+        "namespace": {
+          "description": "the definition namespace",
+          "type": "string"
+        }
+      },
+      "required": [
+        "hosts",
+        "location",
+        "namespace"
+      ]
+    },
+    "SaturationData": {
+      "description": "SaturationData holds the resource saturation of a workload (saturation appender only)",
+      "type": "object",
+      "properties": {
+        "availableReplicas": {
+          "type": "integer"
+        },
+        "cpu": {
+          "allOf": [
+            {
+              "$ref": "#/definitions/ResourceData"
+            }
+          ],
+          "description": "in millicores"
+        },
+        "desiredReplicas": {
+          "type": "integer"
+        },
+        "memory": {
+          "allOf": [
+            {
+              "$ref": "#/definitions/ResourceData"
+            }
+          ],
+          "description": "in MiB"
+        },
+        "restarts": {
+          "description": "container restarts in the time window",
+          "type": "string"
+        }
+      },
+      "required": [
+        "availableReplicas",
+        "desiredReplicas"
+      ]
+    },
+    "ServiceName": {
+      "type": "object",
+      "properties": {
+        "cluster": {
+          "type": "string"
+        },
+        "name": {
+          "type": "string"
+        },
+        "namespace": {
+          "type": "string"
+        }
+      },
+      "required": [
+        "cluster",
+        "namespace",
+        "name"
+      ]
+    }
+  }
+}`
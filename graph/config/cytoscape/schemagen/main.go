@@ -0,0 +1,281 @@
+// Schemagen generates the JSON Schema of the cytoscape graph, Config, into the cytoscape package. The schema is
+// built by reflection on Config, and documented with the comments of the types and fields found in their
+// source. It is run by go generate, in the cytoscape package directory:
+//
+//	go generate ./graph/config/cytoscape
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	"github.com/kiali/kiali/graph/config/cytoscape"
+)
+
+const modulePath = "github.com/kiali/kiali"
+
+// Schema is the subset of JSON Schema (draft-07) used to describe the graph
+type Schema struct {
+	Schema               string             `json:"$schema,omitempty"`
+	Ref                  string             `json:"$ref,omitempty"`
+	AllOf                []*Schema          `json:"allOf,omitempty"`
+	Title                string             `json:"title,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Pattern              string             `json:"pattern,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	Definitions          map[string]*Schema `json:"definitions,omitempty"`
+}
+
+// typeDoc holds the comments of a type and of its fields
+type typeDoc struct {
+	doc    string
+	fields map[string]string
+}
+
+type generator struct {
+	root        string             // the module root directory
+	docs        map[string]typeDoc // key=pkgPath.TypeName
+	parsed      map[string]bool    // the parsed package paths
+	definitions map[string]*Schema
+	types       map[string]reflect.Type // the type of each definition, to detect name clashes
+}
+
+func main() {
+	root := flag.String("root", "../../..", "the module root directory")
+	out := flag.String("out", "schema.go", "the generated file")
+	flag.Parse()
+
+	src, err := generate(*root)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "schemagen: %v\n", err)
+		os.Exit(1)
+	}
+	if err := ioutil.WriteFile(*out, src, 0644); err != nil {
+		fmt.Fprintf(os.Stderr, "schemagen: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// generate returns the source of the generated file
+func generate(root string) ([]byte, error) {
+	g := generator{
+		root:        root,
+		docs:        make(map[string]typeDoc),
+		parsed:      make(map[string]bool),
+		definitions: make(map[string]*Schema),
+		types:       make(map[string]reflect.Type),
+	}
+
+	configType := reflect.TypeOf(cytoscape.Config{})
+	schema, err := g.schema(configType)
+	if err != nil {
+		return nil, err
+	}
+	// the root is the Config definition, the other definitions are referenced
+	schema = g.definitions[configType.Name()]
+	delete(g.definitions, configType.Name())
+	schema.Schema = "http://json-schema.org/draft-07/schema#"
+	schema.Title = fmt.Sprintf("Kiali graph, schema version %s", cytoscape.SchemaVersion)
+	schema.Definitions = g.definitions
+
+	// a client accepts the versions of the major version it knows
+	major := strings.SplitN(cytoscape.SchemaVersion, ".", 2)[0]
+	schema.Properties["version"].Pattern = fmt.Sprintf(`^%s\.[0-9]+$`, major)
+
+	js, err := json.MarshalIndent(schema, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if bytes.ContainsRune(js, '`') {
+		return nil, fmt.Errorf("the schema can not contain a backquote")
+	}
+
+	var src bytes.Buffer
+	src.WriteString("// Code generated by schemagen. DO NOT EDIT.\n\n")
+	src.WriteString("package cytoscape\n\n")
+	src.WriteString("// Schema is the JSON Schema of Config, for the SchemaVersion\n")
+	fmt.Fprintf(&src, "const Schema = `%s`\n", js)
+	return src.Bytes(), nil
+}
+
+// schema returns the schema of a type, a named struct or map type is added to the definitions and referenced
+func (g *generator) schema(t reflect.Type) (*Schema, error) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer"}, nil
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}, nil
+	case reflect.String:
+		return &Schema{Type: "string"}, nil
+	case reflect.Interface:
+		return &Schema{}, nil
+	case reflect.Slice, reflect.Array:
+		items, err := g.schema(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		return &Schema{Type: "array", Items: items}, nil
+	case reflect.Map, reflect.Struct:
+		if t.Name() == "" {
+			return g.define(t, typeDoc{})
+		}
+		if defined, ok := g.types[t.Name()]; ok && defined != t {
+			return nil, fmt.Errorf("types %s and %s have the same name", defined, t)
+		}
+		if _, ok := g.definitions[t.Name()]; !ok {
+			doc, err := g.doc(t)
+			if err != nil {
+				return nil, err
+			}
+			// reserve the name, for recursive types
+			g.definitions[t.Name()] = &Schema{}
+			g.types[t.Name()] = t
+			schema, err := g.define(t, doc)
+			if err != nil {
+				return nil, err
+			}
+			g.definitions[t.Name()] = schema
+		}
+		return &Schema{Ref: "#/definitions/" + t.Name()}, nil
+	default:
+		return nil, fmt.Errorf("unsupported type %s of kind %s", t, t.Kind())
+	}
+}
+
+// define returns the schema of a map or struct type
+func (g *generator) define(t reflect.Type, doc typeDoc) (*Schema, error) {
+	if t.Kind() == reflect.Map {
+		values, err := g.schema(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		return &Schema{Description: doc.doc, Type: "object", AdditionalProperties: values}, nil
+	}
+
+	schema := &Schema{Description: doc.doc, Type: "object", Properties: make(map[string]*Schema)}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" {
+			continue // unexported
+		}
+		name := f.Name
+		omitEmpty := false
+		if tag, ok := f.Tag.Lookup("json"); ok {
+			parts := strings.Split(tag, ",")
+			if parts[0] == "-" {
+				continue
+			}
+			if parts[0] != "" {
+				name = parts[0]
+			}
+			for _, option := range parts[1:] {
+				omitEmpty = omitEmpty || option == "omitempty"
+			}
+		}
+
+		property, err := g.schema(f.Type)
+		if err != nil {
+			return nil, fmt.Errorf("%s.%s: %v", t.Name(), f.Name, err)
+		}
+		if description := doc.fields[f.Name]; description != "" {
+			if property.Ref != "" {
+				// draft-07 ignores the siblings of $ref
+				property = &Schema{Description: description, AllOf: []*Schema{property}}
+			} else {
+				property.Description = description
+			}
+		}
+		schema.Properties[name] = property
+		if !omitEmpty {
+			schema.Required = append(schema.Required, name)
+		}
+	}
+	return schema, nil
+}
+
+// doc returns the comments of a named type, parsing the source of its package if not done yet
+func (g *generator) doc(t reflect.Type) (typeDoc, error) {
+	pkgPath := t.PkgPath()
+	if !strings.HasPrefix(pkgPath, modulePath) {
+		return typeDoc{}, nil
+	}
+	if !g.parsed[pkgPath] {
+		if err := g.parse(pkgPath); err != nil {
+			return typeDoc{}, err
+		}
+		g.parsed[pkgPath] = true
+	}
+	return g.docs[pkgPath+"."+t.Name()], nil
+}
+
+// parse collects the comments of the types of a package of the module
+func (g *generator) parse(pkgPath string) error {
+	dir := filepath.Join(g.root, filepath.FromSlash(strings.TrimPrefix(pkgPath, modulePath)))
+	fset := token.NewFileSet()
+	pkgs, err := parser.ParseDir(fset, dir, func(fi os.FileInfo) bool {
+		return !strings.HasSuffix(fi.Name(), "_test.go")
+	}, parser.ParseComments)
+	if err != nil {
+		return err
+	}
+
+	for _, pkg := range pkgs {
+		for _, file := range pkg.Files {
+			for _, decl := range file.Decls {
+				gen, ok := decl.(*ast.GenDecl)
+				if !ok || gen.Tok != token.TYPE {
+					continue
+				}
+				for _, spec := range gen.Specs {
+					ts := spec.(*ast.TypeSpec)
+					doc := typeDoc{doc: comment(ts.Doc), fields: make(map[string]string)}
+					if doc.doc == "" && len(gen.Specs) == 1 {
+						doc.doc = comment(gen.Doc)
+					}
+					if st, ok := ts.Type.(*ast.StructType); ok {
+						for _, field := range st.Fields.List {
+							text := comment(field.Comment)
+							if text == "" {
+								text = comment(field.Doc)
+							}
+							for _, name := range field.Names {
+								doc.fields[name.Name] = text
+							}
+						}
+					}
+					g.docs[pkgPath+"."+ts.Name.Name] = doc
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// comment returns the text of a comment group, on a single line
+func comment(cg *ast.CommentGroup) string {
+	if cg == nil {
+		return ""
+	}
+	return strings.Join(strings.Fields(cg.Text()), " ")
+}
@@ -0,0 +1,20 @@
+package main
+
+import (
+	"io/ioutil"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestSchemaUpToDate fails when the generated schema does not match the cytoscape types, run go generate
+func TestSchemaUpToDate(t *testing.T) {
+	assert := assert.New(t)
+
+	src, err := generate("../../../..")
+	assert.NoError(err)
+
+	generated, err := ioutil.ReadFile("../schema.go")
+	assert.NoError(err)
+	assert.Equal(string(generated), string(src), "the schema is out of date, run: go generate ./graph/config/cytoscape")
+}
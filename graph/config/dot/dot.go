@@ -0,0 +1,344 @@
+// Package dot provides conversion from our graph to the GraphViz DOT language.
+//
+// The following links are useful for understanding DOT:
+//
+// Language:   https://graphviz.org/doc/info/lang.html
+// Attributes: https://graphviz.org/doc/info/attrs.html
+//
+// Algorithm: Generate the Cytoscape config for the traffic map, it provides the node and
+// edge decoration as well as the compound nodes for requested boxing. Then
+// write each box as a DOT cluster subgraph holding its member nodes, and
+// finally write the edges.
+//
+// Node and edge telemetry is written as custom DOT attributes (e.g. http="10.00",
+// responseTime="20"), GraphViz ignores them for rendering but they are preserved for
+// other tooling.
+//
+// The package provides the DOT implementation of graph/ConfigVendor.
+package dot
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/kiali/kiali/graph"
+	"github.com/kiali/kiali/graph/config/cytoscape"
+)
+
+// ContentType is the media type for DOT content
+const ContentType = "text/vnd.graphviz"
+
+// Config holds the rendered DOT digraph. It implements graph.RawConfig.
+type Config struct {
+	content []byte
+}
+
+// ContentType implements graph.RawConfig
+func (c Config) ContentType() string {
+	return ContentType
+}
+
+// Content implements graph.RawConfig
+func (c Config) Content() []byte {
+	return c.content
+}
+
+// String returns the DOT digraph
+func (c Config) String() string {
+	return string(c.content)
+}
+
+// attribute is a single DOT attribute, kept in a slice to produce predictable output
+type attribute struct {
+	name  string
+	value string
+}
+
+// NewConfig is required by the graph/ConfigVendor interface
+func NewConfig(trafficMap graph.TrafficMap, o graph.ConfigOptions) (result Config) {
+	cyConfig := cytoscape.NewConfig(trafficMap, o)
+
+	// group the nodes by compound parent, the cytoscape nodes are sorted such that
+	// parent nodes come before their children, so member order is predictable.
+	members := make(map[string][]*cytoscape.NodeData)
+	for _, nw := range cyConfig.Elements.Nodes {
+		members[nw.Data.Parent] = append(members[nw.Data.Parent], nw.Data)
+	}
+
+	buf := &bytes.Buffer{}
+	fmt.Fprintf(buf, "digraph %s {\n", quote(o.GraphType))
+	writeAttributes(buf, 1, []attribute{
+		{"label", fmt.Sprintf("%s graph, %s, %ds", o.GraphType, time.Unix(cyConfig.Timestamp, 0).UTC().Format(graph.TF), cyConfig.Duration)},
+		{"labelloc", "t"},
+		{"rankdir", "LR"},
+	})
+	writeMembers(buf, 1, "", members)
+	for _, ew := range cyConfig.Elements.Edges {
+		writeEdge(buf, 1, ew.Data)
+	}
+	buf.WriteString("}\n")
+
+	return Config{content: buf.Bytes()}
+}
+
+// writeMembers writes the member nodes of the parent, recursively writing boxes as cluster subgraphs
+func writeMembers(buf *bytes.Buffer, depth int, parent string, members map[string][]*cytoscape.NodeData) {
+	for _, nd := range members[parent] {
+		if nd.NodeType != graph.NodeTypeBox {
+			writeNode(buf, depth, nd)
+			continue
+		}
+
+		// GraphViz draws a box around subgraphs with the "cluster" name prefix
+		fmt.Fprintf(buf, "%ssubgraph %s {\n", indent(depth), quote("cluster_"+nd.ID))
+		writeAttributes(buf, depth+1, []attribute{
+			{"label", fmt.Sprintf("%s: %s", nd.IsBox, boxName(nd))},
+			{"isBox", nd.IsBox},
+		})
+		writeMembers(buf, depth+1, nd.ID, members)
+		fmt.Fprintf(buf, "%s}\n", indent(depth))
+	}
+}
+
+func writeNode(buf *bytes.Buffer, depth int, nd *cytoscape.NodeData) {
+	attributes := []attribute{
+		{"label", nodeLabel(nd)},
+		{"shape", nodeShape(nd.NodeType)},
+		{"nodeType", nd.NodeType},
+		{"cluster", nd.Cluster},
+		{"namespace", nd.Namespace},
+		{"workload", nd.Workload},
+		{"app", nd.App},
+		{"version", nd.Version},
+		{"service", nd.Service},
+		{"aggregate", nd.Aggregate},
+		{"criticalPathDepth", intAttribute(nd.CriticalPathDepth)},
+	}
+	for _, pt := range nd.Traffic {
+		attributes = append(attributes, rateAttributes(pt.Rates)...)
+	}
+	attributes = append(attributes, flagAttributes(map[string]bool{
+		"hasCB":          nd.HasCB,
+		"hasMissingSC":   nd.HasMissingSC,
+		"hasVS":          nd.HasVS,
+		"isCriticalPath": nd.IsCriticalPath,
+		"isDead":         nd.IsDead,
+		"isFound":        nd.IsFound,
+		"isIdle":         nd.IsIdle,
+		"isInaccessible": nd.IsInaccessible,
+		"isOutside":      nd.IsOutside,
+		"isRoot":         nd.IsRoot,
+		"isSaturated":    nd.IsSaturated,
+		"isServiceEntry": nd.IsServiceEntry != nil,
+	})...)
+	attributes = append(attributes, diffAttributes(nd.Diff)...)
+
+	fmt.Fprintf(buf, "%s%s [%s]\n", indent(depth), quote(nd.ID), formatAttributes(attributes))
+}
+
+func writeEdge(buf *bytes.Buffer, depth int, ed *cytoscape.EdgeData) {
+	attributes := []attribute{
+		{"label", edgeLabel(ed)},
+		{"protocol", ed.Traffic.Protocol},
+	}
+	attributes = append(attributes, rateAttributes(ed.Traffic.Rates)...)
+	attributes = append(attributes,
+		attribute{"responseTime", ed.ResponseTime},
+		attribute{"throughput", ed.Throughput},
+		attribute{"isMTLS", ed.IsMTLS},
+		attribute{"percentIn", ed.PercentIn},
+		attribute{"percentOut", ed.PercentOut},
+		attribute{"operations", operationsAttribute(ed.Operations)},
+	)
+	attributes = append(attributes, flagAttributes(map[string]bool{
+		"isAnomalous":    ed.IsAnomalous,
+		"isCriticalPath": ed.IsCriticalPath,
+		"isFound":        ed.IsFound,
+	})...)
+	attributes = append(attributes, anomalyAttributes(ed.Anomaly)...)
+	attributes = append(attributes, diffAttributes(ed.Diff)...)
+
+	fmt.Fprintf(buf, "%s%s -> %s [%s]\n", indent(depth), quote(ed.Source), quote(ed.Target), formatAttributes(attributes))
+}
+
+func writeAttributes(buf *bytes.Buffer, depth int, attributes []attribute) {
+	for _, a := range attributes {
+		if a.value != "" {
+			fmt.Fprintf(buf, "%s%s=%s\n", indent(depth), a.name, quote(a.value))
+		}
+	}
+}
+
+// formatAttributes returns the attribute list, omitting empty values
+func formatAttributes(attributes []attribute) string {
+	formatted := []string{}
+	for _, a := range attributes {
+		if a.value != "" {
+			formatted = append(formatted, fmt.Sprintf("%s=%s", a.name, quote(a.value)))
+		}
+	}
+	return strings.Join(formatted, ", ")
+}
+
+// rateAttributes returns the rates sorted by rate name
+func rateAttributes(rates map[string]string) []attribute {
+	attributes := []attribute{}
+	for name, val := range rates {
+		attributes = append(attributes, attribute{name, val})
+	}
+	sort.Slice(attributes, func(i, j int) bool {
+		return attributes[i].name < attributes[j].name
+	})
+	return attributes
+}
+
+// flagAttributes returns the set flags sorted by flag name
+func flagAttributes(flags map[string]bool) []attribute {
+	attributes := []attribute{}
+	for name, isSet := range flags {
+		if isSet {
+			attributes = append(attributes, attribute{name, "true"})
+		}
+	}
+	sort.Slice(attributes, func(i, j int) bool {
+		return attributes[i].name < attributes[j].name
+	})
+	return attributes
+}
+
+// anomalyAttributes returns the edge anomaly scores
+func anomalyAttributes(anomaly *cytoscape.AnomalyData) []attribute {
+	if anomaly == nil {
+		return []attribute{}
+	}
+	return []attribute{
+		{"anomalyRequestRate", anomaly.RequestRate},
+		{"anomalyErrorRate", anomaly.ErrorRate},
+		{"anomalyResponseTime", anomaly.ResponseTime},
+	}
+}
+
+// operationsAttribute returns the edge operations as a single value, "" if there are none
+func operationsAttribute(operations []cytoscape.OperationData) string {
+	values := make([]string, len(operations))
+	for i, od := range operations {
+		values[i] = od.String()
+	}
+	return strings.Join(values, "; ")
+}
+
+// diffAttributes returns the diff graph attributes, removed nodes and edges are drawn dashed
+func diffAttributes(diff *cytoscape.DiffData) []attribute {
+	if diff == nil {
+		return []attribute{}
+	}
+	attributes := []attribute{
+		{"diffStatus", diff.Status},
+		{"diffRate", diff.Rate},
+		{"diffPercentErr", diff.PercentErr},
+		{"diffResponseTime", diff.ResponseTime},
+	}
+	if diff.Status == graph.DiffStatusRemoved {
+		attributes = append(attributes, attribute{"style", "dashed"})
+	}
+	return attributes
+}
+
+// intAttribute returns the value, or "" for 0 so that the attribute is omitted
+func intAttribute(val int) string {
+	if val == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%d", val)
+}
+
+func boxName(nd *cytoscape.NodeData) string {
+	switch nd.IsBox {
+	case graph.BoxByApp:
+		return nd.App
+	case graph.BoxByCluster:
+		return nd.Cluster
+	case graph.BoxByNamespace:
+		return nd.Namespace
+	default:
+		return nd.Labels[nd.IsBox]
+	}
+}
+
+func nodeLabel(nd *cytoscape.NodeData) string {
+	var label string
+	switch nd.NodeType {
+	case graph.NodeTypeAggregate:
+		label = nd.Aggregate
+	case graph.NodeTypeApp:
+		label = nd.App
+		if nd.Version != "" {
+			label = fmt.Sprintf("%s\n%s", label, nd.Version)
+		}
+	case graph.NodeTypeService:
+		label = nd.Service
+	case graph.NodeTypeWorkload:
+		label = nd.Workload
+	default:
+		label = graph.Unknown
+	}
+	if nd.IsOutside {
+		label = fmt.Sprintf("%s\n(%s)", label, nd.Namespace)
+	}
+	return label
+}
+
+// nodeShape mimics the Kiali UI node shapes as closely as DOT allows
+func nodeShape(nodeType string) string {
+	switch nodeType {
+	case graph.NodeTypeAggregate:
+		return "pentagon"
+	case graph.NodeTypeApp:
+		return "box"
+	case graph.NodeTypeService:
+		return "triangle"
+	default:
+		return "ellipse"
+	}
+}
+
+// edgeLabel summarizes the edge telemetry, e.g. "10.00rps 5.0%err 20ms"
+func edgeLabel(ed *cytoscape.EdgeData) string {
+	label := []string{}
+	for _, p := range graph.Protocols {
+		if p.Name != ed.Traffic.Protocol {
+			continue
+		}
+		for _, r := range p.EdgeRates {
+			val, ok := ed.Traffic.Rates[string(r.Name)]
+			if !ok {
+				continue
+			}
+			switch {
+			case r.IsTotal:
+				label = append(label, val+p.UnitShort)
+			case r.IsPercentErr:
+				label = append(label, val+"%err")
+			}
+		}
+	}
+	if ed.ResponseTime != "" {
+		label = append(label, ed.ResponseTime+"ms")
+	}
+	return strings.Join(label, " ")
+}
+
+func indent(depth int) string {
+	return strings.Repeat("  ", depth)
+}
+
+// quote returns a DOT quoted string. Newlines are converted to DOT centered line breaks.
+func quote(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	s = strings.ReplaceAll(s, "\n", `\n`)
+	return `"` + s + `"`
+}
@@ -0,0 +1,91 @@
+package dot
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/kiali/kiali/graph"
+)
+
+func testTrafficMap() graph.TrafficMap {
+	trafficMap := graph.NewTrafficMap()
+
+	n0 := graph.NewNode(graph.Unknown, graph.Unknown, "", graph.Unknown, graph.Unknown, graph.Unknown, graph.Unknown, graph.GraphTypeVersionedApp)
+	n1 := graph.NewNode("east", "bookinfo", "productpage", "bookinfo", "productpage-v1", "productpage", "v1", graph.GraphTypeVersionedApp)
+	n2 := graph.NewNode("east", "bookinfo", "reviews", "bookinfo", "reviews-v1", "reviews", "v1", graph.GraphTypeVersionedApp)
+	trafficMap[n0.ID] = &n0
+	trafficMap[n1.ID] = &n1
+	trafficMap[n2.ID] = &n2
+
+	e0 := n0.AddEdge(&n1)
+	e0.Metadata[graph.ProtocolKey] = "http"
+	graph.AddToMetadata("http", 10.0, "200", "-", "productpage.bookinfo.svc.cluster.local", n0.Metadata, n1.Metadata, e0.Metadata)
+	n0.Metadata[graph.IsRoot] = true
+
+	e1 := n1.AddEdge(&n2)
+	e1.Metadata[graph.ProtocolKey] = "http"
+	graph.AddToMetadata("http", 18.0, "200", "-", "reviews.bookinfo.svc.cluster.local", n1.Metadata, n2.Metadata, e1.Metadata)
+	graph.AddToMetadata("http", 2.0, "500", "-", "reviews.bookinfo.svc.cluster.local", n1.Metadata, n2.Metadata, e1.Metadata)
+	e1.Metadata[graph.ResponseTime] = 20.0
+	e1.Metadata[graph.IsMTLS] = 100.0
+
+	return trafficMap
+}
+
+func testConfigOptions(boxBy string) graph.ConfigOptions {
+	return graph.ConfigOptions{
+		BoxBy: boxBy,
+		CommonOptions: graph.CommonOptions{
+			Duration:  10 * time.Minute,
+			GraphType: graph.GraphTypeVersionedApp,
+			QueryTime: 1523364075,
+		},
+	}
+}
+
+func TestDotConfig(t *testing.T) {
+	assert := assert.New(t)
+
+	config := NewConfig(testTrafficMap(), testConfigOptions(graph.BoxByNone))
+	assert.Equal("text/vnd.graphviz", config.ContentType())
+
+	dot := config.String()
+	assert.True(strings.HasPrefix(dot, "digraph \"versionedApp\" {\n"))
+	assert.True(strings.HasSuffix(dot, "}\n"))
+	assert.Contains(dot, `label="versionedApp graph, 2018-04-10 12:41:15, 600s"`)
+	assert.Contains(dot, `[label="productpage\nv1", shape="box", nodeType="app", cluster="east", namespace="bookinfo", workload="productpage-v1", app="productpage", version="v1", httpIn="10.00", httpOut="20.00"]`)
+	assert.Contains(dot, `[label="unknown", shape="ellipse", nodeType="unknown", cluster="unknown", namespace="unknown", workload="unknown", app="unknown", version="unknown", httpOut="10.00", isRoot="true"]`)
+	assert.Contains(dot, `[label="20.00rps 10.0%err 20ms", protocol="http", http="20.00", http5xx="2.00", httpPercentErr="10.0", httpPercentReq="100.0", responseTime="20", isMTLS="100", percentIn="100.0", percentOut="100.0"]`)
+
+	// the versionedApp graph always boxes by app, but an app box needs more than one member
+	assert.NotContains(dot, "subgraph")
+}
+
+func TestDotConfigBoxing(t *testing.T) {
+	assert := assert.New(t)
+
+	dot := NewConfig(testTrafficMap(), testConfigOptions("cluster,namespace")).String()
+
+	clusterBox := strings.Index(dot, `label="cluster: east"`)
+	namespaceBox := strings.Index(dot, `label="namespace: bookinfo"`)
+	productpage := strings.Index(dot, `label="productpage\nv1"`)
+	assert.True(clusterBox > 0)
+	assert.True(namespaceBox > clusterBox)
+	assert.True(productpage > namespaceBox)
+	assert.Equal(4, strings.Count(dot, "subgraph \"cluster_"), "expected a cluster box and a namespace box for both east and unknown")
+	assert.Contains(dot, "\n    subgraph \"cluster_")
+	assert.Contains(dot, "\n      \"")
+}
+
+func TestQuote(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal(`"reviews"`, quote("reviews"))
+	assert.Equal(`"say \"hi\""`, quote(`say "hi"`))
+	assert.Equal(`"productpage\nv1"`, quote("productpage\nv1"))
+	assert.Equal(`"C:\\temp\\"`, quote(`C:\temp\`))
+	assert.Equal(`"\\\""`, quote(`\"`))
+}
@@ -0,0 +1,339 @@
+// Package graphml provides conversion from our graph to the GraphML XML format.
+//
+// The following links are useful for understanding GraphML:
+//
+// Primer:        http://graphml.graphdrawing.org/primer/graphml-primer.html
+// Specification: http://graphml.graphdrawing.org/specification.html
+//
+// Algorithm: Generate the Cytoscape config for the traffic map, it provides the node and
+// edge decoration as well as the compound nodes for requested boxing. Then
+// write each box as a GraphML node holding a nested graph of its member
+// nodes. Edges are declared in the top-level graph.
+//
+// Node and edge telemetry is written as GraphML data, every possible data key is declared
+// up front so that the document can be loaded by generic GraphML tooling.
+//
+// The package provides the GraphML implementation of graph/ConfigVendor.
+package graphml
+
+import (
+	"encoding/xml"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/kiali/kiali/graph"
+	"github.com/kiali/kiali/graph/config/cytoscape"
+)
+
+// ContentType is the media type for GraphML content
+const ContentType = "application/graphml+xml"
+
+const (
+	graphMLNamespace = "http://graphml.graphdrawing.org/xmlns"
+	edgeDefault      = "directed"
+	keyForEdge       = "edge"
+	keyForGraph      = "graph"
+	keyForNode       = "node"
+	typeBoolean      = "boolean"
+	typeLong         = "long"
+	typeString       = "string"
+)
+
+// Key declares a GraphML data attribute
+type Key struct {
+	ID       string `xml:"id,attr"`
+	For      string `xml:"for,attr"`
+	AttrName string `xml:"attr.name,attr"`
+	AttrType string `xml:"attr.type,attr"`
+}
+
+// Data holds a single GraphML data value for a declared Key
+type Data struct {
+	Key   string `xml:"key,attr"`
+	Value string `xml:",chardata"`
+}
+
+// Node is a GraphML node. Box nodes hold the boxed nodes in a nested Graph.
+type Node struct {
+	ID    string `xml:"id,attr"`
+	Data  []Data `xml:"data"`
+	Graph *Graph `xml:"graph,omitempty"`
+}
+
+// Edge is a GraphML edge
+type Edge struct {
+	ID     string `xml:"id,attr"`
+	Source string `xml:"source,attr"`
+	Target string `xml:"target,attr"`
+	Data   []Data `xml:"data"`
+}
+
+// Graph is a GraphML graph, or nested graph
+type Graph struct {
+	ID          string  `xml:"id,attr"`
+	EdgeDefault string  `xml:"edgedefault,attr"`
+	Data        []Data  `xml:"data"`
+	Nodes       []*Node `xml:"node"`
+	Edges       []*Edge `xml:"edge"`
+}
+
+// GraphML is the GraphML document root
+type GraphML struct {
+	XMLName xml.Name `xml:"graphml"`
+	XMLNS   string   `xml:"xmlns,attr"`
+	Keys    []Key    `xml:"key"`
+	Graph   Graph    `xml:"graph"`
+}
+
+// Config holds the GraphML document. It implements graph.RawConfig.
+type Config struct {
+	GraphML GraphML
+}
+
+// ContentType implements graph.RawConfig
+func (c Config) ContentType() string {
+	return ContentType
+}
+
+// Content implements graph.RawConfig
+func (c Config) Content() []byte {
+	content, err := xml.MarshalIndent(c.GraphML, "", "  ")
+	graph.CheckError(err)
+
+	return append([]byte(xml.Header), content...)
+}
+
+// NewConfig is required by the graph/ConfigVendor interface
+func NewConfig(trafficMap graph.TrafficMap, o graph.ConfigOptions) (result Config) {
+	cyConfig := cytoscape.NewConfig(trafficMap, o)
+
+	// group the nodes by compound parent, the cytoscape nodes are sorted such that
+	// parent nodes come before their children, so member order is predictable.
+	members := make(map[string][]*cytoscape.NodeData)
+	for _, nw := range cyConfig.Elements.Nodes {
+		members[nw.Data.Parent] = append(members[nw.Data.Parent], nw.Data)
+	}
+
+	root := Graph{
+		ID:          o.GraphType,
+		EdgeDefault: edgeDefault,
+		Data: []Data{
+			{Key: "graph_graphType", Value: cyConfig.GraphType},
+			{Key: "graph_timestamp", Value: fmt.Sprintf("%d", cyConfig.Timestamp)},
+			{Key: "graph_duration", Value: fmt.Sprintf("%d", cyConfig.Duration)},
+		},
+		Nodes: newNodes("", members),
+	}
+	for _, ew := range cyConfig.Elements.Edges {
+		root.Edges = append(root.Edges, newEdge(ew.Data))
+	}
+
+	result = Config{
+		GraphML: GraphML{
+			XMLNS: graphMLNamespace,
+			Keys:  newKeys(),
+			Graph: root,
+		},
+	}
+	return result
+}
+
+// newKeys declares every data key that can be set on the graph, a node or an edge
+func newKeys() []Key {
+	keys := []Key{
+		newKey(keyForGraph, "graphType", typeString),
+		newKey(keyForGraph, "timestamp", typeLong),
+		newKey(keyForGraph, "duration", typeLong),
+	}
+	for _, name := range []string{"nodeType", "cluster", "namespace", "workload", "app", "version", "service", "aggregate", "isBox"} {
+		keys = append(keys, newKey(keyForNode, name, typeString))
+	}
+	keys = append(keys, newKey(keyForNode, "criticalPathDepth", typeLong))
+	for _, name := range nodeFlagNames {
+		keys = append(keys, newKey(keyForNode, name, typeBoolean))
+	}
+	keys = append(keys, newKey(keyForEdge, "protocol", typeString))
+	for _, p := range graph.Protocols {
+		for _, r := range p.NodeRates {
+			keys = append(keys, newKey(keyForNode, string(r.Name), typeString))
+		}
+		for _, r := range p.EdgeRates {
+			keys = append(keys, newKey(keyForEdge, string(r.Name), typeString))
+		}
+	}
+	for _, name := range []string{"responseTime", "throughput", "isMTLS", "percentIn", "percentOut", "operations"} {
+		keys = append(keys, newKey(keyForEdge, name, typeString))
+	}
+	for _, name := range anomalyNames {
+		keys = append(keys, newKey(keyForEdge, name, typeString))
+	}
+	keys = append(keys, newKey(keyForEdge, "isAnomalous", typeBoolean), newKey(keyForEdge, "isCriticalPath", typeBoolean), newKey(keyForEdge, "isFound", typeBoolean))
+	for _, name := range diffNames {
+		keys = append(keys, newKey(keyForNode, name, typeString), newKey(keyForEdge, name, typeString))
+	}
+	return keys
+}
+
+func newKey(keyFor, name, attrType string) Key {
+	return Key{ID: keyID(keyFor, name), For: keyFor, AttrName: name, AttrType: attrType}
+}
+
+// keyID returns a document-unique key ID, node and edge attributes may share a name
+func keyID(keyFor, name string) string {
+	return fmt.Sprintf("%s_%s", keyFor, name)
+}
+
+var anomalyNames = []string{"anomalyRequestRate", "anomalyErrorRate", "anomalyResponseTime"}
+
+var diffNames = []string{"diffStatus", "diffRate", "diffPercentErr", "diffResponseTime"}
+
+var nodeFlagNames = []string{"hasCB", "hasMissingSC", "hasVS", "isCriticalPath", "isDead", "isFound", "isIdle", "isInaccessible", "isOutside", "isRoot", "isSaturated", "isServiceEntry"}
+
+// newNodes returns the member nodes of the parent, recursively nesting the members of box nodes
+func newNodes(parent string, members map[string][]*cytoscape.NodeData) []*Node {
+	nodes := []*Node{}
+	for _, nd := range members[parent] {
+		n := &Node{ID: nd.ID}
+		n.Data = appendData(n.Data, keyForNode, []Data{
+			{"nodeType", nd.NodeType},
+			{"cluster", nd.Cluster},
+			{"namespace", nd.Namespace},
+			{"workload", nd.Workload},
+			{"app", nd.App},
+			{"version", nd.Version},
+			{"service", nd.Service},
+			{"aggregate", nd.Aggregate},
+			{"isBox", nd.IsBox},
+		})
+		if nd.CriticalPathDepth > 0 {
+			n.Data = appendData(n.Data, keyForNode, []Data{{"criticalPathDepth", fmt.Sprintf("%d", nd.CriticalPathDepth)}})
+		}
+		for _, pt := range nd.Traffic {
+			n.Data = appendData(n.Data, keyForNode, rateData(pt.Rates))
+		}
+		n.Data = appendData(n.Data, keyForNode, flagData(map[string]bool{
+			"hasCB":          nd.HasCB,
+			"hasMissingSC":   nd.HasMissingSC,
+			"hasVS":          nd.HasVS,
+			"isCriticalPath": nd.IsCriticalPath,
+			"isDead":         nd.IsDead,
+			"isFound":        nd.IsFound,
+			"isIdle":         nd.IsIdle,
+			"isInaccessible": nd.IsInaccessible,
+			"isOutside":      nd.IsOutside,
+			"isRoot":         nd.IsRoot,
+			"isSaturated":    nd.IsSaturated,
+			"isServiceEntry": nd.IsServiceEntry != nil,
+		}))
+		n.Data = appendData(n.Data, keyForNode, diffData(nd.Diff))
+
+		if nd.NodeType == graph.NodeTypeBox {
+			// GraphML requires nested graph IDs to be prefixed by the containing node ID
+			n.Graph = &Graph{
+				ID:          nd.ID + ":",
+				EdgeDefault: edgeDefault,
+				Nodes:       newNodes(nd.ID, members),
+			}
+		}
+		nodes = append(nodes, n)
+	}
+	return nodes
+}
+
+func newEdge(ed *cytoscape.EdgeData) *Edge {
+	e := &Edge{
+		ID:     ed.ID,
+		Source: ed.Source,
+		Target: ed.Target,
+	}
+	e.Data = appendData(e.Data, keyForEdge, []Data{{"protocol", ed.Traffic.Protocol}})
+	e.Data = appendData(e.Data, keyForEdge, rateData(ed.Traffic.Rates))
+	e.Data = appendData(e.Data, keyForEdge, []Data{
+		{"responseTime", ed.ResponseTime},
+		{"throughput", ed.Throughput},
+		{"isMTLS", ed.IsMTLS},
+		{"percentIn", ed.PercentIn},
+		{"percentOut", ed.PercentOut},
+		{"operations", operationsValue(ed.Operations)},
+	})
+	e.Data = appendData(e.Data, keyForEdge, anomalyData(ed.Anomaly))
+	if ed.IsAnomalous {
+		e.Data = appendData(e.Data, keyForEdge, []Data{{"isAnomalous", "true"}})
+	}
+	if ed.IsCriticalPath {
+		e.Data = appendData(e.Data, keyForEdge, []Data{{"isCriticalPath", "true"}})
+	}
+	if ed.IsFound {
+		e.Data = appendData(e.Data, keyForEdge, []Data{{"isFound", "true"}})
+	}
+	e.Data = appendData(e.Data, keyForEdge, diffData(ed.Diff))
+	return e
+}
+
+// appendData converts attribute names to key IDs, omitting empty values
+func appendData(data []Data, keyFor string, values []Data) []Data {
+	for _, d := range values {
+		if d.Value != "" {
+			data = append(data, Data{Key: keyID(keyFor, d.Key), Value: d.Value})
+		}
+	}
+	return data
+}
+
+// anomalyData returns the edge anomaly scores, in anomalyNames order
+func anomalyData(anomaly *cytoscape.AnomalyData) []Data {
+	if anomaly == nil {
+		return []Data{}
+	}
+	return []Data{
+		{"anomalyRequestRate", anomaly.RequestRate},
+		{"anomalyErrorRate", anomaly.ErrorRate},
+		{"anomalyResponseTime", anomaly.ResponseTime},
+	}
+}
+
+// operationsValue returns the edge operations as a single value, "" if there are none
+func operationsValue(operations []cytoscape.OperationData) string {
+	values := make([]string, len(operations))
+	for i, od := range operations {
+		values[i] = od.String()
+	}
+	return strings.Join(values, "; ")
+}
+
+// diffData returns the diff graph data, in diffNames order
+func diffData(diff *cytoscape.DiffData) []Data {
+	if diff == nil {
+		return []Data{}
+	}
+	return []Data{
+		{"diffStatus", diff.Status},
+		{"diffRate", diff.Rate},
+		{"diffPercentErr", diff.PercentErr},
+		{"diffResponseTime", diff.ResponseTime},
+	}
+}
+
+// rateData returns the rates sorted by rate name
+func rateData(rates map[string]string) []Data {
+	data := []Data{}
+	for name, val := range rates {
+		data = append(data, Data{name, val})
+	}
+	sort.Slice(data, func(i, j int) bool {
+		return data[i].Key < data[j].Key
+	})
+	return data
+}
+
+// flagData returns the set flags in nodeFlagNames order
+func flagData(flags map[string]bool) []Data {
+	data := []Data{}
+	for _, name := range nodeFlagNames {
+		if flags[name] {
+			data = append(data, Data{name, "true"})
+		}
+	}
+	return data
+}
@@ -0,0 +1,106 @@
+package graphml
+
+import (
+	"encoding/xml"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/kiali/kiali/graph"
+)
+
+func testTrafficMap() graph.TrafficMap {
+	trafficMap := graph.NewTrafficMap()
+
+	n0 := graph.NewNode("east", "bookinfo", "productpage", "bookinfo", "productpage-v1", "productpage", "v1", graph.GraphTypeWorkload)
+	n1 := graph.NewNode("east", "bookinfo", "reviews", "bookinfo", "reviews-v1", "reviews", "v1", graph.GraphTypeWorkload)
+	trafficMap[n0.ID] = &n0
+	trafficMap[n1.ID] = &n1
+
+	e0 := n0.AddEdge(&n1)
+	e0.Metadata[graph.ProtocolKey] = "http"
+	graph.AddToMetadata("http", 20.0, "200", "-", "reviews.bookinfo.svc.cluster.local", n0.Metadata, n1.Metadata, e0.Metadata)
+	e0.Metadata[graph.ResponseTime] = 20.0
+	e0.Metadata[graph.IsMTLS] = 100.0
+	n0.Metadata[graph.IsRoot] = true
+
+	return trafficMap
+}
+
+func dataValue(data []Data, key string) string {
+	for _, d := range data {
+		if d.Key == key {
+			return d.Value
+		}
+	}
+	return ""
+}
+
+func TestGraphMLConfig(t *testing.T) {
+	assert := assert.New(t)
+
+	o := graph.ConfigOptions{
+		BoxBy: graph.BoxByNamespace,
+		CommonOptions: graph.CommonOptions{
+			Duration:  10 * time.Minute,
+			GraphType: graph.GraphTypeWorkload,
+			QueryTime: 1523364075,
+		},
+	}
+	config := NewConfig(testTrafficMap(), o)
+	assert.Equal("application/graphml+xml", config.ContentType())
+
+	// the content must be valid xml
+	content := config.Content()
+	var doc GraphML
+	assert.NoError(xml.Unmarshal(content, &doc))
+	assert.Equal("http://graphml.graphdrawing.org/xmlns", doc.XMLNS)
+
+	// every data value must reference a declared key
+	keys := make(map[string]bool)
+	for _, k := range config.GraphML.Keys {
+		assert.False(keys[k.ID], "duplicate key [%s]", k.ID)
+		keys[k.ID] = true
+	}
+
+	root := config.GraphML.Graph
+	assert.Equal("600", dataValue(root.Data, "graph_duration"))
+	assert.Equal("1523364075", dataValue(root.Data, "graph_timestamp"))
+
+	// both workloads are nested in the namespace box
+	assert.Len(root.Nodes, 1)
+	box := root.Nodes[0]
+	assert.Equal(graph.NodeTypeBox, dataValue(box.Data, "node_nodeType"))
+	assert.Equal(graph.BoxByNamespace, dataValue(box.Data, "node_isBox"))
+	assert.Equal("bookinfo", dataValue(box.Data, "node_namespace"))
+	assert.NotNil(box.Graph)
+	assert.Equal(box.ID+":", box.Graph.ID)
+	assert.Len(box.Graph.Nodes, 2)
+	for _, n := range box.Graph.Nodes {
+		assert.Nil(n.Graph)
+		for _, d := range n.Data {
+			assert.True(keys[d.Key], "undeclared key [%s]", d.Key)
+		}
+		switch dataValue(n.Data, "node_workload") {
+		case "productpage-v1":
+			assert.Equal("20.00", dataValue(n.Data, "node_httpOut"))
+			assert.Equal("true", dataValue(n.Data, "node_isRoot"))
+		case "reviews-v1":
+			assert.Equal("20.00", dataValue(n.Data, "node_httpIn"))
+			assert.Equal("", dataValue(n.Data, "node_isRoot"))
+		default:
+			assert.Fail("unexpected node")
+		}
+	}
+
+	assert.Len(root.Edges, 1)
+	edge := root.Edges[0]
+	for _, d := range edge.Data {
+		assert.True(keys[d.Key], "undeclared key [%s]", d.Key)
+	}
+	assert.Equal("http", dataValue(edge.Data, "edge_protocol"))
+	assert.Equal("20.00", dataValue(edge.Data, "edge_http"))
+	assert.Equal("20", dataValue(edge.Data, "edge_responseTime"))
+	assert.Equal("100", dataValue(edge.Data, "edge_isMTLS"))
+}
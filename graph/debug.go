@@ -0,0 +1,117 @@
+package graph
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	prom_v1 "github.com/prometheus/client_golang/api/prometheus/v1"
+	"github.com/prometheus/common/model"
+)
+
+// The debug phases
+const (
+	DebugPhaseAppender   = "appender"   // an appender run, for a namespace unless it is a finalizer
+	DebugPhaseMarshal    = "marshal"    // the config vendor generation
+	DebugPhaseTrafficMap = "trafficMap" // the TrafficMap build, including the appenders
+)
+
+// Debug collects the timings of a graph generation and the Prometheus queries it executes. It is returned
+// with the graph when requested (debug=true). A nil Debug collects nothing, so that it can be used
+// unconditionally.
+type Debug struct {
+	Phases  []DebugPhase `json:"phases"`
+	Queries []DebugQuery `json:"queries"`
+
+	mutex sync.Mutex
+}
+
+// DebugPhase is the timing of a phase of the graph generation
+type DebugPhase struct {
+	Phase     string  `json:"phase"`
+	Appender  string  `json:"appender,omitempty"`
+	Namespace string  `json:"namespace,omitempty"`
+	Duration  float64 `json:"duration"` // milliseconds
+}
+
+// DebugQuery is a Prometheus query executed for the graph generation
+type DebugQuery struct {
+	Query    string  `json:"query"`
+	Duration float64 `json:"duration"` // milliseconds
+	Series   int     `json:"series"`   // the number of series of the result
+	Error    string  `json:"error,omitempty"`
+}
+
+func NewDebug() *Debug {
+	return &Debug{Phases: []DebugPhase{}, Queries: []DebugQuery{}}
+}
+
+// Time starts the timing of a phase, the returned function ends it. For an appender phase, provide the
+// appender name and the namespace ("" for a finalizer), otherwise provide empty strings.
+func (d *Debug) Time(phase, appender, namespace string) func() {
+	if d == nil {
+		return func() {}
+	}
+	start := time.Now()
+	return func() {
+		d.mutex.Lock()
+		defer d.mutex.Unlock()
+		d.Phases = append(d.Phases, DebugPhase{
+			Phase:     phase,
+			Appender:  appender,
+			Namespace: namespace,
+			Duration:  milliseconds(time.Since(start)),
+		})
+	}
+}
+
+// API returns the Prometheus API recording its queries in the Debug. A nil Debug returns the api.
+func (d *Debug) API(api prom_v1.API) prom_v1.API {
+	if d == nil {
+		return api
+	}
+	return debugAPI{API: api, debug: d}
+}
+
+func (d *Debug) addQuery(query string, duration time.Duration, value model.Value, err error) {
+	q := DebugQuery{Query: query, Duration: milliseconds(duration)}
+	switch v := value.(type) {
+	case model.Vector:
+		q.Series = len(v)
+	case model.Matrix:
+		q.Series = len(v)
+	case *model.Scalar, *model.String:
+		q.Series = 1
+	}
+	if err != nil {
+		q.Error = err.Error()
+	}
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	d.Queries = append(d.Queries, q)
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// debugAPI records the instant and range queries, any other call is delegated
+type debugAPI struct {
+	prom_v1.API
+	debug *Debug
+}
+
+func (a debugAPI) Query(ctx context.Context, query string, ts time.Time) (model.Value, prom_v1.Warnings, error) {
+	start := time.Now()
+	value, warnings, err := a.API.Query(ctx, query, ts)
+	a.debug.addQuery(query, time.Since(start), value, err)
+	return value, warnings, err
+}
+
+func (a debugAPI) QueryRange(ctx context.Context, query string, r prom_v1.Range) (model.Value, prom_v1.Warnings, error) {
+	start := time.Now()
+	value, warnings, err := a.API.QueryRange(ctx, query, r)
+	a.debug.addQuery(query, time.Since(start), value, err)
+	return value, warnings, err
+}
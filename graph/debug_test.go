@@ -0,0 +1,56 @@
+package graph
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/kiali/kiali/prometheus/prometheustest"
+)
+
+func TestDebugTime(t *testing.T) {
+	assert := assert.New(t)
+
+	// a nil Debug collects nothing
+	var debug *Debug
+	assert.NotPanics(func() { debug.Time(DebugPhaseMarshal, "", "")() })
+
+	debug = NewDebug()
+	debug.Time(DebugPhaseAppender, "deadNode", "bookinfo")()
+	debug.Time(DebugPhaseMarshal, "", "")()
+	assert.Len(debug.Phases, 2)
+	assert.Equal(DebugPhase{Phase: DebugPhaseAppender, Appender: "deadNode", Namespace: "bookinfo", Duration: debug.Phases[0].Duration}, debug.Phases[0])
+	assert.Equal(DebugPhaseMarshal, debug.Phases[1].Phase)
+	assert.Empty(debug.Queries)
+}
+
+func TestDebugAPI(t *testing.T) {
+	assert := assert.New(t)
+
+	api := new(prometheustest.PromAPIMock)
+	vector := model.Vector{&model.Sample{Value: 1.0}, &model.Sample{Value: 2.0}}
+	api.On("Query", mock.Anything, "q0", mock.AnythingOfType("time.Time")).Return(vector, nil)
+	api.On("Query", mock.Anything, "q1", mock.AnythingOfType("time.Time")).Return(model.Vector{}, nil)
+
+	var debug *Debug
+	assert.Equal(api, debug.API(api))
+
+	debug = NewDebug()
+	debugAPI := debug.API(api)
+	value, _, err := debugAPI.Query(context.Background(), "q0", time.Now())
+	assert.NoError(err)
+	assert.Equal(vector, value)
+	_, _, err = debugAPI.Query(context.Background(), "q1", time.Now())
+	assert.NoError(err)
+
+	assert.Len(debug.Queries, 2)
+	assert.Equal("q0", debug.Queries[0].Query)
+	assert.Equal(2, debug.Queries[0].Series)
+	assert.Empty(debug.Queries[0].Error)
+	assert.Equal("q1", debug.Queries[1].Query)
+	assert.Equal(0, debug.Queries[1].Series)
+}
@@ -0,0 +1,231 @@
+package graph
+
+// Diff.go provides the vendor-agnostic comparison of two TrafficMaps, used to generate diff graphs.
+
+import (
+	"math"
+)
+
+// The possible DiffStatus values
+const (
+	DiffStatusAdded     string = "added"
+	DiffStatusChanged   string = "changed"
+	DiffStatusRemoved   string = "removed"
+	DiffStatusUnchanged string = "unchanged"
+)
+
+// diffStats holds the telemetry of a node or edge that is compared between time windows
+type diffStats struct {
+	errRate         float64
+	errTotal        float64 // the rate errRate is relative to, if not rate (e.g. the closed tcp connections)
+	hasErrTotal     bool
+	hasResponseTime bool
+	rate            float64
+	responseTime    float64
+}
+
+func (s diffStats) percentErr() float64 {
+	total := s.rate
+	if s.hasErrTotal {
+		total = s.errTotal
+	}
+	if total == 0 {
+		return 0
+	}
+	return s.errRate / total * 100
+}
+
+// DiffTrafficMaps compares trafficMap (generated for the requested time window) to compareTrafficMap (generated
+// for the compare time window). It returns trafficMap, with the removed nodes and edges added back in. Every node
+// and edge is marked with its DiffStatus and the change in its traffic. Nodes and edges found in both maps are
+// marked changed when the request rate, error rate or response time changed by more than tolerance percent.
+func DiffTrafficMaps(trafficMap, compareTrafficMap TrafficMap, tolerance float64) TrafficMap {
+	for id, n := range trafficMap {
+		compareNode, found := compareTrafficMap[id]
+		if !found {
+			applyNodeDiff(n, nil, DiffStatusAdded, tolerance)
+			for _, e := range n.Edges {
+				applyEdgeDiff(e, nil, DiffStatusAdded, tolerance)
+			}
+			continue
+		}
+
+		applyNodeDiff(n, compareNode, "", tolerance)
+		for _, e := range n.Edges {
+			if compareEdge := findEdge(compareNode, e.Dest.ID, e.Metadata[ProtocolKey]); compareEdge != nil {
+				applyEdgeDiff(e, compareEdge, "", tolerance)
+			} else {
+				applyEdgeDiff(e, nil, DiffStatusAdded, tolerance)
+			}
+		}
+	}
+
+	// add back the removed nodes before the removed edges, which may reference them
+	for id, compareNode := range compareTrafficMap {
+		if _, found := trafficMap[id]; !found {
+			n := newRemovedNode(compareNode)
+			applyNodeDiff(n, compareNode, DiffStatusRemoved, tolerance)
+			trafficMap[id] = n
+		}
+	}
+	for id, compareNode := range compareTrafficMap {
+		n := trafficMap[id]
+		for _, compareEdge := range compareNode.Edges {
+			protocol := compareEdge.Metadata[ProtocolKey]
+			if findEdge(n, compareEdge.Dest.ID, protocol) != nil {
+				continue
+			}
+			e := n.AddEdge(trafficMap[compareEdge.Dest.ID])
+			if protocol != nil {
+				e.Metadata[ProtocolKey] = protocol
+			}
+			applyEdgeDiff(e, compareEdge, DiffStatusRemoved, tolerance)
+		}
+	}
+
+	return trafficMap
+}
+
+// newRemovedNode returns a copy of the compare node, without edges or traffic
+func newRemovedNode(compareNode *Node) *Node {
+	n := *compareNode
+	n.Edges = []*Edge{}
+	n.Metadata = NewMetadata()
+	for k, v := range compareNode.Metadata {
+		n.Metadata[k] = v
+	}
+	for _, p := range Protocols {
+		for _, r := range p.NodeRates {
+			delete(n.Metadata, r.Name)
+		}
+	}
+	return &n
+}
+
+func findEdge(n *Node, destID string, protocol interface{}) *Edge {
+	for _, e := range n.Edges {
+		if e.Dest.ID == destID && e.Metadata[ProtocolKey] == protocol {
+			return e
+		}
+	}
+	return nil
+}
+
+// applyNodeDiff sets the node diff metadata. status is determined from the traffic when not supplied.
+// A removed node has no current traffic, an added node has no compare traffic.
+func applyNodeDiff(n, compareNode *Node, status string, tolerance float64) {
+	var current, compare diffStats
+	requests, bytes := nodeDiffStats(n)
+	if status == DiffStatusRemoved {
+		requests, bytes = diffStats{}, diffStats{}
+	}
+	current = requests
+	if compareNode != nil {
+		compareRequests, compareBytes := nodeDiffStats(compareNode)
+		compare = compareRequests
+		// compare request traffic when available, otherwise fall back to byte traffic
+		if requests.rate == 0 && compareRequests.rate == 0 {
+			current, compare = bytes, compareBytes
+		}
+	} else if requests.rate == 0 {
+		current = bytes
+	}
+
+	applyDiff(n.Metadata, current, compare, status, tolerance)
+}
+
+// applyEdgeDiff sets the edge diff metadata. status is determined from the traffic when not supplied.
+// A removed edge has no current traffic, an added edge has no compare traffic.
+func applyEdgeDiff(e, compareEdge *Edge, status string, tolerance float64) {
+	var current, compare diffStats
+	if status != DiffStatusRemoved {
+		current = edgeDiffStats(e)
+	}
+	if compareEdge != nil {
+		compare = edgeDiffStats(compareEdge)
+	}
+
+	applyDiff(e.Metadata, current, compare, status, tolerance)
+}
+
+func applyDiff(md Metadata, current, compare diffStats, status string, tolerance float64) {
+	md[DiffRate] = current.rate - compare.rate
+	if percentErr := current.percentErr() - compare.percentErr(); percentErr != 0 {
+		md[DiffPercentErr] = percentErr
+	}
+	if current.hasResponseTime && compare.hasResponseTime {
+		md[DiffResponseTime] = current.responseTime - compare.responseTime
+	}
+
+	if status == "" {
+		status = DiffStatusUnchanged
+		if isDiffChanged(current.rate, compare.rate, tolerance) ||
+			isDiffChanged(current.errRate, compare.errRate, tolerance) ||
+			(current.hasResponseTime && compare.hasResponseTime && isDiffChanged(current.responseTime, compare.responseTime, tolerance)) {
+			status = DiffStatusChanged
+		}
+	}
+	md[DiffStatus] = status
+}
+
+// isDiffChanged returns true if val changed from compareVal by more than tolerance percent
+func isDiffChanged(val, compareVal, tolerance float64) bool {
+	if val == compareVal {
+		return false
+	}
+	if compareVal == 0 {
+		return true
+	}
+	return math.Abs(val-compareVal)/compareVal*100 > tolerance
+}
+
+// nodeDiffStats returns the incoming request traffic and the incoming byte traffic for the node
+func nodeDiffStats(n *Node) (requests, bytes diffStats) {
+	for _, p := range Protocols {
+		stats := &bytes
+		if p.Unit == requestsPerSecond {
+			stats = &requests
+		}
+		for _, r := range p.NodeRates {
+			switch {
+			case r.IsIn:
+				stats.rate += getMetadataValue(n.Metadata, r.Name)
+			case r.IsErr:
+				stats.errRate += getMetadataValue(n.Metadata, r.Name)
+			}
+		}
+	}
+	return requests, bytes
+}
+
+// edgeDiffStats returns the traffic for the edge protocol, and the response time if reported
+func edgeDiffStats(e *Edge) (stats diffStats) {
+	for _, p := range Protocols {
+		if p.Name != e.Metadata[ProtocolKey] {
+			continue
+		}
+		for _, r := range p.EdgeRates {
+			switch {
+			case r.IsTotal:
+				stats.rate += getMetadataValue(e.Metadata, r.Name)
+			case r.IsErrTotal:
+				stats.hasErrTotal = true
+				stats.errTotal += getMetadataValue(e.Metadata, r.Name)
+			case r.IsErr:
+				stats.errRate += getMetadataValue(e.Metadata, r.Name)
+			}
+		}
+	}
+	if val, ok := e.Metadata[ResponseTime]; ok {
+		stats.hasResponseTime = true
+		stats.responseTime = val.(float64)
+	}
+	return stats
+}
+
+func getMetadataValue(md Metadata, k MetadataKey) float64 {
+	if val, ok := md[k]; ok {
+		return val.(float64)
+	}
+	return 0.0
+}
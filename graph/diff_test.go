@@ -0,0 +1,115 @@
+package graph
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func addDiffTestEdge(source, dest *Node, protocol string, rate, errRate, responseTime float64) *Edge {
+	e := source.AddEdge(dest)
+	e.Metadata[ProtocolKey] = protocol
+	code := "200"
+	if protocol == "grpc" {
+		code = "0"
+	}
+	AddToMetadata(protocol, rate-errRate, code, "-", "", source.Metadata, dest.Metadata, e.Metadata)
+	if protocol == "http" {
+		AddToMetadata(protocol, errRate, "500", "-", "", source.Metadata, dest.Metadata, e.Metadata)
+	}
+	if responseTime > 0 {
+		e.Metadata[ResponseTime] = responseTime
+	}
+	return e
+}
+
+func diffTestNodes(trafficMap TrafficMap, names ...string) []*Node {
+	nodes := []*Node{}
+	for _, name := range names {
+		n := NewNode("east", "bookinfo", "", "bookinfo", name, name, "v1", GraphTypeWorkload)
+		trafficMap[n.ID] = &n
+		nodes = append(nodes, &n)
+	}
+	return nodes
+}
+
+func TestDiffTrafficMaps(t *testing.T) {
+	assert := assert.New(t)
+
+	// compare window: a -> b (http), b -> c (http), b -> d (tcp)
+	compareTrafficMap := NewTrafficMap()
+	cn := diffTestNodes(compareTrafficMap, "a", "b", "c", "d")
+	addDiffTestEdge(cn[0], cn[1], "http", 10.0, 0.0, 20.0)
+	addDiffTestEdge(cn[1], cn[2], "http", 10.0, 1.0, 50.0)
+	addDiffTestEdge(cn[1], cn[3], "tcp", 1000.0, 0.0, 0.0)
+
+	// requested window: a -> b (http, unchanged within tolerance), b -> c (http, slower), b -> e (grpc, new)
+	trafficMap := NewTrafficMap()
+	n := diffTestNodes(trafficMap, "a", "b", "c", "e")
+	ab := addDiffTestEdge(n[0], n[1], "http", 10.5, 0.0, 21.0)
+	bc := addDiffTestEdge(n[1], n[2], "http", 10.0, 1.0, 100.0)
+	be := addDiffTestEdge(n[1], n[3], "grpc", 5.0, 0.0, 0.0)
+
+	trafficMap = DiffTrafficMaps(trafficMap, compareTrafficMap, 10.0)
+	assert.Len(trafficMap, 5)
+
+	a, b, c, d, e := n[0], n[1], n[2], trafficMap[cn[3].ID], n[3]
+	assert.Equal(DiffStatusUnchanged, a.Metadata[DiffStatus])
+	assert.Equal(DiffStatusUnchanged, b.Metadata[DiffStatus]) // node diffs compare incoming traffic only
+	assert.Equal(DiffStatusUnchanged, c.Metadata[DiffStatus])
+	assert.Equal(DiffStatusRemoved, d.Metadata[DiffStatus])
+	assert.Equal(DiffStatusAdded, e.Metadata[DiffStatus])
+
+	// the removed node keeps its identity but not its traffic
+	assert.Equal("d", d.Workload)
+	assert.Empty(d.Edges)
+	_, hasTraffic := d.Metadata[tcpIn]
+	assert.False(hasTraffic)
+	assert.Equal(-1000.0, d.Metadata[DiffRate])
+
+	assert.Equal(DiffStatusUnchanged, ab.Metadata[DiffStatus])
+	assert.InDelta(0.5, ab.Metadata[DiffRate], 0.001)
+	assert.InDelta(1.0, ab.Metadata[DiffResponseTime], 0.001)
+	_, hasPercentErr := ab.Metadata[DiffPercentErr]
+	assert.False(hasPercentErr)
+
+	assert.Equal(DiffStatusChanged, bc.Metadata[DiffStatus])
+	assert.Equal(0.0, bc.Metadata[DiffRate])
+	assert.InDelta(50.0, bc.Metadata[DiffResponseTime], 0.001)
+
+	assert.Equal(DiffStatusAdded, be.Metadata[DiffStatus])
+	assert.Equal(5.0, be.Metadata[DiffRate])
+	_, hasResponseTime := be.Metadata[DiffResponseTime]
+	assert.False(hasResponseTime)
+
+	// the removed edge is added back between the current source and the removed dest
+	assert.Len(b.Edges, 3)
+	bd := b.Edges[2]
+	assert.Equal(d, bd.Dest)
+	assert.Equal("tcp", bd.Metadata[ProtocolKey])
+	assert.Equal(DiffStatusRemoved, bd.Metadata[DiffStatus])
+	assert.Equal(-1000.0, bd.Metadata[DiffRate])
+	_, hasTraffic = bd.Metadata[tcp]
+	assert.False(hasTraffic)
+}
+
+func TestDiffErrorRate(t *testing.T) {
+	assert := assert.New(t)
+
+	compareTrafficMap := NewTrafficMap()
+	cn := diffTestNodes(compareTrafficMap, "a", "b")
+	addDiffTestEdge(cn[0], cn[1], "http", 10.0, 0.0, 0.0)
+
+	trafficMap := NewTrafficMap()
+	n := diffTestNodes(trafficMap, "a", "b")
+	ab := addDiffTestEdge(n[0], n[1], "http", 10.0, 2.0, 0.0)
+
+	DiffTrafficMaps(trafficMap, compareTrafficMap, 50.0)
+
+	// any new errors are a change, regardless of tolerance
+	assert.Equal(DiffStatusChanged, ab.Metadata[DiffStatus])
+	assert.InDelta(20.0, ab.Metadata[DiffPercentErr], 0.001)
+	assert.Equal(DiffStatusChanged, n[1].Metadata[DiffStatus])
+	assert.InDelta(20.0, n[1].Metadata[DiffPercentErr], 0.001)
+	assert.Equal(DiffStatusUnchanged, n[0].Metadata[DiffStatus])
+}
@@ -0,0 +1,632 @@
+// Package find provides server-side evaluation of graph find and hide expressions. The expression
+// language is the one offered by the Kiali UI graph find and hide fields (see the graph find and hide
+// options in the UI defaults of the Kiali config), so that an expression can be applied by the UI or
+// be requested with the graph, and give the same result.
+//
+// An expression is a disjunction (OR) of conjunctions (AND) of conditions:
+//
+//	expression := conjunction [ ("or" | "||") conjunction ]...
+//	conjunction := condition [ ("and" | "&&") condition ]...
+//	condition := [ "!" ] field | field op value
+//	op := "=" | "!=" | ">" | ">=" | "<" | "<=" | "*=" | "!*=" | "^=" | "!^=" | "$=" | "!$="
+//
+// For example: "rt > 1000", "ns = bookinfo and ! healthy", "%error > 10 or http < 1". All conditions
+// in a conjunction must apply to the same element type, node or edge. String comparisons are case-
+// insensitive. Conditions on values not reported for a node or edge (e.g. "%httperr" on a TCP edge)
+// never match.
+//
+// Hide removes the matching nodes and edges from the TrafficMap, along with any node left without edges
+// due to the hiding. Find marks the matching nodes and edges with graph.IsFound.
+package find
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/kiali/kiali/graph"
+)
+
+// The element types targeted by an expression condition
+const (
+	targetEdge string = "edge"
+	targetNode string = "node"
+)
+
+// The value types of an expression field
+const (
+	fieldBool    string = "bool"
+	fieldNumeric string = "numeric"
+	fieldString  string = "string"
+)
+
+// note that the operator alternation order is important, longest operators first
+var (
+	orRegexp        = regexp.MustCompile(`(?i)\s+or\s+|\s*\|\|\s*`)
+	andRegexp       = regexp.MustCompile(`(?i)\s+and\s+|\s*&&\s*`)
+	unaryRegexp     = regexp.MustCompile(`^(!)?\s*([a-zA-Z%][a-zA-Z0-9]*)$`)
+	conditionRegexp = regexp.MustCompile(`^([a-zA-Z%][a-zA-Z0-9]*)\s*(!\*=|!\^=|!\$=|\*=|\^=|\$=|!=|>=|<=|=|>|<)\s*(.+)$`)
+)
+
+// field describes a supported expression field
+type field struct {
+	name      string            // the canonical field name
+	target    string            // node | edge
+	valueType string            // bool | numeric | string
+	key       graph.MetadataKey // the metadata key for rate fields
+	protocol  string            // the protocol for protocol-specific edge fields
+}
+
+// condition is a single parsed expression condition
+type condition struct {
+	field    field
+	negate   bool // unary conditions only
+	operator string
+	num      float64
+	value    string
+}
+
+// conjunction is a set of conditions, all of which must match
+type conjunction struct {
+	target     string
+	conditions []condition
+}
+
+// Expression is a parsed find or hide expression, it matches when any of its conjunctions match
+type Expression struct {
+	conjunctions []conjunction
+}
+
+// Parse parses the expression, returning nil for an empty expression
+func Parse(expression string) (*Expression, error) {
+	expression = strings.TrimSpace(expression)
+	if expression == "" {
+		return nil, nil
+	}
+
+	result := &Expression{}
+	for _, orToken := range orRegexp.Split(expression, -1) {
+		conj := conjunction{}
+		for _, andToken := range andRegexp.Split(orToken, -1) {
+			c, err := parseCondition(strings.TrimSpace(andToken))
+			if err != nil {
+				return nil, err
+			}
+			if conj.target != "" && conj.target != c.field.target {
+				return nil, fmt.Errorf("Invalid expression [%s]: can not combine node and edge conditions with AND", orToken)
+			}
+			conj.target = c.field.target
+			conj.conditions = append(conj.conditions, c)
+		}
+		result.conjunctions = append(result.conjunctions, conj)
+	}
+	return result, nil
+}
+
+func parseCondition(token string) (condition, error) {
+	if token == "" {
+		return condition{}, fmt.Errorf("Invalid expression: missing condition")
+	}
+
+	if match := unaryRegexp.FindStringSubmatch(token); match != nil {
+		f, ok := lookupField(match[2])
+		if !ok {
+			return condition{}, fmt.Errorf("Invalid condition [%s]: unknown field [%s]", token, match[2])
+		}
+		if f.valueType != fieldBool {
+			return condition{}, fmt.Errorf("Invalid condition [%s]: field [%s] requires an operator and value", token, match[2])
+		}
+		return condition{field: f, negate: match[1] == "!"}, nil
+	}
+
+	match := conditionRegexp.FindStringSubmatch(token)
+	if match == nil {
+		return condition{}, fmt.Errorf("Invalid condition [%s]", token)
+	}
+	f, ok := lookupField(match[1])
+	if !ok {
+		return condition{}, fmt.Errorf("Invalid condition [%s]: unknown field [%s]", token, match[1])
+	}
+	c := condition{field: f, operator: match[2], value: strings.ToLower(strings.TrimSpace(match[3]))}
+
+	switch f.valueType {
+	case fieldBool:
+		return condition{}, fmt.Errorf("Invalid condition [%s]: field [%s] does not accept an operator, use [%s] or [!%s]", token, match[1], match[1], match[1])
+	case fieldNumeric:
+		switch c.operator {
+		case "=", "!=", ">", ">=", "<", "<=":
+		default:
+			return condition{}, fmt.Errorf("Invalid condition [%s]: operator [%s] is not supported for numeric field [%s]", token, c.operator, match[1])
+		}
+		num, err := strconv.ParseFloat(c.value, 64)
+		if err != nil {
+			return condition{}, fmt.Errorf("Invalid condition [%s]: value [%s] is not numeric", token, c.value)
+		}
+		c.num = num
+	case fieldString:
+		switch c.operator {
+		case ">", ">=", "<", "<=":
+			return condition{}, fmt.Errorf("Invalid condition [%s]: operator [%s] is not supported for string field [%s]", token, c.operator, match[1])
+		}
+		if f.name == "node" {
+			nodeType, ok := nodeTypeAliases[c.value]
+			if !ok {
+				return condition{}, fmt.Errorf("Invalid condition [%s]: unknown node type [%s]", token, c.value)
+			}
+			c.value = nodeType
+		}
+	}
+
+	return c, nil
+}
+
+var nodeTypeAliases = map[string]string{
+	"aggregate": graph.NodeTypeAggregate,
+	"app":       graph.NodeTypeApp,
+	"op":        graph.NodeTypeAggregate,
+	"operation": graph.NodeTypeAggregate,
+	"service":   graph.NodeTypeService,
+	"svc":       graph.NodeTypeService,
+	"unknown":   graph.NodeTypeUnknown,
+	"wl":        graph.NodeTypeWorkload,
+	"workload":  graph.NodeTypeWorkload,
+}
+
+// fieldAliases maps the supported, lower-cased field names and their aliases to the canonical field
+var fieldAliases = map[string]field{}
+
+func init() {
+	addField := func(f field, aliases ...string) {
+		if _, ok := fieldAliases[f.name]; ok {
+			panic(fmt.Sprintf("duplicate find field [%s]", f.name))
+		}
+		fieldAliases[f.name] = f
+		for _, alias := range aliases {
+			fieldAliases[alias] = f
+		}
+	}
+
+	// node fields
+	addField(field{name: "app", target: targetNode, valueType: fieldString})
+	addField(field{name: "cluster", target: targetNode, valueType: fieldString})
+	addField(field{name: "name", target: targetNode, valueType: fieldString})
+	addField(field{name: "namespace", target: targetNode, valueType: fieldString}, "ns")
+	addField(field{name: "node", target: targetNode, valueType: fieldString})
+	addField(field{name: "service", target: targetNode, valueType: fieldString}, "svc")
+	addField(field{name: "version", target: targetNode, valueType: fieldString})
+	addField(field{name: "workload", target: targetNode, valueType: fieldString}, "wl")
+	addField(field{name: "circuitbreaker", target: targetNode, valueType: fieldBool}, "cb")
+	addField(field{name: "dead", target: targetNode, valueType: fieldBool})
+	addField(field{name: "faultinjection", target: targetNode, valueType: fieldBool}, "fi")
+	addField(field{name: "healthy", target: targetNode, valueType: fieldBool})
+	addField(field{name: "idle", target: targetNode, valueType: fieldBool})
+	addField(field{name: "inaccessible", target: targetNode, valueType: fieldBool})
+	addField(field{name: "outside", target: targetNode, valueType: fieldBool}, "outsider")
+	addField(field{name: "requestrouting", target: targetNode, valueType: fieldBool}, "rr")
+	addField(field{name: "requesttimeout", target: targetNode, valueType: fieldBool}, "rto")
+	addField(field{name: "root", target: targetNode, valueType: fieldBool})
+	addField(field{name: "saturated", target: targetNode, valueType: fieldBool})
+	addField(field{name: "serviceentry", target: targetNode, valueType: fieldBool}, "se")
+	addField(field{name: "sidecar", target: targetNode, valueType: fieldBool}, "sc")
+	addField(field{name: "tcptrafficshifting", target: targetNode, valueType: fieldBool}, "tcpts")
+	addField(field{name: "trafficshifting", target: targetNode, valueType: fieldBool}, "ts")
+	addField(field{name: "virtualservice", target: targetNode, valueType: fieldBool}, "vs")
+
+	// edge fields
+	addField(field{name: "protocol", target: targetEdge, valueType: fieldString})
+	addField(field{name: "%error", target: targetEdge, valueType: fieldNumeric}, "%err")
+	addField(field{name: "responsetime", target: targetEdge, valueType: fieldNumeric}, "rt")
+	addField(field{name: "throughput", target: targetEdge, valueType: fieldNumeric}, "tp")
+	addField(field{name: "anomalous", target: targetEdge, valueType: fieldBool})
+	addField(field{name: "mtls", target: targetEdge, valueType: fieldBool})
+	addField(field{name: "traffic", target: targetEdge, valueType: fieldBool})
+
+	// the protocol rates, e.g. "httpin" (node) or "http5xx" (edge), plus the per-protocol edge percentages
+	for _, p := range graph.Protocols {
+		for _, r := range p.NodeRates {
+			addField(field{name: strings.ToLower(string(r.Name)), target: targetNode, valueType: fieldNumeric, key: r.Name})
+		}
+		for _, r := range p.EdgeRates {
+			f := field{name: strings.ToLower(string(r.Name)), target: targetEdge, valueType: fieldNumeric, key: r.Name, protocol: p.Name}
+			addField(f)
+			switch {
+			case r.IsPercentErr:
+				f.name = fmt.Sprintf("%%%serr", p.Name)
+				addField(f, fmt.Sprintf("%%%serror", p.Name))
+			case r.IsPercentReq:
+				f.name = fmt.Sprintf("%%%straffic", p.Name)
+				addField(f)
+			}
+		}
+	}
+}
+
+func lookupField(name string) (field, bool) {
+	f, ok := fieldAliases[strings.ToLower(name)]
+	return f, ok
+}
+
+// Apply applies the hide expression and then the find expression to the trafficMap. An invalid expression
+// results in a BadRequest panic.
+func Apply(trafficMap graph.TrafficMap, o graph.FindOptions) {
+	hideExpression, err := Parse(o.Hide)
+	if err != nil {
+		graph.BadRequest(fmt.Sprintf("Invalid hide: %s", err.Error()))
+	}
+	findExpression, err := Parse(o.Find)
+	if err != nil {
+		graph.BadRequest(fmt.Sprintf("Invalid find: %s", err.Error()))
+	}
+
+	if hideExpression != nil {
+		hideExpression.hide(trafficMap)
+	}
+	if findExpression != nil {
+		findExpression.find(trafficMap)
+	}
+}
+
+// hide removes matching nodes and edges from the trafficMap, and then any nodes orphaned by the removal
+func (e *Expression) hide(trafficMap graph.TrafficMap) {
+	ev := newEvaluator(trafficMap)
+
+	hiddenNodes := make(map[string]bool)
+	for id, n := range trafficMap {
+		if e.matchNode(n, ev) {
+			hiddenNodes[id] = true
+		}
+	}
+
+	// the connected nodes, before and after hiding
+	connected := make(map[string]bool)
+	stillConnected := make(map[string]bool)
+	for id, n := range trafficMap {
+		edges := []*graph.Edge{}
+		for _, edge := range n.Edges {
+			connected[id] = true
+			connected[edge.Dest.ID] = true
+			if hiddenNodes[id] || hiddenNodes[edge.Dest.ID] || e.matchEdge(edge, ev) {
+				continue
+			}
+			stillConnected[id] = true
+			stillConnected[edge.Dest.ID] = true
+			edges = append(edges, edge)
+		}
+		n.Edges = edges
+	}
+
+	for id := range trafficMap {
+		if hiddenNodes[id] || (connected[id] && !stillConnected[id]) {
+			delete(trafficMap, id)
+		}
+	}
+}
+
+// find marks matching nodes and edges with graph.IsFound
+func (e *Expression) find(trafficMap graph.TrafficMap) {
+	ev := newEvaluator(trafficMap)
+
+	for _, n := range trafficMap {
+		if e.matchNode(n, ev) {
+			n.Metadata[graph.IsFound] = true
+		}
+		for _, edge := range n.Edges {
+			if e.matchEdge(edge, ev) {
+				edge.Metadata[graph.IsFound] = true
+			}
+		}
+	}
+}
+
+func (e *Expression) matchNode(n *graph.Node, ev *evaluator) bool {
+	return e.match(targetNode, func(c condition) bool { return ev.matchNodeCondition(n, c) })
+}
+
+func (e *Expression) matchEdge(edge *graph.Edge, ev *evaluator) bool {
+	return e.match(targetEdge, func(c condition) bool { return ev.matchEdgeCondition(edge, c) })
+}
+
+func (e *Expression) match(target string, matchCondition func(c condition) bool) bool {
+	for _, conj := range e.conjunctions {
+		if conj.target != target {
+			continue
+		}
+		matched := true
+		for _, c := range conj.conditions {
+			if !matchCondition(c) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return true
+		}
+	}
+	return false
+}
+
+// evaluator provides the condition evaluation, with the traffic map context required by some conditions
+type evaluator struct {
+	health       *healthEvaluator
+	inboundEdges map[string][]*graph.Edge // key=destNodeID
+}
+
+func newEvaluator(trafficMap graph.TrafficMap) *evaluator {
+	ev := &evaluator{
+		inboundEdges: make(map[string][]*graph.Edge),
+	}
+	for _, n := range trafficMap {
+		for _, e := range n.Edges {
+			ev.inboundEdges[e.Dest.ID] = append(ev.inboundEdges[e.Dest.ID], e)
+		}
+	}
+	return ev
+}
+
+func (ev *evaluator) matchNodeCondition(n *graph.Node, c condition) bool {
+	switch c.field.valueType {
+	case fieldBool:
+		return ev.nodeBool(n, c.field.name) != c.negate
+	case fieldNumeric:
+		return matchNumeric(getMetadataValue(n.Metadata, c.field.key), c)
+	default:
+		return matchString(nodeString(n, c.field.name), c)
+	}
+}
+
+func (ev *evaluator) matchEdgeCondition(e *graph.Edge, c condition) bool {
+	switch c.field.valueType {
+	case fieldBool:
+		return edgeBool(e, c.field.name) != c.negate
+	case fieldNumeric:
+		val, ok := edgeNumeric(e, c.field)
+		return ok && matchNumeric(val, c)
+	default:
+		protocol, _ := e.Metadata[graph.ProtocolKey].(string)
+		return matchString(protocol, c)
+	}
+}
+
+func nodeString(n *graph.Node, name string) string {
+	switch name {
+	case "app":
+		return n.App
+	case "cluster":
+		return n.Cluster
+	case "name":
+		switch n.NodeType {
+		case graph.NodeTypeAggregate:
+			aggregateValue, _ := n.Metadata[graph.AggregateValue].(string)
+			return aggregateValue
+		case graph.NodeTypeApp:
+			return n.App
+		case graph.NodeTypeService:
+			return n.Service
+		case graph.NodeTypeWorkload:
+			return n.Workload
+		default:
+			return graph.Unknown
+		}
+	case "namespace":
+		return n.Namespace
+	case "node":
+		return n.NodeType
+	case "service":
+		return n.Service
+	case "version":
+		return n.Version
+	case "workload":
+		return n.Workload
+	}
+	return ""
+}
+
+func (ev *evaluator) nodeBool(n *graph.Node, name string) bool {
+	switch name {
+	case "circuitbreaker":
+		return isSet(n.Metadata, graph.HasCB)
+	case "dead":
+		return isSet(n.Metadata, graph.IsDead)
+	case "faultinjection":
+		return isSet(n.Metadata, graph.HasFaultInjection)
+	case "healthy":
+		if ev.health == nil {
+			ev.health = newHealthEvaluator()
+		}
+		return ev.health.isHealthy(n, ev.inboundEdges[n.ID])
+	case "idle":
+		return isSet(n.Metadata, graph.IsIdle)
+	case "inaccessible":
+		return isSet(n.Metadata, graph.IsInaccessible)
+	case "outside":
+		return isSet(n.Metadata, graph.IsOutside)
+	case "requestrouting":
+		return isSet(n.Metadata, graph.HasRequestRouting)
+	case "requesttimeout":
+		return isSet(n.Metadata, graph.HasRequestTimeout)
+	case "root":
+		return isSet(n.Metadata, graph.IsRoot)
+	case "saturated":
+		return isSet(n.Metadata, graph.IsSaturated)
+	case "serviceentry":
+		_, ok := n.Metadata[graph.IsServiceEntry]
+		return ok
+	case "sidecar":
+		return !isSet(n.Metadata, graph.HasMissingSC)
+	case "tcptrafficshifting":
+		return isSet(n.Metadata, graph.HasTCPTrafficShifting)
+	case "trafficshifting":
+		return isSet(n.Metadata, graph.HasTrafficShifting)
+	case "virtualservice":
+		return isSet(n.Metadata, graph.HasVS)
+	}
+	return false
+}
+
+func edgeBool(e *graph.Edge, name string) bool {
+	switch name {
+	case "anomalous":
+		return isSet(e.Metadata, graph.IsAnomalous)
+	case "mtls":
+		return getMetadataValue(e.Metadata, graph.IsMTLS) > 0
+	case "traffic":
+		if protocol, ok := getProtocol(e); ok {
+			total, _, _ := edgeTraffic(e, protocol)
+			return total > 0
+		}
+	}
+	return false
+}
+
+// edgeNumeric returns the edge value for the field, and false if the field does not apply to the edge
+func edgeNumeric(e *graph.Edge, f field) (float64, bool) {
+	protocol, hasProtocol := getProtocol(e)
+
+	switch f.name {
+	case "%error":
+		if !hasProtocol {
+			return 0, false
+		}
+		return percentErr(e, protocol), true
+	case "responsetime":
+		val, ok := e.Metadata[graph.ResponseTime]
+		if !ok {
+			return 0, false
+		}
+		return val.(float64), true
+	case "throughput":
+		val, ok := e.Metadata[graph.Throughput]
+		if !ok {
+			return 0, false
+		}
+		return val.(float64), true
+	}
+
+	// the remaining fields are specific to a protocol
+	if !hasProtocol || f.protocol != protocol.Name {
+		return 0, false
+	}
+	for _, r := range protocol.EdgeRates {
+		if r.Name != f.key {
+			continue
+		}
+		switch {
+		case r.IsPercentErr:
+			return percentErr(e, protocol), true
+		case r.IsPercentReq:
+			return percentReq(e, protocol), true
+		}
+	}
+	return getMetadataValue(e.Metadata, f.key), true
+}
+
+func getProtocol(e *graph.Edge) (graph.Protocol, bool) {
+	for _, p := range graph.Protocols {
+		if p.Name == e.Metadata[graph.ProtocolKey] {
+			return p, true
+		}
+	}
+	return graph.Protocol{}, false
+}
+
+// edgeTraffic returns the total and error rates for the edge, and the rate the error rates are relative to
+func edgeTraffic(e *graph.Edge, protocol graph.Protocol) (total, errs, errTotal float64) {
+	hasErrTotal := false
+	for _, r := range protocol.EdgeRates {
+		switch {
+		case r.IsTotal:
+			total += getMetadataValue(e.Metadata, r.Name)
+		case r.IsErrTotal:
+			hasErrTotal = true
+			errTotal += getMetadataValue(e.Metadata, r.Name)
+		case r.IsErr:
+			errs += getMetadataValue(e.Metadata, r.Name)
+		}
+	}
+	if !hasErrTotal {
+		errTotal = total
+	}
+	return total, errs, errTotal
+}
+
+// percentErr returns the percentage of edge requests (or tcp connections) that are errors
+func percentErr(e *graph.Edge, protocol graph.Protocol) float64 {
+	_, errs, errTotal := edgeTraffic(e, protocol)
+	if errTotal == 0 {
+		return 0
+	}
+	return errs / errTotal * 100
+}
+
+// percentReq returns the percentage of the source node's outgoing requests sent on the edge
+func percentReq(e *graph.Edge, protocol graph.Protocol) float64 {
+	total, _, _ := edgeTraffic(e, protocol)
+	for _, r := range protocol.NodeRates {
+		if r.IsOut {
+			if out := getMetadataValue(e.Source.Metadata, r.Name); out > 0 {
+				return total / out * 100
+			}
+		}
+	}
+	return 0
+}
+
+func matchNumeric(val float64, c condition) bool {
+	switch c.operator {
+	case "=":
+		return val == c.num
+	case "!=":
+		return val != c.num
+	case ">":
+		return val > c.num
+	case ">=":
+		return val >= c.num
+	case "<":
+		return val < c.num
+	case "<=":
+		return val <= c.num
+	}
+	return false
+}
+
+func matchString(val string, c condition) bool {
+	val = strings.ToLower(val)
+	switch c.operator {
+	case "=":
+		return val == c.value
+	case "!=":
+		return val != c.value
+	case "*=":
+		return strings.Contains(val, c.value)
+	case "!*=":
+		return !strings.Contains(val, c.value)
+	case "^=":
+		return strings.HasPrefix(val, c.value)
+	case "!^=":
+		return !strings.HasPrefix(val, c.value)
+	case "$=":
+		return strings.HasSuffix(val, c.value)
+	case "!$=":
+		return !strings.HasSuffix(val, c.value)
+	}
+	return false
+}
+
+func isSet(md graph.Metadata, k graph.MetadataKey) bool {
+	val, ok := md[k]
+	if !ok {
+		return false
+	}
+	if b, isBool := val.(bool); isBool {
+		return b
+	}
+	return true
+}
+
+func getMetadataValue(md graph.Metadata, k graph.MetadataKey) float64 {
+	if val, ok := md[k]; ok {
+		return val.(float64)
+	}
+	return 0.0
+}
@@ -0,0 +1,175 @@
+package find
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/kiali/kiali/config"
+	"github.com/kiali/kiali/graph"
+	"github.com/kiali/kiali/models"
+)
+
+func addTestNode(trafficMap graph.TrafficMap, namespace, workload, app string) *graph.Node {
+	n := graph.NewNode("east", namespace, "", namespace, workload, app, "v1", graph.GraphTypeWorkload)
+	trafficMap[n.ID] = &n
+	return &n
+}
+
+func addTestEdge(source, dest *graph.Node, protocol string, codeRates map[string]float64) *graph.Edge {
+	e := source.AddEdge(dest)
+	e.Metadata[graph.ProtocolKey] = protocol
+	for code, rate := range codeRates {
+		graph.AddToMetadata(protocol, rate, code, "-", "", source.Metadata, dest.Metadata, e.Metadata)
+	}
+	return e
+}
+
+// productpage -> reviews (http, 10% 5xx) -> ratings (http, 1rt)
+// productpage -> details (http)
+// reviews -> mysql (tcp)
+func setupTrafficMap() (graph.TrafficMap, map[string]*graph.Node) {
+	trafficMap := graph.NewTrafficMap()
+	nodes := map[string]*graph.Node{
+		"productpage": addTestNode(trafficMap, "bookinfo", "productpage-v1", "productpage"),
+		"details":     addTestNode(trafficMap, "bookinfo", "details-v1", "details"),
+		"reviews":     addTestNode(trafficMap, "bookinfo", "reviews-v1", "reviews"),
+		"ratings":     addTestNode(trafficMap, "bookinfo", "ratings-v1", "ratings"),
+		"mysql":       addTestNode(trafficMap, "db", "mysql-v1", "mysql"),
+	}
+	nodes["productpage"].Metadata[graph.IsRoot] = true
+	nodes["mysql"].Metadata[graph.HasMissingSC] = true
+
+	addTestEdge(nodes["productpage"], nodes["reviews"], "http", map[string]float64{"200": 9.0, "500": 1.0})
+	addTestEdge(nodes["productpage"], nodes["details"], "http", map[string]float64{"200": 10.0})
+	rr := addTestEdge(nodes["reviews"], nodes["ratings"], "http", map[string]float64{"200": 1.0})
+	rr.Metadata[graph.ResponseTime] = 1500.0
+	addTestEdge(nodes["reviews"], nodes["mysql"], "tcp", map[string]float64{"-": 500.0})
+
+	return trafficMap, nodes
+}
+
+func TestParse(t *testing.T) {
+	assert := assert.New(t)
+
+	expression, err := Parse("  ")
+	assert.NoError(err)
+	assert.Nil(expression)
+
+	for _, valid := range []string{
+		"rt > 1000",
+		"! healthy",
+		"!sc",
+		"name = unknown",
+		"ns=bookinfo AND app *= view || %error >= 5.5",
+		"node = svc and !dead or protocol != tcp && tp > 0",
+		"httpIn5xx > 0",
+	} {
+		expression, err = Parse(valid)
+		assert.NoError(err, valid)
+		assert.NotNil(expression, valid)
+	}
+
+	for _, invalid := range []string{
+		"foo = bar",
+		"rt",
+		"healthy = true",
+		"rt *= 10",
+		"rt > fast",
+		"name > a",
+		"node = pod",
+		"ns = bookinfo and rt > 1000",
+		"rt > 1000 and",
+	} {
+		_, err = Parse(invalid)
+		assert.Error(err, invalid)
+	}
+}
+
+func TestFind(t *testing.T) {
+	assert := assert.New(t)
+
+	trafficMap, nodes := setupTrafficMap()
+	Apply(trafficMap, graph.FindOptions{Find: "rt > 1000 or app ^= PRODUCT or tcpin > 100"})
+
+	assert.Len(trafficMap, 5)
+	assert.Equal(true, nodes["productpage"].Metadata[graph.IsFound])
+	assert.Equal(true, nodes["mysql"].Metadata[graph.IsFound])
+	for _, name := range []string{"details", "reviews", "ratings"} {
+		_, found := nodes[name].Metadata[graph.IsFound]
+		assert.False(found, name)
+	}
+
+	assert.Equal(true, nodes["reviews"].Edges[0].Metadata[graph.IsFound])
+	_, found := nodes["reviews"].Edges[1].Metadata[graph.IsFound]
+	assert.False(found)
+}
+
+func TestFindEdgeValues(t *testing.T) {
+	assert := assert.New(t)
+
+	trafficMap, nodes := setupTrafficMap()
+	Apply(trafficMap, graph.FindOptions{Find: "%httperr >= 10 or %httptraffic = 100 or tcp > 100"})
+
+	assert.Equal(true, nodes["productpage"].Edges[0].Metadata[graph.IsFound]) // 10% errors
+	_, found := nodes["productpage"].Edges[1].Metadata[graph.IsFound]
+	assert.False(found)
+	assert.Equal(true, nodes["reviews"].Edges[0].Metadata[graph.IsFound]) // all of the reviews http requests
+	assert.Equal(true, nodes["reviews"].Edges[1].Metadata[graph.IsFound])
+}
+
+func TestFindHealthy(t *testing.T) {
+	assert := assert.New(t)
+
+	config.Set(config.NewConfig())
+
+	trafficMap, nodes := setupTrafficMap()
+	Apply(trafficMap, graph.FindOptions{Find: "!healthy"})
+
+	// the default tolerance degrades on any 5xx
+	assert.Equal(true, nodes["reviews"].Metadata[graph.IsFound])
+	for _, name := range []string{"productpage", "details", "ratings", "mysql"} {
+		_, found := nodes[name].Metadata[graph.IsFound]
+		assert.False(found, name)
+	}
+
+	// an annotated tolerance overrides the configured tolerances
+	trafficMap, nodes = setupTrafficMap()
+	nodes["reviews"].Metadata[graph.HasHealthConfig] = map[string]string{
+		string(models.RateHealthAnnotation): "5XX,20,30,http,inbound",
+	}
+	Apply(trafficMap, graph.FindOptions{Find: "!healthy"})
+	_, found := nodes["reviews"].Metadata[graph.IsFound]
+	assert.False(found)
+}
+
+func TestHide(t *testing.T) {
+	assert := assert.New(t)
+
+	trafficMap, nodes := setupTrafficMap()
+	Apply(trafficMap, graph.FindOptions{Hide: "name = reviews-v1 or protocol = http and http < 5"})
+
+	// reviews is hidden, ratings and mysql are orphaned
+	assert.Len(trafficMap, 2)
+	assert.Contains(trafficMap, nodes["productpage"].ID)
+	assert.Contains(trafficMap, nodes["details"].ID)
+	assert.Len(nodes["productpage"].Edges, 1)
+	assert.Equal(nodes["details"], nodes["productpage"].Edges[0].Dest)
+
+	// a node without edges is not orphaned by hiding
+	trafficMap, nodes = setupTrafficMap()
+	idle := addTestNode(trafficMap, "bookinfo", "idle-v1", "idle")
+	Apply(trafficMap, graph.FindOptions{Hide: "protocol = tcp", Find: "!sc"})
+	assert.Len(trafficMap, 5)
+	assert.Contains(trafficMap, idle.ID)
+	assert.NotContains(trafficMap, nodes["mysql"].ID)
+	assert.Len(nodes["reviews"].Edges, 1)
+}
+
+func TestApplyInvalid(t *testing.T) {
+	assert := assert.New(t)
+
+	trafficMap, _ := setupTrafficMap()
+	assert.Panics(func() { Apply(trafficMap, graph.FindOptions{Hide: "rt >"}) })
+	assert.Panics(func() { Apply(trafficMap, graph.FindOptions{Find: "bogus"}) })
+}
@@ -0,0 +1,198 @@
+package find
+
+// Health.go evaluates the "healthy" condition. A node is healthy when the error rates of its incoming request
+// traffic are within the health config tolerances. This mirrors the request health evaluation of the Kiali UI,
+// using the configured health rates and any health.kiali.io/rate annotation reported for the node.
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/kiali/kiali/config"
+	"github.com/kiali/kiali/graph"
+	"github.com/kiali/kiali/log"
+	"github.com/kiali/kiali/models"
+)
+
+// the direction of the traffic evaluated for node health
+const healthDirection = "inbound"
+
+type tolerance struct {
+	code      *regexp.Regexp
+	degraded  float64
+	failure   float64
+	protocol  *regexp.Regexp
+	direction *regexp.Regexp
+}
+
+type rate struct {
+	namespace  *regexp.Regexp
+	kind       *regexp.Regexp
+	name       *regexp.Regexp
+	tolerances []tolerance
+}
+
+type healthEvaluator struct {
+	rates []rate
+}
+
+func newHealthEvaluator() *healthEvaluator {
+	he := &healthEvaluator{}
+	for _, r := range config.Get().HealthConfig.Rate {
+		he.rates = append(he.rates, rate{
+			namespace:  compile(r.Namespace),
+			kind:       compile(r.Kind),
+			name:       compile(r.Name),
+			tolerances: newTolerances(r.Tolerance),
+		})
+	}
+	return he
+}
+
+func newTolerances(configTolerances []config.Tolerance) []tolerance {
+	tolerances := []tolerance{}
+	for _, t := range configTolerances {
+		tolerances = append(tolerances, tolerance{
+			code:      compileCode(t.Code),
+			degraded:  float64(t.Degraded),
+			failure:   float64(t.Failure),
+			protocol:  compile(t.Protocol),
+			direction: compile(t.Direction),
+		})
+	}
+	return tolerances
+}
+
+// compile returns the regexp for a health config expression, an empty or invalid expression matches anything
+func compile(expression string) *regexp.Regexp {
+	if expression == "" {
+		return nil
+	}
+	re, err := regexp.Compile(expression)
+	if err != nil {
+		log.Debugf("Ignoring invalid health config expression [%s]: %v", expression, err)
+		return nil
+	}
+	return re
+}
+
+// compileCode returns the regexp for a response code expression, an "X" matches any digit (e.g. "5XX")
+func compileCode(code string) *regexp.Regexp {
+	return compile(strings.NewReplacer("X", `\d`, "x", `\d`).Replace(code))
+}
+
+func matches(re *regexp.Regexp, val string) bool {
+	return re == nil || re.MatchString(val)
+}
+
+// getTolerances returns the annotated tolerances for the node, if any, otherwise the tolerances of the first
+// configured rate that applies to the node
+func (he *healthEvaluator) getTolerances(n *graph.Node) []tolerance {
+	if annotations, ok := n.Metadata[graph.HasHealthConfig].(map[string]string); ok {
+		if annotation, ok := annotations[string(models.RateHealthAnnotation)]; ok {
+			if tolerances, ok := parseRateAnnotation(annotation); ok {
+				return tolerances
+			}
+		}
+	}
+
+	var name string
+	switch n.NodeType {
+	case graph.NodeTypeApp:
+		name = n.App
+	case graph.NodeTypeService:
+		name = n.Service
+	case graph.NodeTypeWorkload:
+		name = n.Workload
+	default:
+		return []tolerance{}
+	}
+	for _, r := range he.rates {
+		if matches(r.namespace, n.Namespace) && matches(r.kind, n.NodeType) && matches(r.name, name) {
+			return r.tolerances
+		}
+	}
+	return []tolerance{}
+}
+
+// parseRateAnnotation parses a health.kiali.io/rate annotation, a semicolon-separated list of tolerances in
+// the form "code,degraded,failure,protocol,direction", e.g. "4XX,10,20,http,inbound"
+func parseRateAnnotation(annotation string) ([]tolerance, bool) {
+	configTolerances := []config.Tolerance{}
+	for _, token := range strings.Split(annotation, ";") {
+		values := strings.Split(token, ",")
+		if len(values) != 5 {
+			return nil, false
+		}
+		degraded, degradedErr := strconv.ParseFloat(strings.TrimSpace(values[1]), 32)
+		failure, failureErr := strconv.ParseFloat(strings.TrimSpace(values[2]), 32)
+		if degradedErr != nil || failureErr != nil {
+			return nil, false
+		}
+		configTolerances = append(configTolerances, config.Tolerance{
+			Code:      strings.TrimSpace(values[0]),
+			Degraded:  float32(degraded),
+			Failure:   float32(failure),
+			Protocol:  strings.TrimSpace(values[3]),
+			Direction: strings.TrimSpace(values[4]),
+		})
+	}
+	return newTolerances(configTolerances), true
+}
+
+// isHealthy returns false if, for any tolerance, the percentage of incoming requests with a matching response
+// code reaches the degraded or failure threshold. A degraded threshold of 0 means any such error is unhealthy.
+func (he *healthEvaluator) isHealthy(n *graph.Node, inboundEdges []*graph.Edge) bool {
+	tolerances := he.getTolerances(n)
+	if len(tolerances) == 0 {
+		return true
+	}
+
+	for _, p := range graph.Protocols {
+		total, codeRates := inboundResponses(p, inboundEdges)
+		if total == 0 {
+			continue
+		}
+		for _, t := range tolerances {
+			if !matches(t.protocol, p.Name) || !matches(t.direction, healthDirection) {
+				continue
+			}
+			errRate := 0.0
+			for code, val := range codeRates {
+				if matches(t.code, code) {
+					errRate += val
+				}
+			}
+			percentErr := errRate / total * 100
+			if percentErr > 0 && (percentErr >= t.degraded || (t.failure > 0 && percentErr >= t.failure)) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// inboundResponses returns the total inbound request rate for the protocol, and the rate for each response code
+func inboundResponses(p graph.Protocol, inboundEdges []*graph.Edge) (total float64, codeRates map[string]float64) {
+	codeRates = make(map[string]float64)
+	for _, e := range inboundEdges {
+		if e.Metadata[graph.ProtocolKey] != p.Name {
+			continue
+		}
+		responses, ok := e.Metadata[p.EdgeResponses].(graph.Responses)
+		if !ok {
+			continue
+		}
+		for code, detail := range responses {
+			// every response is reported with its flags, hosts are reported only for some destinations
+			var val float64
+			for _, flagVal := range detail.Flags {
+				val += flagVal
+			}
+			codeRates[code] += val
+			total += val
+		}
+	}
+	return total, codeRates
+}
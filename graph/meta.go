@@ -15,8 +15,16 @@ func NewMetadata() Metadata {
 const (
 	Aggregate             MetadataKey = "aggregate" // the prom attribute used for aggregation
 	AggregateValue        MetadataKey = "aggregateValue"
+	AnomalyErrorRate      MetadataKey = "anomalyErrorRate"    // error percentage anomaly score (anomaly)
+	AnomalyRequestRate    MetadataKey = "anomalyRequestRate"  // request rate anomaly score (anomaly)
+	AnomalyResponseTime   MetadataKey = "anomalyResponseTime" // response time anomaly score (anomaly)
+	CriticalPathDepth     MetadataKey = "criticalPathDepth"   // number of edges in the critical path of a root node
 	DestPrincipal         MetadataKey = "destPrincipal"
 	DestServices          MetadataKey = "destServices"
+	DiffPercentErr        MetadataKey = "diffPercentErr"   // change in error percentage (diff graph)
+	DiffRate              MetadataKey = "diffRate"         // change in request or byte rate (diff graph)
+	DiffResponseTime      MetadataKey = "diffResponseTime" // change in response time (diff graph)
+	DiffStatus            MetadataKey = "diffStatus"       // added | removed | changed | unchanged (diff graph)
 	HasCB                 MetadataKey = "hasCB"
 	HasFaultInjection     MetadataKey = "hasFaultInjection"
 	HasHealthConfig       MetadataKey = "hasHealthConfig"
@@ -26,20 +34,68 @@ const (
 	HasRequestRouting     MetadataKey = "hasRequestRouting"
 	HasRequestTimeout     MetadataKey = "hasRequestTimeout"
 	HasVS                 MetadataKey = "hasVS"
+	IsAnomalous           MetadataKey = "isAnomalous" // traffic deviates from the baseline (anomaly)
+	IsCriticalPath        MetadataKey = "isCriticalPath"
 	IsDead                MetadataKey = "isDead"
-	IsEgressCluster       MetadataKey = "isEgressCluster" // PassthroughCluster or BlackHoleCluster
+	IsEgressCluster       MetadataKey = "isEgressCluster" // PassthroughCluster, BlackHoleCluster or an external host
+	IsExternalHost        MetadataKey = "isExternalHost"  // a host reached through PassthroughCluster or BlackHoleCluster (externalHost)
+	IsFound               MetadataKey = "isFound"         // matches the find expression (find)
 	IsIdle                MetadataKey = "isIdle"
 	IsInaccessible        MetadataKey = "isInaccessible"
 	IsMTLS                MetadataKey = "isMTLS"
 	IsOutside             MetadataKey = "isOutside"
 	IsRoot                MetadataKey = "isRoot"
+	IsSaturated           MetadataKey = "isSaturated" // resource usage near the limits, or unavailable replicas (saturation)
 	IsServiceEntry        MetadataKey = "isServiceEntry"
+	Labels                MetadataKey = "labels"     // the values of the configured box labels (labels)
+	OperationsKey         MetadataKey = "operations" // top request operations of the edge (operations)
 	ProtocolKey           MetadataKey = "protocol"
 	ResponseTime          MetadataKey = "responseTime"
+	SaturationKey         MetadataKey = "saturation" // resource usage and replica availability of the workload (saturation)
 	SourcePrincipal       MetadataKey = "sourcePrincipal"
 	Throughput            MetadataKey = "throughput"
 )
 
+// Operation holds the traffic of a single request operation of an edge, e.g. a request path or gRPC method
+type Operation struct {
+	Name         string  `json:"name"`
+	ErrorRate    float64 `json:"errorRate"`    // percentage of failed requests
+	Rate         float64 `json:"rate"`         // requests per second
+	ResponseTime float64 `json:"responseTime"` // 95th percentile in millis, 0 if not reported
+}
+
+// Operations holds the top operations of an edge, ordered by descending request rate
+type Operations []Operation
+
+// ExternalHost holds a host reached through the PassthroughCluster or the BlackHoleCluster, i.e. a host not
+// defined in the service registry. The traffic is blocked when sent to the BlackHoleCluster, or when the
+// outbound traffic policy is REGISTRY_ONLY and no ServiceEntry defines the host.
+type ExternalHost struct {
+	Blocked               bool   `json:"blocked"`
+	EgressCluster         string `json:"egressCluster"`          // PassthroughCluster | BlackHoleCluster
+	Host                  string `json:"host"`                   // the requested host, the authority or SNI
+	OutboundTrafficPolicy string `json:"outboundTrafficPolicy"`  // ALLOW_ANY | REGISTRY_ONLY
+	ServiceEntry          string `json:"serviceEntry,omitempty"` // the namespace/name of a ServiceEntry defining the host
+}
+
+// ResourceUsage holds the usage of a resource by the pods of a workload, and the sum of the requests and
+// limits set on their containers (zero if not set).
+type ResourceUsage struct {
+	Usage    float64 `json:"usage"`
+	Requests float64 `json:"requests"`
+	Limits   float64 `json:"limits"`
+}
+
+// Saturation holds the resource saturation of a workload: the CPU (cores) and memory (bytes) usage of its
+// pods, their container restarts in the time window and the availability of the workload replicas
+type Saturation struct {
+	CPU               ResourceUsage `json:"cpu"`
+	Memory            ResourceUsage `json:"memory"`
+	Restarts          float64       `json:"restarts"`
+	DesiredReplicas   int32         `json:"desiredReplicas"`
+	AvailableReplicas int32         `json:"availableReplicas"`
+}
+
 // DestServicesMetadata key=Service.Key()
 type DestServicesMetadata map[string]ServiceName
 
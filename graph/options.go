@@ -22,7 +22,11 @@ import (
 // The supported vendors
 const (
 	VendorCytoscape        string = "cytoscape"
+	VendorDot              string = "dot"
+	VendorEnvoy            string = "envoy"
+	VendorGraphML          string = "graphml"
 	VendorIstio            string = "istio"
+	VendorTracing          string = "tracing"
 	defaultConfigVendor    string = VendorCytoscape
 	defaultTelemetryVendor string = VendorIstio
 )
@@ -32,12 +36,17 @@ const (
 	BoxByCluster              string = "cluster"
 	BoxByNamespace            string = "namespace"
 	BoxByNone                 string = "none"
+	BoxByVersion              string = "version"
 	NamespaceIstio            string = "istio-system"
 	defaultBoxBy              string = BoxByNone
+	defaultDebug              bool   = false
+	defaultDepth              int    = 1
+	defaultDiffTolerance      string = "10"
 	defaultDuration           string = "10m"
 	defaultGraphType          string = GraphTypeWorkload
 	defaultIncludeIdleEdges   bool   = false
 	defaultInjectServiceNodes bool   = false
+	maxDepth                  int    = 5
 )
 
 const (
@@ -51,12 +60,27 @@ type NodeOptions struct {
 	AggregateValue string
 	App            string
 	Cluster        string
+	Depth          int // node graph hops from the node, in both directions
 	Namespace      string
 	Service        string
 	Version        string
 	Workload       string
 }
 
+// DiffOptions are those that apply only to diff graphs. The requested time window is compared to
+// the compare time window, which by default is the window immediately preceding the requested window.
+type DiffOptions struct {
+	CompareDuration  time.Duration
+	CompareQueryTime int64   // unix time in seconds
+	Tolerance        float64 // percentage change required for a node or edge to be considered changed
+}
+
+// FindOptions are applied to the TrafficMap before it is passed to the Config Vendor
+type FindOptions struct {
+	Find string // find expression, matching nodes and edges are marked as found
+	Hide string // hide expression, matching nodes and edges are removed
+}
+
 // CommonOptions are those supplied to Telemetry and Config Vendors
 type CommonOptions struct {
 	Duration  time.Duration
@@ -90,8 +114,11 @@ type TelemetryOptions struct {
 // Options comprises all available options
 type Options struct {
 	ConfigVendor    string
+	Debug           bool // return the generation timings and the Prometheus queries with the graph
 	TelemetryVendor string
 	ConfigOptions
+	DiffOptions
+	FindOptions
 	TelemetryOptions
 }
 
@@ -108,6 +135,11 @@ func NewOptions(r *net_http.Request) Options {
 
 	// query params
 	params := r.URL.Query()
+	var compareDuration model.Duration
+	var compareQueryTime int64
+	var debug bool
+	var depth int
+	var diffTolerance float64
 	var duration model.Duration
 	var includeIdleEdges bool
 	var injectServiceNodes bool
@@ -115,9 +147,16 @@ func NewOptions(r *net_http.Request) Options {
 	appenders := RequestedAppenders{All: true}
 	boxBy := params.Get("boxBy")
 	cluster := params.Get("cluster")
+	compareDurationString := params.Get("compareDuration")
+	compareQueryTimeString := params.Get("compareQueryTime")
 	configVendor := params.Get("configVendor")
+	debugString := params.Get("debug")
+	depthString := params.Get("depth")
+	diffToleranceString := params.Get("diffTolerance")
 	durationString := params.Get("duration")
+	find := params.Get("find")
 	graphType := params.Get("graphType")
+	hide := params.Get("hide")
 	includeIdleEdgesString := params.Get("includeIdleEdges")
 	injectServiceNodesString := params.Get("injectServiceNodes")
 	namespaces := params.Get("namespaces") // csl of namespaces
@@ -137,8 +176,29 @@ func NewOptions(r *net_http.Request) Options {
 	}
 	if configVendor == "" {
 		configVendor = defaultConfigVendor
-	} else if configVendor != VendorCytoscape {
-		BadRequest(fmt.Sprintf("Invalid configVendor [%s]", configVendor))
+	} else {
+		validateConfigVendor(configVendor)
+	}
+	if debugString == "" {
+		debug = defaultDebug
+	} else {
+		var debugErr error
+		debug, debugErr = strconv.ParseBool(debugString)
+		if debugErr != nil {
+			BadRequest(fmt.Sprintf("Invalid debug [%s]", debugString))
+		}
+		if debug && configVendor != VendorCytoscape {
+			BadRequest(fmt.Sprintf("Invalid configVendor [%s], debug is supported only by cytoscape", configVendor))
+		}
+	}
+	if depthString == "" {
+		depth = defaultDepth
+	} else {
+		var depthErr error
+		depth, depthErr = strconv.Atoi(depthString)
+		if depthErr != nil || depth < 1 || depth > maxDepth {
+			BadRequest(fmt.Sprintf("Invalid depth [%s], must be an integer between 1 and %d", depthString, maxDepth))
+		}
 	}
 	if durationString == "" {
 		duration, _ = model.ParseDuration(defaultDuration)
@@ -161,18 +221,7 @@ func NewOptions(r *net_http.Request) Options {
 	if boxBy == "" {
 		boxBy = defaultBoxBy
 	} else {
-		for _, box := range strings.Split(boxBy, ",") {
-			switch strings.TrimSpace(box) {
-			case BoxByApp:
-				continue
-			case BoxByCluster:
-				continue
-			case BoxByNamespace:
-				continue
-			default:
-				BadRequest(fmt.Sprintf("Invalid boxBy [%s]", boxBy))
-			}
-		}
+		validateBoxBy(boxBy)
 	}
 	if includeIdleEdgesString == "" {
 		includeIdleEdges = defaultIncludeIdleEdges
@@ -201,52 +250,61 @@ func NewOptions(r *net_http.Request) Options {
 			BadRequest(fmt.Sprintf("Invalid queryTime [%s]", queryTimeString))
 		}
 	}
+	if compareDurationString == "" {
+		compareDuration = duration
+	} else {
+		var compareDurationErr error
+		compareDuration, compareDurationErr = model.ParseDuration(compareDurationString)
+		if compareDurationErr != nil {
+			BadRequest(fmt.Sprintf("Invalid compareDuration [%s]", compareDurationString))
+		}
+	}
+	if compareQueryTimeString == "" {
+		compareQueryTime = queryTime - int64(time.Duration(duration).Seconds())
+	} else {
+		var compareQueryTimeErr error
+		compareQueryTime, compareQueryTimeErr = strconv.ParseInt(compareQueryTimeString, 10, 64)
+		if compareQueryTimeErr != nil {
+			BadRequest(fmt.Sprintf("Invalid compareQueryTime [%s]", compareQueryTimeString))
+		}
+	}
+	if diffToleranceString == "" {
+		diffToleranceString = defaultDiffTolerance
+	}
+	var diffToleranceErr error
+	diffTolerance, diffToleranceErr = strconv.ParseFloat(diffToleranceString, 64)
+	if diffToleranceErr != nil || diffTolerance < 0 {
+		BadRequest(fmt.Sprintf("Invalid diffTolerance [%s]", diffToleranceString))
+	}
 	if telemetryVendor == "" {
 		telemetryVendor = defaultTelemetryVendor
-	} else if telemetryVendor != VendorIstio {
+	} else if telemetryVendor != VendorIstio && telemetryVendor != VendorEnvoy && telemetryVendor != VendorTracing {
 		BadRequest(fmt.Sprintf("Invalid telemetryVendor [%s]", telemetryVendor))
 	}
 
 	// Process namespaces options:
 	namespaceMap := NewNamespaceInfoMap()
 
-	authInfoContext := r.Context().Value("authInfo")
-	var authInfo *api.AuthInfo
-	if authInfoContext != nil {
-		if authInfoCheck, ok := authInfoContext.(*api.AuthInfo); !ok {
-			Error("authInfo is not of type *api.AuthInfo")
-		} else {
-			authInfo = authInfoCheck
-		}
-	} else {
-		Error("token missing in request context")
-	}
-
-	accessibleNamespaces := getAccessibleNamespaces(authInfo)
+	accessibleNamespaces := getAccessibleNamespaces(getAuthInfo(r))
 
-	// If path variable is set then it is the only relevant namespace (it's a node graph)
+	// If path variable is set then it is the only relevant namespace (it's a node graph), unless the node graph
+	//   expands beyond the node's neighbors, in which case the namespaces query param adds the namespaces in which
+	//   nodes are expanded (as in a namespaces graph, nodes outside of the namespaces are not expanded)
 	// Else if namespaces query param is set it specifies the relevant namespaces
 	// Else error, at least one namespace is required.
 	if namespace != "" {
-		namespaces = namespace
+		if depth > 1 && namespaces != "" {
+			namespaces = namespace + "," + namespaces
+		} else {
+			namespaces = namespace
+		}
 	}
 
 	if namespaces == "" {
 		BadRequest(fmt.Sprintf("At least one namespace must be specified via the namespaces query parameter."))
 	}
 
-	for _, namespaceToken := range strings.Split(namespaces, ",") {
-		namespaceToken = strings.TrimSpace(namespaceToken)
-		if creationTime, found := accessibleNamespaces[namespaceToken]; found {
-			namespaceMap[namespaceToken] = NamespaceInfo{
-				Name:     namespaceToken,
-				Duration: getSafeNamespaceDuration(namespaceToken, creationTime, time.Duration(duration), queryTime),
-				IsIstio:  config.IsIstioNamespace(namespaceToken),
-			}
-		} else {
-			Forbidden(fmt.Sprintf("Requested namespace [%s] is not accessible.", namespaceToken))
-		}
-	}
+	addNamespaces(namespaceMap, namespaces, accessibleNamespaces, time.Duration(duration), queryTime)
 
 	// Service graphs require service injection
 	if graphType == GraphTypeService {
@@ -255,6 +313,7 @@ func NewOptions(r *net_http.Request) Options {
 
 	options := Options{
 		ConfigVendor:    configVendor,
+		Debug:           debug,
 		TelemetryVendor: telemetryVendor,
 		ConfigOptions: ConfigOptions{
 			BoxBy: boxBy,
@@ -265,6 +324,15 @@ func NewOptions(r *net_http.Request) Options {
 				QueryTime: queryTime,
 			},
 		},
+		DiffOptions: DiffOptions{
+			CompareDuration:  time.Duration(compareDuration),
+			CompareQueryTime: compareQueryTime,
+			Tolerance:        diffTolerance,
+		},
+		FindOptions: FindOptions{
+			Find: find,
+			Hide: hide,
+		},
 		TelemetryOptions: TelemetryOptions{
 			AccessibleNamespaces: accessibleNamespaces,
 			Appenders:            appenders,
@@ -282,6 +350,7 @@ func NewOptions(r *net_http.Request) Options {
 				AggregateValue: aggregateValue,
 				App:            app,
 				Cluster:        cluster,
+				Depth:          depth,
 				Namespace:      namespace,
 				Service:        service,
 				Version:        version,
@@ -293,6 +362,47 @@ func NewOptions(r *net_http.Request) Options {
 	return options
 }
 
+// NewReplayOptions returns the options for replaying a graph previously generated with the provided options,
+// such as a snapshot. The config vendor, boxing and find/hide expressions may be overridden by the request
+// query params. The telemetry options are not changed, except for the accessible namespaces which are those
+// of the requesting user, not of the user who generated the graph.
+func NewReplayOptions(r *net_http.Request, o Options) Options {
+	params := r.URL.Query()
+
+	o.TelemetryOptions.AccessibleNamespaces = getAccessibleNamespaces(getAuthInfo(r))
+
+	if configVendor := params.Get("configVendor"); configVendor != "" {
+		validateConfigVendor(configVendor)
+		o.ConfigVendor = configVendor
+	}
+	if boxBy, ok := params["boxBy"]; ok {
+		if o.BoxBy = boxBy[0]; o.BoxBy == "" {
+			o.BoxBy = defaultBoxBy
+		} else {
+			validateBoxBy(o.BoxBy)
+		}
+	}
+	if find, ok := params["find"]; ok {
+		o.Find = find[0]
+	}
+	if hide, ok := params["hide"]; ok {
+		o.Hide = hide[0]
+	}
+
+	return o
+}
+
+// NewBlastRadiusOptions returns the options for the blast radius of a node. The dependents are searched in the
+// graph of the node's namespace and, optionally, of the namespaces query param. Unlike a node graph the
+// namespaces query param does not override the node's namespace, dependents are typically in other namespaces.
+func NewBlastRadiusOptions(r *net_http.Request) Options {
+	o := NewOptions(r)
+	if namespaces := r.URL.Query().Get("namespaces"); namespaces != "" {
+		addNamespaces(o.TelemetryOptions.Namespaces, namespaces, o.AccessibleNamespaces, o.TelemetryOptions.Duration, o.TelemetryOptions.QueryTime)
+	}
+	return o
+}
+
 // GetGraphKind will return the kind of graph represented by the options.
 func (o *TelemetryOptions) GetGraphKind() string {
 	if o.NodeOptions.App != "" ||
@@ -304,6 +414,123 @@ func (o *TelemetryOptions) GetGraphKind() string {
 	return graphKindNamespace
 }
 
+// GetCompareTelemetryOptions returns a copy of the telemetry options adjusted for the compare time window
+// of a diff graph. Requested namespaces that did not yet exist at the compare queryTime are omitted.
+func (o *Options) GetCompareTelemetryOptions() TelemetryOptions {
+	compareOptions := o.TelemetryOptions
+	compareOptions.Duration = o.CompareDuration
+	compareOptions.QueryTime = o.CompareQueryTime
+	compareOptions.Namespaces = NewNamespaceInfoMap()
+
+	compareTime := time.Unix(o.CompareQueryTime, 0)
+	for name, namespaceInfo := range o.TelemetryOptions.Namespaces {
+		creationTime := o.AccessibleNamespaces[name]
+		if !creationTime.IsZero() && !creationTime.Before(compareTime) {
+			log.Debugf("Namespace [%s] did not exist at compareQueryTime [%v]", name, compareTime)
+			continue
+		}
+		compareOptions.Namespaces[name] = NamespaceInfo{
+			Name:     name,
+			Duration: getSafeNamespaceDuration(name, creationTime, o.CompareDuration, o.CompareQueryTime),
+			IsIstio:  namespaceInfo.IsIstio,
+		}
+	}
+
+	return compareOptions
+}
+
+func validateConfigVendor(configVendor string) {
+	if configVendor != VendorCytoscape && configVendor != VendorDot && configVendor != VendorGraphML {
+		BadRequest(fmt.Sprintf("Invalid configVendor [%s]", configVendor))
+	}
+}
+
+func validateBoxBy(boxBy string) {
+	for _, box := range strings.Split(boxBy, ",") {
+		switch strings.TrimSpace(box) {
+		case BoxByApp:
+			continue
+		case BoxByCluster:
+			continue
+		case BoxByNamespace:
+			continue
+		case BoxByVersion:
+			continue
+		default:
+			if !isBoxLabel(strings.TrimSpace(box)) {
+				BadRequest(fmt.Sprintf("Invalid boxBy [%s]", boxBy))
+			}
+		}
+	}
+}
+
+// isBoxLabel returns true if the label is configured as a box label
+func isBoxLabel(label string) bool {
+	for _, boxLabel := range config.Get().GraphBoxing.Labels {
+		if label == boxLabel {
+			return true
+		}
+	}
+	return false
+}
+
+// IsBoxBy returns true if boxing by the node attribute (app, cluster or namespace) or label is requested
+func (o ConfigOptions) IsBoxBy(boxBy string) bool {
+	for _, box := range strings.Split(o.BoxBy, ",") {
+		if strings.TrimSpace(box) == boxBy {
+			return true
+		}
+	}
+	return false
+}
+
+// BoxByLabels returns the requested box labels, including the version, in the requested order. The boxes of
+// a label enclose the boxes of the labels following it.
+func (o ConfigOptions) BoxByLabels() []string {
+	labels := []string{}
+	for _, box := range strings.Split(o.BoxBy, ",") {
+		switch box = strings.TrimSpace(box); box {
+		case BoxByApp, BoxByCluster, BoxByNamespace, BoxByNone, "":
+			continue
+		default:
+			labels = append(labels, box)
+		}
+	}
+	return labels
+}
+
+// addNamespaces adds the comma-separated namespaces to the namespace map, an inaccessible namespace is forbidden
+func addNamespaces(namespaceMap NamespaceInfoMap, namespaces string, accessibleNamespaces map[string]time.Time, duration time.Duration, queryTime int64) {
+	for _, namespaceToken := range strings.Split(namespaces, ",") {
+		namespaceToken = strings.TrimSpace(namespaceToken)
+		if creationTime, found := accessibleNamespaces[namespaceToken]; found {
+			namespaceMap[namespaceToken] = NamespaceInfo{
+				Name:     namespaceToken,
+				Duration: getSafeNamespaceDuration(namespaceToken, creationTime, duration, queryTime),
+				IsIstio:  config.IsIstioNamespace(namespaceToken),
+			}
+		} else {
+			Forbidden(fmt.Sprintf("Requested namespace [%s] is not accessible.", namespaceToken))
+		}
+	}
+}
+
+// getAuthInfo returns the authentication info of the request
+func getAuthInfo(r *net_http.Request) *api.AuthInfo {
+	authInfoContext := r.Context().Value("authInfo")
+	var authInfo *api.AuthInfo
+	if authInfoContext != nil {
+		if authInfoCheck, ok := authInfoContext.(*api.AuthInfo); !ok {
+			Error("authInfo is not of type *api.AuthInfo")
+		} else {
+			authInfo = authInfoCheck
+		}
+	} else {
+		Error("token missing in request context")
+	}
+	return authInfo
+}
+
 // getAccessibleNamespaces returns a Set of all namespaces accessible to the user.
 // The Set is implemented using the map convention. Each map entry is set to the
 // creation timestamp of the namespace, to be used to ensure valid time ranges for
@@ -17,6 +17,7 @@ const (
 type Rate struct {
 	Name         MetadataKey
 	IsErr        bool
+	IsErrTotal   bool // the rate the error rates are relative to, when not the total rate
 	IsIn         bool
 	IsOut        bool
 	IsPercentErr bool
@@ -27,12 +28,13 @@ type Rate struct {
 
 // Protocol describes a supported protocol and the rates it provides
 type Protocol struct {
-	Name          string
-	EdgeRates     []Rate
-	EdgeResponses MetadataKey
-	NodeRates     []Rate
-	Unit          string
-	UnitShort     string
+	Name                    string
+	EdgeConnectionResponses MetadataKey // the connection-weighted responses, for a protocol reporting connections
+	EdgeRates               []Rate
+	EdgeResponses           MetadataKey
+	NodeRates               []Rate
+	Unit                    string
+	UnitShort               string
 }
 
 // Each supported protocol is defined below.  Each rate provided as node or edge metadata must be defined.
@@ -124,19 +126,36 @@ var HTTP = Protocol{
 // TCP Protocol
 //
 const (
-	tcp            = "tcp"
-	tcpResponses   = "tcpResponses"
-	tcpIn          = "tcpIn"
-	tcpOut         = "tcpOut"
-	bytesPerSecond = "bytes per second"
-	bps            = "bps"
+	tcp                    = "tcp"
+	tcpOpened              = "tcpOpened" // connections opened per second
+	tcpClosed              = "tcpClosed" // connections closed per second
+	tcpActive              = "tcpActive" // connections open at the query time
+	tcpErr                 = "tcpErr"    // connections closed with a failure per second
+	tcpPercentErr          = "tcpPercentErr"
+	tcpResponses           = "tcpResponses"
+	tcpConnectionResponses = "tcpConnectionResponses"
+	tcpIn                  = "tcpIn"
+	tcpOut                 = "tcpOut"
+	bytesPerSecond         = "bytes per second"
+	bps                    = "bps"
+
+	// TCPErrCode is the response code of the tcp connections closed with a failure (i.e. with response flags),
+	// any other tcp traffic is reported with a "-" code.
+	TCPErrCode = "ERR"
 )
 
-// TCP Protocol
+// TCP Protocol. The byte rate is the total rate, the connection failures are relative to the closed connections.
+// The responses are byte-weighted, the connection responses are weighted by the closed connections.
 var TCP = Protocol{
-	Name: tcp,
+	Name:                    tcp,
+	EdgeConnectionResponses: tcpConnectionResponses,
 	EdgeRates: []Rate{
 		{Name: tcp, IsTotal: true, Precision: 2},
+		{Name: tcpOpened, Precision: 2},
+		{Name: tcpClosed, IsErrTotal: true, Precision: 2},
+		{Name: tcpActive, Precision: 0},
+		{Name: tcpErr, IsErr: true, Precision: 2},
+		{Name: tcpPercentErr, IsPercentErr: true, Precision: 1},
 	},
 	EdgeResponses: tcpResponses,
 	NodeRates: []Rate{
@@ -225,6 +244,26 @@ func addToMetadataTCP(val float64, flags, host string, sourceMetadata, destMetad
 	addToMetadataResponses(edgeMetadata, tcpResponses, "-", flags, host, val)
 }
 
+// AddTCPConnectionsToMetadata adds the connection telemetry of a single tcp time series to the edge: the rates of
+// the opened and closed connections, and the number of active connections. The responses of an edge reporting
+// connection telemetry are byte-weighted, the closed connections are added to the separate connection responses with
+// their flags and host. A connection closed with flags other than "-" is a failure, reported with the TCPErrCode code.
+func AddTCPConnectionsToMetadata(opened, closed, active float64, flags, host string, edgeMetadata Metadata) {
+	addToMetadataValue(edgeMetadata, tcpOpened, opened)
+	addToMetadataValue(edgeMetadata, tcpActive, active)
+	if closed <= 0.0 {
+		return
+	}
+
+	addToMetadataValue(edgeMetadata, tcpClosed, closed)
+	code := "-"
+	if flags != "-" {
+		code = TCPErrCode
+		addToMetadataValue(edgeMetadata, tcpErr, closed)
+	}
+	addToMetadataResponses(edgeMetadata, tcpConnectionResponses, code, flags, host, closed)
+}
+
 // IsHTTPErr return true if code is 4xx or 5xx
 func IsHTTPErr(code string) bool {
 	return strings.HasPrefix(code, "4") || strings.HasPrefix(code, "5")
@@ -255,6 +294,37 @@ func ResetOutgoingMetadata(sourceMetadata Metadata) {
 	delete(sourceMetadata, tcpOut)
 }
 
+// AddIncomingEdgeToMetadata updates the dest node's incoming traffic with the incoming edge traffic values
+func AddIncomingEdgeToMetadata(destMetadata, edgeMetadata Metadata) {
+	for k, destK := range map[MetadataKey]MetadataKey{
+		grpc:           grpcIn,
+		grpcNoResponse: grpcInNoResponse,
+		grpcErr:        grpcInErr,
+		http:           httpIn,
+		httpNoResponse: httpInNoResponse,
+		http3xx:        httpIn3xx,
+		http4xx:        httpIn4xx,
+		http5xx:        httpIn5xx,
+		tcp:            tcpIn,
+	} {
+		if val, valOk := edgeMetadata[k]; valOk {
+			addToMetadataValue(destMetadata, destK, val.(float64))
+		}
+	}
+}
+
+// ResetIncomingMetadata sets incoming traffic to zero. This is useful when the incoming edges of a node are
+// redirected.
+func ResetIncomingMetadata(destMetadata Metadata) {
+	for _, protocol := range Protocols {
+		for _, rate := range protocol.NodeRates {
+			if !rate.IsOut {
+				delete(destMetadata, rate.Name)
+			}
+		}
+	}
+}
+
 // AggregateNodeTraffic adds all <nodeMetadata> values (for all protocols) into aggregateNodeMetadata.
 func AggregateNodeTraffic(node, aggregateNode *Node) {
 	for _, protocol := range Protocols {
@@ -302,11 +372,15 @@ func AggregateEdgeTraffic(edge, aggregateEdge *Edge) {
 			addToResponses(aggregateEdge.Metadata, httpResponses, responses.(Responses))
 		}
 	case tcp:
-		if val, ok := edge.Metadata[tcp]; ok {
-			addToMetadataValue(aggregateEdge.Metadata, tcp, val.(float64))
+		for _, k := range []MetadataKey{tcp, tcpOpened, tcpClosed, tcpActive, tcpErr} {
+			if val, ok := edge.Metadata[k]; ok {
+				addToMetadataValue(aggregateEdge.Metadata, k, val.(float64))
+			}
 		}
-		if responses, ok := edge.Metadata[tcpResponses]; ok {
-			addToResponses(aggregateEdge.Metadata, tcpResponses, responses.(Responses))
+		for _, k := range []MetadataKey{tcpResponses, tcpConnectionResponses} {
+			if responses, ok := edge.Metadata[k]; ok {
+				addToResponses(aggregateEdge.Metadata, k, responses.(Responses))
+			}
 		}
 
 	default:
@@ -0,0 +1,337 @@
+// Package snapshot provides the persistence of generated graphs. A snapshot holds a TrafficMap, after the
+// appenders have run, along with the graph options used to generate it. A snapshot can later be replayed
+// through any Config Vendor without querying the telemetry again, for example to review the graph seen
+// during an incident.
+//
+// Snapshots are saved to a Store, see NewStore for the supported stores. Snapshots are shared, they are not
+// owned by the user who created them: any user with access to all the namespaces of a snapshot can replay and
+// delete it.
+package snapshot
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/kiali/kiali/graph"
+)
+
+// Info describes a snapshot, it is returned when listing the snapshots of a store
+type Info struct {
+	ID         string    `json:"id"`
+	Name       string    `json:"name,omitempty"`
+	Created    time.Time `json:"created"`
+	Duration   int64     `json:"duration"` // in seconds
+	GraphType  string    `json:"graphType"`
+	Namespaces []string  `json:"namespaces"`
+	QueryTime  int64     `json:"queryTime"` // unix time in seconds
+}
+
+// Snapshot is a persisted graph
+type Snapshot struct {
+	Info
+	Options Options `json:"options"`
+	Nodes   []Node  `json:"nodes"`
+	Edges   []Edge  `json:"edges"`
+}
+
+// Options holds the graph.Options used to generate the snapshot. The graph.Options parts are stored
+// separately because they embed the same CommonOptions, which would clash when flattened to JSON.
+type Options struct {
+	ConfigVendor     string                 `json:"configVendor"`
+	TelemetryVendor  string                 `json:"telemetryVendor"`
+	ConfigOptions    graph.ConfigOptions    `json:"configOptions"`
+	DiffOptions      graph.DiffOptions      `json:"diffOptions"`
+	FindOptions      graph.FindOptions      `json:"findOptions"`
+	TelemetryOptions graph.TelemetryOptions `json:"telemetryOptions"`
+}
+
+// Node is a persisted graph.Node
+type Node struct {
+	ID        string   `json:"id"`
+	NodeType  string   `json:"nodeType"`
+	Cluster   string   `json:"cluster"`
+	Namespace string   `json:"namespace"`
+	Workload  string   `json:"workload,omitempty"`
+	App       string   `json:"app,omitempty"`
+	Version   string   `json:"version,omitempty"`
+	Service   string   `json:"service,omitempty"`
+	Metadata  Metadata `json:"metadata,omitempty"`
+}
+
+// Edge is a persisted graph.Edge, the source and dest nodes are referenced by ID
+type Edge struct {
+	Source   string   `json:"source"`
+	Dest     string   `json:"dest"`
+	Metadata Metadata `json:"metadata,omitempty"`
+}
+
+// NewSnapshot returns a snapshot of the traffic map generated with the options. The traffic map should
+// not yet be processed by find or hide, they are applied when the snapshot is replayed.
+func NewSnapshot(name string, trafficMap graph.TrafficMap, o graph.Options) (*Snapshot, error) {
+	now := time.Now()
+	id, err := newID(now)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Snapshot{
+		Info: Info{
+			ID:         id,
+			Name:       name,
+			Created:    now,
+			Duration:   int64(o.TelemetryOptions.Duration.Seconds()),
+			GraphType:  o.TelemetryOptions.GraphType,
+			Namespaces: []string{},
+			QueryTime:  o.TelemetryOptions.QueryTime,
+		},
+		Options: Options{
+			ConfigVendor:     o.ConfigVendor,
+			TelemetryVendor:  o.TelemetryVendor,
+			ConfigOptions:    o.ConfigOptions,
+			DiffOptions:      o.DiffOptions,
+			FindOptions:      o.FindOptions,
+			TelemetryOptions: o.TelemetryOptions,
+		},
+		Nodes: []Node{},
+		Edges: []Edge{},
+	}
+	for namespace := range o.Namespaces {
+		s.Namespaces = append(s.Namespaces, namespace)
+	}
+	sort.Strings(s.Namespaces)
+
+	// sort by ID for a stable document
+	ids := make([]string, 0, len(trafficMap))
+	for id := range trafficMap {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	for _, id := range ids {
+		n := trafficMap[id]
+		md, err := newMetadata(n.Metadata)
+		if err != nil {
+			return nil, fmt.Errorf("node [%s]: %v", n.ID, err)
+		}
+		s.Nodes = append(s.Nodes, Node{
+			ID:        n.ID,
+			NodeType:  n.NodeType,
+			Cluster:   n.Cluster,
+			Namespace: n.Namespace,
+			Workload:  n.Workload,
+			App:       n.App,
+			Version:   n.Version,
+			Service:   n.Service,
+			Metadata:  md,
+		})
+		for _, e := range n.Edges {
+			md, err := newMetadata(e.Metadata)
+			if err != nil {
+				return nil, fmt.Errorf("edge [%s %s]: %v", e.Source.ID, e.Dest.ID, err)
+			}
+			s.Edges = append(s.Edges, Edge{
+				Source:   e.Source.ID,
+				Dest:     e.Dest.ID,
+				Metadata: md,
+			})
+		}
+	}
+
+	return s, nil
+}
+
+// TrafficMap returns the TrafficMap held by the snapshot
+func (s *Snapshot) TrafficMap() (graph.TrafficMap, error) {
+	trafficMap := graph.NewTrafficMap()
+	for _, sn := range s.Nodes {
+		md, err := sn.Metadata.graphMetadata()
+		if err != nil {
+			return nil, fmt.Errorf("node [%s]: %v", sn.ID, err)
+		}
+		trafficMap[sn.ID] = &graph.Node{
+			ID:        sn.ID,
+			NodeType:  sn.NodeType,
+			Cluster:   sn.Cluster,
+			Namespace: sn.Namespace,
+			Workload:  sn.Workload,
+			App:       sn.App,
+			Version:   sn.Version,
+			Service:   sn.Service,
+			Edges:     []*graph.Edge{},
+			Metadata:  md,
+		}
+	}
+	for _, se := range s.Edges {
+		source, sourceOk := trafficMap[se.Source]
+		dest, destOk := trafficMap[se.Dest]
+		if !sourceOk || !destOk {
+			return nil, fmt.Errorf("edge [%s %s]: unknown node", se.Source, se.Dest)
+		}
+		md, err := se.Metadata.graphMetadata()
+		if err != nil {
+			return nil, fmt.Errorf("edge [%s %s]: %v", se.Source, se.Dest, err)
+		}
+		e := source.AddEdge(dest)
+		e.Metadata = md
+	}
+	return trafficMap, nil
+}
+
+// GraphOptions returns the graph.Options used to generate the snapshot
+func (s *Snapshot) GraphOptions() graph.Options {
+	return graph.Options{
+		ConfigVendor:     s.Options.ConfigVendor,
+		TelemetryVendor:  s.Options.TelemetryVendor,
+		ConfigOptions:    s.Options.ConfigOptions,
+		DiffOptions:      s.Options.DiffOptions,
+		FindOptions:      s.Options.FindOptions,
+		TelemetryOptions: s.Options.TelemetryOptions,
+	}
+}
+
+// newID returns a unique, time-ordered snapshot ID
+func newID(created time.Time) (string, error) {
+	b := make([]byte, 4)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s-%s", created.UTC().Format("20060102-150405"), hex.EncodeToString(b)), nil
+}
+
+// Metadata is a persisted graph.Metadata. The metadata values are interface{} values, each is stored
+// with its type so that it can be restored as the type expected by the Config Vendors.
+type Metadata map[graph.MetadataKey]Value
+
+// Value is a persisted metadata value
+type Value struct {
+	Type  string          `json:"type"`
+	Value json.RawMessage `json:"value"`
+}
+
+// The supported metadata value types
+const (
+	typeBool         = "bool"
+	typeBoolMap      = "boolMap"
+	typeDestServices = "destServices"
+	typeExternalHost = "externalHost"
+	typeFloat        = "float"
+	typeInt          = "int"
+	typeOperations   = "operations"
+	typeResponses    = "responses"
+	typeSaturation   = "saturation"
+	typeServiceEntry = "serviceEntry"
+	typeString       = "string"
+	typeStringMap    = "stringMap"
+)
+
+func newMetadata(md graph.Metadata) (Metadata, error) {
+	if len(md) == 0 {
+		return nil, nil
+	}
+
+	result := make(Metadata, len(md))
+	for k, v := range md {
+		var valueType string
+		switch v.(type) {
+		case bool:
+			valueType = typeBool
+		case map[string]bool:
+			valueType = typeBoolMap
+		case graph.DestServicesMetadata:
+			valueType = typeDestServices
+		case *graph.ExternalHost:
+			valueType = typeExternalHost
+		case float64:
+			valueType = typeFloat
+		case int:
+			valueType = typeInt
+		case graph.Operations:
+			valueType = typeOperations
+		case graph.Responses:
+			valueType = typeResponses
+		case graph.Saturation:
+			valueType = typeSaturation
+		case *graph.SEInfo:
+			valueType = typeServiceEntry
+		case string:
+			valueType = typeString
+		case map[string]string:
+			valueType = typeStringMap
+		default:
+			return nil, fmt.Errorf("unsupported type [%T] for metadata [%s]", v, k)
+		}
+		raw, err := json.Marshal(v)
+		if err != nil {
+			return nil, fmt.Errorf("metadata [%s]: %v", k, err)
+		}
+		result[k] = Value{Type: valueType, Value: raw}
+	}
+	return result, nil
+}
+
+func (md Metadata) graphMetadata() (graph.Metadata, error) {
+	result := graph.NewMetadata()
+	for k, v := range md {
+		var err error
+		switch v.Type {
+		case typeBool:
+			var val bool
+			err = json.Unmarshal(v.Value, &val)
+			result[k] = val
+		case typeBoolMap:
+			var val map[string]bool
+			err = json.Unmarshal(v.Value, &val)
+			result[k] = val
+		case typeDestServices:
+			var val graph.DestServicesMetadata
+			err = json.Unmarshal(v.Value, &val)
+			result[k] = val
+		case typeExternalHost:
+			var val *graph.ExternalHost
+			err = json.Unmarshal(v.Value, &val)
+			result[k] = val
+		case typeFloat:
+			var val float64
+			err = json.Unmarshal(v.Value, &val)
+			result[k] = val
+		case typeInt:
+			var val int
+			err = json.Unmarshal(v.Value, &val)
+			result[k] = val
+		case typeOperations:
+			var val graph.Operations
+			err = json.Unmarshal(v.Value, &val)
+			result[k] = val
+		case typeResponses:
+			var val graph.Responses
+			err = json.Unmarshal(v.Value, &val)
+			result[k] = val
+		case typeSaturation:
+			var val graph.Saturation
+			err = json.Unmarshal(v.Value, &val)
+			result[k] = val
+		case typeServiceEntry:
+			var val *graph.SEInfo
+			err = json.Unmarshal(v.Value, &val)
+			result[k] = val
+		case typeString:
+			var val string
+			err = json.Unmarshal(v.Value, &val)
+			result[k] = val
+		case typeStringMap:
+			var val map[string]string
+			err = json.Unmarshal(v.Value, &val)
+			result[k] = val
+		default:
+			err = fmt.Errorf("unsupported type [%s]", v.Type)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("metadata [%s]: %v", k, err)
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,188 @@
+package snapshot
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/kiali/kiali/config"
+	"github.com/kiali/kiali/graph"
+	"github.com/kiali/kiali/graph/config/cytoscape"
+)
+
+func newTestTrafficMap() graph.TrafficMap {
+	trafficMap := graph.NewTrafficMap()
+
+	ingress := graph.NewNode(graph.Unknown, "istio-system", "", "istio-system", "ingressgateway-unknown", "ingressgateway", graph.Unknown, graph.GraphTypeVersionedApp)
+	ingress.Metadata[graph.IsRoot] = true
+	ingress.Metadata[graph.CriticalPathDepth] = 1
+	productpage := graph.NewNode(graph.Unknown, "bookinfo", "productpage", "bookinfo", "productpage-v1", "productpage", "v1", graph.GraphTypeVersionedApp)
+	productpage.Metadata[graph.HasHealthConfig] = map[string]string{"health.kiali.io/rate": "400,10,20,http,inbound"}
+	productpage.Metadata[graph.DestServices] = graph.NewDestServicesMetadata().Add("productpage", graph.ServiceName{Cluster: graph.Unknown, Namespace: "bookinfo", Name: "productpage"})
+	productpage.Metadata[graph.SaturationKey] = graph.Saturation{CPU: graph.ResourceUsage{Usage: 0.45, Requests: 0.5}, Restarts: 1.0, DesiredReplicas: 1, AvailableReplicas: 1}
+	external := graph.NewNode(graph.Unknown, "bookinfo", "httpbin.org", "", "", "", "", graph.GraphTypeVersionedApp)
+	external.Metadata[graph.IsServiceEntry] = &graph.SEInfo{Hosts: []string{"httpbin.org"}, Location: "MESH_EXTERNAL", Namespace: "bookinfo"}
+	passthrough := graph.NewNode(graph.Unknown, "bookinfo", "PassthroughCluster", "", "", "", "", graph.GraphTypeVersionedApp)
+	passthrough.Metadata[graph.IsEgressCluster] = true
+	passthrough.Metadata[graph.IsExternalHost] = &graph.ExternalHost{EgressCluster: "PassthroughCluster", Host: "api.example.com", OutboundTrafficPolicy: "ALLOW_ANY"}
+
+	trafficMap[ingress.ID] = &ingress
+	trafficMap[productpage.ID] = &productpage
+	trafficMap[external.ID] = &external
+	trafficMap[passthrough.ID] = &passthrough
+
+	e := ingress.AddEdge(&productpage)
+	graph.AddToMetadata("http", 10.0, "200", "-", "productpage.bookinfo.svc.cluster.local", ingress.Metadata, productpage.Metadata, e.Metadata)
+	graph.AddToMetadata("http", 1.0, "503", "UH", "productpage.bookinfo.svc.cluster.local", ingress.Metadata, productpage.Metadata, e.Metadata)
+	e.Metadata[graph.ResponseTime] = 20.0
+	e.Metadata[graph.IsMTLS] = 100.0
+	e.Metadata[graph.IsCriticalPath] = true
+	e.Metadata[graph.OperationsKey] = graph.Operations{{Name: "GET /productpage", ErrorRate: 9.1, Rate: 11.0, ResponseTime: 25.0}}
+
+	e = productpage.AddEdge(&external)
+	graph.AddToMetadata("tcp", 150.0, "", "-", "httpbin.org", productpage.Metadata, external.Metadata, e.Metadata)
+
+	e = productpage.AddEdge(&passthrough)
+	graph.AddToMetadata("http", 2.0, "200", "-", "api.example.com", productpage.Metadata, passthrough.Metadata, e.Metadata)
+
+	return trafficMap
+}
+
+func newTestOptions() graph.Options {
+	o := graph.Options{ConfigVendor: graph.VendorCytoscape, TelemetryVendor: graph.VendorIstio}
+	o.BoxBy = graph.BoxByApp
+	o.ConfigOptions.Duration = 10 * time.Minute
+	o.ConfigOptions.GraphType = graph.GraphTypeVersionedApp
+	o.ConfigOptions.QueryTime = 1600000000
+	o.TelemetryOptions.Duration = 10 * time.Minute
+	o.TelemetryOptions.GraphType = graph.GraphTypeVersionedApp
+	o.TelemetryOptions.QueryTime = 1600000000
+	o.TelemetryOptions.Namespaces = graph.NamespaceInfoMap{
+		"istio-system": {Name: "istio-system", Duration: 10 * time.Minute, IsIstio: true},
+		"bookinfo":     {Name: "bookinfo", Duration: 10 * time.Minute},
+	}
+	o.Find = "rt > 10"
+	return o
+}
+
+func TestSnapshotRoundTrip(t *testing.T) {
+	assert := assert.New(t)
+	config.Set(config.NewConfig())
+
+	trafficMap := newTestTrafficMap()
+	o := newTestOptions()
+
+	s, err := NewSnapshot("incident", trafficMap, o)
+	assert.NoError(err)
+	assert.Equal("incident", s.Name)
+	assert.Equal(int64(600), s.Duration)
+	assert.Equal(graph.GraphTypeVersionedApp, s.GraphType)
+	assert.Equal([]string{"bookinfo", "istio-system"}, s.Namespaces)
+	assert.Equal(int64(1600000000), s.QueryTime)
+	assert.Len(s.Nodes, 4)
+	assert.Len(s.Edges, 3)
+
+	// persist and restore through JSON, as the stores do
+	data, err := json.Marshal(s)
+	assert.NoError(err)
+	restored := &Snapshot{}
+	assert.NoError(json.Unmarshal(data, restored))
+
+	restoredMap, err := restored.TrafficMap()
+	assert.NoError(err)
+	assert.Equal(trafficMap, restoredMap)
+
+	restoredOptions := restored.GraphOptions()
+	assert.Equal(o.BoxBy, restoredOptions.BoxBy)
+	assert.Equal(o.Find, restoredOptions.Find)
+	assert.Equal(o.TelemetryOptions.Namespaces, restoredOptions.TelemetryOptions.Namespaces)
+	assert.Equal(o.ConfigOptions, restoredOptions.ConfigOptions)
+
+	// the replayed config is the same as the config of the original traffic map
+	expected := cytoscape.NewConfig(trafficMap, o.ConfigOptions)
+	actual := cytoscape.NewConfig(restoredMap, restoredOptions.ConfigOptions)
+	assert.Equal(expected, actual)
+}
+
+func TestSnapshotUnsupportedMetadata(t *testing.T) {
+	assert := assert.New(t)
+
+	trafficMap := newTestTrafficMap()
+	for _, n := range trafficMap {
+		n.Metadata["unsupported"] = []int{1}
+		break
+	}
+
+	_, err := NewSnapshot("", trafficMap, newTestOptions())
+	assert.Error(err)
+
+	s := &Snapshot{
+		Nodes: []Node{{ID: "a", Metadata: Metadata{"unsupported": Value{Type: "unknown", Value: []byte("1")}}}},
+	}
+	_, err = s.TrafficMap()
+	assert.Error(err)
+
+	s = &Snapshot{
+		Nodes: []Node{{ID: "a"}},
+		Edges: []Edge{{Source: "a", Dest: "b"}},
+	}
+	_, err = s.TrafficMap()
+	assert.Error(err)
+}
+
+func TestFileStore(t *testing.T) {
+	assert := assert.New(t)
+
+	dir, err := ioutil.TempDir("", "graph-snapshots")
+	assert.NoError(err)
+	defer os.RemoveAll(dir)
+
+	store, err := NewStore(config.GraphSnapshotsConfig{Path: filepath.Join(dir, "store"), Store: StoreFilesystem})
+	assert.NoError(err)
+
+	infos, err := store.List()
+	assert.NoError(err)
+	assert.Empty(infos)
+
+	first, err := NewSnapshot("first", newTestTrafficMap(), newTestOptions())
+	assert.NoError(err)
+	second, err := NewSnapshot("second", newTestTrafficMap(), newTestOptions())
+	assert.NoError(err)
+	second.Created = first.Created.Add(time.Second)
+	assert.NoError(store.Save(second))
+	assert.NoError(store.Save(first))
+
+	infos, err = store.List()
+	assert.NoError(err)
+	assert.Equal([]string{"first", "second"}, []string{infos[0].Name, infos[1].Name})
+	assert.Equal(first.Namespaces, infos[0].Namespaces)
+
+	s, err := store.Get(first.ID)
+	assert.NoError(err)
+	assert.Equal(first.ID, s.ID)
+	assert.Len(s.Nodes, 4)
+
+	assert.NoError(store.Delete(first.ID))
+	_, err = store.Get(first.ID)
+	assert.Equal(ErrNotFound, err)
+	assert.Equal(ErrNotFound, store.Delete(first.ID))
+
+	// IDs are never used as paths
+	_, err = store.Get("../store/" + second.ID)
+	assert.Equal(ErrNotFound, err)
+	assert.Equal(ErrNotFound, store.Delete("../store/"+second.ID))
+	second.ID = "../escape"
+	assert.Error(store.Save(second))
+
+	infos, err = store.List()
+	assert.NoError(err)
+	assert.Len(infos, 1)
+
+	_, err = NewStore(config.GraphSnapshotsConfig{Path: dir, Store: "s3"})
+	assert.Error(err)
+}
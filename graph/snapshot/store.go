@@ -0,0 +1,166 @@
+package snapshot
+
+// Store.go provides the pluggable persistence of snapshots.
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/kiali/kiali/config"
+)
+
+// The supported stores
+const (
+	StoreFilesystem string = "filesystem"
+)
+
+// ErrNotFound is returned when a requested snapshot does not exist
+var ErrNotFound = errors.New("snapshot not found")
+
+// idRegexp matches the valid snapshot IDs, it protects the stores from unsafe IDs (e.g. file paths)
+var idRegexp = regexp.MustCompile(`^[a-zA-Z0-9-]+$`)
+
+// Store persists snapshots
+type Store interface {
+	// Delete removes the snapshot, returns ErrNotFound if it does not exist
+	Delete(id string) error
+	// Get returns the snapshot, returns ErrNotFound if it does not exist
+	Get(id string) (*Snapshot, error)
+	// List returns the Info of every stored snapshot, ordered by creation time
+	List() ([]Info, error)
+	// Save persists the snapshot
+	Save(s *Snapshot) error
+}
+
+// NewStore returns the store configured for graph snapshots
+func NewStore(conf config.GraphSnapshotsConfig) (Store, error) {
+	switch conf.Store {
+	case "", StoreFilesystem:
+		return NewFileStore(conf.Path)
+	default:
+		return nil, fmt.Errorf("graph snapshot store [%s] not supported", conf.Store)
+	}
+}
+
+// FileStore stores each snapshot as a JSON file, named by snapshot ID, in a local directory
+type FileStore struct {
+	dir string
+}
+
+// NewFileStore returns a FileStore for dir, the directory is created if necessary
+func NewFileStore(dir string) (*FileStore, error) {
+	if dir == "" {
+		return nil, errors.New("graph snapshot store path is not set")
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	return &FileStore{dir: dir}, nil
+}
+
+// Delete implements Store
+func (fs *FileStore) Delete(id string) error {
+	if !idRegexp.MatchString(id) {
+		return ErrNotFound
+	}
+	if err := os.Remove(fs.path(id)); err != nil {
+		if os.IsNotExist(err) {
+			return ErrNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+// Get implements Store
+func (fs *FileStore) Get(id string) (*Snapshot, error) {
+	s := &Snapshot{}
+	if err := fs.read(id, s); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// List implements Store
+func (fs *FileStore) List() ([]Info, error) {
+	files, err := ioutil.ReadDir(fs.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	infos := []Info{}
+	for _, f := range files {
+		if f.IsDir() || !strings.HasSuffix(f.Name(), ".json") {
+			continue
+		}
+		info := Info{}
+		// unmarshal only the Info fields of the snapshot
+		if err := fs.read(strings.TrimSuffix(f.Name(), ".json"), &info); err != nil {
+			// a snapshot may be deleted while listing
+			if err == ErrNotFound {
+				continue
+			}
+			return nil, err
+		}
+		infos = append(infos, info)
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].Created.Before(infos[j].Created)
+	})
+
+	return infos, nil
+}
+
+// Save implements Store. The snapshot is written to a temporary file first, so that readers never see a
+// partial snapshot.
+func (fs *FileStore) Save(s *Snapshot) error {
+	if !idRegexp.MatchString(s.ID) {
+		return fmt.Errorf("invalid snapshot id [%s]", s.ID)
+	}
+
+	data, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(fs.dir, ".snapshot-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // no-op after the rename
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), fs.path(s.ID))
+}
+
+func (fs *FileStore) path(id string) string {
+	return filepath.Join(fs.dir, id+".json")
+}
+
+// read unmarshals the snapshot into v, an invalid ID is reported as ErrNotFound
+func (fs *FileStore) read(id string, v interface{}) error {
+	if !idRegexp.MatchString(id) {
+		return ErrNotFound
+	}
+	data, err := ioutil.ReadFile(fs.path(id))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return ErrNotFound
+		}
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
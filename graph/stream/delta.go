@@ -0,0 +1,86 @@
+package stream
+
+// Delta.go provides the comparison of two Cytoscape configs, used to push only graph changes to subscribers.
+
+import (
+	"reflect"
+
+	"github.com/kiali/kiali/graph/config/cytoscape"
+)
+
+// Delta holds the node and edge changes between two refreshes of a graph. Added and updated elements are
+// provided in full, removed elements by ID.
+type Delta struct {
+	Timestamp int64      `json:"timestamp"`
+	Duration  int64      `json:"duration"`
+	GraphType string     `json:"graphType"`
+	Nodes     NodesDelta `json:"nodes"`
+	Edges     EdgesDelta `json:"edges"`
+}
+
+// NodesDelta holds the node changes
+type NodesDelta struct {
+	Added   []*cytoscape.NodeWrapper `json:"added,omitempty"`
+	Removed []string                 `json:"removed,omitempty"`
+	Updated []*cytoscape.NodeWrapper `json:"updated,omitempty"`
+}
+
+// EdgesDelta holds the edge changes
+type EdgesDelta struct {
+	Added   []*cytoscape.EdgeWrapper `json:"added,omitempty"`
+	Removed []string                 `json:"removed,omitempty"`
+	Updated []*cytoscape.EdgeWrapper `json:"updated,omitempty"`
+}
+
+// NewDelta returns the changes from prev to config. Elements are matched by ID, the Cytoscape element IDs are
+// stable between refreshes.
+func NewDelta(prev, config cytoscape.Config) Delta {
+	delta := Delta{
+		Timestamp: config.Timestamp,
+		Duration:  config.Duration,
+		GraphType: config.GraphType,
+	}
+
+	prevNodes := make(map[string]*cytoscape.NodeData, len(prev.Elements.Nodes))
+	for _, nw := range prev.Elements.Nodes {
+		prevNodes[nw.Data.ID] = nw.Data
+	}
+	for _, nw := range config.Elements.Nodes {
+		prevNode, found := prevNodes[nw.Data.ID]
+		switch {
+		case !found:
+			delta.Nodes.Added = append(delta.Nodes.Added, nw)
+		case !reflect.DeepEqual(prevNode, nw.Data):
+			delta.Nodes.Updated = append(delta.Nodes.Updated, nw)
+		}
+		delete(prevNodes, nw.Data.ID)
+	}
+	// iterate prev to report removed elements in config order
+	for _, nw := range prev.Elements.Nodes {
+		if _, removed := prevNodes[nw.Data.ID]; removed {
+			delta.Nodes.Removed = append(delta.Nodes.Removed, nw.Data.ID)
+		}
+	}
+
+	prevEdges := make(map[string]*cytoscape.EdgeData, len(prev.Elements.Edges))
+	for _, ew := range prev.Elements.Edges {
+		prevEdges[ew.Data.ID] = ew.Data
+	}
+	for _, ew := range config.Elements.Edges {
+		prevEdge, found := prevEdges[ew.Data.ID]
+		switch {
+		case !found:
+			delta.Edges.Added = append(delta.Edges.Added, ew)
+		case !reflect.DeepEqual(prevEdge, ew.Data):
+			delta.Edges.Updated = append(delta.Edges.Updated, ew)
+		}
+		delete(prevEdges, ew.Data.ID)
+	}
+	for _, ew := range prev.Elements.Edges {
+		if _, removed := prevEdges[ew.Data.ID]; removed {
+			delta.Edges.Removed = append(delta.Edges.Removed, ew.Data.ID)
+		}
+	}
+
+	return delta
+}
@@ -0,0 +1,225 @@
+// Package stream provides graphs that are regenerated on an interval and pushed to subscribers as a
+// stream of events. The first event for a subscriber is the full graph, each following event holds only
+// the node and edge changes of a refresh.
+//
+// A stream is identified by a key, typically built from the requesting user and the graph options (see
+// NewKey). Every subscriber of a key shares a single stream, and therefore a single graph computation per
+// refresh. A stream is started by its first subscriber and stopped when its last subscriber leaves.
+//
+// Streams currently support only the Cytoscape config vendor.
+package stream
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/kiali/kiali/graph"
+	"github.com/kiali/kiali/graph/config/cytoscape"
+	"github.com/kiali/kiali/log"
+)
+
+// The stream event types
+const (
+	EventTypeDelta string = "delta" // Data is a Delta
+	EventTypeError string = "error" // Data is the error message
+	EventTypeGraph string = "graph" // Data is the full cytoscape.Config
+)
+
+// subscriberBuffer is the number of events a subscriber can fall behind before it is dropped
+const subscriberBuffer = 8
+
+// Event is a single stream event
+type Event struct {
+	ID   uint64 // the refresh sequence number of a graph or delta event, 0 for error events
+	Type string
+	Data interface{}
+}
+
+// GenerateFunc generates the streamed graph, for the time range ending at queryTime
+type GenerateFunc func(queryTime int64) cytoscape.Config
+
+// Subscription delivers the events of a stream until it is closed. The Events channel is closed when
+// the subscription is closed, or when the subscriber falls too far behind the stream.
+type Subscription struct {
+	Events <-chan Event
+
+	events      chan Event
+	initialized bool // true when the subscriber has received the full graph
+	stream      *stream
+}
+
+type stream struct {
+	config      *cytoscape.Config // the latest graph, nil until the first refresh completes
+	generate    GenerateFunc
+	id          uint64 // the latest refresh sequence number
+	interval    time.Duration
+	key         string
+	mutex       sync.Mutex
+	stop        chan struct{}
+	subscribers map[*Subscription]bool
+}
+
+var (
+	streams      = make(map[string]*stream)
+	streamsMutex sync.Mutex
+)
+
+// NewKey returns the stream key for the user token and the graph request query params. The queryTime
+// param is ignored, a stream always generates the graph for the current time.
+func NewKey(token string, params url.Values) string {
+	keyParams := url.Values{}
+	for k, v := range params {
+		if k != "queryTime" {
+			keyParams[k] = v
+		}
+	}
+	// hash the token, the key should not expose it
+	tokenHash := sha256.Sum256([]byte(token))
+	return fmt.Sprintf("%x?%s", tokenHash, keyParams.Encode())
+}
+
+// Subscribe subscribes to the stream for key, starting the stream if necessary. The interval and generate
+// func are used only when the stream is started. lastEventID is the ID of the last event received by a
+// resuming subscriber, or 0. If it matches the latest refresh the full graph is not sent again.
+func Subscribe(key string, interval time.Duration, generate GenerateFunc, lastEventID uint64) *Subscription {
+	streamsMutex.Lock()
+	defer streamsMutex.Unlock()
+
+	s, found := streams[key]
+	if !found {
+		s = &stream{
+			generate:    generate,
+			interval:    interval,
+			key:         key,
+			stop:        make(chan struct{}),
+			subscribers: make(map[*Subscription]bool),
+		}
+		streams[key] = s
+		go s.run()
+		log.Debugf("Started graph stream [%s]", key)
+	}
+
+	events := make(chan Event, subscriberBuffer)
+	sub := &Subscription{
+		Events: events,
+		events: events,
+		stream: s,
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.config != nil {
+		if lastEventID != s.id {
+			sub.events <- Event{ID: s.id, Type: EventTypeGraph, Data: *s.config}
+		}
+		sub.initialized = true
+	}
+	s.subscribers[sub] = true
+
+	return sub
+}
+
+// Close ends the subscription, stopping the stream if there are no remaining subscribers
+func (sub *Subscription) Close() {
+	streamsMutex.Lock()
+	defer streamsMutex.Unlock()
+
+	s := sub.stream
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.subscribers[sub] {
+		delete(s.subscribers, sub)
+		close(sub.events)
+	}
+	if len(s.subscribers) == 0 && streams[s.key] == s {
+		delete(streams, s.key)
+		close(s.stop)
+		log.Debugf("Stopped graph stream [%s]", s.key)
+	}
+}
+
+func (s *stream) run() {
+	s.refresh()
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+			s.refresh()
+		}
+	}
+}
+
+// refresh generates the graph and pushes it to the subscribers, as a full graph or as a delta
+func (s *stream) refresh() {
+	config, err := s.safeGenerate(time.Now().Unix())
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if err != nil {
+		log.Debugf("Failed to refresh graph stream [%s]: %v", s.key, err)
+		s.broadcast(func(_ *Subscription) Event {
+			return Event{Type: EventTypeError, Data: err.Error()}
+		})
+		return
+	}
+
+	var delta Delta
+	if s.config != nil {
+		delta = NewDelta(*s.config, config)
+	}
+	s.config = &config
+	s.id++
+
+	s.broadcast(func(sub *Subscription) Event {
+		if !sub.initialized {
+			sub.initialized = true
+			return Event{ID: s.id, Type: EventTypeGraph, Data: config}
+		}
+		return Event{ID: s.id, Type: EventTypeDelta, Data: delta}
+	})
+}
+
+// broadcast sends an event to each subscriber. A subscriber that is too far behind is dropped, it can
+// subscribe again to receive the full graph. Must be called with the stream mutex held.
+func (s *stream) broadcast(newEvent func(sub *Subscription) Event) {
+	for sub := range s.subscribers {
+		select {
+		case sub.events <- newEvent(sub):
+		default:
+			log.Debugf("Dropping slow subscriber of graph stream [%s]", s.key)
+			delete(s.subscribers, sub)
+			close(sub.events)
+		}
+	}
+}
+
+// safeGenerate generates the graph, converting a generation panic (see graph.Panic) to an error
+func (s *stream) safeGenerate(queryTime int64) (config cytoscape.Config, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			switch e := r.(type) {
+			case graph.Response:
+				err = fmt.Errorf("%s", e.Message)
+			case error:
+				err = e
+			case func() string:
+				err = fmt.Errorf("%s", e())
+			default:
+				err = fmt.Errorf("%v", r)
+			}
+		}
+	}()
+
+	return s.generate(queryTime), nil
+}
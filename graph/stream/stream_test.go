@@ -0,0 +1,155 @@
+package stream
+
+import (
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/kiali/kiali/graph"
+	"github.com/kiali/kiali/graph/config/cytoscape"
+)
+
+func newTestConfig(nodeIDs []string, edgeRates map[string]string) cytoscape.Config {
+	config := cytoscape.Config{GraphType: graph.GraphTypeWorkload}
+	for _, id := range nodeIDs {
+		config.Elements.Nodes = append(config.Elements.Nodes, &cytoscape.NodeWrapper{Data: &cytoscape.NodeData{ID: id, Workload: id}})
+	}
+	for id, rate := range edgeRates {
+		config.Elements.Edges = append(config.Elements.Edges, &cytoscape.EdgeWrapper{Data: &cytoscape.EdgeData{
+			ID:      id,
+			Traffic: cytoscape.ProtocolTraffic{Protocol: "http", Rates: map[string]string{"http": rate}},
+		}})
+	}
+	return config
+}
+
+func TestNewDelta(t *testing.T) {
+	assert := assert.New(t)
+
+	prev := newTestConfig([]string{"a", "b", "c"}, map[string]string{"ab": "1.00", "bc": "2.00"})
+	config := newTestConfig([]string{"a", "b", "d"}, map[string]string{"ab": "1.00", "bd": "3.00"})
+	config.Elements.Nodes[1].Data.IsDead = true
+	config.Timestamp = 100
+
+	delta := NewDelta(prev, config)
+	assert.Equal(int64(100), delta.Timestamp)
+	assert.Len(delta.Nodes.Added, 1)
+	assert.Equal("d", delta.Nodes.Added[0].Data.ID)
+	assert.Len(delta.Nodes.Updated, 1)
+	assert.Equal("b", delta.Nodes.Updated[0].Data.ID)
+	assert.Equal([]string{"c"}, delta.Nodes.Removed)
+	assert.Len(delta.Edges.Added, 1)
+	assert.Equal("bd", delta.Edges.Added[0].Data.ID)
+	assert.Empty(delta.Edges.Updated)
+	assert.Equal([]string{"bc"}, delta.Edges.Removed)
+
+	delta = NewDelta(config, config)
+	assert.Empty(delta.Nodes.Added)
+	assert.Empty(delta.Nodes.Updated)
+	assert.Empty(delta.Nodes.Removed)
+	assert.Empty(delta.Edges.Added)
+	assert.Empty(delta.Edges.Updated)
+	assert.Empty(delta.Edges.Removed)
+}
+
+func TestNewKey(t *testing.T) {
+	assert := assert.New(t)
+
+	params := url.Values{"namespaces": []string{"bookinfo"}, "queryTime": []string{"100"}}
+	otherParams := url.Values{"namespaces": []string{"bookinfo"}, "queryTime": []string{"200"}}
+
+	assert.Equal(NewKey("token", params), NewKey("token", otherParams))
+	assert.NotEqual(NewKey("token", params), NewKey("other-token", params))
+	assert.NotContains(NewKey("token", params), "token")
+}
+
+func nextEvent(t *testing.T, sub *Subscription) Event {
+	select {
+	case event, ok := <-sub.Events:
+		if !ok {
+			t.Fatal("Subscription closed unexpectedly")
+		}
+		return event
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for a stream event")
+	}
+	return Event{}
+}
+
+func TestSubscribe(t *testing.T) {
+	assert := assert.New(t)
+
+	var mutex sync.Mutex
+	generated := 0
+	generate := func(queryTime int64) cytoscape.Config {
+		mutex.Lock()
+		defer mutex.Unlock()
+		generated++
+		if generated%2 == 0 {
+			return newTestConfig([]string{"a", "b"}, map[string]string{"ab": "1.00"})
+		}
+		return newTestConfig([]string{"a"}, map[string]string{})
+	}
+
+	// the initial refresh is made when the stream starts, further refreshes are made by the test
+	sub1 := Subscribe("test", time.Hour, generate, 0)
+	event := nextEvent(t, sub1)
+	assert.Equal(EventTypeGraph, event.Type)
+	assert.Equal(uint64(1), event.ID)
+
+	streamsMutex.Lock()
+	s := streams["test"]
+	streamsMutex.Unlock()
+
+	// a second subscriber shares the stream, it receives the latest graph and then the same deltas
+	sub2 := Subscribe("test", time.Hour, generate, 0)
+	event = nextEvent(t, sub2)
+	assert.Equal(EventTypeGraph, event.Type)
+	assert.Equal(uint64(1), event.ID)
+
+	s.refresh()
+	event1 := nextEvent(t, sub1)
+	event2 := nextEvent(t, sub2)
+	assert.Equal(EventTypeDelta, event1.Type)
+	assert.Equal(event1, event2)
+	assert.Len(event1.Data.(Delta).Nodes.Added, 1)
+	assert.Equal(2, generated)
+
+	// a resuming subscriber at the latest refresh does not receive the full graph again
+	sub2.Close()
+	sub3 := Subscribe("test", time.Hour, generate, event1.ID)
+	s.refresh()
+	event = nextEvent(t, sub3)
+	assert.Equal(EventTypeDelta, event.Type)
+	assert.Equal(event1.ID+1, event.ID)
+	assert.Equal([]string{"b"}, event.Data.(Delta).Nodes.Removed)
+
+	sub1.Close()
+	sub3.Close()
+	_, ok := <-sub3.Events
+	assert.False(ok)
+
+	streamsMutex.Lock()
+	assert.Empty(streams)
+	streamsMutex.Unlock()
+}
+
+func TestSubscribeError(t *testing.T) {
+	assert := assert.New(t)
+
+	generate := func(queryTime int64) cytoscape.Config {
+		graph.Error("prometheus is down")
+		return cytoscape.Config{}
+	}
+
+	sub := Subscribe("error", time.Hour, generate, 0)
+	defer sub.Close()
+
+	event := nextEvent(t, sub)
+	assert.Equal(EventTypeError, event.Type)
+	assert.Equal(uint64(0), event.ID)
+	assert.Equal("prometheus is down", event.Data)
+}
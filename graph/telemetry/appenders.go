@@ -0,0 +1,46 @@
+package telemetry
+
+import (
+	"fmt"
+
+	"github.com/kiali/kiali/graph"
+	"github.com/kiali/kiali/log"
+	"github.com/kiali/kiali/prometheus/internalmetrics"
+)
+
+// UnsupportedAppenders are the appenders, by name, not supported by a telemetry vendor. An appender mapped to
+// true is replaced by the vendor itself (e.g. the vendor reports the response times), requesting it is not an error.
+type UnsupportedAppenders map[string]bool
+
+// FilterAppenders returns the appenders supported by the telemetry vendor. When all appenders are requested the
+// unsupported appenders are skipped, an explicitly requested unsupported appender is a bad request.
+func FilterAppenders(o graph.TelemetryOptions, vendor string, appenders []graph.Appender, unsupported UnsupportedAppenders) []graph.Appender {
+	if !o.Appenders.All {
+		for _, appenderName := range o.Appenders.AppenderNames {
+			if replaced, ok := unsupported[appenderName]; ok && !replaced {
+				graph.BadRequest(fmt.Sprintf("Appender [%s] is not supported by telemetryVendor [%s]", appenderName, vendor))
+			}
+		}
+	}
+
+	supported := []graph.Appender{}
+	for _, a := range appenders {
+		if _, ok := unsupported[a.Name()]; ok {
+			log.Tracef("Skipping appender [%s], not supported by telemetryVendor [%s]", a.Name(), vendor)
+			continue
+		}
+		supported = append(supported, a)
+	}
+	return supported
+}
+
+// ApplyFinalizers runs the finalizer appenders on the final traffic map
+func ApplyFinalizers(trafficMap graph.TrafficMap, finalizers []graph.Appender, globalInfo *graph.AppenderGlobalInfo) {
+	for _, f := range finalizers {
+		finalizerTimer := internalmetrics.GetGraphAppenderTimePrometheusTimer(f.Name())
+		debugTimer := globalInfo.Debug.Time(graph.DebugPhaseAppender, f.Name(), "")
+		f.AppendGraph(trafficMap, globalInfo, nil)
+		finalizerTimer.ObserveDuration()
+		debugTimer()
+	}
+}
@@ -212,3 +212,101 @@ func checkNodeType(expected string, n *graph.Node) {
 		graph.Error(fmt.Sprintf("Expected nodeType [%s] for node [%+v]", expected, n))
 	}
 }
+
+// ReduceToNode returns the TrafficMap reduced to the traffic of the node: the edges from and to the
+// node and, when service nodes are injected, the edges to the service nodes leading to the node. For a
+// node graph depth greater than 1 the reached nodes are expanded the same way, hop after hop, except for
+// the nodes that are not expandable (see IsExpandable). It is used by the telemetry vendors building a
+// node graph from a namespaces graph.
+func ReduceToNode(trafficMap graph.TrafficMap, target graph.Node, o graph.TelemetryOptions) graph.TrafficMap {
+	reached := make(map[string]bool)
+	frontier := make(map[string]bool)
+	for _, n := range trafficMap {
+		if IsNode(n, target) {
+			reached[n.ID] = true
+			frontier[n.ID] = true
+		}
+	}
+
+	edges := make(map[*graph.Edge]bool)
+	for hop := 1; hop <= o.NodeOptions.Depth || hop == 1; hop++ {
+		next := make(map[string]bool)
+		keep := func(e *graph.Edge) {
+			edges[e] = true
+			for _, n := range []*graph.Node{e.Source, e.Dest} {
+				if !reached[n.ID] {
+					reached[n.ID] = true
+					if IsExpandable(n, o) {
+						next[n.ID] = true
+					}
+				}
+			}
+		}
+		for _, n := range trafficMap {
+			for _, e := range n.Edges {
+				if edges[e] {
+					continue
+				}
+				if frontier[e.Source.ID] || frontier[e.Dest.ID] {
+					keep(e)
+					continue
+				}
+				if e.Dest.NodeType == graph.NodeTypeService {
+					for _, svcEdge := range e.Dest.Edges {
+						if frontier[svcEdge.Dest.ID] {
+							keep(e)
+							break
+						}
+					}
+				}
+			}
+		}
+		frontier = next
+	}
+
+	result := graph.NewTrafficMap()
+	for _, n := range trafficMap {
+		keptEdges := []*graph.Edge{}
+		for _, e := range n.Edges {
+			if edges[e] {
+				keptEdges = append(keptEdges, e)
+				result[e.Dest.ID] = e.Dest
+			}
+		}
+		n.Edges = keptEdges
+		if len(keptEdges) > 0 || IsNode(n, target) {
+			result[n.ID] = n
+		}
+	}
+	return result
+}
+
+// IsExpandable returns true if a node reached by a multi-hop node graph is expanded to its own callers
+// and callees. As in a namespaces graph, only the nodes of the requested namespaces are expanded.
+func IsExpandable(n *graph.Node, o graph.TelemetryOptions) bool {
+	switch {
+	case n.NodeType == graph.NodeTypeUnknown || n.Namespace == graph.Unknown:
+		return false
+	case n.Metadata[graph.IsEgressCluster] == true:
+		return false
+	default:
+		return !isOutside(n, o.Namespaces)
+	}
+}
+
+// IsNode returns true if the node is, or is part of, the target node of a node graph
+func IsNode(n *graph.Node, target graph.Node) bool {
+	if n.Namespace != target.Namespace {
+		return false
+	}
+	switch target.NodeType {
+	case graph.NodeTypeApp:
+		return n.NodeType == graph.NodeTypeApp && n.App == target.App && (!graph.IsOKVersion(target.Version) || n.Version == target.Version)
+	case graph.NodeTypeService:
+		return n.NodeType == graph.NodeTypeService && n.Service == target.Service
+	case graph.NodeTypeWorkload:
+		return n.Workload == target.Workload
+	default:
+		return false
+	}
+}
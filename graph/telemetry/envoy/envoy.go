@@ -0,0 +1,443 @@
+// Package envoy provides the Envoy implementation of graph/TelemetryProvider.
+package envoy
+
+// Envoy.go is responsible for generating TrafficMaps using the stats of the Envoy sidecar proxies, for
+// meshes where the Istio standard metrics are not available in Prometheus (e.g. telemetry v2 disabled, or
+// istio_requests_total dropped).  It implements the TelemetryVendor interface.
+//
+// The algorithm is two-pass:
+//   First Pass: Fetch the stats of the sidecar proxies of the requested namespaces through the Kubernetes
+//               API server pod proxy. The outbound cluster stats of a proxy, identified by the cluster name
+//               (e.g. outbound|9080||reviews.bookinfo.svc.cluster.local), provide the traffic from the
+//               proxy's workload to a service. The traffic of a service is apportioned to the workloads
+//               selected by the service by their share of the inbound traffic, as reported by their proxies.
+//
+//   Second Pass: Apply any requested appenders to alter or append to the graph. The appenders querying the
+//                Istio telemetry in Prometheus are not supported. Finalizer appenders are applied last,
+//                to the final graph.
+//
+// Limitations, compared to the Istio telemetry:
+//   - The stats reflect the current state of the proxies, the requested query time is not supported. The
+//     counters are turned into rates using samples retained from previous requests, see statsSampler.
+//   - Only the traffic sent by proxies in the requested namespaces is reported, the traffic from workloads
+//     outside of the requested namespaces, or without a sidecar, is not.
+//   - The request protocol is not reported by the cluster stats, request traffic is reported as HTTP.
+//   - Clusters are not reported, all nodes are in the unknown cluster.
+//
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kiali/kiali/config"
+	"github.com/kiali/kiali/graph"
+	"github.com/kiali/kiali/graph/telemetry"
+	"github.com/kiali/kiali/graph/telemetry/istio/appender"
+	"github.com/kiali/kiali/log"
+	"github.com/kiali/kiali/models"
+	"github.com/kiali/kiali/prometheus"
+	"github.com/kiali/kiali/prometheus/internalmetrics"
+)
+
+// promAppenders are the appenders querying the Istio telemetry in Prometheus, they are not supported
+var promAppenders = telemetry.UnsupportedAppenders{
+	appender.AggregateNodeAppenderName:  false,
+	appender.AnomalyAppenderName:        false,
+	appender.ExternalHostAppenderName:   false,
+	appender.OperationsAppenderName:     false,
+	appender.ResponseTimeAppenderName:   false,
+	appender.SecurityPolicyAppenderName: false,
+	appender.TCPConnectionsAppenderName: false,
+	appender.ThroughputAppenderName:     false,
+}
+
+// workloadStats holds the stats rates of a workload, summed over its proxies
+type workloadStats struct {
+	namespace string
+	name      string
+	app       string
+	version   string
+	labels    map[string]string
+	stats     *proxyStats
+}
+
+// destinationShare is a workload selected by a service, with its share of the service traffic
+type destinationShare struct {
+	workload *workloadStats
+	share    float64
+}
+
+// BuildNamespacesTrafficMap is required by the graph/TelemtryVendor interface. The Prometheus client
+// is not used, it is accepted to satisfy the interface.
+func BuildNamespacesTrafficMap(o graph.TelemetryOptions, client *prometheus.Client, globalInfo *graph.AppenderGlobalInfo) graph.TrafficMap {
+	log.Tracef("Build [%s] graph for [%d] namespaces [%v]", o.GraphType, len(o.Namespaces), o.Namespaces)
+
+	appenders, finalizers := parseAppenders(o)
+
+	workloads := []*workloadStats{}
+	services := []models.Service{}
+	for _, namespace := range o.Namespaces {
+		workloads = append(workloads, fetchWorkloadStats(namespace.Name, namespace.Duration, globalInfo)...)
+		services = append(services, fetchServices(namespace.Name, globalInfo)...)
+	}
+	destinations := buildDestinations(workloads, services)
+
+	trafficMap := graph.NewTrafficMap()
+	for _, namespace := range o.Namespaces {
+		log.Tracef("Build traffic map for namespace [%v]", namespace)
+		namespaceTrafficMap := buildNamespaceTrafficMap(namespace.Name, workloads, destinations, o)
+		namespaceInfo := graph.NewAppenderNamespaceInfo(namespace.Name)
+		for _, a := range appenders {
+			appenderTimer := internalmetrics.GetGraphAppenderTimePrometheusTimer(a.Name())
+			debugTimer := globalInfo.Debug.Time(graph.DebugPhaseAppender, a.Name(), namespaceInfo.Namespace)
+			a.AppendGraph(namespaceTrafficMap, globalInfo, namespaceInfo)
+			appenderTimer.ObserveDuration()
+			debugTimer()
+		}
+		telemetry.MergeTrafficMaps(trafficMap, namespace.Name, namespaceTrafficMap)
+	}
+
+	// The appenders can add/remove/alter nodes. After the manipulations are complete
+	// we can make some final adjustments:
+	// - mark the outsiders (i.e. nodes not in the requested namespaces)
+	// - mark the insider traffic generators (i.e. inside the namespace and only outgoing edges)
+	telemetry.MarkOutsideOrInaccessible(trafficMap, o)
+	telemetry.MarkTrafficGenerators(trafficMap)
+
+	if graph.GraphTypeService == o.GraphType {
+		trafficMap = telemetry.ReduceToServiceGraph(trafficMap)
+	}
+
+	telemetry.ApplyFinalizers(trafficMap, finalizers, globalInfo)
+
+	return trafficMap
+}
+
+// BuildNodeTrafficMap is required by the graph/TelemtryVendor interface. The Prometheus client
+// is not used, it is accepted to satisfy the interface.
+func BuildNodeTrafficMap(o graph.TelemetryOptions, client *prometheus.Client, globalInfo *graph.AppenderGlobalInfo) graph.TrafficMap {
+	if o.NodeOptions.Aggregate != "" {
+		graph.BadRequest(fmt.Sprintf("Aggregate node graphs are not supported by telemetryVendor [%s]", graph.VendorEnvoy))
+	}
+
+	n := graph.NewNode(o.NodeOptions.Cluster, o.NodeOptions.Namespace, o.NodeOptions.Service, o.NodeOptions.Namespace, o.NodeOptions.Workload, o.NodeOptions.App, o.NodeOptions.Version, o.GraphType)
+
+	log.Tracef("Build graph for node [%+v]", n)
+
+	appenders, finalizers := parseAppenders(o)
+
+	// the namespaces other than the node's namespace are requested only by multi-hop node graphs
+	workloads := []*workloadStats{}
+	services := []models.Service{}
+	for _, namespace := range o.Namespaces {
+		workloads = append(workloads, fetchWorkloadStats(namespace.Name, namespace.Duration, globalInfo)...)
+		services = append(services, fetchServices(namespace.Name, globalInfo)...)
+	}
+	destinations := buildDestinations(workloads, services)
+
+	trafficMap := graph.NewTrafficMap()
+	for _, namespace := range o.Namespaces {
+		telemetry.MergeTrafficMaps(trafficMap, namespace.Name, buildNamespaceTrafficMap(namespace.Name, workloads, destinations, o))
+	}
+	trafficMap = telemetry.ReduceToNode(trafficMap, n, o)
+
+	namespaceInfo := graph.NewAppenderNamespaceInfo(o.NodeOptions.Namespace)
+
+	for _, a := range appenders {
+		appenderTimer := internalmetrics.GetGraphAppenderTimePrometheusTimer(a.Name())
+		debugTimer := globalInfo.Debug.Time(graph.DebugPhaseAppender, a.Name(), namespaceInfo.Namespace)
+		a.AppendGraph(trafficMap, globalInfo, namespaceInfo)
+		appenderTimer.ObserveDuration()
+		debugTimer()
+	}
+
+	// The appenders can add/remove/alter nodes. After the manipulations are complete
+	// we can make some final adjustments:
+	// - mark the outsiders (i.e. nodes not in the requested namespaces)
+	// - mark the traffic generators
+	telemetry.MarkOutsideOrInaccessible(trafficMap, o)
+	telemetry.MarkTrafficGenerators(trafficMap)
+
+	telemetry.ApplyFinalizers(trafficMap, finalizers, globalInfo)
+
+	return trafficMap
+}
+
+// parseAppenders returns the requested appenders supported by the Envoy telemetry, and the finalizers
+func parseAppenders(o graph.TelemetryOptions) ([]graph.Appender, []graph.Appender) {
+	appenders, finalizers := appender.ParseAppenders(o)
+	return telemetry.FilterAppenders(o, graph.VendorEnvoy, appenders, promAppenders), finalizers
+}
+
+// fetchWorkloadStats returns the workloads of the namespace with the stats rates of their sidecar proxies.
+// A proxy whose stats can not be fetched is skipped, e.g. a proxy that is starting up.
+func fetchWorkloadStats(namespace string, duration time.Duration, gi *graph.AppenderGlobalInfo) []*workloadStats {
+	workloads, err := gi.Business.Workload.GetWorkloads(namespace)
+	graph.CheckError(err)
+
+	now := time.Now()
+	result := make([]*workloadStats, 0, len(workloads))
+	wg := sync.WaitGroup{}
+	mutex := sync.Mutex{}
+
+	for _, w := range workloads {
+		ws := newWorkloadStats(namespace, w.Name, w.Labels)
+		result = append(result, ws)
+
+		for _, pod := range w.Pods {
+			if len(pod.IstioContainers) == 0 || pod.Status != "Running" {
+				continue
+			}
+			wg.Add(1)
+			go func(pod string, ws *workloadStats) {
+				defer wg.Done()
+
+				data, err := gi.Business.ProxyStatus.GetProxyStats(namespace, pod)
+				if err != nil {
+					log.Warningf("Skipping proxy stats of pod [%s] in namespace [%s]: %v", pod, namespace, err)
+					return
+				}
+				ps, err := parseProxyStats(data)
+				if err != nil {
+					log.Warningf("Skipping proxy stats of pod [%s] in namespace [%s], invalid stats: %v", pod, namespace, err)
+					return
+				}
+				rates := sampler.rates(fmt.Sprintf("%s/%s", namespace, pod), ps, now, duration)
+
+				mutex.Lock()
+				defer mutex.Unlock()
+				ws.stats.add(rates)
+			}(pod.Name, ws)
+		}
+	}
+	wg.Wait()
+
+	return result
+}
+
+func fetchServices(namespace string, gi *graph.AppenderGlobalInfo) []models.Service {
+	serviceDefinitionList, err := gi.Business.Svc.GetServiceDefinitionList(namespace)
+	graph.CheckError(err)
+
+	services := make([]models.Service, 0, len(serviceDefinitionList.ServiceDefinitions))
+	for _, sd := range serviceDefinitionList.ServiceDefinitions {
+		services = append(services, sd.Service)
+	}
+	return services
+}
+
+// newWorkloadStats returns a workload without stats. The app and version are resolved as for the Istio
+// canonical service labels.
+func newWorkloadStats(namespace, name string, labels map[string]string) *workloadStats {
+	istioLabels := config.Get().IstioLabels
+
+	app, ok := labels["service.istio.io/canonical-name"]
+	if !ok {
+		if app, ok = labels[istioLabels.AppLabelName]; !ok {
+			app = name
+		}
+	}
+	version, ok := labels["service.istio.io/canonical-revision"]
+	if !ok {
+		if version, ok = labels[istioLabels.VersionLabelName]; !ok {
+			version = "latest"
+		}
+	}
+
+	return &workloadStats{
+		namespace: namespace,
+		name:      name,
+		app:       app,
+		version:   version,
+		labels:    labels,
+		stats:     newProxyStats(),
+	}
+}
+
+// inboundTraffic returns the total inbound traffic of the workload, requests/sec or sent bytes/sec for TCP
+func (ws *workloadStats) inboundTraffic(protocol string) float64 {
+	total := 0.0
+	for name, cs := range ws.stats.clusters {
+		if direction, _, _, _ := parseClusterName(name); direction != directionInbound {
+			continue
+		}
+		if protocol == graph.TCP.Name {
+			total += cs.sentBytes
+			continue
+		}
+		for _, val := range cs.requests {
+			total += val
+		}
+	}
+	return total
+}
+
+// buildDestinations returns the workloads selected by each service (key=namespace/service)
+func buildDestinations(workloads []*workloadStats, services []models.Service) map[string][]*workloadStats {
+	destinations := make(map[string][]*workloadStats)
+	for _, svc := range services {
+		if len(svc.Selectors) == 0 {
+			continue
+		}
+		for _, ws := range workloads {
+			if ws.namespace == svc.Namespace.Name && isSelected(svc.Selectors, ws.labels) {
+				key := serviceKey(svc.Namespace.Name, svc.Name)
+				destinations[key] = append(destinations[key], ws)
+			}
+		}
+	}
+	return destinations
+}
+
+func isSelected(selectors, labels map[string]string) bool {
+	for k, v := range selectors {
+		if labels[k] != v {
+			return false
+		}
+	}
+	return true
+}
+
+func serviceKey(namespace, service string) string {
+	return fmt.Sprintf("%s/%s", namespace, service)
+}
+
+// parseHost returns the service namespace and name of an Envoy cluster host. A host not in the form
+// <service>.<namespace>.svc.<domain>, e.g. a ServiceEntry host, is assumed to be in the source namespace.
+func parseHost(host, sourceNamespace string) (namespace, service string) {
+	parts := strings.Split(host, ".")
+	if len(parts) >= 3 && parts[2] == "svc" {
+		return parts[1], parts[0]
+	}
+	return sourceNamespace, host
+}
+
+// shares returns the destination workloads with their share of the service traffic, by their share of
+// the inbound traffic. It returns nil if the inbound traffic of the workloads is unknown.
+func shares(destinations []*workloadStats, protocol string) []destinationShare {
+	total := 0.0
+	for _, ws := range destinations {
+		total += ws.inboundTraffic(protocol)
+	}
+	if total <= 0.0 {
+		return nil
+	}
+
+	result := []destinationShare{}
+	for _, ws := range destinations {
+		if val := ws.inboundTraffic(protocol); val > 0.0 {
+			result = append(result, destinationShare{workload: ws, share: val / total})
+		}
+	}
+	return result
+}
+
+// buildNamespaceTrafficMap returns a map of all namespace nodes (key=id). All nodes either directly send
+// and/or receive traffic from a node in the namespace.
+func buildNamespaceTrafficMap(namespace string, workloads []*workloadStats, destinations map[string][]*workloadStats, o graph.TelemetryOptions) graph.TrafficMap {
+	trafficMap := graph.NewTrafficMap()
+
+	for _, source := range workloads {
+		for name, cs := range source.stats.clusters {
+			direction, _, host, _ := parseClusterName(name)
+			if direction != directionOutbound || !cs.hasTraffic {
+				continue
+			}
+			svcNs, svcName := parseHost(host, source.namespace)
+			if source.namespace != namespace && svcNs != namespace {
+				continue
+			}
+
+			protocol := graph.HTTP.Name
+			if len(cs.requests) == 0 {
+				protocol = graph.TCP.Name
+			}
+			dests := shares(destinations[serviceKey(svcNs, svcName)], protocol)
+
+			if protocol == graph.TCP.Name {
+				addTraffic(trafficMap, cs.sentBytes, protocol, "", host, source, svcNs, svcName, dests, o)
+				continue
+			}
+			for code, val := range cs.requests {
+				addTraffic(trafficMap, val, protocol, code, host, source, svcNs, svcName, dests, o)
+			}
+		}
+	}
+
+	return trafficMap
+}
+
+// addTraffic adds the traffic from the source workload to the service. When the service workloads are
+// known the traffic is apportioned to the workloads, through the service node if service nodes are injected.
+func addTraffic(trafficMap graph.TrafficMap, val float64, protocol, code, host string, source *workloadStats, svcNs, svcName string, dests []destinationShare, o graph.TelemetryOptions) {
+	if val <= 0.0 && !o.IncludeIdleEdges {
+		return
+	}
+
+	sourceNode := addNode(trafficMap, source.namespace, "", source.namespace, source.name, source.app, source.version, o)
+
+	if len(dests) == 0 {
+		svcNode := addNode(trafficMap, svcNs, svcName, "", "", "", "", o)
+		addEdgeTraffic(val, protocol, code, host, sourceNode, svcNode)
+		addToDestServices(svcNode.Metadata, svcNs, svcName)
+		return
+	}
+
+	fromNode := sourceNode
+	if o.InjectServiceNodes {
+		fromNode = addNode(trafficMap, svcNs, svcName, "", "", "", "", o)
+		addEdgeTraffic(val, protocol, code, host, sourceNode, fromNode)
+		addToDestServices(fromNode.Metadata, svcNs, svcName)
+	}
+	for _, d := range dests {
+		destNode := addNode(trafficMap, svcNs, svcName, d.workload.namespace, d.workload.name, d.workload.app, d.workload.version, o)
+		addEdgeTraffic(val*d.share, protocol, code, host, fromNode, destNode)
+		addToDestServices(destNode.Metadata, svcNs, svcName)
+	}
+}
+
+func addEdgeTraffic(val float64, protocol, code, host string, source, dest *graph.Node) {
+	var edge *graph.Edge
+	for _, e := range source.Edges {
+		if dest.ID == e.Dest.ID && e.Metadata[graph.ProtocolKey] == protocol {
+			edge = e
+			break
+		}
+	}
+	if nil == edge {
+		edge = source.AddEdge(dest)
+		edge.Metadata[graph.ProtocolKey] = protocol
+	}
+
+	// the stats do not report response flags
+	graph.AddToMetadata(protocol, val, code, "-", host, source.Metadata, dest.Metadata, edge.Metadata)
+}
+
+func addToDestServices(md graph.Metadata, namespace, service string) {
+	if !graph.IsOK(service) {
+		return
+	}
+	destServices, ok := md[graph.DestServices]
+	if !ok {
+		destServices = graph.NewDestServicesMetadata()
+		md[graph.DestServices] = destServices
+	}
+	destService := graph.ServiceName{Cluster: graph.Unknown, Namespace: namespace, Name: service}
+	destServices.(graph.DestServicesMetadata)[destService.Key()] = destService
+}
+
+func addNode(trafficMap graph.TrafficMap, serviceNs, service, workloadNs, workload, app, version string, o graph.TelemetryOptions) *graph.Node {
+	id, nodeType := graph.Id(graph.Unknown, serviceNs, service, workloadNs, workload, app, version, o.GraphType)
+	node, found := trafficMap[id]
+	if !found {
+		namespace := workloadNs
+		if !graph.IsOK(namespace) {
+			namespace = serviceNs
+		}
+		newNode := graph.NewNodeExplicit(id, graph.Unknown, namespace, workload, app, version, service, nodeType, o.GraphType)
+		node = &newNode
+		trafficMap[id] = node
+	}
+	return node
+}
@@ -0,0 +1,216 @@
+package envoy
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/kiali/kiali/config"
+	"github.com/kiali/kiali/graph"
+	"github.com/kiali/kiali/graph/telemetry"
+	"github.com/kiali/kiali/graph/telemetry/istio/appender"
+	"github.com/kiali/kiali/models"
+)
+
+func setupWorkloads() ([]*workloadStats, map[string][]*workloadStats) {
+	config.Set(config.NewConfig())
+
+	productpage := newWorkloadStats("bookinfo", "productpage-v1", map[string]string{"app": "productpage", "version": "v1"})
+	reviewsV1 := newWorkloadStats("bookinfo", "reviews-v1", map[string]string{"app": "reviews", "version": "v1"})
+	reviewsV2 := newWorkloadStats("bookinfo", "reviews-v2", map[string]string{"app": "reviews", "version": "v2"})
+	ratings := newWorkloadStats("bookinfo", "ratings-v1", map[string]string{"app": "ratings", "version": "v1"})
+
+	reviews := productpage.stats.cluster("outbound|9080||reviews.bookinfo.svc.cluster.local")
+	reviews.hasTraffic = true
+	reviews.requests["200"] = 8.0
+	reviews.requests["503"] = 2.0
+	mongodb := productpage.stats.cluster("outbound|27017||mongodb.bookinfo.svc.cluster.local")
+	mongodb.hasTraffic = true
+	mongodb.sentBytes = 400.0
+	// known to the proxy, but never used
+	productpage.stats.cluster("outbound|9080||details.bookinfo.svc.cluster.local")
+
+	inbound := reviewsV1.stats.cluster("inbound|9080||")
+	inbound.hasTraffic = true
+	inbound.requests["200"] = 3.0
+	inbound = reviewsV2.stats.cluster("inbound|9080||")
+	inbound.hasTraffic = true
+	inbound.requests["200"] = 5.0
+	inbound.requests["503"] = 2.0
+
+	workloads := []*workloadStats{productpage, reviewsV1, reviewsV2, ratings}
+	services := []models.Service{
+		{Name: "reviews", Namespace: models.Namespace{Name: "bookinfo"}, Selectors: map[string]string{"app": "reviews"}},
+		{Name: "ratings", Namespace: models.Namespace{Name: "bookinfo"}, Selectors: map[string]string{"app": "ratings"}},
+		{Name: "mongodb", Namespace: models.Namespace{Name: "bookinfo"}},
+	}
+	return workloads, buildDestinations(workloads, services)
+}
+
+func testTelemetryOptions(injectServiceNodes bool) graph.TelemetryOptions {
+	o := graph.TelemetryOptions{InjectServiceNodes: injectServiceNodes}
+	o.GraphType = graph.GraphTypeVersionedApp
+	return o
+}
+
+func edgesByDest(n *graph.Node) map[string]*graph.Edge {
+	edges := make(map[string]*graph.Edge)
+	for _, e := range n.Edges {
+		name := e.Dest.Workload
+		if e.Dest.NodeType == graph.NodeTypeService {
+			name = e.Dest.Service
+		}
+		edges[name] = e
+	}
+	return edges
+}
+
+func TestBuildDestinations(t *testing.T) {
+	assert := assert.New(t)
+
+	_, destinations := setupWorkloads()
+	assert.Len(destinations, 2)
+	assert.Len(destinations["bookinfo/reviews"], 2)
+	assert.Len(destinations["bookinfo/ratings"], 1)
+
+	// the service traffic is apportioned by the share of the inbound traffic
+	reviewsShares := shares(destinations["bookinfo/reviews"], graph.HTTP.Name)
+	assert.Len(reviewsShares, 2)
+	assert.Equal("reviews-v1", reviewsShares[0].workload.name)
+	assert.Equal(0.3, reviewsShares[0].share)
+	assert.Equal("reviews-v2", reviewsShares[1].workload.name)
+	assert.Equal(0.7, reviewsShares[1].share)
+
+	// no inbound traffic, the workloads are unknown
+	assert.Nil(shares(destinations["bookinfo/ratings"], graph.HTTP.Name))
+}
+
+func TestBuildNamespaceTrafficMap(t *testing.T) {
+	assert := assert.New(t)
+
+	workloads, destinations := setupWorkloads()
+	trafficMap := buildNamespaceTrafficMap("bookinfo", workloads, destinations, testTelemetryOptions(false))
+
+	// productpage, reviews-v1, reviews-v2, mongodb
+	assert.Len(trafficMap, 4)
+
+	productpageID, _ := graph.Id(graph.Unknown, "bookinfo", "", "bookinfo", "productpage-v1", "productpage", "v1", graph.GraphTypeVersionedApp)
+	productpage, ok := trafficMap[productpageID]
+	assert.True(ok)
+
+	edges := edgesByDest(productpage)
+	assert.Len(edges, 3)
+
+	reviewsV1 := edges["reviews-v1"]
+	assert.Equal(graph.HTTP.Name, reviewsV1.Metadata[graph.ProtocolKey])
+	assert.InDelta(3.0, reviewsV1.Metadata[graph.HTTP.EdgeRates[0].Name], 0.0001)
+	assert.Equal("reviews", reviewsV1.Dest.App)
+	assert.Equal("v1", reviewsV1.Dest.Version)
+
+	reviewsV2 := edges["reviews-v2"]
+	assert.InDelta(7.0, reviewsV2.Metadata[graph.HTTP.EdgeRates[0].Name], 0.0001)
+	assert.InDelta(1.4, reviewsV2.Metadata[graph.HTTP.EdgeRates[4].Name], 0.0001)
+
+	// mongodb has no selector, the edge leads to the service node
+	mongodb := edges["mongodb"]
+	assert.Equal(graph.NodeTypeService, mongodb.Dest.NodeType)
+	assert.Equal(graph.TCP.Name, mongodb.Metadata[graph.ProtocolKey])
+	assert.Equal(400.0, mongodb.Metadata[graph.TCP.EdgeRates[0].Name])
+
+	// traffic of another namespace is not in the namespace map
+	assert.Empty(buildNamespaceTrafficMap("default", workloads, destinations, testTelemetryOptions(false)))
+}
+
+func TestBuildNamespaceTrafficMapWithServiceNodes(t *testing.T) {
+	assert := assert.New(t)
+
+	workloads, destinations := setupWorkloads()
+	trafficMap := buildNamespaceTrafficMap("bookinfo", workloads, destinations, testTelemetryOptions(true))
+
+	// productpage, reviews, reviews-v1, reviews-v2, mongodb
+	assert.Len(trafficMap, 5)
+
+	productpageID, _ := graph.Id(graph.Unknown, "bookinfo", "", "bookinfo", "productpage-v1", "productpage", "v1", graph.GraphTypeVersionedApp)
+	edges := edgesByDest(trafficMap[productpageID])
+	assert.Len(edges, 2)
+
+	reviews := edges["reviews"]
+	assert.Equal(graph.NodeTypeService, reviews.Dest.NodeType)
+	assert.Equal(10.0, reviews.Metadata[graph.HTTP.EdgeRates[0].Name])
+	assert.Contains(reviews.Dest.Metadata[graph.DestServices], "unknown bookinfo reviews")
+
+	serviceEdges := edgesByDest(reviews.Dest)
+	assert.Len(serviceEdges, 2)
+	assert.InDelta(3.0, serviceEdges["reviews-v1"].Metadata[graph.HTTP.EdgeRates[0].Name], 0.0001)
+	assert.InDelta(7.0, serviceEdges["reviews-v2"].Metadata[graph.HTTP.EdgeRates[0].Name], 0.0001)
+}
+
+func TestBuildNamespaceTrafficMapIdleEdges(t *testing.T) {
+	assert := assert.New(t)
+
+	workloads, destinations := setupWorkloads()
+	productpage := workloads[0]
+	productpage.stats.clusters["outbound|9080||reviews.bookinfo.svc.cluster.local"].requests["200"] = 0.0
+	productpage.stats.clusters["outbound|9080||reviews.bookinfo.svc.cluster.local"].requests["503"] = 0.0
+
+	trafficMap := buildNamespaceTrafficMap("bookinfo", workloads, destinations, testTelemetryOptions(false))
+	assert.Len(trafficMap, 2)
+
+	o := testTelemetryOptions(false)
+	o.IncludeIdleEdges = true
+	trafficMap = buildNamespaceTrafficMap("bookinfo", workloads, destinations, o)
+	// the unused details cluster is not an idle edge
+	assert.Len(trafficMap, 4)
+}
+
+func TestReduceToNode(t *testing.T) {
+	assert := assert.New(t)
+
+	workloads, destinations := setupWorkloads()
+	trafficMap := buildNamespaceTrafficMap("bookinfo", workloads, destinations, testTelemetryOptions(true))
+
+	target := graph.NewNode(graph.Unknown, "bookinfo", "", "bookinfo", "reviews-v2", "", "", graph.GraphTypeWorkload)
+	trafficMap = telemetry.ReduceToNode(trafficMap, target, testTelemetryOptions(true))
+
+	// productpage -> reviews -> reviews-v2
+	assert.Len(trafficMap, 3)
+	for _, n := range trafficMap {
+		switch n.Workload {
+		case "productpage-v1":
+			assert.Len(n.Edges, 1)
+			assert.Equal("reviews", n.Edges[0].Dest.Service)
+		case "reviews-v2":
+			assert.Empty(n.Edges)
+		case "":
+			assert.Equal("reviews", n.Service)
+			assert.Len(n.Edges, 1)
+			assert.Equal("reviews-v2", n.Edges[0].Dest.Workload)
+		default:
+			assert.Fail("unexpected node", n.ID)
+		}
+	}
+}
+
+func TestParseAppenders(t *testing.T) {
+	assert := assert.New(t)
+	config.Set(config.NewConfig())
+
+	o := graph.TelemetryOptions{
+		Appenders:  graph.RequestedAppenders{All: true},
+		Namespaces: graph.NamespaceInfoMap{"bookinfo": {Name: "bookinfo", Duration: 10 * time.Minute}},
+	}
+	appenders, finalizers := parseAppenders(o)
+	assert.NotEmpty(appenders)
+	// the finalizers are opt-in
+	assert.Empty(finalizers)
+	for _, a := range appenders {
+		_, unsupported := promAppenders[a.Name()]
+		assert.False(unsupported, a.Name())
+	}
+
+	o.Appenders = graph.RequestedAppenders{AppenderNames: []string{appender.DeadNodeAppenderName, appender.ResponseTimeAppenderName}}
+	assert.PanicsWithValue(graph.Response{Message: "Appender [responseTime] is not supported by telemetryVendor [envoy]", Code: 400}, func() {
+		parseAppenders(o)
+	})
+}
@@ -0,0 +1,235 @@
+package envoy
+
+// Stats.go is responsible for parsing the stats of the Envoy proxies and for turning the stats counters,
+// which are cumulative since the proxy started, into per-second rates.
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/common/expfmt"
+)
+
+const (
+	// sampleInterval is the minimum time between two retained samples of a proxy
+	sampleInterval = 30 * time.Second
+	// maxSampleAge is the time a sample is retained, rates are computed over at most this time
+	maxSampleAge = time.Hour
+)
+
+// The Envoy cluster name is "<direction>|<port>|<subset>|<host>", e.g. "outbound|9080|v1|reviews.bookinfo.svc.cluster.local"
+// for traffic sent by the proxy, or "inbound|9080||" for traffic received by the proxy.
+const (
+	directionInbound  = "inbound"
+	directionOutbound = "outbound"
+)
+
+// clusterStats holds the stats of an Envoy cluster (i.e. upstream) of a proxy, as counters or as rates
+type clusterStats struct {
+	hasTraffic bool               // the proxy has handled traffic for the cluster since it started
+	requests   map[string]float64 // response code => requests
+	sentBytes  float64
+}
+
+// proxyStats holds the stats of a proxy, or the stats of all the proxies of a workload
+type proxyStats struct {
+	clusters map[string]*clusterStats // cluster name => stats
+	uptime   float64                  // seconds
+}
+
+func newProxyStats() *proxyStats {
+	return &proxyStats{clusters: make(map[string]*clusterStats)}
+}
+
+func (ps *proxyStats) cluster(name string) *clusterStats {
+	cs, ok := ps.clusters[name]
+	if !ok {
+		cs = &clusterStats{requests: make(map[string]float64)}
+		ps.clusters[name] = cs
+	}
+	return cs
+}
+
+// add adds the stats of another proxy of the same workload
+func (ps *proxyStats) add(other *proxyStats) {
+	for name, ocs := range other.clusters {
+		cs := ps.cluster(name)
+		cs.hasTraffic = cs.hasTraffic || ocs.hasTraffic
+		cs.sentBytes += ocs.sentBytes
+		for code, val := range ocs.requests {
+			cs.requests[code] += val
+		}
+	}
+}
+
+// parseClusterName returns the direction, port and host of an Envoy cluster name, ok is false for the
+// clusters not handling application traffic (e.g. xds-grpc, PassthroughCluster).
+func parseClusterName(name string) (direction, port, host string, ok bool) {
+	parts := strings.Split(name, "|")
+	if len(parts) != 4 || (parts[0] != directionInbound && parts[0] != directionOutbound) {
+		return "", "", "", false
+	}
+	return parts[0], parts[1], parts[3], true
+}
+
+// parseProxyStats parses the Prometheus text format stats of an Envoy proxy, keeping only the counters
+// of the clusters handling application traffic. With the default Istio proxy configuration the cluster
+// stats are not reported, they must be included using the proxyStatsMatcher mesh config or the
+// sidecar.istio.io/statsInclusionPrefixes annotation, e.g. with the "cluster.outbound" and "cluster.inbound" prefixes.
+func parseProxyStats(data []byte) (*proxyStats, error) {
+	var parser expfmt.TextParser
+	families, err := parser.TextToMetricFamilies(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	ps := newProxyStats()
+	if family, ok := families["envoy_server_uptime"]; ok && len(family.GetMetric()) > 0 {
+		ps.uptime = family.GetMetric()[0].GetGauge().GetValue()
+	}
+
+	// the requests by response code, the same metric also holds the requests by response code class
+	// (e.g. response_code_class="2xx") which are ignored.
+	if family, ok := families["envoy_cluster_upstream_rq"]; ok {
+		for _, m := range family.GetMetric() {
+			var clusterName, code string
+			for _, l := range m.GetLabel() {
+				switch l.GetName() {
+				case "cluster_name":
+					clusterName = l.GetValue()
+				case "response_code":
+					code = l.GetValue()
+				}
+			}
+			if _, _, _, ok := parseClusterName(clusterName); !ok || code == "" {
+				continue
+			}
+			val := m.GetCounter().GetValue()
+			cs := ps.cluster(clusterName)
+			cs.requests[code] += val
+			cs.hasTraffic = cs.hasTraffic || val > 0
+		}
+	}
+
+	if family, ok := families["envoy_cluster_upstream_cx_tx_bytes_total"]; ok {
+		for _, m := range family.GetMetric() {
+			var clusterName string
+			for _, l := range m.GetLabel() {
+				if l.GetName() == "cluster_name" {
+					clusterName = l.GetValue()
+				}
+			}
+			if _, _, _, ok := parseClusterName(clusterName); !ok {
+				continue
+			}
+			val := m.GetCounter().GetValue()
+			cs := ps.cluster(clusterName)
+			cs.sentBytes += val
+			cs.hasTraffic = cs.hasTraffic || val > 0
+		}
+	}
+
+	return ps, nil
+}
+
+type statsSample struct {
+	stats *proxyStats
+	time  time.Time
+}
+
+// statsSampler retains samples of the proxy stats, so that the counters can be turned into rates
+type statsSampler struct {
+	mutex   sync.Mutex
+	samples map[string][]statsSample // proxy key => samples, oldest first
+}
+
+var sampler = newStatsSampler()
+
+func newStatsSampler() *statsSampler {
+	return &statsSampler{samples: make(map[string][]statsSample)}
+}
+
+// rates records the current stats of the proxy and returns the per-second rates over the duration. The
+// rates are computed from the oldest retained sample within the duration. Without such a sample, e.g. for
+// the first request after Kiali or the proxy started, the rates are averaged over the proxy uptime.
+func (s *statsSampler) rates(key string, current *proxyStats, now time.Time, duration time.Duration) *proxyStats {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.prune(now)
+
+	samples := s.samples[key]
+	// a proxy restart resets the counters
+	if len(samples) > 0 && samples[len(samples)-1].stats.uptime > current.uptime {
+		samples = nil
+	}
+
+	var base *statsSample
+	for i := range samples {
+		if elapsed := now.Sub(samples[i].time); elapsed > 0 && elapsed <= duration {
+			base = &samples[i]
+			break
+		}
+	}
+
+	if len(samples) == 0 || now.Sub(samples[len(samples)-1].time) >= sampleInterval {
+		samples = append(samples, statsSample{stats: current, time: now})
+	}
+	s.samples[key] = samples
+
+	if base != nil {
+		return ratesOf(current, base.stats, now.Sub(base.time).Seconds())
+	}
+	return ratesOf(current, nil, current.uptime)
+}
+
+// prune removes the samples older than maxSampleAge, including those of proxies no longer running
+func (s *statsSampler) prune(now time.Time) {
+	for key, samples := range s.samples {
+		i := 0
+		for i < len(samples) && now.Sub(samples[i].time) > maxSampleAge {
+			i++
+		}
+		if i == len(samples) {
+			delete(s.samples, key)
+		} else if i > 0 {
+			s.samples[key] = samples[i:]
+		}
+	}
+}
+
+// ratesOf returns the per-second rates of the counters since the base counters, or since the proxy
+// started when base is nil.
+func ratesOf(current, base *proxyStats, seconds float64) *proxyStats {
+	result := newProxyStats()
+	result.uptime = current.uptime
+	for name, cs := range current.clusters {
+		rcs := result.cluster(name)
+		rcs.hasTraffic = cs.hasTraffic
+
+		var bcs *clusterStats
+		if base != nil {
+			bcs = base.clusters[name]
+		}
+		if bcs == nil {
+			bcs = &clusterStats{}
+		}
+		if seconds <= 0 {
+			continue
+		}
+		for code, val := range cs.requests {
+			rcs.requests[code] = rate(val, bcs.requests[code], seconds)
+		}
+		rcs.sentBytes = rate(cs.sentBytes, bcs.sentBytes, seconds)
+	}
+	return result
+}
+
+func rate(current, base, seconds float64) float64 {
+	if current <= base {
+		return 0.0
+	}
+	return (current - base) / seconds
+}
@@ -0,0 +1,115 @@
+package envoy
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testProxyStats = `# TYPE envoy_cluster_upstream_cx_tx_bytes_total counter
+envoy_cluster_upstream_cx_tx_bytes_total{cluster_name="outbound|27017||mongodb.bookinfo.svc.cluster.local"} 4000
+envoy_cluster_upstream_cx_tx_bytes_total{cluster_name="outbound|9080||reviews.bookinfo.svc.cluster.local"} 9000
+envoy_cluster_upstream_cx_tx_bytes_total{cluster_name="xds-grpc"} 123456
+# TYPE envoy_cluster_upstream_rq counter
+envoy_cluster_upstream_rq{response_code_class="2xx",cluster_name="outbound|9080||reviews.bookinfo.svc.cluster.local"} 80
+envoy_cluster_upstream_rq{response_code="200",cluster_name="outbound|9080||reviews.bookinfo.svc.cluster.local"} 80
+envoy_cluster_upstream_rq{response_code="503",cluster_name="outbound|9080||reviews.bookinfo.svc.cluster.local"} 20
+envoy_cluster_upstream_rq{response_code="200",cluster_name="inbound|9080||"} 50
+envoy_cluster_upstream_rq{response_code="200",cluster_name="xds-grpc"} 10
+envoy_cluster_upstream_rq{response_code="200",cluster_name="outbound|9080||details.bookinfo.svc.cluster.local"} 0
+# TYPE envoy_server_uptime gauge
+envoy_server_uptime{} 100
+`
+
+func TestParseProxyStats(t *testing.T) {
+	assert := assert.New(t)
+
+	ps, err := parseProxyStats([]byte(testProxyStats))
+	assert.NoError(err)
+	assert.Equal(100.0, ps.uptime)
+
+	// only the inbound and outbound clusters are kept, the response code classes are ignored
+	assert.Len(ps.clusters, 4)
+
+	reviews := ps.clusters["outbound|9080||reviews.bookinfo.svc.cluster.local"]
+	assert.True(reviews.hasTraffic)
+	assert.Equal(map[string]float64{"200": 80.0, "503": 20.0}, reviews.requests)
+	assert.Equal(9000.0, reviews.sentBytes)
+
+	mongodb := ps.clusters["outbound|27017||mongodb.bookinfo.svc.cluster.local"]
+	assert.True(mongodb.hasTraffic)
+	assert.Empty(mongodb.requests)
+	assert.Equal(4000.0, mongodb.sentBytes)
+
+	assert.Equal(map[string]float64{"200": 50.0}, ps.clusters["inbound|9080||"].requests)
+	assert.False(ps.clusters["outbound|9080||details.bookinfo.svc.cluster.local"].hasTraffic)
+
+	_, err = parseProxyStats([]byte("not stats"))
+	assert.Error(err)
+}
+
+func TestParseClusterName(t *testing.T) {
+	assert := assert.New(t)
+
+	direction, port, host, ok := parseClusterName("outbound|9080|v1|reviews.bookinfo.svc.cluster.local")
+	assert.True(ok)
+	assert.Equal(directionOutbound, direction)
+	assert.Equal("9080", port)
+	assert.Equal("reviews.bookinfo.svc.cluster.local", host)
+
+	direction, port, host, ok = parseClusterName("inbound|9080||")
+	assert.True(ok)
+	assert.Equal(directionInbound, direction)
+	assert.Equal("9080", port)
+	assert.Equal("", host)
+
+	for _, name := range []string{"xds-grpc", "PassthroughCluster", "BlackHoleCluster", "prometheus_stats"} {
+		_, _, _, ok = parseClusterName(name)
+		assert.False(ok, name)
+	}
+}
+
+func testStats(uptime, requests float64) *proxyStats {
+	ps := newProxyStats()
+	ps.uptime = uptime
+	cs := ps.cluster("outbound|9080||reviews.bookinfo.svc.cluster.local")
+	cs.hasTraffic = requests > 0
+	cs.requests["200"] = requests
+	return ps
+}
+
+func TestStatsSamplerRates(t *testing.T) {
+	assert := assert.New(t)
+
+	s := newStatsSampler()
+	now := time.Unix(1523364075, 0)
+	cluster := "outbound|9080||reviews.bookinfo.svc.cluster.local"
+
+	// without a previous sample the rates are averaged over the uptime
+	rates := s.rates("bookinfo/productpage-v1-1", testStats(100, 500), now, 10*time.Minute)
+	assert.Equal(5.0, rates.clusters[cluster].requests["200"])
+
+	// a sample taken before the sample interval is not retained
+	s.rates("bookinfo/productpage-v1-1", testStats(110, 550), now.Add(10*time.Second), 10*time.Minute)
+	assert.Len(s.samples["bookinfo/productpage-v1-1"], 1)
+
+	// with a previous sample the rates are computed since the sample
+	rates = s.rates("bookinfo/productpage-v1-1", testStats(160, 1100), now.Add(60*time.Second), 10*time.Minute)
+	assert.Equal(10.0, rates.clusters[cluster].requests["200"])
+	assert.Len(s.samples["bookinfo/productpage-v1-1"], 2)
+
+	// a sample older than the duration is not used
+	rates = s.rates("bookinfo/productpage-v1-1", testStats(220, 1400), now.Add(120*time.Second), 1*time.Minute)
+	assert.Equal(5.0, rates.clusters[cluster].requests["200"])
+
+	// a proxy restart resets the samples
+	rates = s.rates("bookinfo/productpage-v1-1", testStats(10, 20), now.Add(180*time.Second), 10*time.Minute)
+	assert.Equal(2.0, rates.clusters[cluster].requests["200"])
+	assert.Len(s.samples["bookinfo/productpage-v1-1"], 1)
+
+	// the samples of proxies no longer running are removed
+	s.rates("bookinfo/productpage-v1-2", testStats(10, 20), now.Add(2*time.Hour), 10*time.Minute)
+	assert.NotContains(s.samples, "bookinfo/productpage-v1-1")
+	assert.Contains(s.samples, "bookinfo/productpage-v1-2")
+}
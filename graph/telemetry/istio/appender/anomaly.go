@@ -0,0 +1,280 @@
+package appender
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/prometheus/common/model"
+
+	"github.com/kiali/kiali/graph"
+	"github.com/kiali/kiali/graph/telemetry/istio/util"
+	"github.com/kiali/kiali/log"
+	"github.com/kiali/kiali/prometheus"
+)
+
+const (
+	// AnomalyAppenderName uniquely identifies the appender: anomaly
+	AnomalyAppenderName = "anomaly"
+
+	// AnomalyBaselineWeek compares with the 7 days preceding the graph time window
+	AnomalyBaselineWeek = "week"
+	// AnomalyBaselineYesterday compares with the same time window, one day earlier
+	AnomalyBaselineYesterday = "yesterday"
+
+	// anomalyErrorSmoothing is added to both error percentages before comparing them, so that a few errors on
+	// an edge that usually reports none are not reported as an infinite change.
+	anomalyErrorSmoothing = 1.0
+)
+
+// AnomalyAppender is responsible for detecting edges whose traffic deviates from a historical baseline. For
+// each request-based edge it compares the request rate, the error percentage and the average response time
+// of the graph time window with the same values over the baseline window:
+//   - yesterday: the same time window, one day earlier
+//   - week: the 7 days preceding the time window
+//
+// Each comparison is reported as a score, the base 2 logarithm of the ratio of the current value to the
+// baseline value. A score of 1 means the value doubled, -1 means it halved. The error percentages are
+// smoothed by one percentage point, to score errors on edges that usually report none. An edge is
+// marked IsAnomalous when its request rate score deviates by at least the threshold, or when its error or
+// response time score increases by at least the threshold. Edges without baseline traffic are not scored.
+//
+// Unlike the static HealthConfig tolerances, the scores catch slow regressions that stay below the
+// configured error percentages.
+// Name: anomaly
+type AnomalyAppender struct {
+	Baseline           string
+	GraphType          string
+	InjectServiceNodes bool
+	Namespaces         graph.NamespaceInfoMap
+	QueryTime          int64 // unix time in seconds
+	Threshold          float64
+}
+
+// anomalyTraffic holds the request-based traffic rates of an edge
+type anomalyTraffic struct {
+	durationSum float64 // request duration in millis/sec
+	errors      float64 // errors/sec
+	requests    float64 // requests/sec
+}
+
+// anomalyMap maps an edge key "<sourceID> <destID>" to its traffic
+type anomalyMap map[string]*anomalyTraffic
+
+func (m anomalyMap) get(key string) *anomalyTraffic {
+	t, ok := m[key]
+	if !ok {
+		t = &anomalyTraffic{}
+		m[key] = t
+	}
+	return t
+}
+
+// Name implements Appender
+func (a AnomalyAppender) Name() string {
+	return AnomalyAppenderName
+}
+
+// AppendGraph implements Appender
+func (a AnomalyAppender) AppendGraph(trafficMap graph.TrafficMap, globalInfo *graph.AppenderGlobalInfo, namespaceInfo *graph.AppenderNamespaceInfo) {
+	if len(trafficMap) == 0 {
+		return
+	}
+
+	if globalInfo.PromClient == nil {
+		var err error
+		globalInfo.PromClient, err = prometheus.NewClient()
+		graph.CheckError(err)
+	}
+
+	a.appendGraph(trafficMap, namespaceInfo.Namespace, globalInfo.PromClient)
+}
+
+func (a AnomalyAppender) appendGraph(trafficMap graph.TrafficMap, namespace string, client *prometheus.Client) {
+	log.Tracef("Generating anomalies for baseline [%s]; namespace = %v", a.Baseline, namespace)
+
+	duration := a.Namespaces[namespace].Duration
+
+	// the current window ends at the query time, the baseline window is defined by a range and offset
+	currentMap := a.queryTraffic(namespace, fmt.Sprintf("[%vs]", int(duration.Seconds())), client)
+
+	var baselineRange string
+	var baselineOffset time.Duration
+	switch a.Baseline {
+	case AnomalyBaselineWeek:
+		baselineRange = "[7d]"
+		baselineOffset = duration
+	default:
+		baselineRange = fmt.Sprintf("[%vs]", int(duration.Seconds()))
+		baselineOffset = 24 * time.Hour
+	}
+	baselineMap := a.queryTraffic(namespace, fmt.Sprintf("%s offset %vs", baselineRange, int(baselineOffset.Seconds())), client)
+
+	applyAnomalies(trafficMap, currentMap, baselineMap, a.Threshold)
+}
+
+// queryTraffic returns the request-based edge traffic for the namespace, rangeExpr is the query range
+// including any offset modifier.
+func (a AnomalyAppender) queryTraffic(namespace, rangeExpr string, client *prometheus.Client) anomalyMap {
+	trafficMap := make(anomalyMap)
+
+	groupBy := "source_cluster,source_workload_namespace,source_workload,source_canonical_service,source_canonical_revision,destination_cluster,destination_service_namespace,destination_service,destination_service_name,destination_workload_namespace,destination_workload,destination_canonical_service,destination_canonical_revision"
+	requestsGroupBy := groupBy + ",request_protocol,response_code,grpc_response_status"
+
+	// query prometheus in two queries per metric, as for the throughput appender:
+	// 1) query for requests originating from a workload outside the namespace (destination telemetry)
+	// 2) query for requests originating from a workload inside of the namespace (source telemetry)
+	queries := []struct {
+		metric   string
+		groupBy  string
+		selector string
+	}{
+		{"istio_requests_total", requestsGroupBy, fmt.Sprintf(`reporter="destination",source_workload_namespace!="%s",destination_service_namespace="%s"`, namespace, namespace)},
+		{"istio_requests_total", requestsGroupBy, fmt.Sprintf(`reporter="source",source_workload_namespace="%s"`, namespace)},
+		{"istio_request_duration_milliseconds_sum", groupBy, fmt.Sprintf(`reporter="destination",source_workload_namespace!="%s",destination_service_namespace="%s"`, namespace, namespace)},
+		{"istio_request_duration_milliseconds_sum", groupBy, fmt.Sprintf(`reporter="source",source_workload_namespace="%s"`, namespace)},
+	}
+
+	for _, q := range queries {
+		query := fmt.Sprintf(`sum(rate(%s{%s}%s)) by (%s) > 0`,
+			q.metric,
+			q.selector,
+			rangeExpr,
+			q.groupBy)
+		vector := promQuery(query, time.Unix(a.QueryTime, 0), client.GetContext(), client.API(), a)
+		a.populateAnomalyMap(trafficMap, &vector, q.metric == "istio_request_duration_milliseconds_sum")
+	}
+
+	return trafficMap
+}
+
+func applyAnomalies(trafficMap graph.TrafficMap, currentMap, baselineMap anomalyMap, threshold float64) {
+	for _, n := range trafficMap {
+		for _, e := range n.Edges {
+			key := fmt.Sprintf("%s %s", e.Source.ID, e.Dest.ID)
+			current, currentOk := currentMap[key]
+			baseline, baselineOk := baselineMap[key]
+			if !currentOk || !baselineOk || current.requests == 0 || baseline.requests == 0 {
+				continue
+			}
+
+			requestRateScore := anomalyScore(current.requests, baseline.requests)
+			errorRateScore := anomalyScore(100.0*current.errors/current.requests+anomalyErrorSmoothing, 100.0*baseline.errors/baseline.requests+anomalyErrorSmoothing)
+			e.Metadata[graph.AnomalyRequestRate] = requestRateScore
+			e.Metadata[graph.AnomalyErrorRate] = errorRateScore
+			isAnomalous := math.Abs(requestRateScore) >= threshold || errorRateScore >= threshold
+
+			if current.durationSum > 0 && baseline.durationSum > 0 {
+				responseTimeScore := anomalyScore(current.durationSum/current.requests, baseline.durationSum/baseline.requests)
+				e.Metadata[graph.AnomalyResponseTime] = responseTimeScore
+				isAnomalous = isAnomalous || responseTimeScore >= threshold
+			}
+
+			if isAnomalous {
+				e.Metadata[graph.IsAnomalous] = true
+			}
+		}
+	}
+}
+
+// anomalyScore returns log2(current/baseline), values must be > 0
+func anomalyScore(current, baseline float64) float64 {
+	return math.Log2(current / baseline)
+}
+
+func (a AnomalyAppender) populateAnomalyMap(trafficMap anomalyMap, vector *model.Vector, isDuration bool) {
+	for _, s := range *vector {
+		m := s.Metric
+		lSourceCluster, sourceClusterOk := m["source_cluster"]
+		lSourceWlNs, sourceWlNsOk := m["source_workload_namespace"]
+		lSourceWl, sourceWlOk := m["source_workload"]
+		lSourceApp, sourceAppOk := m["source_canonical_service"]
+		lSourceVer, sourceVerOk := m["source_canonical_revision"]
+		lDestCluster, destClusterOk := m["destination_cluster"]
+		lDestSvcNs, destSvcNsOk := m["destination_service_namespace"]
+		lDestSvc, destSvcOk := m["destination_service"]
+		lDestSvcName, destSvcNameOk := m["destination_service_name"]
+		lDestWlNs, destWlNsOk := m["destination_workload_namespace"]
+		lDestWl, destWlOk := m["destination_workload"]
+		lDestApp, destAppOk := m["destination_canonical_service"]
+		lDestVer, destVerOk := m["destination_canonical_revision"]
+
+		if !sourceWlNsOk || !sourceWlOk || !sourceAppOk || !sourceVerOk || !destSvcNsOk || !destSvcNameOk || !destSvcOk || !destWlNsOk || !destWlOk || !destAppOk || !destVerOk {
+			log.Warningf("populateAnomalyMap: Skipping %s, missing expected labels", m.String())
+			continue
+		}
+
+		isErr := false
+		if !isDuration {
+			lProtocol, protocolOk := m["request_protocol"]
+			lCode, codeOk := m["response_code"]
+			lGrpc, grpcOk := m["grpc_response_status"]
+
+			if !protocolOk || !codeOk {
+				log.Warningf("populateAnomalyMap: Skipping %s, missing expected HTTP/GRPC TS labels", m.String())
+				continue
+			}
+
+			isErr = isRequestErr(util.HandleResponseCode(string(lProtocol), string(lCode), grpcOk, string(lGrpc)))
+		}
+
+		sourceWlNs := string(lSourceWlNs)
+		sourceWl := string(lSourceWl)
+		sourceApp := string(lSourceApp)
+		sourceVer := string(lSourceVer)
+		destSvc := string(lDestSvc)
+
+		// handle clusters
+		sourceCluster, destCluster := util.HandleClusters(lSourceCluster, sourceClusterOk, lDestCluster, destClusterOk)
+
+		if util.IsBadSourceTelemetry(sourceCluster, sourceClusterOk, sourceWlNs, sourceWl, sourceApp) {
+			continue
+		}
+
+		val := float64(s.Value)
+
+		// handle unusual destinations
+		destCluster, destSvcNs, destSvcName, destWlNs, destWl, destApp, destVer, _ := util.HandleDestination(sourceCluster, sourceWlNs, sourceWl, destCluster, string(lDestSvcNs), string(lDestSvc), string(lDestSvcName), string(lDestWlNs), string(lDestWl), string(lDestApp), string(lDestVer))
+
+		if util.IsBadDestTelemetry(destCluster, destClusterOk, destSvcNs, destSvc, destSvcName, destWl) {
+			continue
+		}
+
+		// Should not happen but if NaN for any reason, Just skip it
+		if math.IsNaN(val) {
+			continue
+		}
+
+		// don't inject a service node if destSvcName is not set or the dest node is already a service node.
+		inject := false
+		if a.InjectServiceNodes && graph.IsOK(destSvcName) {
+			_, destNodeType := graph.Id(destCluster, destSvcNs, destSvcName, destWlNs, destWl, destApp, destVer, a.GraphType)
+			inject = (graph.NodeTypeService != destNodeType)
+		}
+
+		// Unlike response times, the request, error and duration rates can be aggregated, so when a service node
+		// is injected the traffic is added to both the incoming and outgoing edges.
+		if inject {
+			a.addTraffic(trafficMap, val, isDuration, isErr, sourceCluster, sourceWlNs, "", sourceWl, sourceApp, sourceVer, destCluster, destSvcNs, destSvcName, "", "", "", "")
+			a.addTraffic(trafficMap, val, isDuration, isErr, destCluster, destSvcNs, destSvcName, "", "", "", destCluster, destSvcNs, destSvcName, destWlNs, destWl, destApp, destVer)
+		} else {
+			a.addTraffic(trafficMap, val, isDuration, isErr, sourceCluster, sourceWlNs, "", sourceWl, sourceApp, sourceVer, destCluster, destSvcNs, destSvcName, destWlNs, destWl, destApp, destVer)
+		}
+	}
+}
+
+func (a AnomalyAppender) addTraffic(trafficMap anomalyMap, val float64, isDuration, isErr bool, sourceCluster, sourceNs, sourceSvc, sourceWl, sourceApp, sourceVer, destCluster, destSvcNs, destSvc, destWlNs, destWl, destApp, destVer string) {
+	sourceID, _ := graph.Id(sourceCluster, sourceNs, sourceSvc, sourceNs, sourceWl, sourceApp, sourceVer, a.GraphType)
+	destID, _ := graph.Id(destCluster, destSvcNs, destSvc, destWlNs, destWl, destApp, destVer, a.GraphType)
+	t := trafficMap.get(fmt.Sprintf("%s %s", sourceID, destID))
+
+	switch {
+	case isDuration:
+		t.durationSum += val
+	case isErr:
+		t.errors += val
+		t.requests += val
+	default:
+		t.requests += val
+	}
+}
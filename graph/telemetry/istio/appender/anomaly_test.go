@@ -0,0 +1,164 @@
+package appender
+
+import (
+	"fmt"
+	"math"
+	"testing"
+
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/kiali/kiali/graph"
+)
+
+func anomalyTestMetric(sourceNs, sourceWl, sourceApp, sourceVer, destApp, destVer, code string) model.Metric {
+	m := model.Metric{
+		"source_workload_namespace":      model.LabelValue(sourceNs),
+		"source_workload":                model.LabelValue(sourceWl),
+		"source_canonical_service":       model.LabelValue(sourceApp),
+		"source_canonical_revision":      model.LabelValue(sourceVer),
+		"destination_service_namespace":  "bookinfo",
+		"destination_service":            model.LabelValue(destApp + ".bookinfo.svc.cluster.local"),
+		"destination_service_name":       model.LabelValue(destApp),
+		"destination_workload_namespace": "bookinfo",
+		"destination_workload":           model.LabelValue(destApp + "-" + destVer),
+		"destination_canonical_service":  model.LabelValue(destApp),
+		"destination_canonical_revision": model.LabelValue(destVer),
+	}
+	if code != "" {
+		m["request_protocol"] = "http"
+		m["response_code"] = model.LabelValue(code)
+	}
+	return m
+}
+
+func anomalyTestIngressMetric(code string) model.Metric {
+	return anomalyTestMetric("istio-system", "ingressgateway-unknown", "ingressgateway", graph.Unknown, "productpage", "v1", code)
+}
+
+func anomalyTestProductpageMetric(destVer, code string) model.Metric {
+	return anomalyTestMetric("bookinfo", "productpage-v1", "productpage", "v1", "reviews", destVer, code)
+}
+
+func TestAnomaly(t *testing.T) {
+	assert := assert.New(t)
+
+	appender := AnomalyAppender{
+		Baseline:  AnomalyBaselineYesterday,
+		GraphType: graph.GraphTypeVersionedApp,
+		Threshold: defaultAnomalyThreshold,
+	}
+
+	// current window
+	// - ingress -> productpage: 20 rps
+	// - productpage -> reviews-v1: 10 rps, 20ms
+	// - productpage -> reviews-v2: 10 rps, 20% errors, 100ms
+	current := make(anomalyMap)
+	appender.populateAnomalyMap(current, &model.Vector{
+		&model.Sample{Metric: anomalyTestIngressMetric("200"), Value: 20.0},
+		&model.Sample{Metric: anomalyTestProductpageMetric("v1", "200"), Value: 10.0},
+		&model.Sample{Metric: anomalyTestProductpageMetric("v2", "200"), Value: 8.0},
+		&model.Sample{Metric: anomalyTestProductpageMetric("v2", "500"), Value: 2.0},
+	}, false)
+	appender.populateAnomalyMap(current, &model.Vector{
+		&model.Sample{Metric: anomalyTestProductpageMetric("v1", ""), Value: 200.0},
+		&model.Sample{Metric: anomalyTestProductpageMetric("v2", ""), Value: 1000.0},
+	}, true)
+
+	// baseline window
+	// - ingress -> productpage: 5 rps
+	// - productpage -> reviews-v1: 10 rps, 20ms
+	// - productpage -> reviews-v2: 10 rps, 20ms
+	baseline := make(anomalyMap)
+	appender.populateAnomalyMap(baseline, &model.Vector{
+		&model.Sample{Metric: anomalyTestIngressMetric("200"), Value: 5.0},
+		&model.Sample{Metric: anomalyTestProductpageMetric("v1", "200"), Value: 10.0},
+		&model.Sample{Metric: anomalyTestProductpageMetric("v2", "200"), Value: 10.0},
+	}, false)
+	appender.populateAnomalyMap(baseline, &model.Vector{
+		&model.Sample{Metric: anomalyTestProductpageMetric("v1", ""), Value: 200.0},
+		&model.Sample{Metric: anomalyTestProductpageMetric("v2", ""), Value: 200.0},
+	}, true)
+
+	trafficMap := anomalyTestTraffic()
+	ingressID, _ := graph.Id(graph.Unknown, "istio-system", "", "istio-system", "ingressgateway-unknown", "ingressgateway", graph.Unknown, graph.GraphTypeVersionedApp)
+
+	applyAnomalies(trafficMap, current, baseline, appender.Threshold)
+
+	ingress := trafficMap[ingressID]
+	assert.Equal(1, len(ingress.Edges))
+	edge := ingress.Edges[0]
+	assert.Equal(2.0, edge.Metadata[graph.AnomalyRequestRate])
+	assert.Equal(0.0, edge.Metadata[graph.AnomalyErrorRate])
+	_, ok := edge.Metadata[graph.AnomalyResponseTime]
+	assert.False(ok)
+	assert.Equal(true, edge.Metadata[graph.IsAnomalous])
+
+	productpage := edge.Dest
+	assert.Equal(2, len(productpage.Edges))
+	for _, e := range productpage.Edges {
+		switch e.Dest.Version {
+		case "v1":
+			assert.Equal(0.0, e.Metadata[graph.AnomalyRequestRate])
+			assert.Equal(0.0, e.Metadata[graph.AnomalyErrorRate])
+			assert.Equal(0.0, e.Metadata[graph.AnomalyResponseTime])
+			_, ok := e.Metadata[graph.IsAnomalous]
+			assert.False(ok)
+		case "v2":
+			assert.Equal(0.0, e.Metadata[graph.AnomalyRequestRate])
+			assert.Equal(math.Log2(21.0), e.Metadata[graph.AnomalyErrorRate])
+			assert.Equal(math.Log2(5.0), e.Metadata[graph.AnomalyResponseTime])
+			assert.Equal(true, e.Metadata[graph.IsAnomalous])
+		default:
+			assert.Fail("unexpected edge", e.Dest.ID)
+		}
+	}
+}
+
+func TestApplyAnomaliesWithoutBaseline(t *testing.T) {
+	assert := assert.New(t)
+
+	trafficMap := anomalyTestTraffic()
+	current := make(anomalyMap)
+	baseline := make(anomalyMap)
+	for _, n := range trafficMap {
+		for _, e := range n.Edges {
+			key := fmt.Sprintf("%s %s", e.Source.ID, e.Dest.ID)
+			current[key] = &anomalyTraffic{requests: 10.0}
+		}
+	}
+
+	applyAnomalies(trafficMap, current, baseline, defaultAnomalyThreshold)
+
+	for _, n := range trafficMap {
+		for _, e := range n.Edges {
+			assert.Empty(e.Metadata)
+		}
+	}
+}
+
+func anomalyTestTraffic() graph.TrafficMap {
+	ingress := graph.NewNode(graph.Unknown, "istio-system", "", "istio-system", "ingressgateway-unknown", "ingressgateway", graph.Unknown, graph.GraphTypeVersionedApp)
+	productpage := graph.NewNode(graph.Unknown, "bookinfo", "productpage", "bookinfo", "productpage-v1", "productpage", "v1", graph.GraphTypeVersionedApp)
+	reviewsV1 := graph.NewNode(graph.Unknown, "bookinfo", "reviews", "bookinfo", "reviews-v1", "reviews", "v1", graph.GraphTypeVersionedApp)
+	reviewsV2 := graph.NewNode(graph.Unknown, "bookinfo", "reviews", "bookinfo", "reviews-v2", "reviews", "v2", graph.GraphTypeVersionedApp)
+	trafficMap := graph.NewTrafficMap()
+
+	trafficMap[ingress.ID] = &ingress
+	trafficMap[productpage.ID] = &productpage
+	trafficMap[reviewsV1.ID] = &reviewsV1
+	trafficMap[reviewsV2.ID] = &reviewsV2
+
+	ingress.AddEdge(&productpage)
+	productpage.AddEdge(&reviewsV1)
+	productpage.AddEdge(&reviewsV2)
+
+	return trafficMap
+}
+
+func TestAnomalyOptIn(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.NotContains(parseAppenderNames(graph.RequestedAppenders{All: true}), AnomalyAppenderName)
+	assert.Contains(parseAppenderNames(graph.RequestedAppenders{AppenderNames: []string{AnomalyAppenderName}}), AnomalyAppenderName)
+}
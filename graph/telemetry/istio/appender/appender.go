@@ -2,20 +2,34 @@ package appender
 
 import (
 	"fmt"
+	"regexp"
+	"strconv"
 
 	"github.com/kiali/kiali/config"
 	"github.com/kiali/kiali/graph"
 	"github.com/kiali/kiali/models"
 )
 
+// labelNameRegexp matches valid Prometheus label names, user-supplied labels are used in queries
+var labelNameRegexp = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
 const (
-	defaultAggregate      = "request_operation"
-	defaultQuantile       = 0.95
-	defaultThroughputType = "response"
+	defaultAggregate        = "request_operation"
+	defaultAnomalyBaseline  = AnomalyBaselineYesterday
+	defaultAnomalyThreshold = 1.0
+	defaultOperationLabel   = "request_operation"
+	defaultOperationLimit   = 5
+	defaultQuantile         = 0.95
+	defaultSaturation       = 90.0
+	defaultThroughputType   = "response"
 )
 
-// ParseAppenders determines which appenders should run for this graphing request
-func ParseAppenders(o graph.TelemetryOptions) []graph.Appender {
+// ParseAppenders determines which appenders should run for this graphing request. The appenders run
+// on each namespace traffic map, the finalizers run once on the final traffic map, after the namespace
+// traffic maps are merged and the outsiders and traffic generators are marked. Finalizers are passed a
+// nil AppenderNamespaceInfo. The opt-in appenders, adding costly queries or changing the graph, only run
+// when requested by name.
+func ParseAppenders(o graph.TelemetryOptions) (appenders []graph.Appender, finalizers []graph.Appender) {
 
 	requestedAppenders := make(map[string]bool)
 	if !o.Appenders.All {
@@ -23,22 +37,36 @@ func ParseAppenders(o graph.TelemetryOptions) []graph.Appender {
 			switch appenderName {
 			case AggregateNodeAppenderName:
 				requestedAppenders[AggregateNodeAppenderName] = true
+			case AnomalyAppenderName:
+				requestedAppenders[AnomalyAppenderName] = true
+			case CriticalPathAppenderName:
+				requestedAppenders[CriticalPathAppenderName] = true
 			case DeadNodeAppenderName:
 				requestedAppenders[DeadNodeAppenderName] = true
+			case ExternalHostAppenderName:
+				requestedAppenders[ExternalHostAppenderName] = true
 			case HealthConfigAppenderName:
 				requestedAppenders[HealthConfigAppenderName] = true
 			case IdleNodeAppenderName:
 				requestedAppenders[IdleNodeAppenderName] = true
 			case IstioAppenderName:
 				requestedAppenders[IstioAppenderName] = true
+			case LabelsAppenderName:
+				requestedAppenders[LabelsAppenderName] = true
+			case OperationsAppenderName:
+				requestedAppenders[OperationsAppenderName] = true
 			case ResponseTimeAppenderName:
 				requestedAppenders[ResponseTimeAppenderName] = true
+			case SaturationAppenderName:
+				requestedAppenders[SaturationAppenderName] = true
 			case SecurityPolicyAppenderName:
 				requestedAppenders[SecurityPolicyAppenderName] = true
 			case ServiceEntryAppenderName:
 				requestedAppenders[ServiceEntryAppenderName] = true
 			case SidecarsCheckAppenderName:
 				requestedAppenders[SidecarsCheckAppenderName] = true
+			case TCPConnectionsAppenderName:
+				requestedAppenders[TCPConnectionsAppenderName] = true
 			case ThroughputAppenderName:
 				requestedAppenders[ThroughputAppenderName] = true
 			case "":
@@ -51,13 +79,12 @@ func ParseAppenders(o graph.TelemetryOptions) []graph.Appender {
 
 	// The appender order is important
 	// To pre-process service nodes run service_entry appender first
+	// To break the egress cluster nodes down by external host, run external_host appender next
 	// To reduce processing, filter dead nodes next
 	// To reduce processing, next run appenders that don't apply to idle (aka unused) services
 	// - lazily inject aggregate nodes so other decorations can influence the new nodes/edges, if necessary
 	// Add orphan (idle) services
 	// Run remaining appenders
-	var appenders []graph.Appender
-
 	if _, ok := requestedAppenders[ServiceEntryAppenderName]; ok || o.Appenders.All {
 		a := ServiceEntryAppender{
 			AccessibleNamespaces: o.AccessibleNamespaces,
@@ -65,6 +92,15 @@ func ParseAppenders(o graph.TelemetryOptions) []graph.Appender {
 		}
 		appenders = append(appenders, a)
 	}
+	if _, ok := requestedAppenders[ExternalHostAppenderName]; ok {
+		a := ExternalHostAppender{
+			AccessibleNamespaces: o.AccessibleNamespaces,
+			GraphType:            o.GraphType,
+			Namespaces:           o.Namespaces,
+			QueryTime:            o.QueryTime,
+		}
+		appenders = append(appenders, a)
+	}
 	if _, ok := requestedAppenders[DeadNodeAppenderName]; ok || o.Appenders.All {
 		a := DeadNodeAppender{}
 		appenders = append(appenders, a)
@@ -122,6 +158,66 @@ func ParseAppenders(o graph.TelemetryOptions) []graph.Appender {
 		}
 		appenders = append(appenders, a)
 	}
+	if _, ok := requestedAppenders[TCPConnectionsAppenderName]; ok {
+		a := TCPConnectionsAppender{
+			GraphType:          o.GraphType,
+			InjectServiceNodes: o.InjectServiceNodes,
+			Namespaces:         o.Namespaces,
+			QueryTime:          o.QueryTime,
+		}
+		appenders = append(appenders, a)
+	}
+	if _, ok := requestedAppenders[AnomalyAppenderName]; ok {
+		baseline := o.Params.Get("anomalyBaseline")
+		switch baseline {
+		case "":
+			baseline = defaultAnomalyBaseline
+		case AnomalyBaselineWeek, AnomalyBaselineYesterday:
+			// valid
+		default:
+			graph.BadRequest(fmt.Sprintf("Invalid anomalyBaseline, expecting one of (week, yesterday). [%s]", baseline))
+		}
+		threshold := defaultAnomalyThreshold
+		if thresholdString := o.Params.Get("anomalyThreshold"); thresholdString != "" {
+			var err error
+			if threshold, err = strconv.ParseFloat(thresholdString, 64); err != nil || threshold <= 0 {
+				graph.BadRequest(fmt.Sprintf("Invalid anomalyThreshold, expecting a positive number. [%s]", thresholdString))
+			}
+		}
+		a := AnomalyAppender{
+			Baseline:           baseline,
+			GraphType:          o.GraphType,
+			InjectServiceNodes: o.InjectServiceNodes,
+			Namespaces:         o.Namespaces,
+			QueryTime:          o.QueryTime,
+			Threshold:          threshold,
+		}
+		appenders = append(appenders, a)
+	}
+	if _, ok := requestedAppenders[OperationsAppenderName]; ok {
+		label := o.Params.Get("operationLabel")
+		if label == "" {
+			label = defaultOperationLabel
+		} else if !labelNameRegexp.MatchString(label) {
+			graph.BadRequest(fmt.Sprintf("Invalid operationLabel, expecting a metric attribute name. [%s]", label))
+		}
+		limit := defaultOperationLimit
+		if limitString := o.Params.Get("operationLimit"); limitString != "" {
+			var err error
+			if limit, err = strconv.Atoi(limitString); err != nil || limit <= 0 {
+				graph.BadRequest(fmt.Sprintf("Invalid operationLimit, expecting a positive integer. [%s]", limitString))
+			}
+		}
+		a := OperationsAppender{
+			GraphType:          o.GraphType,
+			InjectServiceNodes: o.InjectServiceNodes,
+			Label:              label,
+			Limit:              limit,
+			Namespaces:         o.Namespaces,
+			QueryTime:          o.QueryTime,
+		}
+		appenders = append(appenders, a)
+	}
 	if _, ok := requestedAppenders[AggregateNodeAppenderName]; ok || o.Appenders.All {
 		aggregate := o.NodeOptions.Aggregate
 		if aggregate == "" {
@@ -157,6 +253,27 @@ func ParseAppenders(o graph.TelemetryOptions) []graph.Appender {
 		a := IstioAppender{}
 		appenders = append(appenders, a)
 	}
+	if _, ok := requestedAppenders[LabelsAppenderName]; ok || isBoxByLabel(o.Params.Get("boxBy")) {
+		a := LabelsAppender{
+			Labels: boxLabels(),
+		}
+		appenders = append(appenders, a)
+	}
+	if _, ok := requestedAppenders[SaturationAppenderName]; ok {
+		threshold := defaultSaturation
+		if thresholdString := o.Params.Get("saturationThreshold"); thresholdString != "" {
+			var err error
+			if threshold, err = strconv.ParseFloat(thresholdString, 64); err != nil || threshold <= 0 || threshold > 100 {
+				graph.BadRequest(fmt.Sprintf("Invalid saturationThreshold, expecting a percentage in (0, 100]. [%s]", thresholdString))
+			}
+		}
+		a := SaturationAppender{
+			Namespaces: o.Namespaces,
+			QueryTime:  o.QueryTime,
+			Threshold:  threshold,
+		}
+		appenders = append(appenders, a)
+	}
 	if _, ok := requestedAppenders[SidecarsCheckAppenderName]; ok || o.Appenders.All {
 		a := SidecarsCheckAppender{
 			AccessibleNamespaces: o.AccessibleNamespaces,
@@ -164,7 +281,12 @@ func ParseAppenders(o graph.TelemetryOptions) []graph.Appender {
 		appenders = append(appenders, a)
 	}
 
-	return appenders
+	if _, ok := requestedAppenders[CriticalPathAppenderName]; ok {
+		a := CriticalPathAppender{}
+		finalizers = append(finalizers, a)
+	}
+
+	return appenders, finalizers
 }
 
 const (
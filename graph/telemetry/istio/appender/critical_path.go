@@ -0,0 +1,74 @@
+package appender
+
+import (
+	"github.com/kiali/kiali/graph"
+)
+
+const CriticalPathAppenderName = "criticalPath"
+
+// CriticalPathAppender is responsible for marking the critical path of each root node. The critical path is
+// the slowest request path starting at the root. An edge response time includes the time spent in any
+// downstream requests, so the path is found by following the slowest outgoing request edge at each hop. Edges
+// without a reported response time (e.g. TCP) are not considered. Cycles end the path.
+//
+// The nodes and edges on a critical path are marked IsCriticalPath. The root of each critical path is also
+// set with the CriticalPathDepth, the number of edges in its critical path.
+//
+// The appender requires response times and therefore relies on the ResponseTimeAppender. It is a finalizer,
+// it must run on the full traffic map, after the traffic generators (roots) are marked.
+// Name: criticalPath
+type CriticalPathAppender struct{}
+
+// Name implements Appender
+func (a CriticalPathAppender) Name() string {
+	return CriticalPathAppenderName
+}
+
+// AppendGraph implements Appender
+func (a CriticalPathAppender) AppendGraph(trafficMap graph.TrafficMap, globalInfo *graph.AppenderGlobalInfo, namespaceInfo *graph.AppenderNamespaceInfo) {
+	if len(trafficMap) == 0 {
+		return
+	}
+
+	for _, n := range trafficMap {
+		if isRoot, ok := n.Metadata[graph.IsRoot]; ok && isRoot.(bool) {
+			a.markCriticalPath(n)
+		}
+	}
+}
+
+func (a CriticalPathAppender) markCriticalPath(root *graph.Node) {
+	path := []*graph.Edge{}
+	visited := map[string]bool{root.ID: true}
+
+	for n := root; ; {
+		var slowest *graph.Edge
+		slowestResponseTime := 0.0
+		for _, e := range n.Edges {
+			if visited[e.Dest.ID] {
+				continue
+			}
+			if val, ok := e.Metadata[graph.ResponseTime]; ok && val.(float64) > slowestResponseTime {
+				slowest = e
+				slowestResponseTime = val.(float64)
+			}
+		}
+		if slowest == nil {
+			break
+		}
+		path = append(path, slowest)
+		visited[slowest.Dest.ID] = true
+		n = slowest.Dest
+	}
+
+	if len(path) == 0 {
+		return
+	}
+
+	root.Metadata[graph.IsCriticalPath] = true
+	root.Metadata[graph.CriticalPathDepth] = len(path)
+	for _, e := range path {
+		e.Metadata[graph.IsCriticalPath] = true
+		e.Dest.Metadata[graph.IsCriticalPath] = true
+	}
+}
@@ -0,0 +1,97 @@
+package appender
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/kiali/kiali/graph"
+)
+
+func addCriticalPathTestEdge(source, dest *graph.Node, protocol string, responseTime float64) *graph.Edge {
+	e := source.AddEdge(dest)
+	e.Metadata[graph.ProtocolKey] = protocol
+	if responseTime > 0 {
+		e.Metadata[graph.ResponseTime] = responseTime
+	}
+	return e
+}
+
+// root -> productpage (100ms)
+// productpage -> details (20ms)
+// productpage -> reviews (80ms) -> ratings (30ms)
+// reviews -> mysql (tcp)
+// ratings -> productpage (90ms, cycle)
+func buildCriticalPathTrafficMap() (graph.TrafficMap, map[string]*graph.Node, map[string]*graph.Edge) {
+	trafficMap := graph.NewTrafficMap()
+	nodes := make(map[string]*graph.Node)
+	for _, name := range []string{"root", "productpage", "details", "reviews", "ratings", "mysql"} {
+		n := graph.NewNode(graph.Unknown, "bookinfo", "", "bookinfo", name+"-v1", name, "v1", graph.GraphTypeVersionedApp)
+		trafficMap[n.ID] = &n
+		nodes[name] = &n
+	}
+	nodes["root"].Metadata[graph.IsRoot] = true
+
+	edges := map[string]*graph.Edge{
+		"root-productpage":    addCriticalPathTestEdge(nodes["root"], nodes["productpage"], "http", 100.0),
+		"productpage-details": addCriticalPathTestEdge(nodes["productpage"], nodes["details"], "http", 20.0),
+		"productpage-reviews": addCriticalPathTestEdge(nodes["productpage"], nodes["reviews"], "http", 80.0),
+		"reviews-ratings":     addCriticalPathTestEdge(nodes["reviews"], nodes["ratings"], "grpc", 30.0),
+		"reviews-mysql":       addCriticalPathTestEdge(nodes["reviews"], nodes["mysql"], "tcp", 0.0),
+		"ratings-productpage": addCriticalPathTestEdge(nodes["ratings"], nodes["productpage"], "http", 90.0),
+	}
+	return trafficMap, nodes, edges
+}
+
+func TestCriticalPath(t *testing.T) {
+	assert := assert.New(t)
+
+	trafficMap, nodes, edges := buildCriticalPathTrafficMap()
+
+	a := CriticalPathAppender{}
+	a.AppendGraph(trafficMap, graph.NewAppenderGlobalInfo(), nil)
+
+	for _, name := range []string{"root", "productpage", "reviews", "ratings"} {
+		assert.Equal(true, nodes[name].Metadata[graph.IsCriticalPath], name)
+	}
+	for _, name := range []string{"details", "mysql"} {
+		_, ok := nodes[name].Metadata[graph.IsCriticalPath]
+		assert.False(ok, name)
+	}
+
+	for _, name := range []string{"root-productpage", "productpage-reviews", "reviews-ratings"} {
+		assert.Equal(true, edges[name].Metadata[graph.IsCriticalPath], name)
+	}
+	for _, name := range []string{"productpage-details", "reviews-mysql", "ratings-productpage"} {
+		_, ok := edges[name].Metadata[graph.IsCriticalPath]
+		assert.False(ok, name)
+	}
+
+	assert.Equal(3, nodes["root"].Metadata[graph.CriticalPathDepth])
+	_, ok := nodes["productpage"].Metadata[graph.CriticalPathDepth]
+	assert.False(ok)
+}
+
+func TestCriticalPathNoResponseTime(t *testing.T) {
+	assert := assert.New(t)
+
+	trafficMap, nodes, edges := buildCriticalPathTrafficMap()
+	delete(edges["root-productpage"].Metadata, graph.ResponseTime)
+
+	a := CriticalPathAppender{}
+	a.AppendGraph(trafficMap, graph.NewAppenderGlobalInfo(), nil)
+
+	for _, n := range trafficMap {
+		_, ok := n.Metadata[graph.IsCriticalPath]
+		assert.False(ok, n.App)
+	}
+	_, ok := nodes["root"].Metadata[graph.CriticalPathDepth]
+	assert.False(ok)
+}
+
+func TestCriticalPathOptIn(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.NotContains(parseAppenderNames(graph.RequestedAppenders{All: true}), CriticalPathAppenderName)
+	assert.Contains(parseAppenderNames(graph.RequestedAppenders{AppenderNames: []string{CriticalPathAppenderName}}), CriticalPathAppenderName)
+}
@@ -0,0 +1,403 @@
+package appender
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/prometheus/common/model"
+
+	"github.com/kiali/kiali/business"
+	"github.com/kiali/kiali/config"
+	"github.com/kiali/kiali/graph"
+	"github.com/kiali/kiali/graph/telemetry/istio/util"
+	"github.com/kiali/kiali/kubernetes"
+	"github.com/kiali/kiali/log"
+	"github.com/kiali/kiali/prometheus"
+)
+
+const (
+	// ExternalHostAppenderName uniquely identifies the appender: externalHost
+	ExternalHostAppenderName = "externalHost"
+
+	blackHoleCluster      = "BlackHoleCluster"
+	meshOutboundPolicyKey = "meshOutboundPolicyKey" // global vendor info mesh outbound traffic policy mode
+	outboundPolicyKey     = "outboundPolicyKey"     // global vendor info map[namespace]outbound traffic policy mode
+)
+
+// ExternalHostAppender is responsible for breaking the traffic sent to the PassthroughCluster and the
+// BlackHoleCluster down by requested host (the authority or SNI, reported as destination_service). Each host
+// becomes an "external host" node, a service node in the source namespace with isExternalHost set in the metadata,
+// replacing the edges to the egress cluster node. Traffic reported without a host stays on the egress cluster
+// node, which is removed when all of its traffic is broken down.
+//
+// An external host is flagged as blocked when its traffic is sent to the BlackHoleCluster, or when the outbound
+// traffic policy of the source namespace is REGISTRY_ONLY and no ServiceEntry exported to the namespace defines the
+// host. The outbound traffic policy is the one of the namespace-wide Sidecar, if any, else of the root namespace
+// Sidecar, else of the mesh configuration. Sidecars selecting workloads are not considered.
+//
+// Like the edges to the egress cluster nodes, the hosts are reported using source telemetry.
+// Name: externalHost
+type ExternalHostAppender struct {
+	AccessibleNamespaces map[string]time.Time
+	GraphType            string
+	Namespaces           graph.NamespaceInfoMap
+	QueryTime            int64 // unix time in seconds
+}
+
+// externalHostTS holds a single time series of the traffic sent to an egress cluster
+type externalHostTS struct {
+	val      float64
+	protocol string
+	code     string
+	flags    string
+	host     string // empty when the series reports no host
+}
+
+// Name implements Appender
+func (a ExternalHostAppender) Name() string {
+	return ExternalHostAppenderName
+}
+
+// AppendGraph implements Appender
+func (a ExternalHostAppender) AppendGraph(trafficMap graph.TrafficMap, globalInfo *graph.AppenderGlobalInfo, namespaceInfo *graph.AppenderNamespaceInfo) {
+	if !hasEgressClusterNodes(trafficMap) {
+		return
+	}
+
+	if globalInfo.PromClient == nil {
+		var err error
+		globalInfo.PromClient, err = prometheus.NewClient()
+		graph.CheckError(err)
+	}
+
+	seriesMap := a.querySeries(namespaceInfo.Namespace, globalInfo.PromClient)
+	policy := a.getOutboundTrafficPolicy(namespaceInfo.Namespace, globalInfo)
+	serviceEntryHosts := loadServiceEntryHosts(a.AccessibleNamespaces, globalInfo)
+
+	a.applyExternalHosts(trafficMap, seriesMap, namespaceInfo.Namespace, policy, serviceEntryHosts)
+}
+
+func hasEgressClusterNodes(trafficMap graph.TrafficMap) bool {
+	for _, n := range trafficMap {
+		if n.Metadata[graph.IsEgressCluster] == true {
+			return true
+		}
+	}
+	return false
+}
+
+func (a ExternalHostAppender) querySeries(namespace string, client *prometheus.Client) map[string][]externalHostTS {
+	log.Tracef("Generating external hosts; namespace = %v", namespace)
+
+	seriesMap := make(map[string][]externalHostTS)
+	duration := int(a.Namespaces[namespace].Duration.Seconds())
+	queryTime := time.Unix(a.QueryTime, 0)
+	selector := fmt.Sprintf(`reporter="source",source_workload_namespace="%s",destination_service_name=~"PassthroughCluster|BlackHoleCluster"`, namespace)
+	groupBy := "source_cluster,source_workload_namespace,source_workload,source_canonical_service,source_canonical_revision,destination_cluster,destination_service_namespace,destination_service,destination_service_name,destination_workload_namespace,destination_workload,destination_canonical_service,destination_canonical_revision"
+
+	// 1) HTTP/GRPC traffic
+	query := fmt.Sprintf(`sum(rate(istio_requests_total{%s}[%vs])) by (%s,request_protocol,response_code,grpc_response_status,response_flags) > 0`, selector, duration, groupBy)
+	vector := promQuery(query, queryTime, client.GetContext(), client.API(), a)
+	a.populateSeriesMap(seriesMap, &vector, false)
+
+	// 2) TCP traffic
+	query = fmt.Sprintf(`sum(rate(istio_tcp_sent_bytes_total{%s}[%vs])) by (%s,response_flags) > 0`, selector, duration, groupBy)
+	vector = promQuery(query, queryTime, client.GetContext(), client.API(), a)
+	a.populateSeriesMap(seriesMap, &vector, true)
+
+	return seriesMap
+}
+
+func (a ExternalHostAppender) populateSeriesMap(seriesMap map[string][]externalHostTS, vector *model.Vector, isTCP bool) {
+	for _, s := range *vector {
+		m := s.Metric
+		lSourceCluster, sourceClusterOk := m["source_cluster"]
+		lSourceWlNs, sourceWlNsOk := m["source_workload_namespace"]
+		lSourceWl, sourceWlOk := m["source_workload"]
+		lSourceApp, sourceAppOk := m["source_canonical_service"]
+		lSourceVer, sourceVerOk := m["source_canonical_revision"]
+		lDestCluster, destClusterOk := m["destination_cluster"]
+		lDestSvcNs, destSvcNsOk := m["destination_service_namespace"]
+		lDestSvc, destSvcOk := m["destination_service"]
+		lDestSvcName, destSvcNameOk := m["destination_service_name"]
+		lDestWlNs, destWlNsOk := m["destination_workload_namespace"]
+		lDestWl, destWlOk := m["destination_workload"]
+		lDestApp, destAppOk := m["destination_canonical_service"]
+		lDestVer, destVerOk := m["destination_canonical_revision"]
+		lFlags, flagsOk := m["response_flags"]
+
+		if !sourceWlNsOk || !sourceWlOk || !sourceAppOk || !sourceVerOk || !destSvcNsOk || !destSvcNameOk || !destSvcOk || !destWlNsOk || !destWlOk || !destAppOk || !destVerOk || !flagsOk {
+			log.Warningf("populateSeriesMap: Skipping %s, missing expected labels", m.String())
+			continue
+		}
+
+		sourceWlNs := string(lSourceWlNs)
+		sourceWl := string(lSourceWl)
+		sourceApp := string(lSourceApp)
+		sourceVer := string(lSourceVer)
+		destSvc := string(lDestSvc)
+
+		// handle clusters
+		sourceCluster, destCluster := util.HandleClusters(lSourceCluster, sourceClusterOk, lDestCluster, destClusterOk)
+
+		if util.IsBadSourceTelemetry(sourceCluster, sourceClusterOk, sourceWlNs, sourceWl, sourceApp) {
+			continue
+		}
+
+		val := float64(s.Value)
+
+		// handle unusual destinations
+		destCluster, destSvcNs, destSvcName, destWlNs, destWl, destApp, destVer, _ := util.HandleDestination(sourceCluster, sourceWlNs, sourceWl, destCluster, string(lDestSvcNs), destSvc, string(lDestSvcName), string(lDestWlNs), string(lDestWl), string(lDestApp), string(lDestVer))
+
+		if util.IsBadDestTelemetry(destCluster, destClusterOk, destSvcNs, destSvc, destSvcName, destWl) {
+			continue
+		}
+
+		// Should not happen but if NaN for any reason, Just skip it
+		if math.IsNaN(val) {
+			continue
+		}
+
+		ts := externalHostTS{val: val, protocol: graph.TCP.Name, flags: string(lFlags)}
+		if !isTCP {
+			lProtocol, protocolOk := m["request_protocol"]
+			lCode, codeOk := m["response_code"]
+			lGrpc, grpcOk := m["grpc_response_status"]
+
+			if !protocolOk || !codeOk {
+				log.Warningf("populateSeriesMap: Skipping %s, missing expected HTTP/GRPC TS labels", m.String())
+				continue
+			}
+
+			ts.protocol = string(lProtocol)
+			ts.code = util.HandleResponseCode(ts.protocol, string(lCode), grpcOk, string(lGrpc))
+		}
+		// destination_service holds the requested host, or the egress cluster name when the host is unknown
+		if graph.IsOK(destSvc) && destSvc != destSvcName {
+			ts.host = destSvc
+		}
+
+		sourceID, _ := graph.Id(sourceCluster, sourceWlNs, "", sourceWlNs, sourceWl, sourceApp, sourceVer, a.GraphType)
+		destID, _ := graph.Id(destCluster, destSvcNs, destSvcName, destWlNs, destWl, destApp, destVer, a.GraphType)
+		key := fmt.Sprintf("%s %s", sourceID, destID)
+
+		seriesMap[key] = append(seriesMap[key], ts)
+	}
+}
+
+// applyExternalHosts replaces the edges to the egress cluster nodes with edges to the external host nodes, for the
+// series reporting a host. The incoming traffic of the egress cluster and external host nodes is then recalculated
+// from their new incoming edges.
+func (a ExternalHostAppender) applyExternalHosts(trafficMap graph.TrafficMap, seriesMap map[string][]externalHostTS, namespace, policy string, serviceEntryHosts serviceEntryHosts) {
+	// the nodes whose incoming edges change
+	touched := make(map[string]*graph.Node)
+
+	sources := make([]*graph.Node, 0, len(trafficMap))
+	for _, n := range trafficMap {
+		sources = append(sources, n)
+	}
+
+	for _, n := range sources {
+		var edges []*graph.Edge
+		var redirected []*graph.Edge
+		for _, e := range n.Edges {
+			if !isExternalHostCandidate(e) {
+				edges = append(edges, e)
+				continue
+			}
+			series := seriesForEdge(seriesMap[fmt.Sprintf("%s %s", n.ID, e.Dest.ID)], e.Metadata[graph.ProtocolKey].(string))
+			if !hasHost(series) {
+				edges = append(edges, e)
+				continue
+			}
+			redirected = append(redirected, e)
+		}
+		if len(redirected) == 0 {
+			continue
+		}
+		n.Edges = edges
+
+		for _, e := range redirected {
+			egress := e.Dest
+			touched[egress.ID] = egress
+			protocol := e.Metadata[graph.ProtocolKey].(string)
+
+			for _, ts := range seriesForEdge(seriesMap[fmt.Sprintf("%s %s", n.ID, egress.ID)], protocol) {
+				dest := egress
+				if ts.host != "" {
+					dest = a.getExternalHostNode(trafficMap, egress, ts.host, namespace, policy, serviceEntryHosts)
+				}
+				touched[dest.ID] = dest
+
+				var edge *graph.Edge
+				for _, ne := range n.Edges {
+					if ne.Dest == dest && ne.Metadata[graph.ProtocolKey] == protocol {
+						edge = ne
+						break
+					}
+				}
+				if edge == nil {
+					edge = n.AddEdge(dest)
+					edge.Metadata[graph.ProtocolKey] = protocol
+				}
+				graph.AddToMetadata(protocol, ts.val, ts.code, ts.flags, ts.host, nil, nil, edge.Metadata)
+			}
+		}
+	}
+
+	// recalculate the incoming traffic, and remove the egress cluster nodes left without traffic
+	for id, dest := range touched {
+		graph.ResetIncomingMetadata(dest.Metadata)
+		hasIncoming := false
+		for _, n := range trafficMap {
+			for _, e := range n.Edges {
+				if e.Dest == dest {
+					graph.AddIncomingEdgeToMetadata(dest.Metadata, e.Metadata)
+					hasIncoming = true
+				}
+			}
+		}
+		if !hasIncoming && len(dest.Edges) == 0 {
+			delete(trafficMap, id)
+		}
+	}
+}
+
+// isExternalHostCandidate returns true for an edge to an egress cluster node
+func isExternalHostCandidate(e *graph.Edge) bool {
+	if e.Dest.Metadata[graph.IsEgressCluster] != true {
+		return false
+	}
+	_, isExternalHost := e.Dest.Metadata[graph.IsExternalHost]
+	return !isExternalHost
+}
+
+func seriesForEdge(series []externalHostTS, protocol string) []externalHostTS {
+	var result []externalHostTS
+	for _, ts := range series {
+		if ts.protocol == protocol {
+			result = append(result, ts)
+		}
+	}
+	return result
+}
+
+func hasHost(series []externalHostTS) bool {
+	for _, ts := range series {
+		if ts.host != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// getExternalHostNode returns the external host node of the host, adding it to the traffic map if necessary
+func (a ExternalHostAppender) getExternalHostNode(trafficMap graph.TrafficMap, egress *graph.Node, host, namespace, policy string, serviceEntryHosts serviceEntryHosts) *graph.Node {
+	id, _ := graph.Id(egress.Cluster, namespace, host, "", "", "", "", a.GraphType)
+	if n, ok := trafficMap[id]; ok {
+		return n
+	}
+
+	n := graph.NewNode(egress.Cluster, namespace, host, "", "", "", "", a.GraphType)
+	info := &graph.ExternalHost{
+		EgressCluster:         egress.Service,
+		Host:                  host,
+		OutboundTrafficPolicy: policy,
+	}
+	if se, ok := matchServiceEntry(host, namespace, serviceEntryHosts); ok {
+		info.ServiceEntry = fmt.Sprintf("%s/%s", se.namespace, se.name)
+	}
+	info.Blocked = egress.Service == blackHoleCluster || (policy == kubernetes.OutboundTrafficPolicyRegistryOnly && info.ServiceEntry == "")
+	n.Metadata[graph.IsEgressCluster] = true
+	n.Metadata[graph.IsExternalHost] = info
+	trafficMap[n.ID] = &n
+
+	return &n
+}
+
+// matchServiceEntry returns a ServiceEntry exported to the namespace and defining the host, exactly or with a
+// wildcard prefix (e.g. *.wikipedia.org). A port of the host is ignored.
+func matchServiceEntry(host, namespace string, serviceEntryHosts serviceEntryHosts) (*serviceEntry, bool) {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	for seHost, serviceEntriesForHost := range serviceEntryHosts {
+		if seHost != host && !(strings.HasPrefix(seHost, "*") && strings.HasSuffix(host, seHost[1:])) {
+			continue
+		}
+		for _, se := range serviceEntriesForHost {
+			if isExportedToNamespace(se, namespace) {
+				return se, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// getOutboundTrafficPolicy returns the outbound traffic policy mode of the namespace
+func (a ExternalHostAppender) getOutboundTrafficPolicy(namespace string, globalInfo *graph.AppenderGlobalInfo) string {
+	var policies map[string]string
+	if existing, ok := globalInfo.Vendor[outboundPolicyKey]; ok {
+		policies = existing.(map[string]string)
+	} else {
+		policies = make(map[string]string)
+		globalInfo.Vendor[outboundPolicyKey] = policies
+	}
+	if policy, ok := policies[namespace]; ok {
+		return policy
+	}
+
+	policy := a.getSidecarOutboundTrafficPolicy(namespace, globalInfo)
+	if rootNamespace := config.Get().IstioNamespace; policy == "" && rootNamespace != namespace {
+		if _, ok := a.AccessibleNamespaces[rootNamespace]; ok {
+			policy = a.getSidecarOutboundTrafficPolicy(rootNamespace, globalInfo)
+		}
+	}
+	if policy == "" {
+		policy = getMeshOutboundTrafficPolicy(globalInfo)
+	}
+	policies[namespace] = policy
+
+	return policy
+}
+
+// getSidecarOutboundTrafficPolicy returns the outbound traffic policy mode of the namespace-wide Sidecar of the
+// namespace, or "" if not set
+func (a ExternalHostAppender) getSidecarOutboundTrafficPolicy(namespace string, globalInfo *graph.AppenderGlobalInfo) string {
+	istioCfg, err := globalInfo.Business.IstioConfig.GetIstioConfigList(business.IstioConfigCriteria{
+		IncludeSidecars: true,
+		Namespace:       namespace,
+	})
+	graph.CheckError(err)
+
+	for _, sc := range istioCfg.Sidecars {
+		if sc.Spec.WorkloadSelector != nil {
+			continue
+		}
+		if otp, ok := sc.Spec.OutboundTrafficPolicy.(map[string]interface{}); ok {
+			if mode, ok := otp["mode"].(string); ok && mode != "" {
+				return mode
+			}
+		}
+	}
+	return ""
+}
+
+// getMeshOutboundTrafficPolicy returns the outbound traffic policy mode of the mesh, ALLOW_ANY if it can not be read
+func getMeshOutboundTrafficPolicy(globalInfo *graph.AppenderGlobalInfo) string {
+	if policy, ok := globalInfo.Vendor[meshOutboundPolicyKey]; ok {
+		return policy.(string)
+	}
+
+	policy, err := globalInfo.Business.Mesh.OutboundTrafficPolicyMode()
+	if err != nil {
+		log.Warningf("Unable to read the mesh outbound traffic policy, assuming %s: %v", kubernetes.OutboundTrafficPolicyAllowAny, err)
+		policy = kubernetes.OutboundTrafficPolicyAllowAny
+	}
+	globalInfo.Vendor[meshOutboundPolicyKey] = policy
+
+	return policy
+}
@@ -0,0 +1,154 @@
+package appender
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/kiali/kiali/config"
+	"github.com/kiali/kiali/graph"
+	"github.com/kiali/kiali/kubernetes"
+)
+
+func externalHostTestMetric(egressCluster, host, protocol, code, flags string) model.Metric {
+	m := model.Metric{
+		"source_workload_namespace":      "bookinfo",
+		"source_workload":                "reviews-v1",
+		"source_canonical_service":       "reviews",
+		"source_canonical_revision":      "v1",
+		"destination_service_namespace":  "unknown",
+		"destination_service":            model.LabelValue(host),
+		"destination_service_name":       model.LabelValue(egressCluster),
+		"destination_workload_namespace": "unknown",
+		"destination_workload":           "unknown",
+		"destination_canonical_service":  "unknown",
+		"destination_canonical_revision": "unknown",
+		"response_flags":                 model.LabelValue(flags)}
+	if protocol != graph.TCP.Name {
+		m["request_protocol"] = model.LabelValue(protocol)
+		m["response_code"] = model.LabelValue(code)
+	}
+	return m
+}
+
+func TestExternalHosts(t *testing.T) {
+	assert := assert.New(t)
+	config.Set(config.NewConfig())
+
+	trafficMap := graph.NewTrafficMap()
+	reviews := graph.NewNode(graph.Unknown, "bookinfo", "", "bookinfo", "reviews-v1", "reviews", "v1", graph.GraphTypeWorkload)
+	passthrough := graph.NewNode(graph.Unknown, "unknown", "PassthroughCluster", "unknown", "unknown", "unknown", "unknown", graph.GraphTypeWorkload)
+	blackHole := graph.NewNode(graph.Unknown, "unknown", "BlackHoleCluster", "unknown", "unknown", "unknown", "unknown", graph.GraphTypeWorkload)
+	trafficMap[reviews.ID] = &reviews
+	trafficMap[passthrough.ID] = &passthrough
+	trafficMap[blackHole.ID] = &blackHole
+	assert.Equal(true, passthrough.Metadata[graph.IsEgressCluster])
+
+	e := reviews.AddEdge(&passthrough)
+	e.Metadata[graph.ProtocolKey] = graph.HTTP.Name
+	graph.AddToMetadata(graph.HTTP.Name, 6.0, "200", "-", "PassthroughCluster", reviews.Metadata, passthrough.Metadata, e.Metadata)
+	e = reviews.AddEdge(&blackHole)
+	e.Metadata[graph.ProtocolKey] = graph.TCP.Name
+	graph.AddToMetadata(graph.TCP.Name, 100.0, "", "-", "BlackHoleCluster", reviews.Metadata, blackHole.Metadata, e.Metadata)
+
+	a := ExternalHostAppender{
+		GraphType:  graph.GraphTypeWorkload,
+		Namespaces: graph.NamespaceInfoMap{"bookinfo": {Name: "bookinfo", Duration: 60 * time.Second}},
+	}
+	seriesMap := make(map[string][]externalHostTS)
+	a.populateSeriesMap(seriesMap, &model.Vector{
+		&model.Sample{Metric: externalHostTestMetric("PassthroughCluster", "api.example.com", "http", "200", "-"), Value: 3.0},
+		&model.Sample{Metric: externalHostTestMetric("PassthroughCluster", "api.example.com", "http", "503", "UF"), Value: 1.0},
+		&model.Sample{Metric: externalHostTestMetric("PassthroughCluster", "en.wikipedia.org:443", "http", "200", "-"), Value: 1.0},
+		&model.Sample{Metric: externalHostTestMetric("PassthroughCluster", "PassthroughCluster", "http", "200", "-"), Value: 1.0},
+	}, false)
+	a.populateSeriesMap(seriesMap, &model.Vector{
+		&model.Sample{Metric: externalHostTestMetric("BlackHoleCluster", "mysql.example.com", "tcp", "", "-"), Value: 100.0},
+	}, true)
+
+	serviceEntryHosts := newServiceEntryHosts()
+	serviceEntryHosts.addHost("*.wikipedia.org", &serviceEntry{name: "wikipedia", namespace: "bookinfo"})
+
+	a.applyExternalHosts(trafficMap, seriesMap, "bookinfo", kubernetes.OutboundTrafficPolicyRegistryOnly, serviceEntryHosts)
+
+	// the black hole traffic is fully broken down, the hostless passthrough traffic remains
+	assert.Equal(5, len(trafficMap))
+	assert.Nil(trafficMap[blackHole.ID])
+	assert.Equal(4, len(reviews.Edges))
+
+	edges := make(map[string]*graph.Edge)
+	for _, e := range reviews.Edges {
+		edges[e.Dest.Service] = e
+	}
+
+	apiEdge := edges["api.example.com"]
+	assert.Equal(4.0, apiEdge.Metadata[graph.HTTP.EdgeRates[0].Name])
+	assert.Equal(1.0, apiEdge.Metadata["http5xx"])
+	assert.Equal(4.0, apiEdge.Dest.Metadata["httpIn"])
+	assert.Equal(1.0, apiEdge.Dest.Metadata["httpIn5xx"])
+	assert.Equal("bookinfo", apiEdge.Dest.Namespace)
+	assert.Equal(true, apiEdge.Dest.Metadata[graph.IsEgressCluster])
+	assert.Equal(&graph.ExternalHost{
+		Blocked:               true,
+		EgressCluster:         "PassthroughCluster",
+		Host:                  "api.example.com",
+		OutboundTrafficPolicy: kubernetes.OutboundTrafficPolicyRegistryOnly,
+	}, apiEdge.Dest.Metadata[graph.IsExternalHost])
+
+	wikipedia := edges["en.wikipedia.org:443"].Dest
+	assert.Equal(1.0, wikipedia.Metadata["httpIn"])
+	assert.Equal(&graph.ExternalHost{
+		Blocked:               false,
+		EgressCluster:         "PassthroughCluster",
+		Host:                  "en.wikipedia.org:443",
+		OutboundTrafficPolicy: kubernetes.OutboundTrafficPolicyRegistryOnly,
+		ServiceEntry:          "bookinfo/wikipedia",
+	}, wikipedia.Metadata[graph.IsExternalHost])
+
+	// the hostless traffic stays on the egress cluster node, whose incoming traffic is recalculated
+	assert.Equal(1.0, edges["PassthroughCluster"].Metadata[graph.HTTP.EdgeRates[0].Name])
+	assert.Equal(1.0, passthrough.Metadata["httpIn"])
+	assert.Nil(passthrough.Metadata[graph.IsExternalHost])
+
+	// black hole traffic is always blocked, and the source outgoing traffic is unchanged
+	mysql := edges["mysql.example.com"]
+	assert.Equal(graph.TCP.Name, mysql.Metadata[graph.ProtocolKey])
+	assert.Equal(100.0, mysql.Metadata[graph.TCP.EdgeRates[0].Name])
+	assert.Equal(100.0, mysql.Dest.Metadata["tcpIn"])
+	assert.Equal(true, mysql.Dest.Metadata[graph.IsExternalHost].(*graph.ExternalHost).Blocked)
+	assert.Equal(6.0, reviews.Metadata["httpOut"])
+	assert.Equal(100.0, reviews.Metadata["tcpOut"])
+}
+
+func TestExternalHostsAllowAny(t *testing.T) {
+	assert := assert.New(t)
+
+	serviceEntryHosts := newServiceEntryHosts()
+	serviceEntryHosts.addHost("api.example.com", &serviceEntry{name: "api", namespace: "other", exportTo: []interface{}{"."}})
+
+	// a service entry not exported to the namespace does not match
+	_, ok := matchServiceEntry("api.example.com", "bookinfo", serviceEntryHosts)
+	assert.False(ok)
+	se, ok := matchServiceEntry("api.example.com:443", "other", serviceEntryHosts)
+	assert.True(ok)
+	assert.Equal("api", se.name)
+
+	trafficMap := graph.NewTrafficMap()
+	passthrough := graph.NewNode(graph.Unknown, "unknown", "PassthroughCluster", "unknown", "unknown", "unknown", "unknown", graph.GraphTypeWorkload)
+	trafficMap[passthrough.ID] = &passthrough
+
+	a := ExternalHostAppender{GraphType: graph.GraphTypeWorkload}
+	n := a.getExternalHostNode(trafficMap, &passthrough, "api.example.com", "bookinfo", kubernetes.OutboundTrafficPolicyAllowAny, serviceEntryHosts)
+	assert.False(n.Metadata[graph.IsExternalHost].(*graph.ExternalHost).Blocked)
+	assert.Equal(n, a.getExternalHostNode(trafficMap, &passthrough, "api.example.com", "bookinfo", kubernetes.OutboundTrafficPolicyAllowAny, serviceEntryHosts))
+	assert.Equal(2, len(trafficMap))
+}
+
+func TestExternalHostsOptIn(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.NotContains(parseAppenderNames(graph.RequestedAppenders{All: true}), ExternalHostAppenderName)
+	assert.Contains(parseAppenderNames(graph.RequestedAppenders{AppenderNames: []string{ExternalHostAppenderName}}), ExternalHostAppenderName)
+}
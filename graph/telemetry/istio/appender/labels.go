@@ -0,0 +1,105 @@
+package appender
+
+import (
+	"strings"
+
+	"github.com/kiali/kiali/config"
+	"github.com/kiali/kiali/graph"
+)
+
+const LabelsAppenderName = "labels"
+
+// LabelsAppender is responsible for adding the values of the configured box labels (see GraphBoxing) to
+// the nodes, allowing the config vendors to box the nodes by label. A workload node has the labels of its
+// workload, a service node those of its service. An app node has a label if all of the app workloads
+// have the same value for it. It runs when requested by name or when boxing by label.
+// Name: labels
+type LabelsAppender struct {
+	Labels []string
+}
+
+// Name implements Appender
+func (a LabelsAppender) Name() string {
+	return LabelsAppenderName
+}
+
+// AppendGraph implements Appender
+func (a LabelsAppender) AppendGraph(trafficMap graph.TrafficMap, globalInfo *graph.AppenderGlobalInfo, namespaceInfo *graph.AppenderNamespaceInfo) {
+	if len(trafficMap) == 0 || len(a.Labels) == 0 {
+		return
+	}
+
+	a.applyLabels(trafficMap, globalInfo, namespaceInfo)
+}
+
+func (a LabelsAppender) applyLabels(trafficMap graph.TrafficMap, globalInfo *graph.AppenderGlobalInfo, namespaceInfo *graph.AppenderNamespaceInfo) {
+	for _, n := range trafficMap {
+		if n.Namespace != namespaceInfo.Namespace {
+			continue
+		}
+		var labels map[string]string
+		switch n.NodeType {
+		case graph.NodeTypeApp:
+			workloads := getAppWorkloads(namespaceInfo.Namespace, n.App, n.Version, globalInfo)
+			for i, workload := range workloads {
+				workloadLabels := a.filter(workload.Labels)
+				if i == 0 {
+					labels = workloadLabels
+					continue
+				}
+				for k, v := range labels {
+					if workloadLabels[k] != v {
+						delete(labels, k)
+					}
+				}
+			}
+		case graph.NodeTypeService:
+			if srv, found := getServiceDefinition(namespaceInfo.Namespace, n.Service, globalInfo); found {
+				labels = a.filter(srv.Labels)
+			}
+		case graph.NodeTypeWorkload:
+			if workload, found := getWorkload(namespaceInfo.Namespace, n.Workload, globalInfo); found {
+				labels = a.filter(workload.Labels)
+			}
+		default:
+			continue
+		}
+		if len(labels) > 0 {
+			n.Metadata[graph.Labels] = labels
+		}
+	}
+}
+
+// isBoxByLabel returns true if boxing by a configured box label, other than the version, is requested
+func isBoxByLabel(boxBy string) bool {
+	for _, box := range strings.Split(boxBy, ",") {
+		for _, label := range boxLabels() {
+			if strings.TrimSpace(box) == label {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// filter returns the configured labels of the labels
+func (a LabelsAppender) filter(labels map[string]string) map[string]string {
+	result := make(map[string]string)
+	for _, label := range a.Labels {
+		if value, ok := labels[label]; ok {
+			result[label] = value
+		}
+	}
+	return result
+}
+
+// boxLabels returns the configured box labels, the version is boxed by the node version
+func boxLabels() []string {
+	labels := []string{}
+	for _, label := range config.Get().GraphBoxing.Labels {
+		if label != graph.BoxByVersion {
+			labels = append(labels, label)
+		}
+	}
+	return labels
+}
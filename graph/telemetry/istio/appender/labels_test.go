@@ -0,0 +1,55 @@
+package appender
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/kiali/kiali/graph"
+)
+
+func TestLabelsAppender(t *testing.T) {
+	assert := assert.New(t)
+
+	services := buildFakeServicesHealth("")
+	services[0].ObjectMeta.Labels = map[string]string{"team": "payments", "other": "value"}
+	deployments := buildFakeWorkloadDeployments()
+	deployments[0].Spec.Template.ObjectMeta.Labels["team"] = "payments"
+	deployments[0].Spec.Template.ObjectMeta.Labels["tier"] = "backend"
+	// the pod, not controlled by the deployment, is a workload of the app too
+	pods := buildFakeWorkloadPods()
+	pods[0].ObjectMeta.Labels["team"] = "payments"
+	pods[0].ObjectMeta.Labels["tier"] = "frontend"
+	businessLayer := setupHealthConfig(services, deployments, pods)
+
+	// only the configured labels are added, an app has the labels shared by its workloads
+	expected := map[string]map[string]string{
+		graph.NodeTypeApp:      {"team": "payments"},
+		graph.NodeTypeService:  {"team": "payments"},
+		graph.NodeTypeWorkload: {"team": "payments", "tier": "backend"},
+	}
+	for _, trafficMap := range []graph.TrafficMap{buildAppTrafficMap(), buildServiceTrafficMap(), buildWorkloadTrafficMap()} {
+		globalInfo := graph.NewAppenderGlobalInfo()
+		globalInfo.Business = businessLayer
+		namespaceInfo := graph.NewAppenderNamespaceInfo("testNamespace")
+
+		a := LabelsAppender{Labels: boxLabels()}
+		a.AppendGraph(trafficMap, globalInfo, namespaceInfo)
+
+		for _, node := range trafficMap {
+			assert.Equal(expected[node.NodeType], node.Metadata[graph.Labels], node.NodeType)
+		}
+	}
+}
+
+func TestLabelsOptIn(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.NotContains(parseAppenderNames(graph.RequestedAppenders{All: true}), LabelsAppenderName)
+	assert.Contains(parseAppenderNames(graph.RequestedAppenders{AppenderNames: []string{LabelsAppenderName}}), LabelsAppenderName)
+
+	// boxing by label requests the labels, the version is not a label of the appender
+	assert.True(isBoxByLabel("app, team"))
+	assert.False(isBoxByLabel("app,version"))
+	assert.False(isBoxByLabel(""))
+}
@@ -0,0 +1,267 @@
+package appender
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/prometheus/common/model"
+
+	"github.com/kiali/kiali/graph"
+	"github.com/kiali/kiali/graph/telemetry/istio/util"
+	"github.com/kiali/kiali/log"
+	"github.com/kiali/kiali/prometheus"
+)
+
+const (
+	// OperationsAppenderName uniquely identifies the appender: operations
+	OperationsAppenderName = "operations"
+)
+
+// OperationsAppender is responsible for breaking down the traffic of each HTTP and gRPC edge by request
+// operation. The operation is the value of a user-specified metric attribute, by default the
+// request_operation attribute set by Istio request classification, but it can be any attribute holding
+// a request path or gRPC method. For each edge the top operations, by request rate, are reported with
+// their request rate, error percentage and 95th percentile response time.
+//
+// Unlike the AggregateNodeAppender, the graph topology is not changed.
+// Response times can not be aggregated, so when service nodes are injected the response times are
+// reported only on the edges leaving the service nodes, as for the ResponseTimeAppender.
+// Name: operations
+type OperationsAppender struct {
+	GraphType          string
+	InjectServiceNodes bool
+	Label              string // the metric attribute identifying the operation
+	Limit              int    // the maximum number of operations reported per edge
+	Namespaces         graph.NamespaceInfoMap
+	QueryTime          int64 // unix time in seconds
+}
+
+// operationTraffic holds the traffic of a single operation of an edge
+type operationTraffic struct {
+	errors       float64 // errors/sec
+	requests     float64 // requests/sec
+	responseTime float64 // 95th percentile in millis
+}
+
+// operationsMap maps an edge key "<sourceID> <destID>" to the traffic of each of its operations
+type operationsMap map[string]map[string]*operationTraffic
+
+func (m operationsMap) get(edgeKey, operation string) *operationTraffic {
+	operations, ok := m[edgeKey]
+	if !ok {
+		operations = make(map[string]*operationTraffic)
+		m[edgeKey] = operations
+	}
+	t, ok := operations[operation]
+	if !ok {
+		t = &operationTraffic{}
+		operations[operation] = t
+	}
+	return t
+}
+
+// Name implements Appender
+func (a OperationsAppender) Name() string {
+	return OperationsAppenderName
+}
+
+// AppendGraph implements Appender
+func (a OperationsAppender) AppendGraph(trafficMap graph.TrafficMap, globalInfo *graph.AppenderGlobalInfo, namespaceInfo *graph.AppenderNamespaceInfo) {
+	if len(trafficMap) == 0 {
+		return
+	}
+
+	if globalInfo.PromClient == nil {
+		var err error
+		globalInfo.PromClient, err = prometheus.NewClient()
+		graph.CheckError(err)
+	}
+
+	a.appendGraph(trafficMap, namespaceInfo.Namespace, globalInfo.PromClient)
+}
+
+func (a OperationsAppender) appendGraph(trafficMap graph.TrafficMap, namespace string, client *prometheus.Client) {
+	log.Tracef("Generating operations for label [%s]; namespace = %v", a.Label, namespace)
+
+	duration := a.Namespaces[namespace].Duration
+	operations := make(operationsMap)
+
+	groupBy := fmt.Sprintf("source_cluster,source_workload_namespace,source_workload,source_canonical_service,source_canonical_revision,destination_cluster,destination_service_namespace,destination_service,destination_service_name,destination_workload_namespace,destination_workload,destination_canonical_service,destination_canonical_revision,%s", a.Label)
+
+	// query prometheus in two queries per metric, as for the anomaly appender:
+	// 1) query for requests originating from a workload outside the namespace (destination telemetry)
+	// 2) query for requests originating from a workload inside of the namespace (source telemetry)
+	//   note: in Prometheus a negative test on an unset label matches everything, so requests without an
+	//     operation are excluded using a regex also matching the empty value.
+	selectors := []string{
+		fmt.Sprintf(`reporter="destination",source_workload_namespace!="%s",destination_service_namespace="%s",%s!~"|unknown"`, namespace, namespace, a.Label),
+		fmt.Sprintf(`reporter="source",source_workload_namespace="%s",%s!~"|unknown"`, namespace, a.Label),
+	}
+
+	for _, selector := range selectors {
+		query := fmt.Sprintf(`sum(rate(%s{%s}[%vs])) by (%s,request_protocol,response_code,grpc_response_status) > 0`,
+			"istio_requests_total",
+			selector,
+			int(duration.Seconds()), // range duration for the query
+			groupBy)
+		vector := promQuery(query, time.Unix(a.QueryTime, 0), client.GetContext(), client.API(), a)
+		a.populateOperationsMap(operations, &vector, false)
+
+		query = fmt.Sprintf(`histogram_quantile(0.95, sum(rate(%s{%s}[%vs])) by (le,%s)) > 0`,
+			"istio_request_duration_milliseconds_bucket",
+			selector,
+			int(duration.Seconds()), // range duration for the query
+			groupBy)
+		vector = promQuery(query, time.Unix(a.QueryTime, 0), client.GetContext(), client.API(), a)
+		a.populateOperationsMap(operations, &vector, true)
+	}
+
+	applyOperations(trafficMap, operations, a.Limit)
+}
+
+// applyOperations sets the top operations, by request rate, of each request-based edge
+func applyOperations(trafficMap graph.TrafficMap, operations operationsMap, limit int) {
+	for _, n := range trafficMap {
+		for _, e := range n.Edges {
+			if protocol, ok := e.Metadata[graph.ProtocolKey]; !ok || (protocol != graph.HTTP.Name && protocol != graph.GRPC.Name) {
+				continue
+			}
+			edgeOperations, ok := operations[fmt.Sprintf("%s %s", e.Source.ID, e.Dest.ID)]
+			if !ok {
+				continue
+			}
+
+			result := graph.Operations{}
+			for name, t := range edgeOperations {
+				if t.requests == 0 {
+					continue
+				}
+				result = append(result, graph.Operation{
+					Name:         name,
+					ErrorRate:    100.0 * t.errors / t.requests,
+					Rate:         t.requests,
+					ResponseTime: t.responseTime,
+				})
+			}
+			if len(result) == 0 {
+				continue
+			}
+
+			sort.Slice(result, func(i, j int) bool {
+				if result[i].Rate != result[j].Rate {
+					return result[i].Rate > result[j].Rate
+				}
+				return result[i].Name < result[j].Name
+			})
+			if len(result) > limit {
+				result = result[:limit]
+			}
+			e.Metadata[graph.OperationsKey] = result
+		}
+	}
+}
+
+func (a OperationsAppender) populateOperationsMap(operations operationsMap, vector *model.Vector, isResponseTime bool) {
+	for _, s := range *vector {
+		m := s.Metric
+		lSourceCluster, sourceClusterOk := m["source_cluster"]
+		lSourceWlNs, sourceWlNsOk := m["source_workload_namespace"]
+		lSourceWl, sourceWlOk := m["source_workload"]
+		lSourceApp, sourceAppOk := m["source_canonical_service"]
+		lSourceVer, sourceVerOk := m["source_canonical_revision"]
+		lDestCluster, destClusterOk := m["destination_cluster"]
+		lDestSvcNs, destSvcNsOk := m["destination_service_namespace"]
+		lDestSvc, destSvcOk := m["destination_service"]
+		lDestSvcName, destSvcNameOk := m["destination_service_name"]
+		lDestWlNs, destWlNsOk := m["destination_workload_namespace"]
+		lDestWl, destWlOk := m["destination_workload"]
+		lDestApp, destAppOk := m["destination_canonical_service"]
+		lDestVer, destVerOk := m["destination_canonical_revision"]
+		lOperation, operationOk := m[model.LabelName(a.Label)]
+
+		if !sourceWlNsOk || !sourceWlOk || !sourceAppOk || !sourceVerOk || !destSvcNsOk || !destSvcNameOk || !destSvcOk || !destWlNsOk || !destWlOk || !destAppOk || !destVerOk || !operationOk {
+			log.Warningf("populateOperationsMap: Skipping %s, missing expected labels", m.String())
+			continue
+		}
+
+		isErr := false
+		if !isResponseTime {
+			lProtocol, protocolOk := m["request_protocol"]
+			lCode, codeOk := m["response_code"]
+			lGrpc, grpcOk := m["grpc_response_status"]
+
+			if !protocolOk || !codeOk {
+				log.Warningf("populateOperationsMap: Skipping %s, missing expected HTTP/GRPC TS labels", m.String())
+				continue
+			}
+
+			isErr = isRequestErr(util.HandleResponseCode(string(lProtocol), string(lCode), grpcOk, string(lGrpc)))
+		}
+
+		sourceWlNs := string(lSourceWlNs)
+		sourceWl := string(lSourceWl)
+		sourceApp := string(lSourceApp)
+		sourceVer := string(lSourceVer)
+		destSvc := string(lDestSvc)
+		operation := string(lOperation)
+
+		// handle clusters
+		sourceCluster, destCluster := util.HandleClusters(lSourceCluster, sourceClusterOk, lDestCluster, destClusterOk)
+
+		if util.IsBadSourceTelemetry(sourceCluster, sourceClusterOk, sourceWlNs, sourceWl, sourceApp) {
+			continue
+		}
+
+		val := float64(s.Value)
+
+		// handle unusual destinations
+		destCluster, destSvcNs, destSvcName, destWlNs, destWl, destApp, destVer, _ := util.HandleDestination(sourceCluster, sourceWlNs, sourceWl, destCluster, string(lDestSvcNs), string(lDestSvc), string(lDestSvcName), string(lDestWlNs), string(lDestWl), string(lDestApp), string(lDestVer))
+
+		if util.IsBadDestTelemetry(destCluster, destClusterOk, destSvcNs, destSvc, destSvcName, destWl) {
+			continue
+		}
+
+		// Should not happen but if NaN for any reason, Just skip it
+		if math.IsNaN(val) {
+			continue
+		}
+
+		// don't inject a service node if destSvcName is not set or the dest node is already a service node.
+		inject := false
+		if a.InjectServiceNodes && graph.IsOK(destSvcName) {
+			_, destNodeType := graph.Id(destCluster, destSvcNs, destSvcName, destWlNs, destWl, destApp, destVer, a.GraphType)
+			inject = (graph.NodeTypeService != destNodeType)
+		}
+
+		if inject {
+			// Only set response time on the outgoing edge. On the incoming edge, we can't validly aggregate response times of the outgoing edges (kiali-2297)
+			if !isResponseTime {
+				a.addTraffic(operations, operation, val, isResponseTime, isErr, sourceCluster, sourceWlNs, "", sourceWl, sourceApp, sourceVer, destCluster, destSvcNs, destSvcName, "", "", "", "")
+			}
+			a.addTraffic(operations, operation, val, isResponseTime, isErr, destCluster, destSvcNs, destSvcName, "", "", "", destCluster, destSvcNs, destSvcName, destWlNs, destWl, destApp, destVer)
+		} else {
+			a.addTraffic(operations, operation, val, isResponseTime, isErr, sourceCluster, sourceWlNs, "", sourceWl, sourceApp, sourceVer, destCluster, destSvcNs, destSvcName, destWlNs, destWl, destApp, destVer)
+		}
+	}
+}
+
+func (a OperationsAppender) addTraffic(operations operationsMap, operation string, val float64, isResponseTime, isErr bool, sourceCluster, sourceNs, sourceSvc, sourceWl, sourceApp, sourceVer, destCluster, destSvcNs, destSvc, destWlNs, destWl, destApp, destVer string) {
+	sourceID, _ := graph.Id(sourceCluster, sourceNs, sourceSvc, sourceNs, sourceWl, sourceApp, sourceVer, a.GraphType)
+	destID, _ := graph.Id(destCluster, destSvcNs, destSvc, destWlNs, destWl, destApp, destVer, a.GraphType)
+	t := operations.get(fmt.Sprintf("%s %s", sourceID, destID), operation)
+
+	switch {
+	case isResponseTime:
+		// the queries do not overlap, but defer to the first reported value, as for the response time appender
+		if t.responseTime == 0 {
+			t.responseTime = val
+		}
+	case isErr:
+		t.errors += val
+		t.requests += val
+	default:
+		t.requests += val
+	}
+}
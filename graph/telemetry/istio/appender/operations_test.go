@@ -0,0 +1,151 @@
+package appender
+
+import (
+	"testing"
+
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/kiali/kiali/graph"
+)
+
+func operationsTestMetric(destApp, destVer, operation, code string) model.Metric {
+	m := model.Metric{
+		"source_workload_namespace":      "bookinfo",
+		"source_workload":                "productpage-v1",
+		"source_canonical_service":       "productpage",
+		"source_canonical_revision":      "v1",
+		"destination_service_namespace":  "bookinfo",
+		"destination_service":            model.LabelValue(destApp + ".bookinfo.svc.cluster.local"),
+		"destination_service_name":       model.LabelValue(destApp),
+		"destination_workload_namespace": "bookinfo",
+		"destination_workload":           model.LabelValue(destApp + "-" + destVer),
+		"destination_canonical_service":  model.LabelValue(destApp),
+		"destination_canonical_revision": model.LabelValue(destVer),
+		"request_operation":              model.LabelValue(operation),
+	}
+	if code != "" {
+		m["request_protocol"] = "http"
+		m["response_code"] = model.LabelValue(code)
+	}
+	return m
+}
+
+func operationsTestTraffic(injectServiceNodes bool) (graph.TrafficMap, *graph.Node) {
+	trafficMap := graph.NewTrafficMap()
+	productpage := graph.NewNode(graph.Unknown, "bookinfo", "productpage", "bookinfo", "productpage-v1", "productpage", "v1", graph.GraphTypeVersionedApp)
+	reviews := graph.NewNode(graph.Unknown, "bookinfo", "reviews", "", "", "", "", graph.GraphTypeVersionedApp)
+	reviewsV1 := graph.NewNode(graph.Unknown, "bookinfo", "reviews", "bookinfo", "reviews-v1", "reviews", "v1", graph.GraphTypeVersionedApp)
+	mongodbV1 := graph.NewNode(graph.Unknown, "bookinfo", "mongodb", "bookinfo", "mongodb-v1", "mongodb", "v1", graph.GraphTypeVersionedApp)
+	trafficMap[productpage.ID] = &productpage
+	trafficMap[reviewsV1.ID] = &reviewsV1
+	trafficMap[mongodbV1.ID] = &mongodbV1
+
+	if injectServiceNodes {
+		trafficMap[reviews.ID] = &reviews
+		productpage.AddEdge(&reviews).Metadata[graph.ProtocolKey] = graph.HTTP.Name
+		reviews.AddEdge(&reviewsV1).Metadata[graph.ProtocolKey] = graph.HTTP.Name
+	} else {
+		productpage.AddEdge(&reviewsV1).Metadata[graph.ProtocolKey] = graph.HTTP.Name
+	}
+	productpage.AddEdge(&mongodbV1).Metadata[graph.ProtocolKey] = graph.TCP.Name
+
+	return trafficMap, &productpage
+}
+
+func operationsTestVectors() (model.Vector, model.Vector) {
+	requests := model.Vector{
+		&model.Sample{Metric: operationsTestMetric("reviews", "v1", "GET /reviews", "200"), Value: 8.0},
+		&model.Sample{Metric: operationsTestMetric("reviews", "v1", "GET /reviews", "503"), Value: 2.0},
+		&model.Sample{Metric: operationsTestMetric("reviews", "v1", "POST /reviews", "200"), Value: 4.0},
+		&model.Sample{Metric: operationsTestMetric("reviews", "v1", "DELETE /reviews", "200"), Value: 1.0},
+		// the request classification is not expected for tcp, but make sure it is not applied to a tcp edge
+		&model.Sample{Metric: operationsTestMetric("mongodb", "v1", "find", "200"), Value: 1.0},
+	}
+	responseTimes := model.Vector{
+		&model.Sample{Metric: operationsTestMetric("reviews", "v1", "GET /reviews", ""), Value: 40.0},
+		&model.Sample{Metric: operationsTestMetric("reviews", "v1", "POST /reviews", ""), Value: 120.0},
+	}
+	return requests, responseTimes
+}
+
+func TestOperations(t *testing.T) {
+	assert := assert.New(t)
+
+	appender := OperationsAppender{
+		GraphType: graph.GraphTypeVersionedApp,
+		Label:     defaultOperationLabel,
+		Limit:     2,
+	}
+
+	requests, responseTimes := operationsTestVectors()
+	operations := make(operationsMap)
+	appender.populateOperationsMap(operations, &requests, false)
+	appender.populateOperationsMap(operations, &responseTimes, true)
+
+	trafficMap, productpage := operationsTestTraffic(false)
+	applyOperations(trafficMap, operations, appender.Limit)
+
+	assert.Equal(2, len(productpage.Edges))
+	for _, e := range productpage.Edges {
+		switch e.Dest.App {
+		case "reviews":
+			// the top 2 operations, DELETE is dropped
+			assert.Equal(graph.Operations{
+				{Name: "GET /reviews", ErrorRate: 20.0, Rate: 10.0, ResponseTime: 40.0},
+				{Name: "POST /reviews", ErrorRate: 0.0, Rate: 4.0, ResponseTime: 120.0},
+			}, e.Metadata[graph.OperationsKey])
+		case "mongodb":
+			_, ok := e.Metadata[graph.OperationsKey]
+			assert.False(ok)
+		default:
+			assert.Fail("unexpected edge", e.Dest.ID)
+		}
+	}
+}
+
+func TestOperationsWithServiceNodes(t *testing.T) {
+	assert := assert.New(t)
+
+	appender := OperationsAppender{
+		GraphType:          graph.GraphTypeVersionedApp,
+		InjectServiceNodes: true,
+		Label:              defaultOperationLabel,
+		Limit:              defaultOperationLimit,
+	}
+
+	requests, responseTimes := operationsTestVectors()
+	operations := make(operationsMap)
+	appender.populateOperationsMap(operations, &requests, false)
+	appender.populateOperationsMap(operations, &responseTimes, true)
+
+	trafficMap, productpage := operationsTestTraffic(true)
+	applyOperations(trafficMap, operations, appender.Limit)
+
+	// the request rates are reported on both edges, the response times only on the edge leaving the service
+	var reviews *graph.Node
+	for _, e := range productpage.Edges {
+		if e.Dest.NodeType == graph.NodeTypeService {
+			reviews = e.Dest
+			assert.Equal(graph.Operations{
+				{Name: "GET /reviews", ErrorRate: 20.0, Rate: 10.0},
+				{Name: "POST /reviews", Rate: 4.0},
+				{Name: "DELETE /reviews", Rate: 1.0},
+			}, e.Metadata[graph.OperationsKey])
+		}
+	}
+	assert.NotNil(reviews)
+	assert.Equal(1, len(reviews.Edges))
+	assert.Equal(graph.Operations{
+		{Name: "GET /reviews", ErrorRate: 20.0, Rate: 10.0, ResponseTime: 40.0},
+		{Name: "POST /reviews", Rate: 4.0, ResponseTime: 120.0},
+		{Name: "DELETE /reviews", Rate: 1.0},
+	}, reviews.Edges[0].Metadata[graph.OperationsKey])
+}
+
+func TestOperationsOptIn(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.NotContains(parseAppenderNames(graph.RequestedAppenders{All: true}), OperationsAppenderName)
+	assert.Contains(parseAppenderNames(graph.RequestedAppenders{AppenderNames: []string{OperationsAppenderName}}), OperationsAppenderName)
+}
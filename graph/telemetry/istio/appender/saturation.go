@@ -0,0 +1,182 @@
+package appender
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/prometheus/common/model"
+
+	"github.com/kiali/kiali/graph"
+	"github.com/kiali/kiali/log"
+	"github.com/kiali/kiali/models"
+	"github.com/kiali/kiali/prometheus"
+)
+
+const (
+	// SaturationAppenderName uniquely identifies the appender: saturation
+	SaturationAppenderName = "saturation"
+)
+
+// SaturationAppender is responsible for decorating the workload nodes with the resource saturation of the
+// workload: the CPU and memory usage of its pods versus their requests and limits, the container restarts
+// in the time window and the availability of its replicas. It tells a node slow because it is saturated
+// from a node slow because of a slow dependency.
+//
+// The resource usage is provided by the container metrics (cAdvisor), the requests, limits and restarts by
+// kube-state-metrics. A workload is reported for its current pods, the pods replaced in the time window
+// are not reported. A workload node is marked saturated when its CPU or memory usage reaches the threshold
+// percentage of its limits, or when replicas are not available.
+// Name: saturation
+type SaturationAppender struct {
+	Namespaces graph.NamespaceInfoMap
+	QueryTime  int64   // unix time in seconds
+	Threshold  float64 // percentage of the limits
+}
+
+// podSaturation holds the resource usage of a pod, summed over its containers
+type podSaturation struct {
+	cpu      graph.ResourceUsage
+	memory   graph.ResourceUsage
+	restarts float64
+}
+
+// Name implements Appender
+func (a SaturationAppender) Name() string {
+	return SaturationAppenderName
+}
+
+// AppendGraph implements Appender
+func (a SaturationAppender) AppendGraph(trafficMap graph.TrafficMap, globalInfo *graph.AppenderGlobalInfo, namespaceInfo *graph.AppenderNamespaceInfo) {
+	if !hasWorkloadNodes(trafficMap, namespaceInfo.Namespace) {
+		return
+	}
+
+	if globalInfo.PromClient == nil {
+		var err error
+		globalInfo.PromClient, err = prometheus.NewClient()
+		graph.CheckError(err)
+	}
+
+	workloads, err := globalInfo.Business.Workload.GetWorkloads(namespaceInfo.Namespace)
+	graph.CheckError(err)
+
+	pods := a.queryPods(namespaceInfo.Namespace, globalInfo.PromClient)
+	applySaturation(trafficMap, namespaceInfo.Namespace, workloads, pods, a.Threshold)
+}
+
+func hasWorkloadNodes(trafficMap graph.TrafficMap, namespace string) bool {
+	for _, n := range trafficMap {
+		if n.NodeType == graph.NodeTypeWorkload && n.Namespace == namespace {
+			return true
+		}
+	}
+	return false
+}
+
+// queryPods returns the resource usage of the pods of the namespace (key=pod name)
+func (a SaturationAppender) queryPods(namespace string, client *prometheus.Client) map[string]*podSaturation {
+	log.Tracef("Generating saturation; namespace = %v", namespace)
+
+	duration := int(a.Namespaces[namespace].Duration.Seconds())
+	queryTime := time.Unix(a.QueryTime, 0)
+	pods := make(map[string]*podSaturation)
+	get := func(pod model.LabelValue) *podSaturation {
+		p, ok := pods[string(pod)]
+		if !ok {
+			p = &podSaturation{}
+			pods[string(pod)] = p
+		}
+		return p
+	}
+
+	// the container metrics include a pod-level series without a container, it is excluded
+	containers := fmt.Sprintf(`namespace="%s",container!="",container!="POD"`, namespace)
+
+	query := fmt.Sprintf(`sum(rate(container_cpu_usage_seconds_total{%s}[%vs])) by (pod)`, containers, duration)
+	for _, s := range promQuery(query, queryTime, client.GetContext(), client.API(), a) {
+		get(s.Metric["pod"]).cpu.Usage = float64(s.Value)
+	}
+
+	query = fmt.Sprintf(`sum(max_over_time(container_memory_working_set_bytes{%s}[%vs])) by (pod)`, containers, duration)
+	for _, s := range promQuery(query, queryTime, client.GetContext(), client.API(), a) {
+		get(s.Metric["pod"]).memory.Usage = float64(s.Value)
+	}
+
+	query = fmt.Sprintf(`sum(increase(kube_pod_container_status_restarts_total{namespace="%s"}[%vs])) by (pod)`, namespace, duration)
+	for _, s := range promQuery(query, queryTime, client.GetContext(), client.API(), a) {
+		get(s.Metric["pod"]).restarts = float64(s.Value)
+	}
+
+	for _, metric := range []string{"kube_pod_container_resource_requests", "kube_pod_container_resource_limits"} {
+		query = fmt.Sprintf(`sum(%s{namespace="%s",resource=~"cpu|memory"}) by (pod,resource)`, metric, namespace)
+		for _, s := range promQuery(query, queryTime, client.GetContext(), client.API(), a) {
+			p := get(s.Metric["pod"])
+			resource := &p.cpu
+			if s.Metric["resource"] == "memory" {
+				resource = &p.memory
+			}
+			if metric == "kube_pod_container_resource_requests" {
+				resource.Requests = float64(s.Value)
+			} else {
+				resource.Limits = float64(s.Value)
+			}
+		}
+	}
+
+	return pods
+}
+
+// applySaturation sets the saturation of the workload nodes of the namespace, summing the resource usage
+// of the workload pods
+func applySaturation(trafficMap graph.TrafficMap, namespace string, workloads models.Workloads, pods map[string]*podSaturation, threshold float64) {
+	byName := make(map[string]*models.Workload, len(workloads))
+	for _, w := range workloads {
+		byName[w.Name] = w
+	}
+
+	for _, n := range trafficMap {
+		if n.NodeType != graph.NodeTypeWorkload || n.Namespace != namespace {
+			continue
+		}
+		w, ok := byName[n.Workload]
+		if !ok {
+			continue
+		}
+
+		saturation := graph.Saturation{
+			DesiredReplicas:   w.DesiredReplicas,
+			AvailableReplicas: w.AvailableReplicas,
+		}
+		for _, pod := range w.Pods {
+			p, ok := pods[pod.Name]
+			if !ok {
+				continue
+			}
+			addResourceUsage(&saturation.CPU, p.cpu)
+			addResourceUsage(&saturation.Memory, p.memory)
+			saturation.Restarts += p.restarts
+		}
+
+		n.Metadata[graph.SaturationKey] = saturation
+		if isSaturated(saturation, threshold) {
+			n.Metadata[graph.IsSaturated] = true
+		}
+	}
+}
+
+func addResourceUsage(total *graph.ResourceUsage, usage graph.ResourceUsage) {
+	total.Usage += usage.Usage
+	total.Requests += usage.Requests
+	total.Limits += usage.Limits
+}
+
+// isSaturated returns true if the CPU or memory usage reaches the threshold percentage of the limits, or
+// if replicas are not available
+func isSaturated(s graph.Saturation, threshold float64) bool {
+	for _, r := range []graph.ResourceUsage{s.CPU, s.Memory} {
+		if r.Limits > 0.0 && 100.0*r.Usage/r.Limits >= threshold {
+			return true
+		}
+	}
+	return s.AvailableReplicas < s.DesiredReplicas
+}
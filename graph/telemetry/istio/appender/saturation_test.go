@@ -0,0 +1,106 @@
+package appender
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/kiali/kiali/graph"
+	"github.com/kiali/kiali/models"
+)
+
+func saturationTestWorkload(name string, desired, available int32, pods ...string) *models.Workload {
+	w := &models.Workload{DesiredReplicas: desired, AvailableReplicas: available}
+	w.Name = name
+	for _, pod := range pods {
+		w.Pods = append(w.Pods, &models.Pod{Name: pod})
+	}
+	return w
+}
+
+func TestSaturation(t *testing.T) {
+	assert := assert.New(t)
+
+	trafficMap := graph.NewTrafficMap()
+	productpage := graph.NewNode(graph.Unknown, "bookinfo", "", "bookinfo", "productpage-v1", "productpage", "v1", graph.GraphTypeWorkload)
+	reviews := graph.NewNode(graph.Unknown, "bookinfo", "", "bookinfo", "reviews-v1", "reviews", "v1", graph.GraphTypeWorkload)
+	ratings := graph.NewNode(graph.Unknown, "bookinfo", "", "bookinfo", "ratings-v1", "ratings", "v1", graph.GraphTypeWorkload)
+	details := graph.NewNode(graph.Unknown, "other", "", "other", "details-v1", "details", "v1", graph.GraphTypeWorkload)
+	trafficMap[productpage.ID] = &productpage
+	trafficMap[reviews.ID] = &reviews
+	trafficMap[ratings.ID] = &ratings
+	trafficMap[details.ID] = &details
+
+	workloads := models.Workloads{
+		saturationTestWorkload("productpage-v1", 2, 2, "productpage-v1-a", "productpage-v1-b"),
+		saturationTestWorkload("reviews-v1", 1, 1, "reviews-v1-a"),
+		saturationTestWorkload("ratings-v1", 2, 1, "ratings-v1-a"),
+		saturationTestWorkload("details-v1", 1, 1, "details-v1-a"),
+	}
+	pods := map[string]*podSaturation{
+		"productpage-v1-a": {
+			cpu:    graph.ResourceUsage{Usage: 0.1, Requests: 0.2, Limits: 0.5},
+			memory: graph.ResourceUsage{Usage: 100.0, Requests: 200.0},
+		},
+		"productpage-v1-b": {
+			cpu:      graph.ResourceUsage{Usage: 0.3, Requests: 0.2, Limits: 0.5},
+			memory:   graph.ResourceUsage{Usage: 100.0, Requests: 200.0},
+			restarts: 2.0,
+		},
+		"reviews-v1-a": {
+			memory: graph.ResourceUsage{Usage: 95.0, Requests: 50.0, Limits: 100.0},
+		},
+		"details-v1-a": {
+			cpu: graph.ResourceUsage{Usage: 1.0, Limits: 1.0},
+		},
+	}
+
+	applySaturation(trafficMap, "bookinfo", workloads, pods, 90.0)
+
+	// the pods are summed, 40% of the CPU limits
+	assert.Equal(graph.Saturation{
+		CPU:               graph.ResourceUsage{Usage: 0.4, Requests: 0.4, Limits: 1.0},
+		Memory:            graph.ResourceUsage{Usage: 200.0, Requests: 400.0},
+		Restarts:          2.0,
+		DesiredReplicas:   2,
+		AvailableReplicas: 2,
+	}, productpage.Metadata[graph.SaturationKey])
+	assert.NotContains(productpage.Metadata, graph.IsSaturated)
+
+	// 95% of the memory limits
+	assert.Equal(true, reviews.Metadata[graph.IsSaturated])
+
+	// an unavailable replica, without resource usage
+	assert.Equal(graph.Saturation{DesiredReplicas: 2, AvailableReplicas: 1}, ratings.Metadata[graph.SaturationKey])
+	assert.Equal(true, ratings.Metadata[graph.IsSaturated])
+
+	// only the nodes of the namespace are decorated
+	assert.NotContains(details.Metadata, graph.SaturationKey)
+}
+
+func TestSaturationThreshold(t *testing.T) {
+	assert := assert.New(t)
+
+	o := graph.TelemetryOptions{Appenders: graph.RequestedAppenders{AppenderNames: []string{SaturationAppenderName}}}
+	o.Params = url.Values{}
+	appenders, _ := ParseAppenders(o)
+	assert.Len(appenders, 1)
+	assert.Equal(90.0, appenders[0].(SaturationAppender).Threshold)
+
+	o.Params.Set("saturationThreshold", "75.5")
+	appenders, _ = ParseAppenders(o)
+	assert.Equal(75.5, appenders[0].(SaturationAppender).Threshold)
+
+	o.Params.Set("saturationThreshold", "150")
+	assert.PanicsWithValue(graph.Response{Message: "Invalid saturationThreshold, expecting a percentage in (0, 100]. [150]", Code: 400}, func() {
+		ParseAppenders(o)
+	})
+}
+
+func TestSaturationOptIn(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.NotContains(parseAppenderNames(graph.RequestedAppenders{All: true}), SaturationAppenderName)
+	assert.Contains(parseAppenderNames(graph.RequestedAppenders{AppenderNames: []string{SaturationAppenderName}}), SaturationAppenderName)
+}
@@ -158,35 +158,7 @@ func (a ServiceEntryAppender) applyServiceEntries(trafficMap graph.TrafficMap, g
 // but exported to all namespaces (exportTo: *). It's possible that would allow traffic to flow from an
 // accessible workload through a serviceEntry whose definition we can't fetch.
 func (a ServiceEntryAppender) getServiceEntry(namespace, serviceName string, globalInfo *graph.AppenderGlobalInfo) (*serviceEntry, bool) {
-	serviceEntryHosts, found := getServiceEntryHosts(globalInfo)
-	if !found {
-		for ns := range a.AccessibleNamespaces {
-			istioCfg, err := globalInfo.Business.IstioConfig.GetIstioConfigList(business.IstioConfigCriteria{
-				IncludeServiceEntries: true,
-				Namespace:             ns,
-			})
-			graph.CheckError(err)
-
-			for _, entry := range istioCfg.ServiceEntries {
-				if entry.Spec.Hosts != nil {
-					location := "MESH_EXTERNAL"
-					if entry.Spec.Location == "MESH_INTERNAL" {
-						location = "MESH_INTERNAL"
-					}
-					se := serviceEntry{
-						exportTo:  entry.Spec.ExportTo,
-						location:  location,
-						name:      entry.Metadata.Name,
-						namespace: entry.Metadata.Namespace,
-					}
-					for _, host := range entry.Spec.Hosts.([]interface{}) {
-						serviceEntryHosts.addHost(host.(string), &se)
-					}
-				}
-			}
-		}
-		globalInfo.Vendor[serviceEntryHostsKey] = serviceEntryHosts
-	}
+	serviceEntryHosts := loadServiceEntryHosts(a.AccessibleNamespaces, globalInfo)
 
 	for host, serviceEntriesForHost := range serviceEntryHosts {
 		for _, se := range serviceEntriesForHost {
@@ -223,6 +195,44 @@ func (a ServiceEntryAppender) getServiceEntry(namespace, serviceName string, glo
 	return nil, false
 }
 
+// loadServiceEntryHosts returns the hosts of the service entries defined in the accessible namespaces, loading
+// them once per graph request.
+func loadServiceEntryHosts(accessibleNamespaces map[string]time.Time, globalInfo *graph.AppenderGlobalInfo) serviceEntryHosts {
+	serviceEntryHosts, found := getServiceEntryHosts(globalInfo)
+	if found {
+		return serviceEntryHosts
+	}
+
+	for ns := range accessibleNamespaces {
+		istioCfg, err := globalInfo.Business.IstioConfig.GetIstioConfigList(business.IstioConfigCriteria{
+			IncludeServiceEntries: true,
+			Namespace:             ns,
+		})
+		graph.CheckError(err)
+
+		for _, entry := range istioCfg.ServiceEntries {
+			if entry.Spec.Hosts != nil {
+				location := "MESH_EXTERNAL"
+				if entry.Spec.Location == "MESH_INTERNAL" {
+					location = "MESH_INTERNAL"
+				}
+				se := serviceEntry{
+					exportTo:  entry.Spec.ExportTo,
+					location:  location,
+					name:      entry.Metadata.Name,
+					namespace: entry.Metadata.Namespace,
+				}
+				for _, host := range entry.Spec.Hosts.([]interface{}) {
+					serviceEntryHosts.addHost(host.(string), &se)
+				}
+			}
+		}
+	}
+	globalInfo.Vendor[serviceEntryHostsKey] = serviceEntryHosts
+
+	return serviceEntryHosts
+}
+
 func isExportedToNamespace(se *serviceEntry, namespace string) bool {
 	if se.exportTo == nil {
 		return true
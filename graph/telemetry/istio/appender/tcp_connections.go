@@ -0,0 +1,213 @@
+package appender
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/prometheus/common/model"
+
+	"github.com/kiali/kiali/graph"
+	"github.com/kiali/kiali/graph/telemetry/istio/util"
+	"github.com/kiali/kiali/log"
+	"github.com/kiali/kiali/prometheus"
+)
+
+const (
+	// TCPConnectionsAppenderName uniquely identifies the appender: tcpConnections
+	TCPConnectionsAppenderName = "tcpConnections"
+)
+
+// TCPConnectionsAppender is responsible for adding the connection telemetry to the tcp edges: the rates of the
+// opened and closed connections, the number of active connections, and the rate of the connections closed with
+// a failure (i.e. with response flags, like UF for an upstream connection failure). The failures are reported as
+// tcp errors, relative to the closed connections, and the closed connections are reported as connection responses,
+// alongside the byte-weighted responses. The active connections are the opened minus the closed connections, since
+// the proxy start.
+//
+// Connection failures may never reach the destination proxy, so the connections are reported using source
+// telemetry. Only the existing tcp edges are decorated.
+// Name: tcpConnections
+type TCPConnectionsAppender struct {
+	GraphType          string
+	InjectServiceNodes bool
+	Namespaces         graph.NamespaceInfoMap
+	QueryTime          int64 // unix time in seconds
+}
+
+// tcpConnections holds the connection time series of an edge
+type tcpConnections []tcpConnectionsTS
+
+// tcpConnectionsTS holds a single connection time series, reporting one of opened, closed or active
+type tcpConnectionsTS struct {
+	opened float64
+	closed float64
+	active float64
+	flags  string
+	host   string
+}
+
+// Name implements Appender
+func (a TCPConnectionsAppender) Name() string {
+	return TCPConnectionsAppenderName
+}
+
+// AppendGraph implements Appender
+func (a TCPConnectionsAppender) AppendGraph(trafficMap graph.TrafficMap, globalInfo *graph.AppenderGlobalInfo, namespaceInfo *graph.AppenderNamespaceInfo) {
+	if !hasTCPEdges(trafficMap) {
+		return
+	}
+
+	if globalInfo.PromClient == nil {
+		var err error
+		globalInfo.PromClient, err = prometheus.NewClient()
+		graph.CheckError(err)
+	}
+
+	a.appendGraph(trafficMap, namespaceInfo.Namespace, globalInfo.PromClient)
+}
+
+func hasTCPEdges(trafficMap graph.TrafficMap) bool {
+	for _, n := range trafficMap {
+		for _, e := range n.Edges {
+			if e.Metadata[graph.ProtocolKey] == graph.TCP.Name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (a TCPConnectionsAppender) appendGraph(trafficMap graph.TrafficMap, namespace string, client *prometheus.Client) {
+	log.Tracef("Generating tcp connections; namespace = %v", namespace)
+
+	// create map to quickly look up the connections of an edge
+	connectionsMap := make(map[string]tcpConnections)
+	duration := int(a.Namespaces[namespace].Duration.Seconds())
+	queryTime := time.Unix(a.QueryTime, 0)
+
+	groupBy := "source_cluster,source_workload_namespace,source_workload,source_canonical_service,source_canonical_revision,destination_cluster,destination_service_namespace,destination_service,destination_service_name,destination_workload_namespace,destination_workload,destination_canonical_service,destination_canonical_revision"
+
+	// query prometheus for the connections originating from a workload outside the namespace, and then for
+	// the connections originating from a workload inside of the namespace
+	for _, selector := range []string{
+		fmt.Sprintf(`reporter="source",source_workload_namespace!="%s",destination_service_namespace="%s"`, namespace, namespace),
+		fmt.Sprintf(`reporter="source",source_workload_namespace="%s"`, namespace),
+	} {
+		// 1) the opened connections rate
+		query := fmt.Sprintf(`sum(rate(istio_tcp_connections_opened_total{%s}[%vs])) by (%s) > 0`, selector, duration, groupBy)
+		vector := promQuery(query, queryTime, client.GetContext(), client.API(), a)
+		a.populateConnectionsMap(connectionsMap, &vector, func(val float64, _ model.Metric) tcpConnectionsTS {
+			return tcpConnectionsTS{opened: val}
+		})
+
+		// 2) the closed connections rate, by flags to report the failures
+		query = fmt.Sprintf(`sum(rate(istio_tcp_connections_closed_total{%s}[%vs])) by (%s,response_flags) > 0`, selector, duration, groupBy)
+		vector = promQuery(query, queryTime, client.GetContext(), client.API(), a)
+		a.populateConnectionsMap(connectionsMap, &vector, func(val float64, m model.Metric) tcpConnectionsTS {
+			return tcpConnectionsTS{closed: val, flags: string(m["response_flags"]), host: string(m["destination_service"])}
+		})
+
+		// 3) the active connections
+		query = fmt.Sprintf(`(sum(istio_tcp_connections_opened_total{%s}) by (%s) - sum(istio_tcp_connections_closed_total{%s}) by (%s)) > 0`, selector, groupBy, selector, groupBy)
+		vector = promQuery(query, queryTime, client.GetContext(), client.API(), a)
+		a.populateConnectionsMap(connectionsMap, &vector, func(val float64, _ model.Metric) tcpConnectionsTS {
+			return tcpConnectionsTS{active: val}
+		})
+	}
+
+	applyTCPConnections(trafficMap, connectionsMap)
+}
+
+func applyTCPConnections(trafficMap graph.TrafficMap, connectionsMap map[string]tcpConnections) {
+	for _, n := range trafficMap {
+		for _, e := range n.Edges {
+			if e.Metadata[graph.ProtocolKey] != graph.TCP.Name {
+				continue
+			}
+			connections, ok := connectionsMap[fmt.Sprintf("%s %s", e.Source.ID, e.Dest.ID)]
+			if !ok {
+				continue
+			}
+			for _, ts := range connections {
+				graph.AddTCPConnectionsToMetadata(ts.opened, ts.closed, ts.active, ts.flags, ts.host, e.Metadata)
+			}
+		}
+	}
+}
+
+func (a TCPConnectionsAppender) populateConnectionsMap(connectionsMap map[string]tcpConnections, vector *model.Vector, toTS func(val float64, m model.Metric) tcpConnectionsTS) {
+	for _, s := range *vector {
+		m := s.Metric
+		lSourceCluster, sourceClusterOk := m["source_cluster"]
+		lSourceWlNs, sourceWlNsOk := m["source_workload_namespace"]
+		lSourceWl, sourceWlOk := m["source_workload"]
+		lSourceApp, sourceAppOk := m["source_canonical_service"]
+		lSourceVer, sourceVerOk := m["source_canonical_revision"]
+		lDestCluster, destClusterOk := m["destination_cluster"]
+		lDestSvcNs, destSvcNsOk := m["destination_service_namespace"]
+		lDestSvc, destSvcOk := m["destination_service"]
+		lDestSvcName, destSvcNameOk := m["destination_service_name"]
+		lDestWlNs, destWlNsOk := m["destination_workload_namespace"]
+		lDestWl, destWlOk := m["destination_workload"]
+		lDestApp, destAppOk := m["destination_canonical_service"]
+		lDestVer, destVerOk := m["destination_canonical_revision"]
+
+		if !sourceWlNsOk || !sourceWlOk || !sourceAppOk || !sourceVerOk || !destSvcNsOk || !destSvcNameOk || !destSvcOk || !destWlNsOk || !destWlOk || !destAppOk || !destVerOk {
+			log.Warningf("populateConnectionsMap: Skipping %s, missing expected labels", m.String())
+			continue
+		}
+
+		sourceWlNs := string(lSourceWlNs)
+		sourceWl := string(lSourceWl)
+		sourceApp := string(lSourceApp)
+		sourceVer := string(lSourceVer)
+		destSvc := string(lDestSvc)
+
+		// handle clusters
+		sourceCluster, destCluster := util.HandleClusters(lSourceCluster, sourceClusterOk, lDestCluster, destClusterOk)
+
+		if util.IsBadSourceTelemetry(sourceCluster, sourceClusterOk, sourceWlNs, sourceWl, sourceApp) {
+			continue
+		}
+
+		val := float64(s.Value)
+
+		// handle unusual destinations
+		destCluster, destSvcNs, destSvcName, destWlNs, destWl, destApp, destVer, _ := util.HandleDestination(sourceCluster, sourceWlNs, sourceWl, destCluster, string(lDestSvcNs), string(lDestSvc), string(lDestSvcName), string(lDestWlNs), string(lDestWl), string(lDestApp), string(lDestVer))
+
+		if util.IsBadDestTelemetry(destCluster, destClusterOk, destSvcNs, destSvc, destSvcName, destWl) {
+			continue
+		}
+
+		// Should not happen but if NaN for any reason, Just skip it
+		if math.IsNaN(val) {
+			continue
+		}
+
+		ts := toTS(val, m)
+
+		// don't inject a service node if destSvcName is not set or the dest node is already a service node.
+		inject := false
+		if a.InjectServiceNodes && graph.IsOK(destSvcName) {
+			_, destNodeType := graph.Id(destCluster, destSvcNs, destSvcName, destWlNs, destWl, destApp, destVer, a.GraphType)
+			inject = (graph.NodeTypeService != destNodeType)
+		}
+
+		// like the traffic, the connections are reported on both the incoming and outgoing edges of an injected service node
+		if inject {
+			a.addConnections(connectionsMap, ts, sourceCluster, sourceWlNs, "", sourceWl, sourceApp, sourceVer, destCluster, destSvcNs, destSvcName, "", "", "", "")
+			a.addConnections(connectionsMap, ts, destCluster, destSvcNs, destSvcName, "", "", "", destCluster, destSvcNs, destSvcName, destWlNs, destWl, destApp, destVer)
+		} else {
+			a.addConnections(connectionsMap, ts, sourceCluster, sourceWlNs, "", sourceWl, sourceApp, sourceVer, destCluster, destSvcNs, destSvcName, destWlNs, destWl, destApp, destVer)
+		}
+	}
+}
+
+func (a TCPConnectionsAppender) addConnections(connectionsMap map[string]tcpConnections, ts tcpConnectionsTS, sourceCluster, sourceNs, sourceSvc, sourceWl, sourceApp, sourceVer, destCluster, destSvcNs, destSvc, destWlNs, destWl, destApp, destVer string) {
+	sourceID, _ := graph.Id(sourceCluster, sourceNs, sourceSvc, sourceNs, sourceWl, sourceApp, sourceVer, a.GraphType)
+	destID, _ := graph.Id(destCluster, destSvcNs, destSvc, destWlNs, destWl, destApp, destVer, a.GraphType)
+	key := fmt.Sprintf("%s %s", sourceID, destID)
+
+	connectionsMap[key] = append(connectionsMap[key], ts)
+}
@@ -0,0 +1,127 @@
+package appender
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/kiali/kiali/config"
+	"github.com/kiali/kiali/graph"
+)
+
+func tcpConnectionsTestMetric(flags string) model.Metric {
+	m := model.Metric{
+		"source_workload_namespace":      "bookinfo",
+		"source_workload":                "ratings-v1",
+		"source_canonical_service":       "ratings",
+		"source_canonical_revision":      "v1",
+		"destination_service_namespace":  "bookinfo",
+		"destination_service":            "mysqldb.bookinfo.svc.cluster.local",
+		"destination_service_name":       "mysqldb",
+		"destination_workload_namespace": "bookinfo",
+		"destination_workload":           "mysqldb-v1",
+		"destination_canonical_service":  "mysqldb",
+		"destination_canonical_revision": "v1"}
+	if flags != "" {
+		m["response_flags"] = model.LabelValue(flags)
+	}
+	return m
+}
+
+func TestTCPConnections(t *testing.T) {
+	assert := assert.New(t)
+	config.Set(config.NewConfig())
+
+	trafficMap := graph.NewTrafficMap()
+	ratings := graph.NewNode(graph.Unknown, "bookinfo", "ratings", "bookinfo", "ratings-v1", "ratings", "v1", graph.GraphTypeWorkload)
+	mysqldbService := graph.NewNode(graph.Unknown, "bookinfo", "mysqldb", "", "", "", "", graph.GraphTypeWorkload)
+	mysqldb := graph.NewNode(graph.Unknown, "bookinfo", "mysqldb", "bookinfo", "mysqldb-v1", "mysqldb", "v1", graph.GraphTypeWorkload)
+	trafficMap[ratings.ID] = &ratings
+	trafficMap[mysqldbService.ID] = &mysqldbService
+	trafficMap[mysqldb.ID] = &mysqldb
+	host := "mysqldb.bookinfo.svc.cluster.local"
+	for _, e := range []*graph.Edge{ratings.AddEdge(&mysqldbService), mysqldbService.AddEdge(&mysqldb)} {
+		e.Metadata[graph.ProtocolKey] = graph.TCP.Name
+		graph.AddToMetadata(graph.TCP.Name, 1000.0, "", "-", host, e.Source.Metadata, e.Dest.Metadata, e.Metadata)
+	}
+
+	a := TCPConnectionsAppender{
+		GraphType:          graph.GraphTypeWorkload,
+		InjectServiceNodes: true,
+		Namespaces:         graph.NamespaceInfoMap{"bookinfo": {Name: "bookinfo", Duration: 60 * time.Second}},
+	}
+	connectionsMap := make(map[string]tcpConnections)
+	a.populateConnectionsMap(connectionsMap, &model.Vector{
+		&model.Sample{Metric: tcpConnectionsTestMetric(""), Value: 2.0},
+	}, func(val float64, _ model.Metric) tcpConnectionsTS { return tcpConnectionsTS{opened: val} })
+	a.populateConnectionsMap(connectionsMap, &model.Vector{
+		&model.Sample{Metric: tcpConnectionsTestMetric("-"), Value: 1.5},
+		&model.Sample{Metric: tcpConnectionsTestMetric("UF"), Value: 0.5},
+	}, func(val float64, m model.Metric) tcpConnectionsTS {
+		return tcpConnectionsTS{closed: val, flags: string(m["response_flags"]), host: string(m["destination_service"])}
+	})
+	a.populateConnectionsMap(connectionsMap, &model.Vector{
+		&model.Sample{Metric: tcpConnectionsTestMetric(""), Value: 10.0},
+	}, func(val float64, _ model.Metric) tcpConnectionsTS { return tcpConnectionsTS{active: val} })
+
+	applyTCPConnections(trafficMap, connectionsMap)
+
+	// the connections are reported on both edges of the injected service node
+	for _, e := range []*graph.Edge{ratings.Edges[0], mysqldbService.Edges[0]} {
+		assert.Equal(1000.0, e.Metadata[graph.TCP.EdgeRates[0].Name])
+		assert.Equal(2.0, e.Metadata["tcpOpened"])
+		assert.Equal(2.0, e.Metadata["tcpClosed"])
+		assert.Equal(10.0, e.Metadata["tcpActive"])
+		assert.Equal(0.5, e.Metadata["tcpErr"])
+
+		// the byte-weighted responses are kept, the closed connections are the connection responses
+		assert.Equal(graph.ResponseFlags{"-": 1000.0}, e.Metadata[graph.TCP.EdgeResponses].(graph.Responses)["-"].Flags)
+		assert.Equal(graph.Responses{
+			"-": &graph.ResponseDetail{
+				Flags: graph.ResponseFlags{"-": 1.5},
+				Hosts: graph.ResponseHosts{host: 1.5},
+			},
+			graph.TCPErrCode: &graph.ResponseDetail{
+				Flags: graph.ResponseFlags{"UF": 0.5},
+				Hosts: graph.ResponseHosts{host: 0.5},
+			},
+		}, e.Metadata[graph.TCP.EdgeConnectionResponses])
+	}
+}
+
+func TestTCPConnectionsWithoutClosed(t *testing.T) {
+	assert := assert.New(t)
+	config.Set(config.NewConfig())
+
+	trafficMap := graph.NewTrafficMap()
+	ratings := graph.NewNode(graph.Unknown, "bookinfo", "ratings", "bookinfo", "ratings-v1", "ratings", "v1", graph.GraphTypeWorkload)
+	mysqldb := graph.NewNode(graph.Unknown, "bookinfo", "mysqldb", "bookinfo", "mysqldb-v1", "mysqldb", "v1", graph.GraphTypeWorkload)
+	trafficMap[ratings.ID] = &ratings
+	trafficMap[mysqldb.ID] = &mysqldb
+	e := ratings.AddEdge(&mysqldb)
+	e.Metadata[graph.ProtocolKey] = graph.TCP.Name
+	graph.AddToMetadata(graph.TCP.Name, 1000.0, "", "-", "", ratings.Metadata, mysqldb.Metadata, e.Metadata)
+
+	a := TCPConnectionsAppender{GraphType: graph.GraphTypeWorkload}
+	connectionsMap := make(map[string]tcpConnections)
+	a.populateConnectionsMap(connectionsMap, &model.Vector{
+		&model.Sample{Metric: tcpConnectionsTestMetric(""), Value: 3.0},
+	}, func(val float64, _ model.Metric) tcpConnectionsTS { return tcpConnectionsTS{active: val} })
+
+	applyTCPConnections(trafficMap, connectionsMap)
+
+	// without closed connections there are no connection responses
+	assert.Equal(3.0, e.Metadata["tcpActive"])
+	assert.NotContains(e.Metadata, graph.MetadataKey("tcpClosed"))
+	assert.NotContains(e.Metadata, graph.TCP.EdgeConnectionResponses)
+	assert.Equal(graph.ResponseFlags{"-": 1000.0}, e.Metadata[graph.TCP.EdgeResponses].(graph.Responses)["-"].Flags)
+}
+
+func TestTCPConnectionsOptIn(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.NotContains(parseAppenderNames(graph.RequestedAppenders{All: true}), TCPConnectionsAppenderName)
+	assert.Contains(parseAppenderNames(graph.RequestedAppenders{AppenderNames: []string{TCPConnectionsAppenderName}}), TCPConnectionsAppenderName)
+}
@@ -38,3 +38,17 @@ func promQuery(query string, queryTime time.Time, ctx context.Context, api prom_
 
 	return nil
 }
+
+// isRequestErr returns true for failed requests, in line with the edge error rates: requests without a
+// response and HTTP 4xx/5xx or gRPC non-zero codes.
+func isRequestErr(code string) bool {
+	switch {
+	case code == "-":
+		return true
+	case len(code) == 3:
+		// HTTP code, may be reported for gRPC by older Istio telemetry
+		return graph.IsHTTPErr(code)
+	default:
+		return graph.IsGRPCErr(code)
+	}
+}
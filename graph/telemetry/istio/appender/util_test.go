@@ -1,10 +1,14 @@
 package appender
 
 import (
+	"testing"
+
 	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 
 	"github.com/kiali/kiali/config"
+	"github.com/kiali/kiali/graph"
 	"github.com/kiali/kiali/prometheus"
 	"github.com/kiali/kiali/prometheus/prometheustest"
 )
@@ -38,3 +42,26 @@ func mockQuery(api *prometheustest.PromAPIMock, query string, ret *model.Vector)
 		mock.AnythingOfType("time.Time"),
 	).Return(*ret, nil)
 }
+
+// parseAppenderNames returns the names of the appenders and finalizers run for the requested appenders
+func parseAppenderNames(requested graph.RequestedAppenders) []string {
+	config.Set(config.NewConfig())
+	appenders, finalizers := ParseAppenders(graph.TelemetryOptions{Appenders: requested})
+	names := []string{}
+	for _, a := range append(appenders, finalizers...) {
+		names = append(names, a.Name())
+	}
+	return names
+}
+
+func TestIsRequestErr(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.True(isRequestErr("-"))
+	assert.True(isRequestErr("404"))
+	assert.True(isRequestErr("503"))
+	assert.True(isRequestErr("14"))
+	assert.False(isRequestErr("200"))
+	assert.False(isRequestErr("302"))
+	assert.False(isRequestErr("0"))
+}
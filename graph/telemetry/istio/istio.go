@@ -8,7 +8,8 @@ package istio
 //   First Pass: Query Prometheus (istio-requests-total metric) to retrieve the source-destination
 //               dependencies. Build a traffic map to provide a full representation of nodes and edges.
 //
-//   Second Pass: Apply any requested appenders to alter or append to the graph.
+//   Second Pass: Apply any requested appenders to alter or append to the graph. Finalizer appenders
+//                are applied last, to the final graph.
 //
 // Supports three vendor-specific query parameters:
 //   aggregate: Must be a valid metric attribute (default: request_operation)
@@ -43,7 +44,7 @@ const (
 func BuildNamespacesTrafficMap(o graph.TelemetryOptions, client *prometheus.Client, globalInfo *graph.AppenderGlobalInfo) graph.TrafficMap {
 	log.Tracef("Build [%s] graph for [%d] namespaces [%v]", o.GraphType, len(o.Namespaces), o.Namespaces)
 
-	appenders := appender.ParseAppenders(o)
+	appenders, finalizers := appender.ParseAppenders(o)
 	trafficMap := graph.NewTrafficMap()
 
 	for _, namespace := range o.Namespaces {
@@ -52,8 +53,10 @@ func BuildNamespacesTrafficMap(o graph.TelemetryOptions, client *prometheus.Clie
 		namespaceInfo := graph.NewAppenderNamespaceInfo(namespace.Name)
 		for _, a := range appenders {
 			appenderTimer := internalmetrics.GetGraphAppenderTimePrometheusTimer(a.Name())
+			debugTimer := globalInfo.Debug.Time(graph.DebugPhaseAppender, a.Name(), namespaceInfo.Namespace)
 			a.AppendGraph(namespaceTrafficMap, globalInfo, namespaceInfo)
 			appenderTimer.ObserveDuration()
+			debugTimer()
 		}
 		telemetry.MergeTrafficMaps(trafficMap, namespace.Name, namespaceTrafficMap)
 	}
@@ -69,6 +72,8 @@ func BuildNamespacesTrafficMap(o graph.TelemetryOptions, client *prometheus.Clie
 		trafficMap = telemetry.ReduceToServiceGraph(trafficMap)
 	}
 
+	telemetry.ApplyFinalizers(trafficMap, finalizers, globalInfo)
+
 	return trafficMap
 }
 
@@ -327,15 +332,20 @@ func BuildNodeTrafficMap(o graph.TelemetryOptions, client *prometheus.Client, gl
 
 	log.Tracef("Build graph for node [%+v]", n)
 
-	appenders := appender.ParseAppenders(o)
+	appenders, finalizers := appender.ParseAppenders(o)
 	trafficMap := buildNodeTrafficMap(o.Cluster, o.NodeOptions.Namespace, n, o, client)
+	if o.NodeOptions.Depth > 1 {
+		expandNodeTrafficMap(trafficMap, n, o, client)
+	}
 
 	namespaceInfo := graph.NewAppenderNamespaceInfo(o.NodeOptions.Namespace)
 
 	for _, a := range appenders {
 		appenderTimer := internalmetrics.GetGraphAppenderTimePrometheusTimer(a.Name())
+		debugTimer := globalInfo.Debug.Time(graph.DebugPhaseAppender, a.Name(), namespaceInfo.Namespace)
 		a.AppendGraph(trafficMap, globalInfo, namespaceInfo)
 		appenderTimer.ObserveDuration()
+		debugTimer()
 	}
 
 	// The appenders can add/remove/alter nodes. After the manipulations are complete
@@ -349,9 +359,58 @@ func BuildNodeTrafficMap(o graph.TelemetryOptions, client *prometheus.Client, gl
 	// the current decision is to not reduce the node graph to provide more detail.  This may be
 	// confusing to users, we'll see...
 
+	telemetry.ApplyFinalizers(trafficMap, finalizers, globalInfo)
+
 	return trafficMap
 }
 
+// expandNodeTrafficMap adds to the node graph the traffic of the nodes up to o.NodeOptions.Depth hops from
+// the target node, in both directions. Each hop expands the nodes reached by the previous hop, as if each
+// was the target node of a node graph. As in a namespaces graph, only the nodes of the requested namespaces
+// are expanded, the others are only reported (and marked as outsiders).
+func expandNodeTrafficMap(trafficMap graph.TrafficMap, target graph.Node, o graph.TelemetryOptions, client *prometheus.Client) {
+	expanded := make(map[string]bool)
+	frontier := []*graph.Node{}
+	for id, n := range trafficMap {
+		if telemetry.IsNode(n, target) {
+			expanded[id] = true
+		}
+	}
+	for id, n := range trafficMap {
+		if !expanded[id] {
+			frontier = append(frontier, n)
+		}
+	}
+
+	for hop := 2; hop <= o.NodeOptions.Depth; hop++ {
+		next := []*graph.Node{}
+		for _, n := range frontier {
+			if expanded[n.ID] || !telemetry.IsExpandable(n, o) {
+				continue
+			}
+			expanded[n.ID] = true
+
+			log.Tracef("Expand node graph, hop [%d] node [%s]", hop, n.ID)
+			nodeTrafficMap := buildNodeTrafficMap(n.Cluster, n.Namespace, *n, o, client)
+			for id, nodeNode := range nodeTrafficMap {
+				if _, found := trafficMap[id]; !found {
+					next = append(next, nodeNode)
+				}
+			}
+			telemetry.MergeTrafficMaps(trafficMap, n.Namespace, nodeTrafficMap)
+		}
+		frontier = next
+	}
+
+	// the merged edges may still refer to the duplicate nodes removed by the merges
+	for _, n := range trafficMap {
+		for _, e := range n.Edges {
+			e.Source = n
+			e.Dest = trafficMap[e.Dest.ID]
+		}
+	}
+}
+
 // buildNodeTrafficMap returns a map of all nodes requesting or requested by the target node (key=id). Node graphs
 // are from the perspective of the node, as such we use destination telemetry for incoming traffic and source telemetry
 // for outgoing traffic.
@@ -583,15 +642,17 @@ func handleAggregateNodeTrafficMap(o graph.TelemetryOptions, client *prometheus.
 	if !o.Appenders.All {
 		o.Appenders.AppenderNames = append(o.Appenders.AppenderNames, appender.AggregateNodeAppenderName)
 	}
-	appenders := appender.ParseAppenders(o)
+	appenders, finalizers := appender.ParseAppenders(o)
 	trafficMap := buildAggregateNodeTrafficMap(o.NodeOptions.Namespace, n, o, client)
 
 	namespaceInfo := graph.NewAppenderNamespaceInfo(o.NodeOptions.Namespace)
 
 	for _, a := range appenders {
 		appenderTimer := internalmetrics.GetGraphAppenderTimePrometheusTimer(a.Name())
+		debugTimer := globalInfo.Debug.Time(graph.DebugPhaseAppender, a.Name(), namespaceInfo.Namespace)
 		a.AppendGraph(trafficMap, globalInfo, namespaceInfo)
 		appenderTimer.ObserveDuration()
+		debugTimer()
 	}
 
 	// The appenders can add/remove/alter nodes. After the manipulations are complete
@@ -601,6 +662,8 @@ func handleAggregateNodeTrafficMap(o graph.TelemetryOptions, client *prometheus.
 	telemetry.MarkOutsideOrInaccessible(trafficMap, o)
 	telemetry.MarkTrafficGenerators(trafficMap)
 
+	telemetry.ApplyFinalizers(trafficMap, finalizers, globalInfo)
+
 	return trafficMap
 }
 
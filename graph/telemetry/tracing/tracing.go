@@ -0,0 +1,516 @@
+// Package tracing provides the tracing implementation of graph/TelemetryProvider.
+package tracing
+
+// Tracing.go is responsible for generating TrafficMaps using the traces stored in Jaeger. It implements the
+// TelemetryVendor interface. Unlike the Istio telemetry, the traces also report the calls of workloads not in
+// the mesh, and the calls to dependencies instrumented only by the application tracing (e.g. a database).
+//
+// The algorithm is two-pass:
+//   First Pass: Fetch the traces of the apps of the requested namespaces, for the requested time window. Each
+//               span whose parent span belongs to another service is a call between the two services, a client
+//               span without children and reporting a peer (e.g. peer.service, db.instance) is a call to the peer.
+//               The calls provide the edges, with their rates, response codes and response times.
+//
+//   Second Pass: Apply any requested appenders to alter or append to the graph. The appenders querying the
+//                Istio telemetry in Prometheus are not supported, the response times are reported by the
+//                vendor. Finalizer appenders are applied last, to the final graph.
+//
+// Limitations, compared to the Istio telemetry:
+//   - The rates are those of the sampled traces, typically a small fraction of the requests.
+//   - The traces identify apps and versions, not workloads or services. Only the app and versionedApp graph
+//     types are supported and service nodes are not injected, except for peers reported by client spans.
+//   - A call without an HTTP or gRPC status is reported as an HTTP request, with response code 500 when the
+//     span reports an error.
+//
+// Supports two vendor-specific query parameters:
+//   responseTime: Must be one of: avg | 50 | 95 | 99 (default 95)
+//   traceLimit: The maximum number of traces fetched per app (default 100)
+//
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	jaegerModels "github.com/jaegertracing/jaeger/model/json"
+
+	"github.com/kiali/kiali/config"
+	"github.com/kiali/kiali/graph"
+	"github.com/kiali/kiali/graph/telemetry"
+	"github.com/kiali/kiali/graph/telemetry/istio/appender"
+	"github.com/kiali/kiali/log"
+	"github.com/kiali/kiali/models"
+	"github.com/kiali/kiali/prometheus"
+	"github.com/kiali/kiali/prometheus/internalmetrics"
+)
+
+const (
+	defaultQuantile   = 0.95
+	defaultTraceLimit = 100
+)
+
+// promAppenders are the appenders querying the Istio telemetry in Prometheus, they are not supported. The
+// response times are provided by the vendor, the responseTime appender is not run.
+var promAppenders = telemetry.UnsupportedAppenders{
+	appender.AggregateNodeAppenderName:  false,
+	appender.AnomalyAppenderName:        false,
+	appender.ExternalHostAppenderName:   false,
+	appender.OperationsAppenderName:     false,
+	appender.ResponseTimeAppenderName:   true,
+	appender.SecurityPolicyAppenderName: false,
+	appender.TCPConnectionsAppenderName: false,
+	appender.ThroughputAppenderName:     false,
+}
+
+// node identifies a traced app, or a peer reported by a client span
+type node struct {
+	namespace string
+	app       string
+	version   string
+	service   string // set only for a peer
+}
+
+// edgeCalls holds the calls from a source to a destination, for a protocol
+type edgeCalls struct {
+	source    node
+	dest      node
+	protocol  string
+	codes     map[string]int
+	durations []float64 // millis
+}
+
+// callsMap maps an edge key "<source> <dest> <protocol>" to its calls
+type callsMap map[string]*edgeCalls
+
+func (m callsMap) add(source, dest node, protocol, code string, duration float64) {
+	key := fmt.Sprintf("%v %v %s", source, dest, protocol)
+	calls, ok := m[key]
+	if !ok {
+		calls = &edgeCalls{source: source, dest: dest, protocol: protocol, codes: make(map[string]int)}
+		m[key] = calls
+	}
+	calls.codes[code]++
+	calls.durations = append(calls.durations, duration)
+}
+
+// BuildNamespacesTrafficMap is required by the graph/TelemtryVendor interface. The Prometheus client
+// is not used, it is accepted to satisfy the interface.
+func BuildNamespacesTrafficMap(o graph.TelemetryOptions, client *prometheus.Client, globalInfo *graph.AppenderGlobalInfo) graph.TrafficMap {
+	log.Tracef("Build [%s] graph for [%d] namespaces [%v]", o.GraphType, len(o.Namespaces), o.Namespaces)
+
+	validateOptions(o)
+	appenders, finalizers := parseAppenders(o)
+
+	traces := make(map[jaegerModels.TraceID]jaegerModels.Trace)
+	for _, namespace := range o.Namespaces {
+		fetchTraces(traces, namespace.Name, o, globalInfo)
+	}
+	calls := buildCalls(traces, o)
+
+	trafficMap := graph.NewTrafficMap()
+	for _, namespace := range o.Namespaces {
+		log.Tracef("Build traffic map for namespace [%v]", namespace)
+		namespaceTrafficMap := buildNamespaceTrafficMap(namespace.Name, calls, o)
+		namespaceInfo := graph.NewAppenderNamespaceInfo(namespace.Name)
+		for _, a := range appenders {
+			appenderTimer := internalmetrics.GetGraphAppenderTimePrometheusTimer(a.Name())
+			debugTimer := globalInfo.Debug.Time(graph.DebugPhaseAppender, a.Name(), namespaceInfo.Namespace)
+			a.AppendGraph(namespaceTrafficMap, globalInfo, namespaceInfo)
+			appenderTimer.ObserveDuration()
+			debugTimer()
+		}
+		telemetry.MergeTrafficMaps(trafficMap, namespace.Name, namespaceTrafficMap)
+	}
+
+	// The appenders can add/remove/alter nodes. After the manipulations are complete
+	// we can make some final adjustments:
+	// - mark the outsiders (i.e. nodes not in the requested namespaces)
+	// - mark the insider traffic generators (i.e. inside the namespace and only outgoing edges)
+	telemetry.MarkOutsideOrInaccessible(trafficMap, o)
+	telemetry.MarkTrafficGenerators(trafficMap)
+
+	telemetry.ApplyFinalizers(trafficMap, finalizers, globalInfo)
+
+	return trafficMap
+}
+
+// BuildNodeTrafficMap is required by the graph/TelemtryVendor interface. The Prometheus client
+// is not used, it is accepted to satisfy the interface.
+func BuildNodeTrafficMap(o graph.TelemetryOptions, client *prometheus.Client, globalInfo *graph.AppenderGlobalInfo) graph.TrafficMap {
+	if o.NodeOptions.Aggregate != "" {
+		graph.BadRequest(fmt.Sprintf("Aggregate node graphs are not supported by telemetryVendor [%s]", graph.VendorTracing))
+	}
+	if o.NodeOptions.App == "" {
+		graph.BadRequest(fmt.Sprintf("Only app node graphs are supported by telemetryVendor [%s]", graph.VendorTracing))
+	}
+	validateOptions(o)
+
+	n := graph.NewNode(graph.Unknown, o.NodeOptions.Namespace, "", o.NodeOptions.Namespace, "", o.NodeOptions.App, o.NodeOptions.Version, o.GraphType)
+
+	log.Tracef("Build graph for node [%+v]", n)
+
+	appenders, finalizers := parseAppenders(o)
+
+	// the namespaces other than the node's namespace are requested only by multi-hop node graphs
+	traces := make(map[jaegerModels.TraceID]jaegerModels.Trace)
+	for _, namespace := range o.Namespaces {
+		fetchTraces(traces, namespace.Name, o, globalInfo)
+	}
+	calls := buildCalls(traces, o)
+
+	trafficMap := graph.NewTrafficMap()
+	for _, namespace := range o.Namespaces {
+		telemetry.MergeTrafficMaps(trafficMap, namespace.Name, buildNamespaceTrafficMap(namespace.Name, calls, o))
+	}
+	trafficMap = telemetry.ReduceToNode(trafficMap, n, o)
+
+	namespaceInfo := graph.NewAppenderNamespaceInfo(o.NodeOptions.Namespace)
+
+	for _, a := range appenders {
+		appenderTimer := internalmetrics.GetGraphAppenderTimePrometheusTimer(a.Name())
+		debugTimer := globalInfo.Debug.Time(graph.DebugPhaseAppender, a.Name(), namespaceInfo.Namespace)
+		a.AppendGraph(trafficMap, globalInfo, namespaceInfo)
+		appenderTimer.ObserveDuration()
+		debugTimer()
+	}
+
+	// The appenders can add/remove/alter nodes. After the manipulations are complete
+	// we can make some final adjustments:
+	// - mark the outsiders (i.e. nodes not in the requested namespaces)
+	// - mark the traffic generators
+	telemetry.MarkOutsideOrInaccessible(trafficMap, o)
+	telemetry.MarkTrafficGenerators(trafficMap)
+
+	telemetry.ApplyFinalizers(trafficMap, finalizers, globalInfo)
+
+	return trafficMap
+}
+
+func validateOptions(o graph.TelemetryOptions) {
+	if !config.Get().ExternalServices.Tracing.Enabled {
+		graph.BadRequest(fmt.Sprintf("TelemetryVendor [%s] requires tracing to be enabled", graph.VendorTracing))
+	}
+	if o.GraphType != graph.GraphTypeApp && o.GraphType != graph.GraphTypeVersionedApp {
+		graph.BadRequest(fmt.Sprintf("Invalid graphType [%s], telemetryVendor [%s] supports: %s | %s", o.GraphType, graph.VendorTracing, graph.GraphTypeApp, graph.GraphTypeVersionedApp))
+	}
+}
+
+// parseAppenders returns the requested appenders supported by the tracing telemetry, and the finalizers
+func parseAppenders(o graph.TelemetryOptions) ([]graph.Appender, []graph.Appender) {
+	appenders, finalizers := appender.ParseAppenders(o)
+	return telemetry.FilterAppenders(o, graph.VendorTracing, appenders, promAppenders), finalizers
+}
+
+// fetchTraces adds the traces of the apps of the namespace, the same trace is typically returned for
+// several apps.
+func fetchTraces(traces map[jaegerModels.TraceID]jaegerModels.Trace, namespace string, o graph.TelemetryOptions, gi *graph.AppenderGlobalInfo) {
+	workloadList, err := gi.Business.Workload.GetWorkloadList(namespace, false)
+	graph.CheckError(err)
+
+	appLabelName := config.Get().IstioLabels.AppLabelName
+	apps := make(map[string]bool)
+	for _, w := range workloadList.Workloads {
+		if app, ok := w.Labels[appLabelName]; ok && app != "" {
+			apps[app] = true
+		}
+	}
+
+	limit := defaultTraceLimit
+	if limitString := o.Params.Get("traceLimit"); limitString != "" {
+		if limit, err = strconv.Atoi(limitString); err != nil || limit <= 0 {
+			graph.BadRequest(fmt.Sprintf("Invalid traceLimit, expecting a positive integer. [%s]", limitString))
+		}
+	}
+	end := time.Unix(o.QueryTime, 0)
+	query := models.TracingQuery{
+		Start: end.Add(-o.Namespaces[namespace].Duration),
+		End:   end,
+		Limit: limit,
+	}
+
+	wg := sync.WaitGroup{}
+	mutex := sync.Mutex{}
+	errs := []string{}
+	for app := range apps {
+		wg.Add(1)
+		go func(app string) {
+			defer wg.Done()
+
+			r, err := gi.Business.Jaeger.GetAppTraces(namespace, app, query)
+
+			mutex.Lock()
+			defer mutex.Unlock()
+			if err != nil {
+				errs = append(errs, fmt.Sprintf("app [%s]: %v", app, err))
+				return
+			}
+			for _, trace := range r.Data {
+				traces[trace.TraceID] = trace
+			}
+		}(app)
+	}
+	wg.Wait()
+
+	if len(errs) > 0 {
+		graph.Error(fmt.Sprintf("Failed to fetch traces for namespace [%s]: %s", namespace, strings.Join(errs, "; ")))
+	}
+}
+
+// buildCalls returns the calls reported by the traces
+func buildCalls(traces map[jaegerModels.TraceID]jaegerModels.Trace, o graph.TelemetryOptions) callsMap {
+	accessibleNamespaces := make(map[string]bool)
+	for namespace := range o.AccessibleNamespaces {
+		accessibleNamespaces[namespace] = true
+	}
+	for namespace := range o.Namespaces {
+		accessibleNamespaces[namespace] = true
+	}
+
+	calls := make(callsMap)
+	for _, trace := range traces {
+		spans := make(map[jaegerModels.SpanID]*jaegerModels.Span, len(trace.Spans))
+		hasChildren := make(map[jaegerModels.SpanID]bool)
+		for i := range trace.Spans {
+			span := &trace.Spans[i]
+			spans[span.SpanID] = span
+			if parentID, ok := parentSpanID(span); ok {
+				hasChildren[parentID] = true
+			}
+		}
+
+		for _, span := range spans {
+			spanNode, ok := nodeOf(span, trace.Processes, accessibleNamespaces)
+			if !ok {
+				continue
+			}
+			protocol, code := responseOf(span)
+			duration := float64(span.Duration) / 1000.0
+
+			if parentID, ok := parentSpanID(span); ok {
+				if parent, found := spans[parentID]; found {
+					if parentNode, ok := nodeOf(parent, trace.Processes, accessibleNamespaces); ok && parentNode != spanNode {
+						calls.add(parentNode, spanNode, protocol, code, duration)
+					}
+				}
+			}
+
+			if hasChildren[span.SpanID] || stringTag(span.Tags, "span.kind") != "client" {
+				continue
+			}
+			if peer := peerOf(span); peer != "" {
+				peerNode := node{namespace: spanNode.namespace, service: peer}
+				calls.add(spanNode, peerNode, protocol, code, duration)
+			}
+		}
+	}
+	return calls
+}
+
+// parentSpanID returns the ID of the parent span, from the span references or the deprecated parentSpanID
+func parentSpanID(span *jaegerModels.Span) (jaegerModels.SpanID, bool) {
+	for _, ref := range span.References {
+		if ref.TraceID == span.TraceID && (ref.RefType == jaegerModels.ChildOf || ref.RefType == jaegerModels.FollowsFrom) {
+			return ref.SpanID, true
+		}
+	}
+	if span.ParentSpanID != "" {
+		return span.ParentSpanID, true
+	}
+	return "", false
+}
+
+// nodeOf returns the app of the span. The spans of the Istio proxies are tagged with the canonical service,
+// revision and namespace. Otherwise the app is resolved from the Jaeger service name, "<app>.<namespace>"
+// when the tracing namespace selector is enabled.
+func nodeOf(span *jaegerModels.Span, processes map[jaegerModels.ProcessID]jaegerModels.Process, namespaces map[string]bool) (node, bool) {
+	n := node{
+		namespace: stringTag(span.Tags, "istio.namespace"),
+		app:       stringTag(span.Tags, "istio.canonical_service"),
+		version:   stringTag(span.Tags, "istio.canonical_revision"),
+	}
+	if n.namespace != "" && n.app != "" {
+		if n.version == "" {
+			n.version = graph.Unknown
+		}
+		return n, true
+	}
+
+	process := span.Process
+	if process == nil {
+		if p, ok := processes[span.ProcessID]; ok {
+			process = &p
+		}
+	}
+	if process == nil || process.ServiceName == "" {
+		return node{}, false
+	}
+
+	// a service name without a known namespace is assumed to be in the Istio namespace, as for the Jaeger client
+	n = node{namespace: config.Get().IstioNamespace, app: process.ServiceName, version: graph.Unknown}
+	if i := strings.LastIndex(process.ServiceName, "."); i > 0 && namespaces[process.ServiceName[i+1:]] {
+		n.app = process.ServiceName[:i]
+		n.namespace = process.ServiceName[i+1:]
+	}
+	return n, true
+}
+
+// responseOf returns the protocol and response code of the span
+func responseOf(span *jaegerModels.Span) (protocol, code string) {
+	if code := stringTag(span.Tags, "grpc.status_code"); code != "" {
+		return graph.GRPC.Name, code
+	}
+	if code := stringTag(span.Tags, "rpc.grpc.status_code"); code != "" {
+		return graph.GRPC.Name, code
+	}
+	if code := stringTag(span.Tags, "http.status_code"); code != "" {
+		return graph.HTTP.Name, code
+	}
+	if stringTag(span.Tags, "error") == "true" {
+		return graph.HTTP.Name, "500"
+	}
+	return graph.HTTP.Name, "200"
+}
+
+// peerOf returns the peer called by a client span, using the OpenTracing and OpenTelemetry conventions
+func peerOf(span *jaegerModels.Span) string {
+	if peer := stringTag(span.Tags, "peer.service"); peer != "" {
+		return peer
+	}
+	for _, key := range []string{"db.instance", "db.name"} {
+		if peer := stringTag(span.Tags, key); peer != "" {
+			return peer
+		}
+	}
+	for _, key := range []string{"net.peer.name", "peer.hostname"} {
+		if peer := stringTag(span.Tags, key); peer != "" {
+			return peer
+		}
+	}
+	return ""
+}
+
+// stringTag returns the value of the tag as a string, or "" if the span does not have the tag
+func stringTag(tags []jaegerModels.KeyValue, key string) string {
+	for _, tag := range tags {
+		if tag.Key != key {
+			continue
+		}
+		switch v := tag.Value.(type) {
+		case string:
+			return v
+		case bool:
+			return strconv.FormatBool(v)
+		case float64:
+			return strconv.FormatFloat(v, 'f', -1, 64)
+		case int64:
+			return strconv.FormatInt(v, 10)
+		default:
+			return fmt.Sprintf("%v", v)
+		}
+	}
+	return ""
+}
+
+// buildNamespaceTrafficMap returns a map of all namespace nodes (key=id). All nodes either directly send
+// and/or receive requests from a node in the namespace.
+func buildNamespaceTrafficMap(namespace string, calls callsMap, o graph.TelemetryOptions) graph.TrafficMap {
+	trafficMap := graph.NewTrafficMap()
+	seconds := o.Namespaces[namespace].Duration.Seconds()
+	if seconds <= 0 {
+		return trafficMap
+	}
+
+	quantile := defaultQuantile
+	switch o.Params.Get("responseTime") {
+	case "":
+		// default
+	case "avg":
+		quantile = 0.0
+	case "50":
+		quantile = 0.5
+	case "95":
+		quantile = 0.95
+	case "99":
+		quantile = 0.99
+	default:
+		graph.BadRequest(fmt.Sprintf(`Invalid responseTime, must be one of: avg | 50 | 95 | 99: [%s]`, o.Params.Get("responseTime")))
+	}
+
+	for _, c := range calls {
+		if c.source.namespace != namespace && c.dest.namespace != namespace {
+			continue
+		}
+		source := addNode(trafficMap, c.source, o)
+		dest := addNode(trafficMap, c.dest, o)
+
+		var edge *graph.Edge
+		for _, e := range source.Edges {
+			if dest.ID == e.Dest.ID && e.Metadata[graph.ProtocolKey] == c.protocol {
+				edge = e
+				break
+			}
+		}
+		if nil == edge {
+			edge = source.AddEdge(dest)
+			edge.Metadata[graph.ProtocolKey] = c.protocol
+		}
+
+		host := c.dest.app
+		if c.dest.service != "" {
+			host = c.dest.service
+			addToDestServices(dest.Metadata, c.dest.namespace, c.dest.service)
+		}
+		for code, count := range c.codes {
+			// the traces do not report response flags
+			graph.AddToMetadata(c.protocol, float64(count)/seconds, code, "-", host, source.Metadata, dest.Metadata, edge.Metadata)
+		}
+		edge.Metadata[graph.ResponseTime] = responseTime(c.durations, quantile)
+	}
+
+	return trafficMap
+}
+
+// responseTime returns the average (quantile 0) or the quantile of the durations
+func responseTime(durations []float64, quantile float64) float64 {
+	if len(durations) == 0 {
+		return 0.0
+	}
+	if quantile == 0.0 {
+		total := 0.0
+		for _, d := range durations {
+			total += d
+		}
+		return total / float64(len(durations))
+	}
+	sorted := append([]float64{}, durations...)
+	sort.Float64s(sorted)
+	i := int(math.Ceil(quantile*float64(len(sorted)))) - 1
+	if i < 0 {
+		i = 0
+	}
+	return sorted[i]
+}
+
+func addToDestServices(md graph.Metadata, namespace, service string) {
+	destServices, ok := md[graph.DestServices]
+	if !ok {
+		destServices = graph.NewDestServicesMetadata()
+		md[graph.DestServices] = destServices
+	}
+	destService := graph.ServiceName{Cluster: graph.Unknown, Namespace: namespace, Name: service}
+	destServices.(graph.DestServicesMetadata)[destService.Key()] = destService
+}
+
+func addNode(trafficMap graph.TrafficMap, n node, o graph.TelemetryOptions) *graph.Node {
+	id, nodeType := graph.Id(graph.Unknown, n.namespace, n.service, n.namespace, "", n.app, n.version, o.GraphType)
+	existing, found := trafficMap[id]
+	if !found {
+		newNode := graph.NewNodeExplicit(id, graph.Unknown, n.namespace, "", n.app, n.version, n.service, nodeType, o.GraphType)
+		existing = &newNode
+		trafficMap[id] = existing
+	}
+	return existing
+}
@@ -0,0 +1,236 @@
+package tracing
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	jaegerModels "github.com/jaegertracing/jaeger/model/json"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/kiali/kiali/config"
+	"github.com/kiali/kiali/graph"
+	"github.com/kiali/kiali/graph/telemetry"
+	"github.com/kiali/kiali/graph/telemetry/istio/appender"
+)
+
+func tag(key string, value interface{}) jaegerModels.KeyValue {
+	return jaegerModels.KeyValue{Key: key, Value: value}
+}
+
+func span(id, parent jaegerModels.SpanID, process jaegerModels.ProcessID, duration uint64, tags ...jaegerModels.KeyValue) jaegerModels.Span {
+	s := jaegerModels.Span{TraceID: "t1", SpanID: id, ProcessID: process, Duration: duration, Tags: tags}
+	if parent != "" {
+		s.References = []jaegerModels.Reference{{RefType: jaegerModels.ChildOf, TraceID: "t1", SpanID: parent}}
+	}
+	return s
+}
+
+// productpage.bookinfo -> reviews (istio proxy, v2) -> ratings.bookinfo -> mysql
+func setupTraces() map[jaegerModels.TraceID]jaegerModels.Trace {
+	config.Set(config.NewConfig())
+
+	trace := jaegerModels.Trace{
+		TraceID: "t1",
+		Spans: []jaegerModels.Span{
+			span("a", "", "p1", 50000, tag("span.kind", "server")),
+			span("b", "a", "p2", 40000, tag("http.status_code", float64(200)),
+				tag("istio.canonical_service", "reviews"), tag("istio.canonical_revision", "v2"), tag("istio.namespace", "bookinfo")),
+			span("c", "b", "p3", 20000, tag("grpc.status_code", "0")),
+			span("d", "c", "p3", 5000, tag("span.kind", "client"), tag("db.instance", "mysql"), tag("error", true)),
+			// a call within the same app is not an edge
+			span("e", "c", "p3", 1000),
+		},
+		Processes: map[jaegerModels.ProcessID]jaegerModels.Process{
+			"p1": {ServiceName: "productpage.bookinfo"},
+			"p2": {ServiceName: "reviews.bookinfo"},
+			"p3": {ServiceName: "ratings.bookinfo"},
+		},
+	}
+	return map[jaegerModels.TraceID]jaegerModels.Trace{trace.TraceID: trace}
+}
+
+func testTelemetryOptions() graph.TelemetryOptions {
+	o := graph.TelemetryOptions{
+		Namespaces: graph.NamespaceInfoMap{"bookinfo": {Name: "bookinfo", Duration: 10 * time.Second}},
+	}
+	o.Params = url.Values{}
+	o.GraphType = graph.GraphTypeVersionedApp
+	return o
+}
+
+func TestBuildCalls(t *testing.T) {
+	assert := assert.New(t)
+
+	calls := buildCalls(setupTraces(), testTelemetryOptions())
+	assert.Len(calls, 3)
+
+	byDest := make(map[string]*edgeCalls)
+	for _, c := range calls {
+		byDest[c.dest.app+c.dest.service] = c
+	}
+
+	reviews := byDest["reviews"]
+	assert.Equal(node{namespace: "bookinfo", app: "productpage", version: graph.Unknown}, reviews.source)
+	assert.Equal(node{namespace: "bookinfo", app: "reviews", version: "v2"}, reviews.dest)
+	assert.Equal(graph.HTTP.Name, reviews.protocol)
+	assert.Equal(map[string]int{"200": 1}, reviews.codes)
+	assert.Equal([]float64{40.0}, reviews.durations)
+
+	ratings := byDest["ratings"]
+	assert.Equal("reviews", ratings.source.app)
+	assert.Equal(graph.GRPC.Name, ratings.protocol)
+	assert.Equal(map[string]int{"0": 1}, ratings.codes)
+
+	// the database is reported by the client span
+	mysql := byDest["mysql"]
+	assert.Equal("ratings", mysql.source.app)
+	assert.Equal(node{namespace: "bookinfo", service: "mysql"}, mysql.dest)
+	assert.Equal(map[string]int{"500": 1}, mysql.codes)
+}
+
+func TestNodeOf(t *testing.T) {
+	assert := assert.New(t)
+	config.Set(config.NewConfig())
+
+	namespaces := map[string]bool{"bookinfo": true}
+	processes := map[jaegerModels.ProcessID]jaegerModels.Process{
+		"p1": {ServiceName: "productpage.bookinfo"},
+		"p2": {ServiceName: "istio-ingressgateway"},
+		"p3": {ServiceName: "reviews.unknown-namespace"},
+	}
+
+	n, ok := nodeOf(&jaegerModels.Span{ProcessID: "p1"}, processes, namespaces)
+	assert.True(ok)
+	assert.Equal(node{namespace: "bookinfo", app: "productpage", version: graph.Unknown}, n)
+
+	n, ok = nodeOf(&jaegerModels.Span{ProcessID: "p2"}, processes, namespaces)
+	assert.True(ok)
+	assert.Equal(node{namespace: "istio-system", app: "istio-ingressgateway", version: graph.Unknown}, n)
+
+	n, ok = nodeOf(&jaegerModels.Span{ProcessID: "p3"}, processes, namespaces)
+	assert.True(ok)
+	assert.Equal("reviews.unknown-namespace", n.app)
+
+	_, ok = nodeOf(&jaegerModels.Span{ProcessID: "p4"}, processes, namespaces)
+	assert.False(ok)
+}
+
+func TestBuildNamespaceTrafficMap(t *testing.T) {
+	assert := assert.New(t)
+
+	o := testTelemetryOptions()
+	trafficMap := buildNamespaceTrafficMap("bookinfo", buildCalls(setupTraces(), o), o)
+
+	// productpage, reviews, ratings, mysql
+	assert.Len(trafficMap, 4)
+
+	reviewsID, _ := graph.Id(graph.Unknown, "", "", "bookinfo", "", "reviews", "v2", graph.GraphTypeVersionedApp)
+	reviews, ok := trafficMap[reviewsID]
+	assert.True(ok)
+	assert.Equal(graph.NodeTypeApp, reviews.NodeType)
+	assert.Equal(0.1, reviews.Metadata[graph.HTTP.NodeRates[0].Name])
+
+	assert.Len(reviews.Edges, 1)
+	edge := reviews.Edges[0]
+	assert.Equal("ratings", edge.Dest.App)
+	assert.Equal(graph.GRPC.Name, edge.Metadata[graph.ProtocolKey])
+	assert.Equal(0.1, edge.Metadata[graph.GRPC.EdgeRates[0].Name])
+	assert.Equal(20.0, edge.Metadata[graph.ResponseTime])
+
+	mysqlID, _ := graph.Id(graph.Unknown, "bookinfo", "mysql", "bookinfo", "", "", "", graph.GraphTypeVersionedApp)
+	mysql, ok := trafficMap[mysqlID]
+	assert.True(ok)
+	assert.Equal(graph.NodeTypeService, mysql.NodeType)
+	assert.Contains(mysql.Metadata[graph.DestServices], "unknown bookinfo mysql")
+
+	// traffic of another namespace is not in the namespace map
+	assert.Empty(buildNamespaceTrafficMap("default", buildCalls(setupTraces(), o), o))
+}
+
+func TestBuildNamespaceTrafficMapResponseTime(t *testing.T) {
+	assert := assert.New(t)
+
+	o := testTelemetryOptions()
+	o.Params.Set("responseTime", "foo")
+	assert.Panics(func() {
+		buildNamespaceTrafficMap("bookinfo", buildCalls(setupTraces(), o), o)
+	})
+
+	durations := []float64{10.0, 20.0, 30.0, 100.0}
+	assert.Equal(40.0, responseTime(durations, 0.0))
+	assert.Equal(20.0, responseTime(durations, 0.5))
+	assert.Equal(100.0, responseTime(durations, 0.95))
+	assert.Equal(0.0, responseTime(nil, 0.95))
+}
+
+func TestReduceToNode(t *testing.T) {
+	assert := assert.New(t)
+
+	o := testTelemetryOptions()
+	trafficMap := buildNamespaceTrafficMap("bookinfo", buildCalls(setupTraces(), o), o)
+
+	target := graph.NewNode(graph.Unknown, "bookinfo", "", "bookinfo", "", "ratings", "", graph.GraphTypeApp)
+	trafficMap = telemetry.ReduceToNode(trafficMap, target, o)
+
+	// reviews -> ratings -> mysql
+	assert.Len(trafficMap, 3)
+	for _, n := range trafficMap {
+		assert.NotEqual("productpage", n.App)
+	}
+
+	// productpage -> reviews -> ratings -> mysql
+	o.NodeOptions.Depth = 2
+	trafficMap = buildNamespaceTrafficMap("bookinfo", buildCalls(setupTraces(), o), o)
+	trafficMap = telemetry.ReduceToNode(trafficMap, target, o)
+	assert.Len(trafficMap, 4)
+}
+
+func TestValidateOptions(t *testing.T) {
+	assert := assert.New(t)
+
+	conf := config.NewConfig()
+	conf.ExternalServices.Tracing.Enabled = true
+	config.Set(conf)
+
+	o := testTelemetryOptions()
+	assert.NotPanics(func() { validateOptions(o) })
+
+	o.GraphType = graph.GraphTypeWorkload
+	assert.PanicsWithValue(graph.Response{Message: "Invalid graphType [workload], telemetryVendor [tracing] supports: app | versionedApp", Code: 400}, func() {
+		validateOptions(o)
+	})
+
+	conf.ExternalServices.Tracing.Enabled = false
+	config.Set(conf)
+	o.GraphType = graph.GraphTypeApp
+	assert.PanicsWithValue(graph.Response{Message: "TelemetryVendor [tracing] requires tracing to be enabled", Code: 400}, func() {
+		validateOptions(o)
+	})
+}
+
+func TestParseAppenders(t *testing.T) {
+	assert := assert.New(t)
+	config.Set(config.NewConfig())
+
+	o := testTelemetryOptions()
+	o.Appenders = graph.RequestedAppenders{All: true}
+	appenders, finalizers := parseAppenders(o)
+	assert.NotEmpty(appenders)
+	// the finalizers are opt-in
+	assert.Empty(finalizers)
+	for _, a := range appenders {
+		_, unsupported := promAppenders[a.Name()]
+		assert.False(unsupported, a.Name())
+	}
+
+	// the response times are provided by the vendor
+	o.Appenders = graph.RequestedAppenders{AppenderNames: []string{appender.DeadNodeAppenderName, appender.ResponseTimeAppenderName}}
+	appenders, _ = parseAppenders(o)
+	assert.Len(appenders, 1)
+
+	o.Appenders = graph.RequestedAppenders{AppenderNames: []string{appender.ThroughputAppenderName}}
+	assert.PanicsWithValue(graph.Response{Message: "Appender [throughput] is not supported by telemetryVendor [tracing]", Code: 400}, func() {
+		parseAppenders(o)
+	})
+}
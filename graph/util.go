@@ -24,6 +24,11 @@ func Forbidden(message string) {
 	Panic(message, nethttp.StatusForbidden)
 }
 
+// NotFound panics with NotFound and the provided message
+func NotFound(message string) {
+	Panic(message, nethttp.StatusNotFound)
+}
+
 // Panic panics with the provided HTTP response code and message
 func Panic(message string, code int) Response {
 	panic(Response{
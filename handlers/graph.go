@@ -13,32 +13,71 @@ package handlers
 //              configuration returned to the caller.
 //
 // The current Handlers:
-//   GraphNamespaces: Generate a graph for one or more requested namespaces.
-//   GraphNode:       Generate a graph for a specific node, detailing the immediate incoming and outgoing traffic.
+//   GraphNamespaces:       Generate a graph for one or more requested namespaces.
+//   GraphNamespacesDiff:   Generate a graph for one or more requested namespaces, comparing two time windows.
+//   GraphNamespacesStream: Stream a refreshed graph for one or more requested namespaces, as server-sent events.
+//   GraphNode:             Generate a graph for a specific node, detailing the incoming and outgoing traffic up to the requested depth.
+//   GraphNodeBlastRadius:  List the nodes depending on a specific node, directly or transitively, with their traffic to the node.
+//   GraphSnapshotCreate:   Generate a graph for one or more requested namespaces and save it as a snapshot.
+//   GraphSnapshots:        List the saved graph snapshots.
+//   GraphSnapshot:         Replay a saved graph snapshot, without querying the telemetry.
+//   GraphSnapshotDelete:   Delete a saved graph snapshot, snapshots are shared by the users with access to their namespaces.
+//   GraphSchema:           Return the JSON Schema of the cytoscape graph.
 //
 // The handlers accept the following query parameters (see notes below)
 //   appenders:       Comma-separated list of TelemetryVendor-specific appenders to run. (default: all)
-//   configVendor:    default: cytoscape
+//   configVendor:    cytoscape | dot | graphml (default: cytoscape)
 //   duration:        time.Duration indicating desired query range duration, (default: 10m)
+//   find:            Find expression, matching nodes and edges are marked isFound (default: none)
 //   graphType:       Determines how to present the telemetry data. app | service | versionedApp | workload (default: workload)
-//   boxBy:           If supported by vendor, visually box by a specified node attribute (default: none)
-//   namespaces:      Comma-separated list of namespace names to use in the graph. Will override namespace path param
+//   hide:            Hide expression, matching nodes and edges are removed from the graph (default: none)
+//   name:            Snapshot create only, an optional snapshot name (default: none)
+//   boxBy:           If supported by vendor, visually box by a specified node attribute, version or configured label (default: none)
+//   compareDuration: Diff graph only, time.Duration of the compare time window (default: duration)
+//   compareQueryTime: Diff graph only, Unix time (seconds) ending the compare time window (default: queryTime-duration)
+//   debug:           Return the generation timings and the Prometheus queries with the graph, cytoscape only (default: false)
+//   depth:           Node graph only, hops from the node expanded in both directions, maximum 5 (default: 1)
+//   diffTolerance:   Diff graph only, percentage change for a node or edge to be marked changed (default: 10)
+//   namespaces:      Comma-separated list of namespace names to use in the graph. Will override namespace path param,
+//                    except for the blast radius and the node graph with depth > 1, which add the namespaces to the
+//                    namespace path param
 //   queryTime:       Unix time (seconds) for query such that range is queryTime-duration..queryTime (default now)
-//   TelemetryVendor: default: istio
+//   refreshInterval: Stream only, time.Duration between graph refreshes, minimum 5s (default: 15s)
+//   telemetryVendor: istio | envoy | tracing (default: istio), envoy builds the graph from the sidecar proxy stats, without Prometheus,
+//                    tracing builds the graph from the sampled Jaeger traces (app graph types only)
 //
 //  Note: some handlers may ignore some query parameters.
+//  Note: snapshot replay accepts only configVendor, boxBy, find and hide, other options are those of the snapshot.
 //  Note: vendors may support additional, vendor-specific query parameters.
 //
 import (
+	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
 	"runtime/debug"
+	"strconv"
+	"time"
 
+	"github.com/gorilla/mux"
+
+	"github.com/kiali/kiali/business"
+	"github.com/kiali/kiali/config"
 	"github.com/kiali/kiali/graph"
 	"github.com/kiali/kiali/graph/api"
+	"github.com/kiali/kiali/graph/config/cytoscape"
+	"github.com/kiali/kiali/graph/snapshot"
+	"github.com/kiali/kiali/graph/stream"
 	"github.com/kiali/kiali/log"
 )
 
+const (
+	defaultRefreshInterval = 15 * time.Second
+	minRefreshInterval     = 5 * time.Second
+	// streamWriteTimeout bounds each write of a graph stream, which outlives the server write timeout
+	streamWriteTimeout = 30 * time.Second
+)
+
 // GraphNamespaces is a REST http.HandlerFunc handling graph generation for 1 or more namespaces
 func GraphNamespaces(w http.ResponseWriter, r *http.Request) {
 	defer handlePanic(w)
@@ -52,6 +91,136 @@ func GraphNamespaces(w http.ResponseWriter, r *http.Request) {
 	respond(w, code, payload)
 }
 
+// GraphNamespacesDiff is a REST http.HandlerFunc handling diff graph generation for 1 or more namespaces
+func GraphNamespacesDiff(w http.ResponseWriter, r *http.Request) {
+	defer handlePanic(w)
+
+	o := graph.NewOptions(r)
+
+	business, err := getBusiness(r)
+	graph.CheckError(err)
+
+	code, payload := api.GraphNamespacesDiff(business, o)
+	respond(w, code, payload)
+}
+
+// GraphNamespacesStream is a REST http.HandlerFunc streaming graph updates for 1 or more namespaces, as server-sent
+// events. The first event is the full graph, each following event holds only the node and edge changes of a refresh.
+// Requests from the same user, with the same options, share a single stream. A client resuming with the Last-Event-ID
+// of the latest refresh is not sent the full graph again.
+func GraphNamespacesStream(w http.ResponseWriter, r *http.Request) {
+	defer handlePanic(w)
+
+	o := graph.NewOptions(r)
+	if o.ConfigVendor != graph.VendorCytoscape {
+		graph.BadRequest(fmt.Sprintf("Invalid configVendor [%s], graph streams support only cytoscape", o.ConfigVendor))
+	}
+
+	refreshInterval := defaultRefreshInterval
+	if refreshIntervalString := r.URL.Query().Get("refreshInterval"); refreshIntervalString != "" {
+		var err error
+		refreshInterval, err = time.ParseDuration(refreshIntervalString)
+		if err != nil || refreshInterval < minRefreshInterval {
+			graph.BadRequest(fmt.Sprintf("Invalid refreshInterval [%s], minimum is [%v]", refreshIntervalString, minRefreshInterval))
+		}
+	}
+	// an invalid or missing Last-Event-ID just means the client receives the full graph
+	lastEventID, _ := strconv.ParseUint(r.Header.Get("Last-Event-ID"), 10, 64)
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		graph.Error("Graph streams are not supported by the response writer")
+	}
+
+	authInfo, err := getAuthInfo(r)
+	graph.CheckError(err)
+	business, err := getBusiness(r)
+	graph.CheckError(err)
+
+	generate := func(queryTime int64) cytoscape.Config {
+		o.ConfigOptions.QueryTime = queryTime
+		o.TelemetryOptions.QueryTime = queryTime
+		_, payload := api.GraphNamespaces(business, o)
+		return payload.(cytoscape.Config)
+	}
+
+	// the server write timeout applies to the whole response, it is replaced by a deadline for each write when the
+	// response writer supports it, otherwise the stream ends with the server write timeout
+	deadliner, ok := getWriteDeadliner(w)
+	if !ok {
+		log.Warningf("Write deadlines are not supported by the response writer, the graph stream ends with the server write timeout")
+	}
+	extendWriteDeadline := func() {
+		if deadliner == nil {
+			return
+		}
+		if err := deadliner.SetWriteDeadline(time.Now().Add(streamWriteTimeout)); err != nil {
+			log.Warningf("Unable to set a write deadline, the graph stream ends with the server write timeout: %v", err)
+			deadliner = nil
+		}
+	}
+	extendWriteDeadline()
+
+	sub := stream.Subscribe(stream.NewKey(authInfo.Token, r.URL.Query()), refreshInterval, generate, lastEventID)
+	defer sub.Close()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case event, ok := <-sub.Events:
+			if !ok {
+				return
+			}
+			extendWriteDeadline()
+			if err := writeStreamEvent(w, event); err != nil {
+				log.Debugf("Ending graph stream: %v", err)
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+// writeDeadliner is implemented by the response writers supporting a write deadline
+type writeDeadliner interface {
+	SetWriteDeadline(deadline time.Time) error
+}
+
+// getWriteDeadliner returns the response writer, or the first writer it wraps, supporting a write deadline
+func getWriteDeadliner(w http.ResponseWriter) (writeDeadliner, bool) {
+	for {
+		if deadliner, ok := w.(writeDeadliner); ok {
+			return deadliner, true
+		}
+		unwrapper, ok := w.(interface{ Unwrap() http.ResponseWriter })
+		if !ok {
+			return nil, false
+		}
+		w = unwrapper.Unwrap()
+	}
+}
+
+// writeStreamEvent writes the event in the server-sent events format, the event data is JSON
+func writeStreamEvent(w io.Writer, event stream.Event) error {
+	data, err := json.Marshal(event.Data)
+	if err != nil {
+		return err
+	}
+	if event.ID != 0 {
+		if _, err := fmt.Fprintf(w, "id: %d\n", event.ID); err != nil {
+			return err
+		}
+	}
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Type, data)
+	return err
+}
+
 // GraphNode is a REST http.HandlerFunc handling node-detail graph config generation.
 func GraphNode(w http.ResponseWriter, r *http.Request) {
 	defer handlePanic(w)
@@ -65,6 +234,135 @@ func GraphNode(w http.ResponseWriter, r *http.Request) {
 	respond(w, code, payload)
 }
 
+// GraphNodeBlastRadius is a REST http.HandlerFunc returning the dependents of a node, i.e. the nodes whose
+// requests depend on the node, directly or transitively.
+func GraphNodeBlastRadius(w http.ResponseWriter, r *http.Request) {
+	defer handlePanic(w)
+
+	o := graph.NewBlastRadiusOptions(r)
+
+	business, err := getBusiness(r)
+	graph.CheckError(err)
+
+	code, result := api.BlastRadius(business, o)
+	RespondWithJSON(w, code, result)
+}
+
+// GraphSnapshotCreate is a REST http.HandlerFunc generating a graph for 1 or more namespaces and saving it as a snapshot
+func GraphSnapshotCreate(w http.ResponseWriter, r *http.Request) {
+	defer handlePanic(w)
+
+	o := graph.NewOptions(r)
+
+	store, err := snapshot.NewStore(config.Get().GraphSnapshots)
+	graph.CheckError(err)
+	business, err := getBusiness(r)
+	graph.CheckError(err)
+
+	code, info := api.SnapshotNamespaces(business, store, r.URL.Query().Get("name"), o)
+	RespondWithJSON(w, code, info)
+}
+
+// GraphSnapshots is a REST http.HandlerFunc listing the graph snapshots. Only snapshots of namespaces accessible
+// to the user are listed.
+func GraphSnapshots(w http.ResponseWriter, r *http.Request) {
+	defer handlePanic(w)
+
+	store, err := snapshot.NewStore(config.Get().GraphSnapshots)
+	graph.CheckError(err)
+	business, err := getBusiness(r)
+	graph.CheckError(err)
+
+	infos, err := store.List()
+	graph.CheckError(err)
+	accessibleNamespaces := getSnapshotAccessibleNamespaces(business)
+
+	result := []snapshot.Info{}
+	for _, info := range infos {
+		if isSnapshotAccessible(info, accessibleNamespaces) {
+			result = append(result, info)
+		}
+	}
+	RespondWithJSON(w, http.StatusOK, result)
+}
+
+// GraphSnapshot is a REST http.HandlerFunc replaying a graph snapshot through the requested config vendor
+func GraphSnapshot(w http.ResponseWriter, r *http.Request) {
+	defer handlePanic(w)
+
+	store, err := snapshot.NewStore(config.Get().GraphSnapshots)
+	graph.CheckError(err)
+	business, err := getBusiness(r)
+	graph.CheckError(err)
+
+	s := getAccessibleSnapshot(store, business, mux.Vars(r)["snapshot"])
+	o := graph.NewReplayOptions(r, s.GraphOptions())
+
+	code, payload := api.GraphSnapshot(s, o)
+	respond(w, code, payload)
+}
+
+// GraphSnapshotDelete is a REST http.HandlerFunc deleting a graph snapshot. Snapshots are shared, any user with access
+// to all the namespaces of the snapshot can delete it, not only its creator.
+func GraphSnapshotDelete(w http.ResponseWriter, r *http.Request) {
+	defer handlePanic(w)
+
+	store, err := snapshot.NewStore(config.Get().GraphSnapshots)
+	graph.CheckError(err)
+	business, err := getBusiness(r)
+	graph.CheckError(err)
+
+	s := getAccessibleSnapshot(store, business, mux.Vars(r)["snapshot"])
+	if err := store.Delete(s.ID); err == snapshot.ErrNotFound {
+		graph.NotFound(fmt.Sprintf("Graph snapshot [%s] not found", s.ID))
+	} else {
+		graph.CheckError(err)
+	}
+	RespondWithCode(w, http.StatusNoContent)
+}
+
+// getAccessibleSnapshot returns the snapshot, a snapshot of a namespace inaccessible to the user is reported as
+// not found, to not disclose its existence.
+func getAccessibleSnapshot(store snapshot.Store, business *business.Layer, id string) *snapshot.Snapshot {
+	s, err := store.Get(id)
+	if err == snapshot.ErrNotFound {
+		graph.NotFound(fmt.Sprintf("Graph snapshot [%s] not found", id))
+	}
+	graph.CheckError(err)
+
+	if !isSnapshotAccessible(s.Info, getSnapshotAccessibleNamespaces(business)) {
+		graph.NotFound(fmt.Sprintf("Graph snapshot [%s] not found", id))
+	}
+	return s
+}
+
+func getSnapshotAccessibleNamespaces(business *business.Layer) map[string]bool {
+	namespaces, err := business.Namespace.GetNamespaces()
+	graph.CheckError(err)
+
+	accessibleNamespaces := make(map[string]bool, len(namespaces))
+	for _, namespace := range namespaces {
+		accessibleNamespaces[namespace.Name] = true
+	}
+	return accessibleNamespaces
+}
+
+func isSnapshotAccessible(info snapshot.Info, accessibleNamespaces map[string]bool) bool {
+	for _, namespace := range info.Namespaces {
+		if !accessibleNamespaces[namespace] {
+			return false
+		}
+	}
+	return true
+}
+
+// GraphSchema is a REST http.HandlerFunc returning the JSON Schema of the cytoscape graph, for its schema version
+func GraphSchema(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/schema+json")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write([]byte(cytoscape.Schema))
+}
+
 func handlePanic(w http.ResponseWriter) {
 	code := http.StatusInternalServerError
 	if r := recover(); r != nil {
@@ -94,6 +392,12 @@ func handlePanic(w http.ResponseWriter) {
 
 func respond(w http.ResponseWriter, code int, payload interface{}) {
 	if code == http.StatusOK {
+		if raw, ok := payload.(graph.RawConfig); ok {
+			w.Header().Set("Content-Type", raw.ContentType())
+			w.WriteHeader(code)
+			_, _ = w.Write(raw.Content())
+			return
+		}
 		RespondWithJSONIndent(w, code, payload)
 		return
 	}
@@ -0,0 +1,183 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	core_v1 "k8s.io/api/core/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/clientcmd/api"
+
+	"github.com/kiali/kiali/business"
+	"github.com/kiali/kiali/config"
+	"github.com/kiali/kiali/graph"
+	"github.com/kiali/kiali/graph/config/cytoscape"
+	"github.com/kiali/kiali/graph/snapshot"
+	"github.com/kiali/kiali/graph/telemetry"
+	"github.com/kiali/kiali/kubernetes"
+	"github.com/kiali/kiali/kubernetes/kubetest"
+	"github.com/kiali/kiali/prometheus/prometheustest"
+)
+
+// userClientFactory returns the client of each user, by token
+type userClientFactory map[string]kubernetes.ClientInterface
+
+func (f userClientFactory) GetClient(authInfo *api.AuthInfo) (kubernetes.ClientInterface, error) {
+	return f[authInfo.Token], nil
+}
+
+func mockUserNamespaces(namespaces ...string) *kubetest.K8SClientMock {
+	k8s := new(kubetest.K8SClientMock)
+	nss := []core_v1.Namespace{}
+	for _, ns := range namespaces {
+		nss = append(nss, core_v1.Namespace{ObjectMeta: meta_v1.ObjectMeta{Name: ns}})
+	}
+	k8s.On("IsOpenShift").Return(false)
+	k8s.On("GetNamespaces", "").Return(nss, nil)
+	return k8s
+}
+
+// setupSnapshotReplay saves a snapshot of bookinfo calling a service of the payments namespace, generated by a user
+// with access to both namespaces. Users alice (bookinfo, payments), bob (bookinfo) and carol (payments) replay it.
+func setupSnapshotReplay(t *testing.T) (*httptest.Server, string) {
+	dir, err := ioutil.TempDir("", "graph-snapshots")
+	assert.NoError(t, err)
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	conf := config.NewConfig()
+	conf.KubernetesConfig.CacheEnabled = false
+	conf.GraphSnapshots.Path = dir
+	config.Set(conf)
+
+	business.SetWithBackends(userClientFactory{
+		"alice": mockUserNamespaces("bookinfo", "payments"),
+		"bob":   mockUserNamespaces("bookinfo"),
+		"carol": mockUserNamespaces("payments"),
+	}, new(prometheustest.PromClientMock))
+
+	trafficMap := graph.NewTrafficMap()
+	productpage := graph.NewNode(graph.Unknown, "bookinfo", "productpage", "bookinfo", "productpage-v1", "productpage", "v1", graph.GraphTypeVersionedApp)
+	billing := graph.NewNode(graph.Unknown, "payments", "billing", "payments", "billing-v1", "billing", "v1", graph.GraphTypeVersionedApp)
+	trafficMap[productpage.ID] = &productpage
+	trafficMap[billing.ID] = &billing
+	e := productpage.AddEdge(&billing)
+	graph.AddToMetadata("http", 10.0, "200", "-", "billing.payments.svc.cluster.local", productpage.Metadata, billing.Metadata, e.Metadata)
+
+	o := graph.Options{ConfigVendor: graph.VendorCytoscape, TelemetryVendor: graph.VendorIstio}
+	o.ConfigOptions.Duration = 10 * time.Minute
+	o.ConfigOptions.GraphType = graph.GraphTypeVersionedApp
+	o.TelemetryOptions.Duration = 10 * time.Minute
+	o.TelemetryOptions.GraphType = graph.GraphTypeVersionedApp
+	o.TelemetryOptions.Namespaces = graph.NamespaceInfoMap{"bookinfo": {Name: "bookinfo", Duration: 10 * time.Minute}}
+	o.TelemetryOptions.AccessibleNamespaces = map[string]time.Time{"bookinfo": {}, "payments": {}}
+	telemetry.MarkOutsideOrInaccessible(trafficMap, o.TelemetryOptions)
+
+	s, err := snapshot.NewSnapshot("incident", trafficMap, o)
+	assert.NoError(t, err)
+	store, err := snapshot.NewStore(conf.GraphSnapshots)
+	assert.NoError(t, err)
+	assert.NoError(t, store.Save(s))
+
+	mr := mux.NewRouter()
+	mr.HandleFunc("/api/namespaces/graph/snapshots/{snapshot}", http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			context := context.WithValue(r.Context(), "authInfo", &api.AuthInfo{Token: r.Header.Get("X-User")})
+			GraphSnapshot(w, r.WithContext(context))
+		})).Methods("GET")
+	mr.HandleFunc("/api/namespaces/graph/snapshots/{snapshot}", http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			context := context.WithValue(r.Context(), "authInfo", &api.AuthInfo{Token: r.Header.Get("X-User")})
+			GraphSnapshotDelete(w, r.WithContext(context))
+		})).Methods("DELETE")
+
+	return httptest.NewServer(mr), s.ID
+}
+
+func replaySnapshot(t *testing.T, url, user string) (int, *cytoscape.Config) {
+	req, err := http.NewRequest("GET", url, nil)
+	assert.NoError(t, err)
+	req.Header.Set("X-User", user)
+
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return resp.StatusCode, nil
+	}
+	config := &cytoscape.Config{}
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(config))
+	return resp.StatusCode, config
+}
+
+func deleteSnapshot(t *testing.T, url, user string) int {
+	req, err := http.NewRequest("DELETE", url, nil)
+	assert.NoError(t, err)
+	req.Header.Set("X-User", user)
+
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+	return resp.StatusCode
+}
+
+func findNodeData(config *cytoscape.Config, namespace string) *cytoscape.NodeData {
+	for _, n := range config.Elements.Nodes {
+		if n.Data.Namespace == namespace {
+			return n.Data
+		}
+	}
+	return nil
+}
+
+func TestSnapshotReplayAccess(t *testing.T) {
+	assert := assert.New(t)
+
+	ts, id := setupSnapshotReplay(t)
+	defer ts.Close()
+	url := ts.URL + "/api/namespaces/graph/snapshots/" + id
+
+	code, config := replaySnapshot(t, url, "alice")
+	if assert.Equal(http.StatusOK, code) {
+		billing := findNodeData(config, "payments")
+		assert.True(billing.IsOutside)
+		assert.False(billing.IsInaccessible)
+	}
+
+	// bob can replay the snapshot of bookinfo, but the payments node is inaccessible to bob
+	code, config = replaySnapshot(t, url, "bob")
+	if assert.Equal(http.StatusOK, code) {
+		billing := findNodeData(config, "payments")
+		assert.True(billing.IsOutside)
+		assert.True(billing.IsInaccessible)
+		assert.False(findNodeData(config, "bookinfo").IsInaccessible)
+	}
+
+	// carol cannot access the requested namespace, the snapshot is not found
+	code, _ = replaySnapshot(t, url, "carol")
+	assert.Equal(http.StatusNotFound, code)
+}
+
+func TestSnapshotDeleteShared(t *testing.T) {
+	assert := assert.New(t)
+
+	ts, id := setupSnapshotReplay(t)
+	defer ts.Close()
+	url := ts.URL + "/api/namespaces/graph/snapshots/" + id
+
+	// carol cannot access the requested namespace, the snapshot is not found
+	assert.Equal(http.StatusNotFound, deleteSnapshot(t, url, "carol"))
+
+	// the snapshot is shared, bob did not create it but can access its namespace
+	assert.Equal(http.StatusNoContent, deleteSnapshot(t, url, "bob"))
+	code, _ := replaySnapshot(t, url, "alice")
+	assert.Equal(http.StatusNotFound, code)
+}
@@ -0,0 +1,48 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// deadlineResponseWriter is a response writer supporting a write deadline
+type deadlineResponseWriter struct {
+	http.ResponseWriter
+	deadline time.Time
+}
+
+func (w *deadlineResponseWriter) SetWriteDeadline(deadline time.Time) error {
+	w.deadline = deadline
+	return nil
+}
+
+// wrappingResponseWriter is a response writer wrapping another one, as the router middlewares do
+type wrappingResponseWriter struct {
+	http.ResponseWriter
+}
+
+func (w *wrappingResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func TestGetWriteDeadliner(t *testing.T) {
+	assert := assert.New(t)
+
+	_, ok := getWriteDeadliner(httptest.NewRecorder())
+	assert.False(ok)
+
+	_, ok = getWriteDeadliner(&wrappingResponseWriter{ResponseWriter: httptest.NewRecorder()})
+	assert.False(ok)
+
+	w := &deadlineResponseWriter{ResponseWriter: httptest.NewRecorder()}
+	deadliner, ok := getWriteDeadliner(&wrappingResponseWriter{ResponseWriter: &wrappingResponseWriter{ResponseWriter: w}})
+	assert.True(ok)
+
+	deadline := time.Now().Add(streamWriteTimeout)
+	assert.NoError(deadliner.SetWriteDeadline(deadline))
+	assert.Equal(deadline, w.deadline)
+}
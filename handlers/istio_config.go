@@ -10,6 +10,7 @@ import (
 
 	"github.com/kiali/kiali/business"
 	"github.com/kiali/kiali/config"
+	"github.com/kiali/kiali/kubernetes"
 	"github.com/kiali/kiali/log"
 	"github.com/kiali/kiali/models"
 )
@@ -269,3 +270,48 @@ func IstioConfigPermissions(w http.ResponseWriter, r *http.Request) {
 	}
 	RespondWithJSON(w, http.StatusOK, istioConfigPermissions)
 }
+
+const (
+	// dryRunMaxBodySize is the maximum size of the manifests validated by a dry run
+	dryRunMaxBodySize = 1 << 20
+	// bodyTooLargeError is the error message of http.MaxBytesReader, read before Go 1.19 typed it as http.MaxBytesError
+	bodyTooLargeError = "http: request body too large"
+)
+
+// IstioValidationsDryRun validates the Istio objects of the request body, YAML or JSON manifests, as if they were
+// applied, and returns the checks they introduce and resolve. The namespace query param sets the namespace of the
+// objects without one.
+func IstioValidationsDryRun(w http.ResponseWriter, r *http.Request) {
+	business, err := getBusiness(r)
+	if err != nil {
+		RespondWithError(w, http.StatusInternalServerError, "Services initialization error: "+err.Error())
+		return
+	}
+
+	objects, err := kubernetes.ParseIstioObjects(http.MaxBytesReader(w, r.Body, dryRunMaxBodySize))
+	if err != nil {
+		if strings.Contains(err.Error(), bodyTooLargeError) {
+			RespondWithError(w, http.StatusRequestEntityTooLarge, "Dry run request too large: "+err.Error())
+			return
+		}
+		RespondWithError(w, http.StatusBadRequest, "Dry run request with bad objects: "+err.Error())
+		return
+	}
+	if namespace := r.URL.Query().Get("namespace"); namespace != "" {
+		for _, obj := range objects {
+			if obj.GetObjectMeta().Namespace == "" {
+				meta := obj.GetObjectMeta()
+				meta.Namespace = namespace
+				obj.SetObjectMeta(meta)
+			}
+		}
+	}
+
+	diff, err := business.Validations.GetDryRunValidations(objects)
+	if err != nil {
+		handleErrorResponse(w, err)
+		return
+	}
+
+	RespondWithJSON(w, http.StatusOK, diff)
+}
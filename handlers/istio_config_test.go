@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/client-go/tools/clientcmd/api"
+
+	"github.com/kiali/kiali/business"
+	"github.com/kiali/kiali/config"
+	"github.com/kiali/kiali/kubernetes/kubetest"
+	"github.com/kiali/kiali/prometheus/prometheustest"
+)
+
+func setupIstioValidationsDryRun() *httptest.Server {
+	conf := config.NewConfig()
+	conf.KubernetesConfig.CacheEnabled = false
+	config.Set(conf)
+
+	k8s := new(kubetest.K8SClientMock)
+	k8s.On("IsOpenShift").Return(false)
+	business.SetWithBackends(kubetest.NewK8SClientFactoryMock(k8s), new(prometheustest.PromClientMock))
+
+	mr := mux.NewRouter()
+	mr.HandleFunc("/api/istio/validations/dryrun", http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			context := context.WithValue(r.Context(), "authInfo", &api.AuthInfo{Token: "test"})
+			IstioValidationsDryRun(w, r.WithContext(context))
+		}))
+
+	return httptest.NewServer(mr)
+}
+
+func TestIstioValidationsDryRunBodyLimit(t *testing.T) {
+	assert := assert.New(t)
+
+	ts := setupIstioValidationsDryRun()
+	defer ts.Close()
+	url := ts.URL + "/api/istio/validations/dryrun"
+
+	// a manifest over the limit is rejected before being parsed
+	manifest := "apiVersion: networking.istio.io/v1alpha3\nkind: VirtualService\nmetadata:\n  name: reviews\n  annotations:\n    note: " +
+		strings.Repeat("x", dryRunMaxBodySize) + "\n"
+	resp, err := http.Post(url, "application/yaml", strings.NewReader(manifest))
+	if assert.NoError(err) {
+		resp.Body.Close()
+		assert.Equal(http.StatusRequestEntityTooLarge, resp.StatusCode)
+	}
+
+	resp, err = http.Post(url, "application/yaml", strings.NewReader("kind: ["))
+	if assert.NoError(err) {
+		resp.Body.Close()
+		assert.Equal(http.StatusBadRequest, resp.StatusCode)
+	}
+}
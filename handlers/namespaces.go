@@ -54,6 +54,26 @@ func NamespaceValidationSummary(w http.ResponseWriter, r *http.Request) {
 	RespondWithJSON(w, http.StatusOK, validationSummary)
 }
 
+// MeshValidations is the API returning the validations of all the accessible namespaces, validated together to
+// report the conflicts between namespaces
+func MeshValidations(w http.ResponseWriter, r *http.Request) {
+	business, err := getBusiness(r)
+	if err != nil {
+		log.Error(err)
+		RespondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	validations, err := business.Validations.GetMeshValidations()
+	if err != nil {
+		log.Error(err)
+		RespondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	RespondWithJSON(w, http.StatusOK, validations)
+}
+
 // NamespaceUpdate is the API to perform a patch on a Namespace configuration
 func NamespaceUpdate(w http.ResponseWriter, r *http.Request) {
 	params := mux.Vars(r)
@@ -30,6 +30,7 @@ import (
 	"github.com/kiali/kiali/server"
 	"github.com/kiali/kiali/status"
 	"github.com/kiali/kiali/util"
+	"github.com/kiali/kiali/validate"
 )
 
 // Identifies the build. These are set via ldflags during the build (see Makefile).
@@ -54,6 +55,11 @@ func main() {
 	log.InitializeLogger()
 	util.Clock = util.RealClock{}
 
+	// the validate command validates local manifests instead of running the server
+	if len(os.Args) > 1 && os.Args[1] == "validate" {
+		os.Exit(validate.Run(os.Args[2:], os.Stdout))
+	}
+
 	// process command line
 	flag.Parse()
 	validateFlags()
@@ -0,0 +1,44 @@
+package kubernetes
+
+import (
+	"fmt"
+	"io"
+
+	"k8s.io/apimachinery/pkg/util/yaml"
+)
+
+// ParseIstioObjects parses Istio objects from YAML documents or JSON objects, as written in manifests.
+// Empty documents are skipped and objects of a kind that is not an Istio type return an error.
+func ParseIstioObjects(r io.Reader) ([]IstioObject, error) {
+	decoder := yaml.NewYAMLOrJSONDecoder(r, 4096)
+	objects := make([]IstioObject, 0)
+
+	for {
+		obj := &GenericIstioObject{}
+		if err := decoder.Decode(obj); err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, err
+		}
+		if obj.Kind == "" && obj.Name == "" {
+			continue
+		}
+		if _, found := GetResourceType(obj.Kind); !found {
+			return nil, fmt.Errorf("object [kind: %s] [name: %s] is not an Istio object", obj.Kind, obj.Name)
+		}
+		objects = append(objects, obj)
+	}
+
+	return objects, nil
+}
+
+// GetResourceType returns the resource type of an Istio kind, e.g. virtualservices for VirtualService
+func GetResourceType(kind string) (string, bool) {
+	for resourceType, k := range PluralType {
+		if k == kind {
+			return resourceType, true
+		}
+	}
+	return "", false
+}
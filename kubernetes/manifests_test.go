@@ -0,0 +1,56 @@
+package kubernetes
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseIstioObjects(t *testing.T) {
+	assert := assert.New(t)
+
+	manifests := `
+apiVersion: networking.istio.io/v1alpha3
+kind: VirtualService
+metadata:
+  name: reviews
+  namespace: bookinfo
+spec:
+  hosts:
+  - reviews
+---
+---
+{"apiVersion": "networking.istio.io/v1alpha3", "kind": "DestinationRule", "metadata": {"name": "reviews"}, "spec": {"host": "reviews"}}
+`
+	objects, err := ParseIstioObjects(strings.NewReader(manifests))
+	assert.NoError(err)
+	assert.Len(objects, 2)
+	assert.Equal("VirtualService", objects[0].GetTypeMeta().Kind)
+	assert.Equal("bookinfo", objects[0].GetObjectMeta().Namespace)
+	assert.Equal([]interface{}{"reviews"}, objects[0].GetSpec()["hosts"])
+	assert.Equal("DestinationRule", objects[1].GetTypeMeta().Kind)
+	assert.Equal("", objects[1].GetObjectMeta().Namespace)
+	assert.Equal("reviews", objects[1].GetSpec()["host"])
+}
+
+func TestParseIstioObjectsErrors(t *testing.T) {
+	assert := assert.New(t)
+
+	_, err := ParseIstioObjects(strings.NewReader("apiVersion: v1\nkind: Service\nmetadata:\n  name: reviews\n"))
+	assert.Error(err)
+
+	_, err = ParseIstioObjects(strings.NewReader("kind: [VirtualService"))
+	assert.Error(err)
+}
+
+func TestGetResourceType(t *testing.T) {
+	assert := assert.New(t)
+
+	resourceType, found := GetResourceType("VirtualService")
+	assert.True(found)
+	assert.Equal(VirtualServices, resourceType)
+
+	_, found = GetResourceType("Service")
+	assert.False(found)
+}
@@ -0,0 +1,269 @@
+// Package memory provides a kubernetes.ClientInterface serving the objects of Kubernetes and Istio manifests from
+// memory, to run the Kiali business logic (e.g. the Istio validations) without a cluster.
+package memory
+
+import (
+	"fmt"
+	"sort"
+
+	apps_v1 "k8s.io/api/apps/v1"
+	batch_v1 "k8s.io/api/batch/v1"
+	batch_v1beta1 "k8s.io/api/batch/v1beta1"
+	core_v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/kiali/kiali/kubernetes"
+)
+
+// Client is a read only kubernetes.ClientInterface on a set of objects held in memory.
+// Only the methods needed by the Istio validations are implemented, calling the other ones panics.
+type Client struct {
+	kubernetes.ClientInterface
+	namespaces map[string]*core_v1.Namespace
+	objects    []runtime.Object
+}
+
+// NewClient returns an empty Client, see AddObject and LoadManifests to populate it
+func NewClient() *Client {
+	return &Client{namespaces: map[string]*core_v1.Namespace{}}
+}
+
+// AddObject adds a Namespace, a namespaced Kubernetes object of a kind used by the validations (e.g. Service,
+// Deployment, Pod, ConfigMap) or an Istio object. The namespaces of the objects are implicitly added.
+func (c *Client) AddObject(obj runtime.Object) error {
+	if !isSupported(obj) {
+		return fmt.Errorf("objects of type %T are not supported", obj)
+	}
+	if ns, ok := obj.(*core_v1.Namespace); ok {
+		c.namespaces[ns.Name] = ns
+		return nil
+	}
+
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		return err
+	}
+	if accessor.GetNamespace() == "" {
+		return fmt.Errorf("object [type: %T] [name: %s] has no namespace", obj, accessor.GetName())
+	}
+	if _, found := c.namespaces[accessor.GetNamespace()]; !found {
+		c.namespaces[accessor.GetNamespace()] = &core_v1.Namespace{ObjectMeta: meta_v1.ObjectMeta{Name: accessor.GetNamespace()}}
+	}
+	c.objects = append(c.objects, obj)
+	return nil
+}
+
+func isSupported(obj runtime.Object) bool {
+	switch o := obj.(type) {
+	case *core_v1.Namespace, *core_v1.ConfigMap, *core_v1.Pod, *core_v1.ReplicationController, *core_v1.Service,
+		*apps_v1.DaemonSet, *apps_v1.Deployment, *apps_v1.ReplicaSet, *apps_v1.StatefulSet,
+		*batch_v1.Job, *batch_v1beta1.CronJob:
+		return true
+	case kubernetes.IstioObject:
+		_, found := kubernetes.GetResourceType(o.GetTypeMeta().Kind)
+		return found
+	}
+	return false
+}
+
+// list returns the objects of a namespace, or of all the namespaces when it is empty
+func (c *Client) list(namespace string) []runtime.Object {
+	objects := []runtime.Object{}
+	for _, obj := range c.objects {
+		if accessor, err := meta.Accessor(obj); err == nil && (namespace == "" || accessor.GetNamespace() == namespace) {
+			objects = append(objects, obj)
+		}
+	}
+	return objects
+}
+
+func (c *Client) IsOpenShift() bool {
+	return false
+}
+
+func (c *Client) GetToken() string {
+	return ""
+}
+
+func (c *Client) GetNamespace(namespace string) (*core_v1.Namespace, error) {
+	if ns, found := c.namespaces[namespace]; found {
+		return ns, nil
+	}
+	return &core_v1.Namespace{}, kubernetes.NewNotFound(namespace, "", "namespaces")
+}
+
+// GetNamespaces returns the namespaces sorted by name
+func (c *Client) GetNamespaces(labelSelector string) ([]core_v1.Namespace, error) {
+	selector, err := labels.Parse(labelSelector)
+	if err != nil {
+		return nil, err
+	}
+	namespaces := []core_v1.Namespace{}
+	for _, ns := range c.namespaces {
+		if selector.Matches(labels.Set(ns.Labels)) {
+			namespaces = append(namespaces, *ns)
+		}
+	}
+	sort.Slice(namespaces, func(i, j int) bool {
+		return namespaces[i].Name < namespaces[j].Name
+	})
+	return namespaces, nil
+}
+
+func (c *Client) GetConfigMap(namespace, name string) (*core_v1.ConfigMap, error) {
+	for _, obj := range c.list(namespace) {
+		if cm, ok := obj.(*core_v1.ConfigMap); ok && cm.Name == name {
+			return cm, nil
+		}
+	}
+	return &core_v1.ConfigMap{}, kubernetes.NewNotFound(name, "", "configmaps")
+}
+
+func (c *Client) GetCronJobs(namespace string) ([]batch_v1beta1.CronJob, error) {
+	cronJobs := []batch_v1beta1.CronJob{}
+	for _, obj := range c.list(namespace) {
+		if cj, ok := obj.(*batch_v1beta1.CronJob); ok {
+			cronJobs = append(cronJobs, *cj)
+		}
+	}
+	return cronJobs, nil
+}
+
+func (c *Client) GetDaemonSets(namespace string) ([]apps_v1.DaemonSet, error) {
+	daemonSets := []apps_v1.DaemonSet{}
+	for _, obj := range c.list(namespace) {
+		if ds, ok := obj.(*apps_v1.DaemonSet); ok {
+			daemonSets = append(daemonSets, *ds)
+		}
+	}
+	return daemonSets, nil
+}
+
+func (c *Client) GetDeployments(namespace string) ([]apps_v1.Deployment, error) {
+	deployments := []apps_v1.Deployment{}
+	for _, obj := range c.list(namespace) {
+		if d, ok := obj.(*apps_v1.Deployment); ok {
+			deployments = append(deployments, *d)
+		}
+	}
+	return deployments, nil
+}
+
+func (c *Client) GetJobs(namespace string) ([]batch_v1.Job, error) {
+	jobs := []batch_v1.Job{}
+	for _, obj := range c.list(namespace) {
+		if j, ok := obj.(*batch_v1.Job); ok {
+			jobs = append(jobs, *j)
+		}
+	}
+	return jobs, nil
+}
+
+// GetPods returns the pods of a namespace matching the label selector, an empty selector selects all of them
+func (c *Client) GetPods(namespace, labelSelector string) ([]core_v1.Pod, error) {
+	selector, err := labels.Parse(labelSelector)
+	if err != nil {
+		return []core_v1.Pod{}, err
+	}
+	pods := []core_v1.Pod{}
+	for _, obj := range c.list(namespace) {
+		if p, ok := obj.(*core_v1.Pod); ok && selector.Matches(labels.Set(p.Labels)) {
+			pods = append(pods, *p)
+		}
+	}
+	return pods, nil
+}
+
+func (c *Client) GetReplicationControllers(namespace string) ([]core_v1.ReplicationController, error) {
+	controllers := []core_v1.ReplicationController{}
+	for _, obj := range c.list(namespace) {
+		if rc, ok := obj.(*core_v1.ReplicationController); ok {
+			controllers = append(controllers, *rc)
+		}
+	}
+	return controllers, nil
+}
+
+func (c *Client) GetReplicaSets(namespace string) ([]apps_v1.ReplicaSet, error) {
+	replicaSets := []apps_v1.ReplicaSet{}
+	for _, obj := range c.list(namespace) {
+		if rs, ok := obj.(*apps_v1.ReplicaSet); ok {
+			replicaSets = append(replicaSets, *rs)
+		}
+	}
+	return replicaSets, nil
+}
+
+// GetServices returns the services of a namespace, or only the ones whose selector matches the labels when set
+func (c *Client) GetServices(namespace string, selectorLabels map[string]string) ([]core_v1.Service, error) {
+	services := []core_v1.Service{}
+	for _, obj := range c.list(namespace) {
+		s, ok := obj.(*core_v1.Service)
+		if !ok {
+			continue
+		}
+		if selectorLabels != nil {
+			svcSelector := labels.Set(s.Spec.Selector).AsSelector()
+			if svcSelector.Empty() || !svcSelector.Matches(labels.Set(selectorLabels)) {
+				continue
+			}
+		}
+		services = append(services, *s)
+	}
+	return services, nil
+}
+
+func (c *Client) GetStatefulSets(namespace string) ([]apps_v1.StatefulSet, error) {
+	statefulSets := []apps_v1.StatefulSet{}
+	for _, obj := range c.list(namespace) {
+		if ss, ok := obj.(*apps_v1.StatefulSet); ok {
+			statefulSets = append(statefulSets, *ss)
+		}
+	}
+	return statefulSets, nil
+}
+
+func (c *Client) GetIstioObject(namespace, resourceType, name string) (kubernetes.IstioObject, error) {
+	istioObjects, err := c.GetIstioObjects(namespace, resourceType, "")
+	if err != nil {
+		return nil, err
+	}
+	for _, obj := range istioObjects {
+		if obj.GetObjectMeta().Name == name {
+			return obj, nil
+		}
+	}
+	return nil, kubernetes.NewNotFound(name, kubernetes.ResourceTypesToAPI[resourceType], resourceType)
+}
+
+// GetIstioObjects returns the Istio objects of a type in a namespace, or in all the namespaces when it is empty
+func (c *Client) GetIstioObjects(namespace, resourceType, labelSelector string) ([]kubernetes.IstioObject, error) {
+	kind, found := kubernetes.PluralType[resourceType]
+	if !found {
+		return []kubernetes.IstioObject{}, fmt.Errorf("%s not found in PluralType", resourceType)
+	}
+	selector, err := labels.Parse(labelSelector)
+	if err != nil {
+		return []kubernetes.IstioObject{}, err
+	}
+	istioObjects := []kubernetes.IstioObject{}
+	for _, obj := range c.list(namespace) {
+		if istioObject, ok := obj.(kubernetes.IstioObject); ok && istioObject.GetTypeMeta().Kind == kind && selector.Matches(labels.Set(istioObject.GetObjectMeta().Labels)) {
+			istioObjects = append(istioObjects, istioObject.DeepCopyIstioObject())
+		}
+	}
+	return istioObjects, nil
+}
+
+// GetProxyStatus returns no status, there is no control plane to sync the proxies with
+func (c *Client) GetProxyStatus() ([]*kubernetes.ProxyStatus, error) {
+	return []*kubernetes.ProxyStatus{}, nil
+}
+
+// GetRegistryStatus returns no status, there is no control plane with a service registry
+func (c *Client) GetRegistryStatus() ([]*kubernetes.RegistryStatus, error) {
+	return []*kubernetes.RegistryStatus{}, nil
+}
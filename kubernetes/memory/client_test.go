@@ -0,0 +1,159 @@
+package memory
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	core_v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/kiali/kiali/kubernetes"
+)
+
+const manifests = `
+apiVersion: v1
+kind: Namespace
+metadata:
+  name: bookinfo
+  labels:
+    istio-injection: enabled
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: reviews
+  namespace: bookinfo
+spec:
+  selector:
+    app: reviews
+---
+apiVersion: v1
+kind: List
+items:
+- apiVersion: v1
+  kind: Pod
+  metadata:
+    name: reviews-v1
+    namespace: bookinfo
+    labels:
+      app: reviews
+      version: v1
+- apiVersion: v1
+  kind: Pod
+  metadata:
+    name: reviews-v2
+    namespace: bookinfo
+    labels:
+      app: reviews
+      version: v2
+---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRole
+metadata:
+  name: reader
+---
+apiVersion: example.com/v1
+kind: Unknown
+metadata:
+  name: unknown
+---
+{"apiVersion": "networking.istio.io/v1alpha3", "kind": "DestinationRule", "metadata": {"name": "reviews", "labels": {"app": "reviews"}}, "spec": {"host": "reviews"}}
+---
+`
+
+func TestLoadManifests(t *testing.T) {
+	assert := assert.New(t)
+
+	client := NewClient()
+	assert.NoError(client.LoadManifests(strings.NewReader(manifests), "default"))
+
+	namespaces, err := client.GetNamespaces("")
+	assert.NoError(err)
+	assert.Len(namespaces, 2)
+	assert.Equal("bookinfo", namespaces[0].Name)
+	assert.Equal("default", namespaces[1].Name)
+
+	namespaces, err = client.GetNamespaces("istio-injection=enabled")
+	assert.NoError(err)
+	assert.Len(namespaces, 1)
+	assert.Equal("bookinfo", namespaces[0].Name)
+
+	services, err := client.GetServices("bookinfo", nil)
+	assert.NoError(err)
+	assert.Len(services, 1)
+
+	services, err = client.GetServices("bookinfo", map[string]string{"app": "reviews", "version": "v1"})
+	assert.NoError(err)
+	assert.Len(services, 1)
+
+	services, err = client.GetServices("bookinfo", map[string]string{"app": "ratings"})
+	assert.NoError(err)
+	assert.Empty(services)
+
+	pods, err := client.GetPods("bookinfo", "")
+	assert.NoError(err)
+	assert.Len(pods, 2)
+
+	pods, err = client.GetPods("bookinfo", "version=v2")
+	assert.NoError(err)
+	assert.Len(pods, 1)
+	assert.Equal("reviews-v2", pods[0].Name)
+
+	drs, err := client.GetIstioObjects("default", kubernetes.DestinationRules, "app=reviews")
+	assert.NoError(err)
+	assert.Len(drs, 1)
+	assert.Equal("reviews", drs[0].GetSpec()["host"])
+
+	drs, err = client.GetIstioObjects("", kubernetes.DestinationRules, "")
+	assert.NoError(err)
+	assert.Len(drs, 1)
+
+	vss, err := client.GetIstioObjects("default", kubernetes.VirtualServices, "")
+	assert.NoError(err)
+	assert.Empty(vss)
+
+	dr, err := client.GetIstioObject("default", kubernetes.DestinationRules, "reviews")
+	assert.NoError(err)
+	assert.Equal("reviews", dr.GetObjectMeta().Name)
+}
+
+func TestLoadManifestsErrors(t *testing.T) {
+	assert := assert.New(t)
+
+	client := NewClient()
+	assert.Error(client.LoadManifests(strings.NewReader("metadata:\n  name: reviews\n"), "default"))
+	assert.Error(client.LoadManifests(strings.NewReader("apiVersion: v1\nkind: Service\nspec: [\n"), "default"))
+}
+
+func TestNotFound(t *testing.T) {
+	assert := assert.New(t)
+
+	client := NewClient()
+	_, err := client.GetNamespace("bookinfo")
+	assert.True(errors.IsNotFound(err))
+
+	_, err = client.GetConfigMap("istio-system", "istio")
+	assert.True(errors.IsNotFound(err))
+
+	_, err = client.GetIstioObject("bookinfo", kubernetes.DestinationRules, "reviews")
+	assert.True(errors.IsNotFound(err))
+}
+
+func TestAddObject(t *testing.T) {
+	assert := assert.New(t)
+
+	client := NewClient()
+	assert.NoError(client.AddObject(&core_v1.ConfigMap{ObjectMeta: meta_v1.ObjectMeta{Name: "istio", Namespace: "istio-system"}}))
+	assert.Error(client.AddObject(&core_v1.ConfigMap{ObjectMeta: meta_v1.ObjectMeta{Name: "istio"}}))
+	assert.Error(client.AddObject(&core_v1.Secret{ObjectMeta: meta_v1.ObjectMeta{Name: "istio", Namespace: "istio-system"}}))
+
+	cm, err := client.GetConfigMap("istio-system", "istio")
+	assert.NoError(err)
+	assert.Equal("istio", cm.Name)
+
+	ns, err := client.GetNamespace("istio-system")
+	assert.NoError(err)
+	assert.Equal("istio-system", ns.Name)
+}
@@ -0,0 +1,125 @@
+package memory
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/yaml"
+	"k8s.io/client-go/kubernetes/scheme"
+
+	"github.com/kiali/kiali/kubernetes"
+	"github.com/kiali/kiali/log"
+)
+
+var manifestExtensions = map[string]bool{
+	".json": true,
+	".yaml": true,
+	".yml":  true,
+}
+
+// LoadDir loads the manifests of the YAML and JSON files of a directory and its subdirectories.
+// See LoadManifests for the objects loaded and the namespace used.
+func (c *Client) LoadDir(dir, defaultNamespace string) error {
+	return filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() || !manifestExtensions[strings.ToLower(filepath.Ext(path))] {
+			return nil
+		}
+
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		if err := c.LoadManifests(f, defaultNamespace); err != nil {
+			return fmt.Errorf("%s: %v", path, err)
+		}
+		return nil
+	})
+}
+
+// LoadManifests loads the objects of YAML documents or JSON objects, as written in manifests. Lists are expanded,
+// objects without namespace are placed in the default namespace and the kinds not used by the validations are skipped.
+func (c *Client) LoadManifests(r io.Reader, defaultNamespace string) error {
+	decoder := yaml.NewYAMLOrJSONDecoder(r, 4096)
+
+	for {
+		raw := json.RawMessage{}
+		if err := decoder.Decode(&raw); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		if err := c.loadManifest(raw, defaultNamespace); err != nil {
+			return err
+		}
+	}
+}
+
+func (c *Client) loadManifest(raw json.RawMessage, defaultNamespace string) error {
+	// Empty documents, e.g. a trailing "---", are decoded as null
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil
+	}
+
+	typeMeta := meta_v1.TypeMeta{}
+	if err := json.Unmarshal(raw, &typeMeta); err != nil {
+		return err
+	}
+	if typeMeta.Kind == "" {
+		return fmt.Errorf("object has no kind")
+	}
+
+	if strings.HasSuffix(typeMeta.Kind, "List") {
+		list := struct {
+			Items []json.RawMessage `json:"items"`
+		}{}
+		if err := json.Unmarshal(raw, &list); err != nil {
+			return err
+		}
+		for _, item := range list.Items {
+			if err := c.loadManifest(item, defaultNamespace); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	var obj runtime.Object
+	if _, found := kubernetes.GetResourceType(typeMeta.Kind); found {
+		istioObject := &kubernetes.GenericIstioObject{}
+		if err := json.Unmarshal(raw, istioObject); err != nil {
+			return err
+		}
+		obj = istioObject
+	} else {
+		var err error
+		if obj, _, err = scheme.Codecs.UniversalDeserializer().Decode(raw, nil, nil); err != nil {
+			if runtime.IsNotRegisteredError(err) {
+				log.Debugf("Skipping object of unknown kind [%s]", typeMeta.Kind)
+				return nil
+			}
+			return err
+		}
+	}
+
+	if !isSupported(obj) {
+		log.Debugf("Skipping object of kind [%s], it is not used by the validations", typeMeta.Kind)
+		return nil
+	}
+	if accessor, err := meta.Accessor(obj); err == nil && accessor.GetNamespace() == "" && typeMeta.Kind != "Namespace" {
+		accessor.SetNamespace(defaultNamespace)
+	}
+	return c.AddObject(obj)
+}
@@ -233,9 +233,18 @@ type IstioObjectList interface {
 	GetItems() []IstioObject
 }
 
+// The modes of the outbound traffic policy, for traffic to hosts not in the service registry
+const (
+	OutboundTrafficPolicyAllowAny     = "ALLOW_ANY"
+	OutboundTrafficPolicyRegistryOnly = "REGISTRY_ONLY"
+)
+
 type IstioMeshConfig struct {
 	DisableMixerHttpReports bool  `yaml:"disableMixerHttpReports,omitempty"`
 	EnableAutoMtls          *bool `yaml:"enableAutoMtls,omitempty"`
+	OutboundTrafficPolicy   struct {
+		Mode string `yaml:"mode,omitempty"`
+	} `yaml:"outboundTrafficPolicy,omitempty"`
 }
 
 // IstioDetails is a wrapper to group all Istio objects related to a Service.
@@ -247,6 +256,9 @@ type IstioDetails struct {
 	Gateways               []IstioObject `json:"gateways"`
 	Sidecars               []IstioObject `json:"sidecars"`
 	RequestAuthentications []IstioObject `json:"requestauthentications"`
+	EnvoyFilters           []IstioObject `json:"envoyfilters"`
+	WorkloadEntries        []IstioObject `json:"workloadentries"`
+	WorkloadGroups         []IstioObject `json:"workloadgroups"`
 }
 
 // MTLSDetails is a wrapper to group all Istio objects related to non-local mTLS configurations
@@ -468,3 +480,11 @@ func (imc IstioMeshConfig) GetEnableAutoMtls() bool {
 	}
 	return *imc.EnableAutoMtls
 }
+
+// GetOutboundTrafficPolicyMode returns the mode of the mesh outbound traffic policy, ALLOW_ANY by default
+func (imc IstioMeshConfig) GetOutboundTrafficPolicyMode() string {
+	if imc.OutboundTrafficPolicy.Mode == "" {
+		return OutboundTrafficPolicyAllowAny
+	}
+	return imc.OutboundTrafficPolicy.Mode
+}
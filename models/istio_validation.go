@@ -7,6 +7,14 @@ import (
 // NamespaceValidations represents a set of IstioValidations grouped by namespace
 type NamespaceValidations map[string]IstioValidations
 
+// IstioValidationsDiff represents the changes of the validations introduced by a change of the Istio objects
+type IstioValidationsDiff struct {
+	// Validations with the checks introduced by the change, grouped by namespace
+	New NamespaceValidations `json:"new"`
+	// Validations with the checks resolved by the change, grouped by namespace
+	Resolved NamespaceValidations `json:"resolved"`
+}
+
 // IstioValidationKey is the key value composed of an Istio ObjectType and Name.
 type IstioValidationKey struct {
 	ObjectType string `json:"objectType"`
@@ -100,6 +108,9 @@ var ObjectTypeSingular = map[string]string{
 	"sidecars":               "sidecar",
 	"peerauthentications":    "peerauthentication",
 	"requestauthentications": "requestauthentication",
+	"envoyfilters":           "envoyfilter",
+	"workloadentries":        "workloadentry",
+	"workloadgroups":         "workloadgroup",
 }
 
 var checkDescriptors = map[string]IstioCheck{
@@ -155,6 +166,34 @@ var checkDescriptors = map[string]IstioCheck{
 		Message:  "KIA0209 This subset has not labels",
 		Severity: WarningSeverity,
 	},
+	"destinationrules.multimatch.exported": {
+		Message:  "KIA0210 More than one DestinationRule exported to all namespaces for the same host",
+		Severity: WarningSeverity,
+	},
+	"envoyfilter.patch.conflictingpriority": {
+		Message:  "KIA1201 More than one EnvoyFilter patches the same match with the same priority",
+		Severity: WarningSeverity,
+	},
+	"envoyfilter.patch.unknownapplyto": {
+		Message:  "KIA1202 Unknown applyTo value",
+		Severity: ErrorSeverity,
+	},
+	"envoyfilter.patch.unknowncontext": {
+		Message:  "KIA1203 Unknown patch context",
+		Severity: ErrorSeverity,
+	},
+	"envoyfilter.patch.matchmismatch": {
+		Message:  "KIA1204 This match can not select the configuration objects of the applyTo type",
+		Severity: ErrorSeverity,
+	},
+	"envoyfilter.proxyversion.invalid": {
+		Message:  "KIA1205 The proxy version is not a valid regular expression",
+		Severity: ErrorSeverity,
+	},
+	"envoyfilter.proxyversion.nomatch": {
+		Message:  "KIA1206 The proxy version does not match the running Istio version",
+		Severity: WarningSeverity,
+	},
 	"gateways.multimatch": {
 		Message:  "KIA0301 More than one Gateway for the same host port combination",
 		Severity: WarningSeverity,
@@ -259,6 +298,30 @@ var checkDescriptors = map[string]IstioCheck{
 		Message:  "KIA1107 Subset not found",
 		Severity: WarningSeverity,
 	},
+	"workloadentries.selector.noservice": {
+		Message:  "KIA1301 No Service or ServiceEntry selects the labels of this WorkloadEntry",
+		Severity: WarningSeverity,
+	},
+	"workloadentries.port.nomatch": {
+		Message:  "KIA1302 This port name does not match any port of the Services selecting this WorkloadEntry",
+		Severity: WarningSeverity,
+	},
+	"workloadentries.address.duplicate": {
+		Message:  "KIA1303 More than one WorkloadEntry with the same address",
+		Severity: WarningSeverity,
+	},
+	"workloadgroups.template.noserviceaccount": {
+		Message:  "KIA1401 The template has no service account",
+		Severity: WarningSeverity,
+	},
+	"workloadgroups.template.nonetwork": {
+		Message:  "KIA1402 The template has no network",
+		Severity: WarningSeverity,
+	},
+	"workloadgroups.port.nomatch": {
+		Message:  "KIA1403 This port name does not match any port of the Services selecting this WorkloadGroup",
+		Severity: WarningSeverity,
+	},
 	"validation.unable.cross-namespace": {
 		Message:  "KIA0001 Unable to verify the validity, cross-namespace validation is not supported for this field",
 		Severity: Unknown,
@@ -368,6 +431,58 @@ func (iv IstioValidations) MergeReferences(validations IstioValidations) IstioVa
 	return iv
 }
 
+// GroupByNamespace returns the validations grouped by the namespace of their objects
+func (iv IstioValidations) GroupByNamespace() NamespaceValidations {
+	nv := NamespaceValidations{}
+	for k, v := range iv {
+		if _, found := nv[k.Namespace]; !found {
+			nv[k.Namespace] = IstioValidations{}
+		}
+		nv[k.Namespace][k] = v
+	}
+	return nv
+}
+
+// DiffValidations returns the checks of after not found in before as new, and the checks of before not found in
+// after as resolved. Every validation of the diff keeps the validity of the object in its own set.
+func DiffValidations(before, after IstioValidations) IstioValidationsDiff {
+	return IstioValidationsDiff{
+		New:      after.missingChecks(before).GroupByNamespace(),
+		Resolved: before.missingChecks(after).GroupByNamespace(),
+	}
+}
+
+// missingChecks returns the validations with the checks not found in the other validations
+func (iv IstioValidations) missingChecks(other IstioValidations) IstioValidations {
+	result := IstioValidations{}
+	for k, v := range iv {
+		checks := make([]*IstioCheck, 0)
+	Missing:
+		for _, check := range v.Checks {
+			if o, found := other[k]; found {
+				for _, existing := range o.Checks {
+					if check.Path == existing.Path &&
+						check.Severity == existing.Severity &&
+						check.Message == existing.Message {
+						continue Missing
+					}
+				}
+			}
+			checks = append(checks, check)
+		}
+		if len(checks) > 0 {
+			result[k] = &IstioValidation{
+				Name:       v.Name,
+				ObjectType: v.ObjectType,
+				Valid:      v.Valid,
+				Checks:     checks,
+				References: v.References,
+			}
+		}
+	}
+	return result
+}
+
 func (iv IstioValidations) SummarizeValidation(ns string) IstioValidationSummary {
 	ivs := IstioValidationSummary{}
 	for k, v := range iv {
@@ -69,3 +69,51 @@ func TestSummarizeValidations(t *testing.T) {
 	assert.Equal(2, summary.Errors)
 	assert.Equal(2, summary.Errors)
 }
+
+func TestDiffValidations(t *testing.T) {
+	assert := assert.New(t)
+
+	fooKey := IstioValidationKey{ObjectType: "virtualservice", Name: "foo", Namespace: "bookinfo"}
+	barKey := IstioValidationKey{ObjectType: "destinationrule", Name: "bar", Namespace: "bookinfo2"}
+	before := IstioValidations{
+		fooKey: &IstioValidation{
+			Name:       "foo",
+			ObjectType: "virtualservice",
+			Valid:      false,
+			Checks: []*IstioCheck{
+				{Severity: ErrorSeverity, Message: "Message 1", Path: "spec/http[0]"},
+				{Severity: WarningSeverity, Message: "Message 2", Path: "spec/hosts"},
+			},
+		},
+		barKey: &IstioValidation{Name: "bar", ObjectType: "destinationrule", Valid: true, Checks: []*IstioCheck{}},
+	}
+	after := IstioValidations{
+		fooKey: &IstioValidation{
+			Name:       "foo",
+			ObjectType: "virtualservice",
+			Valid:      true,
+			Checks: []*IstioCheck{
+				{Severity: WarningSeverity, Message: "Message 2", Path: "spec/hosts"},
+			},
+		},
+		barKey: &IstioValidation{
+			Name:       "bar",
+			ObjectType: "destinationrule",
+			Valid:      true,
+			Checks: []*IstioCheck{
+				{Severity: WarningSeverity, Message: "Message 3", Path: "spec/host"},
+			},
+		},
+	}
+
+	diff := DiffValidations(before, after)
+
+	assert.Len(diff.New, 1)
+	assert.Len(diff.New["bookinfo2"], 1)
+	assert.Equal("Message 3", diff.New["bookinfo2"][barKey].Checks[0].Message)
+
+	assert.Len(diff.Resolved, 1)
+	assert.Len(diff.Resolved["bookinfo"][fooKey].Checks, 1)
+	assert.Equal("Message 1", diff.Resolved["bookinfo"][fooKey].Checks[0].Message)
+	assert.False(diff.Resolved["bookinfo"][fooKey].Valid)
+}
@@ -231,6 +231,17 @@ func (s Datapoint) MarshalJSON() ([]byte, error) {
 	}.MarshalJSON()
 }
 
+// UnmarshalJSON implements json.Unmarshaler.
+func (s *Datapoint) UnmarshalJSON(b []byte) error {
+	var sp pmod.SamplePair
+	if err := sp.UnmarshalJSON(b); err != nil {
+		return err
+	}
+	s.Timestamp = int64(sp.Timestamp)
+	s.Value = float64(sp.Value)
+	return nil
+}
+
 func convertSamplePair(from *pmod.SamplePair, scale float64) Datapoint {
 	return Datapoint{
 		Timestamp: int64(from.Timestamp),
@@ -91,7 +91,7 @@ func (nss Namespaces) Includes(namespace string) bool {
 }
 
 func (nss Namespaces) GetNames() []string {
-	names := make([]string, len(nss))
+	names := make([]string, 0, len(nss))
 	for _, ns := range nss {
 		names = append(names, ns.Name)
 	}
@@ -23,6 +23,8 @@ const (
 // MetricsType defines all of Kiali's own internal metrics.
 type MetricsType struct {
 	GraphNodes               *prometheus.GaugeVec
+	GraphCacheHits           *prometheus.CounterVec
+	GraphCacheMisses         *prometheus.CounterVec
 	GraphGenerationTime      *prometheus.HistogramVec
 	GraphAppenderTime        *prometheus.HistogramVec
 	GraphMarshalTime         *prometheus.HistogramVec
@@ -43,6 +45,20 @@ var Metrics = MetricsType{
 		},
 		[]string{labelGraphKind, labelGraphType, labelWithServiceNodes},
 	),
+	GraphCacheHits: prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "kiali_graph_cache_hits_total",
+			Help: "Counts the total number of TrafficMaps served from the graph cache, or shared with an identical in-flight request.",
+		},
+		[]string{labelGraphKind, labelGraphType, labelWithServiceNodes},
+	),
+	GraphCacheMisses: prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "kiali_graph_cache_misses_total",
+			Help: "Counts the total number of TrafficMaps generated because they were not in the graph cache.",
+		},
+		[]string{labelGraphKind, labelGraphType, labelWithServiceNodes},
+	),
 	GraphGenerationTime: prometheus.NewHistogramVec(
 		prometheus.HistogramOpts{
 			Name: "kiali_graph_generation_duration_seconds",
@@ -141,6 +157,8 @@ func (sof *SuccessOrFailureMetricType) ObserveNow(err *error) {
 func RegisterInternalMetrics() {
 	prometheus.MustRegister(
 		Metrics.GraphNodes,
+		Metrics.GraphCacheHits,
+		Metrics.GraphCacheMisses,
 		Metrics.GraphGenerationTime,
 		Metrics.GraphAppenderTime,
 		Metrics.GraphMarshalTime,
@@ -164,6 +182,24 @@ func SetGraphNodes(graphKind string, graphType string, withServiceNodes bool, no
 	}).Set(float64(nodeCount))
 }
 
+// GetGraphCacheHitsMetric returns the counter of TrafficMaps served from the graph cache
+func GetGraphCacheHitsMetric(graphKind string, graphType string, withServiceNodes bool) prometheus.Counter {
+	return Metrics.GraphCacheHits.With(prometheus.Labels{
+		labelGraphKind:        graphKind,
+		labelGraphType:        graphType,
+		labelWithServiceNodes: strconv.FormatBool(withServiceNodes),
+	})
+}
+
+// GetGraphCacheMissesMetric returns the counter of TrafficMaps generated on a graph cache miss
+func GetGraphCacheMissesMetric(graphKind string, graphType string, withServiceNodes bool) prometheus.Counter {
+	return Metrics.GraphCacheMisses.With(prometheus.Labels{
+		labelGraphKind:        graphKind,
+		labelGraphType:        graphType,
+		labelWithServiceNodes: strconv.FormatBool(withServiceNodes),
+	})
+}
+
 // GetGraphGenerationTimePrometheusTimer returns a timer that can be used to store
 // a value for the graph generation time metric. The timer is ticking immediately
 // when this function returns.
@@ -114,6 +114,22 @@ func (srw *statusResponseWriter) WriteHeader(code int) {
 	srw.StatusCode = code
 }
 
+// Unwrap returns the wrapped ResponseWriter, it lets handlers reach its methods (e.g. write deadlines)
+func (srw *statusResponseWriter) Unwrap() http.ResponseWriter {
+	return srw.ResponseWriter
+}
+
+// flushStatusResponseWriter is a statusResponseWriter implementing http.Flusher, it is only used when the wrapped
+// ResponseWriter can flush so that streaming handlers can tell whether flushing is supported
+type flushStatusResponseWriter struct {
+	*statusResponseWriter
+}
+
+// Flush implements http.Flusher, it is required by streaming handlers
+func (fsrw flushStatusResponseWriter) Flush() {
+	fsrw.ResponseWriter.(http.Flusher).Flush()
+}
+
 // updateMetric evaluates the StatusCode, if there is an error, increase the API failure counter, otherwise save the duration
 func updateMetric(route string, srw *statusResponseWriter, timer *prometheus.Timer) {
 	// Always measure the duration even if the API call ended in an error
@@ -131,9 +147,13 @@ func metricHandler(next http.Handler, route Route) http.Handler {
 			ResponseWriter: w,
 			StatusCode:     http.StatusOK,
 		}
+		var rw http.ResponseWriter = srw
+		if _, ok := w.(http.Flusher); ok {
+			rw = flushStatusResponseWriter{srw}
+		}
 		promtimer := internalmetrics.GetAPIProcessingTimePrometheusTimer(route.Name)
 		defer updateMetric(route.Name, srw, promtimer)
-		next.ServeHTTP(srw, r)
+		next.ServeHTTP(rw, r)
 	})
 }
 
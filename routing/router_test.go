@@ -170,3 +170,46 @@ func TestMetricHandlerAPIFailures(t *testing.T) {
 		}
 	}
 }
+
+// noFlushResponseWriter hides the http.Flusher implementation of the recorder
+type noFlushResponseWriter struct {
+	http.ResponseWriter
+}
+
+func TestMetricHandlerFlush(t *testing.T) {
+	assert := assert.New(t)
+
+	var flushSupported bool
+	handler := metricHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var flusher http.Flusher
+		flusher, flushSupported = w.(http.Flusher)
+		if flushSupported {
+			flusher.Flush()
+		}
+	}), Route{Name: "stream"})
+
+	req := httptest.NewRequest("GET", "/stream", nil)
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	assert.True(flushSupported)
+	assert.True(rr.Flushed)
+
+	handler.ServeHTTP(noFlushResponseWriter{httptest.NewRecorder()}, req)
+	assert.False(flushSupported)
+}
+
+func TestMetricHandlerUnwrap(t *testing.T) {
+	assert := assert.New(t)
+
+	rr := httptest.NewRecorder()
+	var unwrapped http.ResponseWriter
+	handler := metricHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// the handler must reach the recorder through the wrapper, to use the methods it does not expose
+		unwrapper, ok := w.(interface{ Unwrap() http.ResponseWriter })
+		assert.True(ok)
+		unwrapped = unwrapper.Unwrap()
+	}), Route{Name: "stream"})
+
+	handler.ServeHTTP(rr, httptest.NewRequest("GET", "/stream", nil))
+	assert.Equal(rr, unwrapped)
+}
@@ -221,6 +221,30 @@ func NewRoutes() (r *Routes) {
 			handlers.IstioConfigPermissions,
 			true,
 		},
+		// swagger:route POST /istio/validations/dryrun config istioValidationsDryRun
+		// ---
+		// Endpoint to validate Istio objects, sent as YAML or JSON manifests, as if they were applied, without writing them to the cluster
+		//
+		//     Consumes:
+		//     - application/json
+		//     - application/yaml
+		//
+		//     Produces:
+		//     - application/json
+		//
+		//     Schemes: http, https
+		//
+		// responses:
+		//      500: internalError
+		//      400: badRequestError
+		//      200: istioValidationsDryRunResponse
+		{
+			"IstioValidationsDryRun",
+			"POST",
+			"/api/istio/validations/dryrun",
+			handlers.IstioValidationsDryRun,
+			true,
+		},
 		// swagger:route GET /namespaces/{namespace}/istio config istioConfigList
 		// ---
 		// Endpoint to get the list of Istio Config of a namespace
@@ -1006,6 +1030,26 @@ func NewRoutes() (r *Routes) {
 			handlers.NamespaceValidationSummary,
 			true,
 		},
+		// swagger:route GET /mesh/validations validations meshValidations
+		// ---
+		// Get the validations of all the accessible namespaces, validated together to report the conflicts between namespaces
+		//
+		//     Produces:
+		//     - application/json
+		//
+		//     Schemes: http, https
+		//
+		// responses:
+		//      200: meshValidationsResponse
+		//      500: internalError
+		//
+		{
+			"MeshValidations",
+			"GET",
+			"/api/mesh/validations",
+			handlers.MeshValidations,
+			true,
+		},
 		// swagger:route GET /mesh/tls tls meshTls
 		// ---
 		// Get TLS status for the whole mesh
@@ -1090,6 +1134,153 @@ func NewRoutes() (r *Routes) {
 			handlers.GraphNamespaces,
 			true,
 		},
+		// swagger:route GET /namespaces/graph/diff graphs graphNamespacesDiff
+		// ---
+		// The backing JSON for a namespaces graph comparing two time windows.
+		//
+		//     Produces:
+		//     - application/json
+		//
+		//     Schemes: http, https
+		//
+		// responses:
+		//      400: badRequestError
+		//      500: internalError
+		//      200: graphResponse
+		//
+		{
+			"GraphNamespacesDiff",
+			"GET",
+			"/api/namespaces/graph/diff",
+			handlers.GraphNamespacesDiff,
+			true,
+		},
+		// swagger:route GET /namespaces/graph/stream graphs graphNamespacesStream
+		// ---
+		// A stream of server-sent events for a namespaces graph that is refreshed on an interval. The first
+		// event (graph) is the full graph, each following event (delta) holds only the node and edge changes.
+		//
+		//     Produces:
+		//     - text/event-stream
+		//
+		//     Schemes: http, https
+		//
+		// responses:
+		//      400: badRequestError
+		//      500: internalError
+		//      200: graphResponse
+		//
+		{
+			"GraphNamespacesStream",
+			"GET",
+			"/api/namespaces/graph/stream",
+			handlers.GraphNamespacesStream,
+			true,
+		},
+		// swagger:route POST /namespaces/graph/snapshots graphs graphSnapshotCreate
+		// ---
+		// Generates a namespaces graph and saves it as a graph snapshot, to be replayed later.
+		//
+		//     Produces:
+		//     - application/json
+		//
+		//     Schemes: http, https
+		//
+		// responses:
+		//      400: badRequestError
+		//      500: internalError
+		//      201: graphSnapshotResponse
+		//
+		{
+			"GraphSnapshotCreate",
+			"POST",
+			"/api/namespaces/graph/snapshots",
+			handlers.GraphSnapshotCreate,
+			true,
+		},
+		// swagger:route GET /namespaces/graph/snapshots graphs graphSnapshots
+		// ---
+		// Lists the graph snapshots of accessible namespaces.
+		//
+		//     Produces:
+		//     - application/json
+		//
+		//     Schemes: http, https
+		//
+		// responses:
+		//      500: internalError
+		//      200: graphSnapshotsResponse
+		//
+		{
+			"GraphSnapshots",
+			"GET",
+			"/api/namespaces/graph/snapshots",
+			handlers.GraphSnapshots,
+			true,
+		},
+		// swagger:route GET /namespaces/graph/snapshots/{snapshot} graphs graphSnapshot
+		// ---
+		// Replays a graph snapshot through the requested config vendor, telemetry is not queried.
+		//
+		//     Produces:
+		//     - application/json
+		//
+		//     Schemes: http, https
+		//
+		// responses:
+		//      400: badRequestError
+		//      404: notFoundError
+		//      500: internalError
+		//      200: graphResponse
+		//
+		{
+			"GraphSnapshot",
+			"GET",
+			"/api/namespaces/graph/snapshots/{snapshot}",
+			handlers.GraphSnapshot,
+			true,
+		},
+		// swagger:route DELETE /namespaces/graph/snapshots/{snapshot} graphs graphSnapshotDelete
+		// ---
+		// Deletes a graph snapshot. Snapshots are shared, any user with access to all the namespaces of the
+		// snapshot can delete it, not only its creator.
+		//
+		//     Produces:
+		//     - application/json
+		//
+		//     Schemes: http, https
+		//
+		// responses:
+		//      404: notFoundError
+		//      500: internalError
+		//      204
+		//
+		{
+			"GraphSnapshotDelete",
+			"DELETE",
+			"/api/namespaces/graph/snapshots/{snapshot}",
+			handlers.GraphSnapshotDelete,
+			true,
+		},
+		// swagger:route GET /graph/schema graphs graphSchema
+		// ---
+		// The JSON Schema of the graph returned by the cytoscape config vendor, for the current schema version.
+		//
+		//     Produces:
+		//     - application/schema+json
+		//
+		//     Schemes: http, https
+		//
+		// responses:
+		//      200: graphSchemaResponse
+		//
+		{
+			"GraphSchema",
+			"GET",
+			"/api/graph/schema",
+			handlers.GraphSchema,
+			true,
+		},
 		// swagger:route GET /namespaces/{namespace}/aggregates/{aggregate}/{aggregateValue}/graph graphs graphAggregate
 		// ---
 		// The backing JSON for an aggregate node detail graph. (supported graphTypes: app | versionedApp | workload)
@@ -1219,6 +1410,90 @@ func NewRoutes() (r *Routes) {
 			handlers.GraphNode,
 			true,
 		},
+		// swagger:route GET /namespaces/{namespace}/applications/{app}/versions/{version}/graph/blastradius graphs graphAppVersionBlastRadius
+		// ---
+		// The dependents of a versioned app node, the nodes whose requests depend on the node. (supported graphTypes: app | versionedApp)
+		//
+		//     Produces:
+		//     - application/json
+		//
+		//     Schemes: http, https
+		//
+		// responses:
+		//      400: badRequestError
+		//      500: internalError
+		//      200: graphBlastRadiusResponse
+		//
+		{
+			"GraphAppVersionBlastRadius",
+			"GET",
+			"/api/namespaces/{namespace}/applications/{app}/versions/{version}/graph/blastradius",
+			handlers.GraphNodeBlastRadius,
+			true,
+		},
+		// swagger:route GET /namespaces/{namespace}/applications/{app}/graph/blastradius graphs graphAppBlastRadius
+		// ---
+		// The dependents of an app node, the nodes whose requests depend on the node. (supported graphTypes: app | versionedApp)
+		//
+		//     Produces:
+		//     - application/json
+		//
+		//     Schemes: http, https
+		//
+		// responses:
+		//      400: badRequestError
+		//      500: internalError
+		//      200: graphBlastRadiusResponse
+		//
+		{
+			"GraphAppBlastRadius",
+			"GET",
+			"/api/namespaces/{namespace}/applications/{app}/graph/blastradius",
+			handlers.GraphNodeBlastRadius,
+			true,
+		},
+		// swagger:route GET /namespaces/{namespace}/services/{service}/graph/blastradius graphs graphServiceBlastRadius
+		// ---
+		// The dependents of a service node, the nodes whose requests depend on the node.
+		//
+		//     Produces:
+		//     - application/json
+		//
+		//     Schemes: http, https
+		//
+		// responses:
+		//      400: badRequestError
+		//      500: internalError
+		//      200: graphBlastRadiusResponse
+		//
+		{
+			"GraphServiceBlastRadius",
+			"GET",
+			"/api/namespaces/{namespace}/services/{service}/graph/blastradius",
+			handlers.GraphNodeBlastRadius,
+			true,
+		},
+		// swagger:route GET /namespaces/{namespace}/workloads/{workload}/graph/blastradius graphs graphWorkloadBlastRadius
+		// ---
+		// The dependents of a workload node, the nodes whose requests depend on the node.
+		//
+		//     Produces:
+		//     - application/json
+		//
+		//     Schemes: http, https
+		//
+		// responses:
+		//      400: badRequestError
+		//      500: internalError
+		//      200: graphBlastRadiusResponse
+		//
+		{
+			"GraphWorkloadBlastRadius",
+			"GET",
+			"/api/namespaces/{namespace}/workloads/{workload}/graph/blastradius",
+			handlers.GraphNodeBlastRadius,
+			true,
+		},
 		// swagger:route GET /grafana integrations grafanaInfo
 		// ---
 		// Get the grafana URL and other descriptors
@@ -111,6 +111,19 @@ func istioVersion() (*ExternalServiceInfo, error) {
 	return parseIstioRawVersion(rawVersion)
 }
 
+// IstioVersion returns the version of the running Istio release, e.g. 1.9.0. It is empty for the other Istio
+// implementations and versions (e.g. Maistra, snapshots), whose versions do not identify the proxy version.
+func IstioVersion() (string, error) {
+	product, err := istioVersion()
+	if err != nil {
+		return "", err
+	}
+	if product.Name != "Istio" {
+		return "", nil
+	}
+	return product.Version, nil
+}
+
 func parseIstioRawVersion(rawVersion string) (*ExternalServiceInfo, error) {
 	product := ExternalServiceInfo{Name: "Unknown", Version: "Unknown"}
 
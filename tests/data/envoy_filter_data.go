@@ -0,0 +1,37 @@
+package data
+
+import (
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/kiali/kiali/kubernetes"
+)
+
+func CreateEnvoyFilter(name string, namespace string) kubernetes.IstioObject {
+	return (&kubernetes.GenericIstioObject{
+		ObjectMeta: meta_v1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+		},
+		Spec: map[string]interface{}{},
+	}).DeepCopyIstioObject()
+}
+
+func AddSelectorToEnvoyFilter(selector map[string]interface{}, ef kubernetes.IstioObject) kubernetes.IstioObject {
+	ef.GetSpec()["workloadSelector"] = selector
+	return ef
+}
+
+func AddPatchToEnvoyFilter(applyTo string, match map[string]interface{}, ef kubernetes.IstioObject) kubernetes.IstioObject {
+	patch := map[string]interface{}{
+		"applyTo": applyTo,
+		"patch": map[string]interface{}{
+			"operation": "MERGE",
+		},
+	}
+	if match != nil {
+		patch["match"] = match
+	}
+	patches, _ := ef.GetSpec()["configPatches"].([]interface{})
+	ef.GetSpec()["configPatches"] = append(patches, patch)
+	return ef
+}
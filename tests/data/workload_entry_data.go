@@ -0,0 +1,40 @@
+package data
+
+import (
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/kiali/kiali/kubernetes"
+)
+
+func CreateWorkloadEntry(name string, namespace string, address string, labels map[string]interface{}) kubernetes.IstioObject {
+	return (&kubernetes.GenericIstioObject{
+		ObjectMeta: meta_v1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+		},
+		Spec: map[string]interface{}{
+			"address": address,
+			"labels":  labels,
+		},
+	}).DeepCopyIstioObject()
+}
+
+func AddPortsToWorkloadEntry(ports map[string]interface{}, we kubernetes.IstioObject) kubernetes.IstioObject {
+	we.GetSpec()["ports"] = ports
+	return we
+}
+
+func CreateWorkloadGroup(name string, namespace string, labels map[string]interface{}, template map[string]interface{}) kubernetes.IstioObject {
+	return (&kubernetes.GenericIstioObject{
+		ObjectMeta: meta_v1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+		},
+		Spec: map[string]interface{}{
+			"metadata": map[string]interface{}{
+				"labels": labels,
+			},
+			"template": template,
+		},
+	}).DeepCopyIstioObject()
+}
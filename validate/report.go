@@ -0,0 +1,146 @@
+package validate
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/kiali/kiali/models"
+)
+
+// sortedKeys returns the keys of the validations sorted by namespace, object type and name
+func sortedKeys(validations models.NamespaceValidations) []models.IstioValidationKey {
+	keys := []models.IstioValidationKey{}
+	for _, nsValidations := range validations {
+		for key := range nsValidations {
+			keys = append(keys, key)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].Namespace != keys[j].Namespace {
+			return keys[i].Namespace < keys[j].Namespace
+		}
+		if keys[i].ObjectType != keys[j].ObjectType {
+			return keys[i].ObjectType < keys[j].ObjectType
+		}
+		return keys[i].Name < keys[j].Name
+	})
+	return keys
+}
+
+func lookup(validations models.NamespaceValidations, key models.IstioValidationKey) *models.IstioValidation {
+	return validations[key.Namespace][key]
+}
+
+// writeText writes a line per check, grouped by object, followed by a summary
+func writeText(w io.Writer, validations models.NamespaceValidations) error {
+	keys := sortedKeys(validations)
+	summary := models.IstioValidationSummary{ObjectCount: len(keys)}
+
+	for _, key := range keys {
+		validation := lookup(validations, key)
+		if len(validation.Checks) == 0 {
+			continue
+		}
+		if _, err := fmt.Fprintf(w, "%s/%s/%s\n", key.Namespace, key.ObjectType, key.Name); err != nil {
+			return err
+		}
+		for _, check := range validation.Checks {
+			if check.Severity == models.ErrorSeverity {
+				summary.Errors++
+			} else if check.Severity == models.WarningSeverity {
+				summary.Warnings++
+			}
+			line := fmt.Sprintf("  %-7s %s", check.Severity, check.Message)
+			if check.Path != "" {
+				line += " [" + check.Path + "]"
+			}
+			if _, err := fmt.Fprintln(w, line); err != nil {
+				return err
+			}
+		}
+	}
+
+	_, err := fmt.Fprintf(w, "%d objects validated: %d errors, %d warnings\n", summary.ObjectCount, summary.Errors, summary.Warnings)
+	return err
+}
+
+// writeJSON writes the validations as returned by the mesh validations endpoint
+func writeJSON(w io.Writer, validations models.NamespaceValidations) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(validations)
+}
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name     string          `xml:"name,attr"`
+	Tests    int             `xml:"tests,attr"`
+	Failures int             `xml:"failures,attr"`
+	Cases    []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	ClassName string         `xml:"classname,attr"`
+	Name      string         `xml:"name,attr"`
+	Failures  []junitFailure `xml:"failure,omitempty"`
+	SystemOut string         `xml:"system-out,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// writeJUnit writes a test suite per namespace and a test case per object. The checks with error severity are
+// failures, the warnings are reported as the output of the test case so they do not fail the builds.
+func writeJUnit(w io.Writer, validations models.NamespaceValidations) error {
+	report := junitTestSuites{}
+
+	for _, key := range sortedKeys(validations) {
+		if len(report.Suites) == 0 || report.Suites[len(report.Suites)-1].Name != key.Namespace {
+			report.Suites = append(report.Suites, junitTestSuite{Name: key.Namespace})
+		}
+		suite := &report.Suites[len(report.Suites)-1]
+
+		testCase := junitTestCase{ClassName: key.Namespace + "." + key.ObjectType, Name: key.Name}
+		warnings := []string{}
+		for _, check := range lookup(validations, key).Checks {
+			if check.Severity == models.ErrorSeverity {
+				testCase.Failures = append(testCase.Failures, junitFailure{Message: check.Message, Type: string(check.Severity), Text: check.Path})
+			} else {
+				warnings = append(warnings, fmt.Sprintf("%s: %s [%s]", check.Severity, check.Message, check.Path))
+			}
+		}
+		testCase.SystemOut = strings.Join(warnings, "\n")
+
+		suite.Cases = append(suite.Cases, testCase)
+		suite.Tests++
+		report.Tests++
+		if len(testCase.Failures) > 0 {
+			suite.Failures++
+			report.Failures++
+		}
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(report); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
@@ -0,0 +1,125 @@
+// Package validate implements the validate command, running the Istio validations of Kiali on local manifests
+// instead of a cluster, e.g. in CI pipelines.
+package validate
+
+import (
+	"flag"
+	"fmt"
+	"io"
+
+	core_v1 "k8s.io/api/core/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/kiali/kiali/business"
+	"github.com/kiali/kiali/config"
+	"github.com/kiali/kiali/kubernetes/memory"
+	"github.com/kiali/kiali/log"
+	"github.com/kiali/kiali/models"
+)
+
+// Exit codes of the validate command
+const (
+	ExitValid   = 0 // no validation with error severity
+	ExitInvalid = 1 // at least one validation with error severity
+	ExitFailure = 2 // the command could not validate the manifests
+)
+
+var writers = map[string]func(io.Writer, models.NamespaceValidations) error{
+	"json":  writeJSON,
+	"junit": writeJUnit,
+	"text":  writeText,
+}
+
+// Run runs the validate command with its arguments, writing the report to out. It returns the exit code.
+func Run(args []string, out io.Writer) int {
+	flags := flag.NewFlagSet("validate", flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: kiali validate [flags] DIR...\n\nValidates the Istio objects of the Kubernetes and Istio manifests of the directories.\n\nFlags:\n")
+		flags.PrintDefaults()
+	}
+	configFile := flags.String("config", "", "Path to the YAML configuration file, e.g. to set the Istio namespace or the excluded namespaces.")
+	namespace := flags.String("namespace", "default", "Namespace of the objects without namespace.")
+	output := flags.String("output", "text", "Output format: text, json or junit.")
+	if err := flags.Parse(args); err != nil {
+		return ExitFailure
+	}
+
+	write, found := writers[*output]
+	if !found || flags.NArg() == 0 {
+		flags.Usage()
+		return ExitFailure
+	}
+
+	if err := loadConfig(*configFile); err != nil {
+		log.Errorf("Unable to load the configuration: %v", err)
+		return ExitFailure
+	}
+
+	client := memory.NewClient()
+	for _, dir := range flags.Args() {
+		if err := client.LoadDir(dir, *namespace); err != nil {
+			log.Errorf("Unable to load the manifests: %v", err)
+			return ExitFailure
+		}
+	}
+	if err := addDefaultIstioConfigMap(client); err != nil {
+		log.Errorf("Unable to add the Istio ConfigMap: %v", err)
+		return ExitFailure
+	}
+
+	validations, err := business.NewWithBackends(client, nil, nil).Validations.GetMeshValidations()
+	if err != nil {
+		log.Errorf("Unable to validate the manifests: %v", err)
+		return ExitFailure
+	}
+
+	if err := write(out, validations); err != nil {
+		log.Errorf("Unable to write the validations: %v", err)
+		return ExitFailure
+	}
+
+	if hasErrors(validations) {
+		return ExitInvalid
+	}
+	return ExitValid
+}
+
+func loadConfig(configFile string) error {
+	cfg := config.NewConfig()
+	if configFile != "" {
+		c, err := config.LoadFromFile(configFile)
+		if err != nil {
+			return err
+		}
+		cfg = c
+	}
+	// There is no control plane to get the Istio version from
+	cfg.ExternalServices.Istio.UrlServiceVersion = ""
+	config.Set(cfg)
+	return nil
+}
+
+// addDefaultIstioConfigMap adds an empty Istio ConfigMap, i.e. the default mesh config, when the manifests have none
+func addDefaultIstioConfigMap(client *memory.Client) error {
+	cfg := config.Get()
+	if _, err := client.GetConfigMap(cfg.IstioNamespace, cfg.ExternalServices.Istio.ConfigMapName); err == nil {
+		return nil
+	}
+	log.Debugf("No Istio ConfigMap [%s] in namespace [%s], using the default mesh config", cfg.ExternalServices.Istio.ConfigMapName, cfg.IstioNamespace)
+	return client.AddObject(&core_v1.ConfigMap{
+		ObjectMeta: meta_v1.ObjectMeta{Name: cfg.ExternalServices.Istio.ConfigMapName, Namespace: cfg.IstioNamespace},
+	})
+}
+
+func hasErrors(validations models.NamespaceValidations) bool {
+	for _, nsValidations := range validations {
+		for _, validation := range nsValidations {
+			for _, check := range validation.Checks {
+				if check.Severity == models.ErrorSeverity {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
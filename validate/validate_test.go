@@ -0,0 +1,183 @@
+package validate
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/kiali/kiali/models"
+)
+
+const workloadManifests = `
+apiVersion: v1
+kind: Service
+metadata:
+  name: reviews
+  namespace: bookinfo
+spec:
+  selector:
+    app: reviews
+  ports:
+  - name: http
+    port: 9080
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: reviews-v1
+  namespace: bookinfo
+spec:
+  selector:
+    matchLabels:
+      app: reviews
+      version: v1
+  template:
+    metadata:
+      labels:
+        app: reviews
+        version: v1
+    spec:
+      containers:
+      - name: reviews
+        image: reviews
+`
+
+const validIstioManifests = `
+apiVersion: networking.istio.io/v1alpha3
+kind: DestinationRule
+metadata:
+  name: reviews
+  namespace: bookinfo
+spec:
+  host: reviews
+  subsets:
+  - name: v1
+    labels:
+      version: v1
+`
+
+// The v2 subset selects no workload (error) and the virtual service routes to an unknown subset (warning)
+const invalidIstioManifests = `
+apiVersion: networking.istio.io/v1alpha3
+kind: DestinationRule
+metadata:
+  name: reviews
+  namespace: bookinfo
+spec:
+  host: reviews
+  subsets:
+  - name: v1
+    labels:
+      version: v1
+  - name: v2
+    labels:
+      version: v2
+---
+apiVersion: networking.istio.io/v1alpha3
+kind: VirtualService
+metadata:
+  name: reviews
+  namespace: bookinfo
+spec:
+  hosts:
+  - reviews
+  http:
+  - route:
+    - destination:
+        host: reviews
+        subset: v1
+      weight: 50
+    - destination:
+        host: reviews
+        subset: v3
+      weight: 50
+`
+
+func writeManifests(t *testing.T, istioManifests string) string {
+	dir, err := ioutil.TempDir("", "validate")
+	assert.NoError(t, err)
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	assert.NoError(t, os.Mkdir(filepath.Join(dir, "istio"), 0755))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "workloads.yaml"), []byte(workloadManifests), 0644))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "istio", "reviews.yml"), []byte(istioManifests), 0644))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "README.md"), []byte("# Not a manifest"), 0644))
+	return dir
+}
+
+func TestRunValid(t *testing.T) {
+	assert := assert.New(t)
+	dir := writeManifests(t, validIstioManifests)
+
+	out := &bytes.Buffer{}
+	assert.Equal(ExitValid, Run([]string{dir}, out))
+	assert.Contains(out.String(), "1 objects validated: 0 errors, 0 warnings")
+}
+
+func TestRunText(t *testing.T) {
+	assert := assert.New(t)
+	dir := writeManifests(t, invalidIstioManifests)
+
+	out := &bytes.Buffer{}
+	assert.Equal(ExitInvalid, Run([]string{dir}, out))
+	assert.Contains(out.String(), "bookinfo/destinationrule/reviews\n  error   KIA0203")
+	assert.Contains(out.String(), "bookinfo/virtualservice/reviews\n  warning KIA1107")
+	assert.Contains(out.String(), "2 objects validated: 1 errors, 1 warnings")
+}
+
+func TestRunJSON(t *testing.T) {
+	assert := assert.New(t)
+	dir := writeManifests(t, invalidIstioManifests)
+
+	out := &bytes.Buffer{}
+	assert.Equal(ExitInvalid, Run([]string{"-output", "json", dir}, out))
+
+	validations := map[string]map[string]map[string]models.IstioValidation{}
+	assert.NoError(json.Unmarshal(out.Bytes(), &validations))
+	assert.False(validations["bookinfo"]["destinationrule"]["reviews"].Valid)
+	assert.Equal(models.ErrorSeverity, validations["bookinfo"]["destinationrule"]["reviews"].Checks[0].Severity)
+	assert.True(validations["bookinfo"]["virtualservice"]["reviews"].Valid)
+	assert.Equal(models.WarningSeverity, validations["bookinfo"]["virtualservice"]["reviews"].Checks[0].Severity)
+}
+
+func TestRunJUnit(t *testing.T) {
+	assert := assert.New(t)
+	dir := writeManifests(t, invalidIstioManifests)
+
+	out := &bytes.Buffer{}
+	assert.Equal(ExitInvalid, Run([]string{"-output", "junit", dir}, out))
+
+	report := junitTestSuites{}
+	assert.NoError(xml.Unmarshal(out.Bytes(), &report))
+	assert.Equal(2, report.Tests)
+	assert.Equal(1, report.Failures)
+	assert.Len(report.Suites, 1)
+	assert.Equal("bookinfo", report.Suites[0].Name)
+
+	cases := report.Suites[0].Cases
+	assert.Len(cases, 2)
+	assert.Equal("bookinfo.destinationrule", cases[0].ClassName)
+	assert.Len(cases[0].Failures, 1)
+	assert.Equal("spec/subsets[1]", cases[0].Failures[0].Text)
+	assert.Equal("bookinfo.virtualservice", cases[1].ClassName)
+	assert.Empty(cases[1].Failures)
+	assert.Contains(cases[1].SystemOut, "KIA1107")
+}
+
+func TestRunFailures(t *testing.T) {
+	assert := assert.New(t)
+	dir := writeManifests(t, validIstioManifests)
+
+	assert.Equal(ExitFailure, Run([]string{}, ioutil.Discard))
+	assert.Equal(ExitFailure, Run([]string{"-output", "xml", dir}, ioutil.Discard))
+	assert.Equal(ExitFailure, Run([]string{filepath.Join(dir, "missing")}, ioutil.Discard))
+
+	assert.NoError(ioutil.WriteFile(filepath.Join(dir, "broken.yaml"), []byte("kind: Service\nspec: [\n"), 0644))
+	assert.Equal(ExitFailure, Run([]string{dir}, ioutil.Discard))
+}